@@ -0,0 +1,187 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/models"
+
+	"github.com/labstack/echo"
+	"github.com/uber-go/zap"
+)
+
+//APIKeyWithToken is returned when an API key is created or rotated, the only time its token is available
+type APIKeyWithToken struct {
+	*models.APIKey
+	Token string `json:"token"`
+}
+
+//CreateAPIKeyHandler is the handler responsible for creating API keys of a game
+func CreateAPIKeyHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		gameID := c.Param("gameID")
+		l := app.Logger.With(
+			zap.String("source", "CreateAPIKeyHandler"),
+			zap.String("operation", "CreateAPIKey"),
+			zap.String("gameID", gameID),
+		)
+		c.Set("route", "CreateAPIKey")
+
+		log.D(l, "Creating API key...")
+
+		var payload CreateAPIKeyPayload
+		err := WithSegment("payload", c, func() error {
+			if err := LoadJSONPayload(&payload, c, l); err != nil {
+				log.E(l, "Invalid json payload!", func(cm log.CM) {
+					cm.Write(zap.Error(err))
+				})
+				return err
+			}
+
+			return nil
+		})
+		if err != nil {
+			return FailWithError(400, err, c)
+		}
+
+		var key *models.APIKey
+		var token string
+		err = WithSegment("model", c, func() error {
+			_, err := models.GetGameByID(gameID, app.MongoDb, app.Logger)
+			if err != nil {
+				return err
+			}
+
+			key, token, err = models.CreateAPIKey(
+				gameID, payload.Name, payload.Scopes,
+				&models.RealClock{}, app.MongoDb, app.Logger,
+			)
+			return err
+		})
+		if _, ok := err.(*errors.DocumentNotFoundError); ok {
+			return FailWithError(404, err, c)
+		}
+		if err != nil {
+			log.E(l, "Failed to create API key!", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+
+		log.I(l, "Created API key successfully.", func(cm log.CM) {
+			cm.Write(zap.String("keyID", key.ID))
+		})
+		return c.JSON(http.StatusOK, &APIKeyWithToken{APIKey: key, Token: token})
+	}
+}
+
+//GetAPIKeysHandler is the handler responsible for listing the API keys of a game
+func GetAPIKeysHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		gameID := c.Param("gameID")
+		l := app.Logger.With(
+			zap.String("source", "GetAPIKeysHandler"),
+			zap.String("operation", "GetAPIKeys"),
+			zap.String("gameID", gameID),
+		)
+		c.Set("route", "GetAPIKeys")
+
+		var keys []*models.APIKey
+		err := WithSegment("model", c, func() error {
+			var err error
+			keys, err = models.GetAPIKeys(gameID, app.MongoDb, app.Logger)
+			return err
+		})
+		if err != nil {
+			log.E(l, "Failed to get API keys!", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+
+		return c.JSON(http.StatusOK, keys)
+	}
+}
+
+//RotateAPIKeyHandler is the handler responsible for replacing an API key of a game. The replaced key
+//keeps working for graceSeconds (query string, defaults to api.apiKeys.rotationGraceSeconds).
+func RotateAPIKeyHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		gameID := c.Param("gameID")
+		keyID := c.Param("keyID")
+		l := app.Logger.With(
+			zap.String("source", "RotateAPIKeyHandler"),
+			zap.String("operation", "RotateAPIKey"),
+			zap.String("gameID", gameID),
+			zap.String("keyID", keyID),
+		)
+		c.Set("route", "RotateAPIKey")
+
+		graceSeconds := app.Config.GetInt("api.apiKeys.rotationGraceSeconds")
+		if value := c.QueryParam("graceSeconds"); value != "" {
+			var err error
+			graceSeconds, err = strconv.Atoi(value)
+			if err != nil || graceSeconds < 0 {
+				return FailWith(400, "graceSeconds must be a non-negative integer", c)
+			}
+		}
+
+		var key *models.APIKey
+		var token string
+		err := WithSegment("model", c, func() error {
+			var err error
+			key, token, err = models.RotateAPIKey(
+				gameID, keyID, graceSeconds,
+				&models.RealClock{}, app.MongoDb, app.Logger,
+			)
+			return err
+		})
+		if _, ok := err.(*errors.DocumentNotFoundError); ok {
+			return FailWithError(404, err, c)
+		}
+		if err != nil {
+			log.E(l, "Failed to rotate API key!", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+
+		log.I(l, "Rotated API key successfully.", func(cm log.CM) {
+			cm.Write(zap.String("newKeyID", key.ID))
+		})
+		return c.JSON(http.StatusOK, &APIKeyWithToken{APIKey: key, Token: token})
+	}
+}
+
+//RevokeAPIKeyHandler is the handler responsible for revoking an API key of a game
+func RevokeAPIKeyHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		gameID := c.Param("gameID")
+		keyID := c.Param("keyID")
+		l := app.Logger.With(
+			zap.String("source", "RevokeAPIKeyHandler"),
+			zap.String("operation", "RevokeAPIKey"),
+			zap.String("gameID", gameID),
+			zap.String("keyID", keyID),
+		)
+		c.Set("route", "RevokeAPIKey")
+
+		err := WithSegment("model", c, func() error {
+			return models.RevokeAPIKey(gameID, keyID, app.MongoDb, app.Logger)
+		})
+		if _, ok := err.(*errors.DocumentNotFoundError); ok {
+			return FailWithError(404, err, c)
+		}
+		if err != nil {
+			log.E(l, "Failed to revoke API key!", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+
+		log.I(l, "Revoked API key successfully.")
+		return c.String(http.StatusOK, "{\"success\":true}")
+	}
+}
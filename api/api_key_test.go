@@ -0,0 +1,211 @@
+package api_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/api"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("API Key Handler", func() {
+	var logger zap.Logger
+	var app *api.App
+	var game *models.Game
+
+	createKey := func(scopes ...string) *api.APIKeyWithToken {
+		payload := &api.CreateAPIKeyPayload{Name: "server", Scopes: scopes}
+		jsonPayload, err := payload.ToJSON()
+		Expect(err).NotTo(HaveOccurred())
+
+		status, body := Post(app, fmt.Sprintf("/games/%s/api-keys", game.ID), string(jsonPayload))
+		Expect(status).To(Equal(http.StatusOK), body)
+
+		key := &api.APIKeyWithToken{}
+		err = json.Unmarshal([]byte(body), key)
+		Expect(err).NotTo(HaveOccurred())
+		return key
+	}
+
+	updateGame := func(gameID, token string) (int, string) {
+		payload := &api.UpdateGamePayload{
+			Name:                         "game",
+			DonationCooldownHours:        8,
+			DonationRequestCooldownHours: 24,
+		}
+		jsonPayload, err := payload.ToJSON()
+		Expect(err).NotTo(HaveOccurred())
+		url := fmt.Sprintf("/games/%s", gameID)
+		return PutWithHeaders(app, url, string(jsonPayload), map[string]string{api.APIKeyHeader: token})
+	}
+
+	createDonationRequest := func(token string) (int, string) {
+		payload := &api.CreateDonationRequestPayload{
+			Item:   GetFirstItem(game).Key,
+			Player: "player-0",
+			Clan:   "clan-0",
+		}
+		jsonPayload, err := payload.ToJSON()
+		Expect(err).NotTo(HaveOccurred())
+		url := fmt.Sprintf("/games/%s/donation-requests", game.ID)
+		return PostWithHeaders(app, url, string(jsonPayload), map[string]string{api.APIKeyHeader: token})
+	}
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		app = GetDefaultTestApp(logger)
+
+		var err error
+		game, err = GetTestGame(app.MongoDb, app.Logger, true)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		app.Stop()
+	})
+
+	Describe("Create API Key", func() {
+		It("Should create a key and return its token once", func() {
+			key := createKey(models.APIKeyScopeAdmin, models.APIKeyScopeClient)
+			Expect(key.ID).NotTo(BeEmpty())
+			Expect(key.Token).To(HavePrefix(key.ID + "."))
+			Expect(key.Scopes).To(Equal([]string{models.APIKeyScopeAdmin, models.APIKeyScopeClient}))
+
+			status, body := Get(app, fmt.Sprintf("/games/%s/api-keys", game.ID))
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).NotTo(ContainSubstring("token"))
+			Expect(body).NotTo(ContainSubstring("secretHash"))
+
+			var keys []*models.APIKey
+			err := json.Unmarshal([]byte(body), &keys)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keys).To(HaveLen(1))
+			Expect(keys[0].ID).To(Equal(key.ID))
+		})
+
+		It("Should fail with invalid scopes", func() {
+			payload := &api.CreateAPIKeyPayload{Name: "server", Scopes: []string{"root"}}
+			jsonPayload, err := payload.ToJSON()
+			Expect(err).NotTo(HaveOccurred())
+
+			status, body := Post(app, fmt.Sprintf("/games/%s/api-keys", game.ID), string(jsonPayload))
+			Expect(status).To(Equal(http.StatusBadRequest))
+			Expect(body).To(ContainSubstring("root is not a valid scope"))
+		})
+	})
+
+	Describe("API Key Middleware", func() {
+		It("Should not require keys when disabled", func() {
+			status, body := updateGame(game.ID, "")
+			Expect(status).To(Equal(http.StatusOK), body)
+		})
+
+		It("Should require a valid key of the game", func() {
+			app.Config.Set("api.apiKeys.enabled", true)
+
+			status, _ := updateGame(game.ID, "")
+			Expect(status).To(Equal(http.StatusUnauthorized))
+
+			status, _ = updateGame(game.ID, "invalid.token")
+			Expect(status).To(Equal(http.StatusUnauthorized))
+
+			app.Config.Set("api.apiKeys.enabled", false)
+			key := createKey(models.APIKeyScopeAdmin)
+			app.Config.Set("api.apiKeys.enabled", true)
+
+			status, body := updateGame(game.ID, key.Token)
+			Expect(status).To(Equal(http.StatusOK), body)
+
+			other, err := GetTestGame(app.MongoDb, app.Logger, true)
+			Expect(err).NotTo(HaveOccurred())
+			status, body = updateGame(other.ID, key.Token)
+			Expect(status).To(Equal(http.StatusForbidden))
+			Expect(body).To(ContainSubstring(fmt.Sprintf("can't access the admin scope of game %s", other.ID)))
+		})
+
+		It("Should require the scope of the route", func() {
+			key := createKey(models.APIKeyScopeClient)
+			app.Config.Set("api.apiKeys.enabled", true)
+
+			status, body := createDonationRequest(key.Token)
+			Expect(status).To(Equal(http.StatusOK), body)
+
+			status, _ = updateGame(game.ID, key.Token)
+			Expect(status).To(Equal(http.StatusForbidden))
+		})
+
+		It("Should accept the global basic auth credentials for every game", func() {
+			app.Config.Set("api.basicAuth.user", "operator")
+			app.Config.Set("api.basicAuth.pass", "secret")
+			app.Config.Set("api.apiKeys.enabled", true)
+
+			auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("operator:secret"))
+			payload := &api.CreateAPIKeyPayload{Name: "server", Scopes: []string{models.APIKeyScopeClient}}
+			jsonPayload, err := payload.ToJSON()
+			Expect(err).NotTo(HaveOccurred())
+
+			url := fmt.Sprintf("/games/%s/api-keys", game.ID)
+			status, body := PostWithHeaders(app, url, string(jsonPayload), map[string]string{"Authorization": auth})
+			Expect(status).To(Equal(http.StatusOK), body)
+		})
+
+		It("Should record the key name as the actor of changes", func() {
+			key := createKey(models.APIKeyScopeAdmin)
+			app.Config.Set("api.apiKeys.enabled", true)
+
+			status, body := updateGame(game.ID, key.Token)
+			Expect(status).To(Equal(http.StatusOK), body)
+
+			entries, err := models.GetAuditLog(game.ID, 1, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries[0].Actor).To(Equal("server"))
+		})
+	})
+
+	Describe("Rotate API Key", func() {
+		It("Should return a new token and expire the old key", func() {
+			key := createKey(models.APIKeyScopeAdmin)
+
+			status, body := Post(app, fmt.Sprintf("/games/%s/api-keys/%s/rotate?graceSeconds=0", game.ID, key.ID), "")
+			Expect(status).To(Equal(http.StatusOK), body)
+
+			var rotated api.APIKeyWithToken
+			err := json.Unmarshal([]byte(body), &rotated)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rotated.ID).NotTo(Equal(key.ID))
+
+			app.Config.Set("api.apiKeys.enabled", true)
+			status, _ = updateGame(game.ID, key.Token)
+			Expect(status).To(Equal(http.StatusUnauthorized))
+			status, body = updateGame(game.ID, rotated.Token)
+			Expect(status).To(Equal(http.StatusOK), body)
+		})
+
+		It("Should fail for keys that do not exist", func() {
+			status, _ := Post(app, fmt.Sprintf("/games/%s/api-keys/invalid-key/rotate", game.ID), "")
+			Expect(status).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("Revoke API Key", func() {
+		It("Should remove the key", func() {
+			key := createKey(models.APIKeyScopeAdmin)
+
+			status, _ := Delete(app, fmt.Sprintf("/games/%s/api-keys/%s", game.ID, key.ID), "")
+			Expect(status).To(Equal(http.StatusOK))
+
+			status, _ = Delete(app, fmt.Sprintf("/games/%s/api-keys/%s", game.ID, key.ID), "")
+			Expect(status).To(Equal(http.StatusNotFound))
+		})
+	})
+})
@@ -2,13 +2,19 @@ package api
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"os/signal"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	mgo "gopkg.in/mgo.v2"
 	redsync "gopkg.in/redsync.v1"
 
+	"google.golang.org/grpc"
+
 	"github.com/garyburd/redigo/redis"
 	raven "github.com/getsentry/raven-go"
 	"github.com/labstack/echo"
@@ -18,8 +24,15 @@ import (
 	"github.com/labstack/echo/middleware"
 	newrelic "github.com/newrelic/go-agent"
 	"github.com/spf13/viper"
+	"github.com/topfreegames/donations/events"
 	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/metrics"
+	"github.com/topfreegames/donations/models"
+	"github.com/topfreegames/donations/stream"
+	"github.com/topfreegames/donations/tracing"
+	"github.com/topfreegames/donations/webhooks"
 	"github.com/uber-go/zap"
+	"golang.org/x/net/context"
 )
 
 // App is a struct that represents a Donations Api APP
@@ -37,12 +50,55 @@ type App struct {
 	Background   bool
 	Fast         bool
 	Redsync      *redsync.Redsync
-	Redis        redis.Conn
+	Redis        *redis.Pool
 	NewRelic     newrelic.Application
+	Tracer       tracing.Tracer
+	Webhooks     *webhooks.Worker
+	Events       events.EventPublisher
+	Stream       *stream.Hub
+	GRPC         *grpc.Server
+	Admin        *echo.Echo
+	AdminEngine  engine.Server
+	status       int32
+	expirerStop  chan struct{}
+	expirerDone  chan struct{}
+}
+
+const (
+	//AppStatusStarting is the status of an app that was not started yet
+	AppStatusStarting = "starting"
+	//AppStatusReady is the status of an app serving requests
+	AppStatusReady = "ready"
+	//AppStatusStopping is the status of an app being stopped
+	AppStatusStopping = "stopping"
+)
+
+//status values of App.status, indexes of appStatuses
+const (
+	appStarting int32 = iota
+	appReady
+	appStopping
+)
+
+var appStatuses = []string{AppStatusStarting, AppStatusReady, AppStatusStopping}
+
+//AppOption changes an application before it is configured
+type AppOption func(app *App)
+
+//WithConfig returns an option that sets a configuration key, overriding the configuration file and
+//the environment variables. Keys read when the application is configured, such as the ones of the
+//routes, can only be changed with it.
+func WithConfig(key string, value interface{}) AppOption {
+	return func(app *App) {
+		app.Config.Set(key, value)
+	}
 }
 
 // GetApp returns a new Donations Application
-func GetApp(host string, port int, configPath string, debug bool, logger zap.Logger, background bool, fast bool) (*App, error) {
+func GetApp(
+	host string, port int, configPath string, debug bool, logger zap.Logger, background bool, fast bool,
+	options ...AppOption,
+) (*App, error) {
 	app := &App{
 		Host:       host,
 		Port:       port,
@@ -53,6 +109,9 @@ func GetApp(host string, port int, configPath string, debug bool, logger zap.Log
 		Background: background,
 		Fast:       fast,
 	}
+	for _, option := range options {
+		option(app)
+	}
 	err := app.Configure()
 	if err != nil {
 		return nil, err
@@ -71,6 +130,12 @@ func (app *App) Configure() error {
 	app.configureSentry()
 	app.configureNewRelic()
 
+	err = app.configureTracing()
+	if err != nil {
+		app.Logger.Error("Failed to configure tracing.", zap.Error(err))
+		return err
+	}
+
 	err = app.configureApplication()
 	if err != nil {
 		app.Logger.Error("Failed to configure application.", zap.Error(err))
@@ -120,15 +185,93 @@ func (app *App) configureNewRelic() error {
 	return nil
 }
 
+func (app *App) configureTracing() error {
+	l := app.Logger.With(
+		zap.String("source", "app"),
+		zap.String("operation", "configureTracing"),
+		zap.String("exporters", app.Config.GetString("tracing.exporters")),
+	)
+
+	tracer, err := tracing.NewTracer(app.Config, app.NewRelic)
+	if err != nil {
+		return err
+	}
+
+	app.Tracer = tracer
+	l.Info("Configured tracing successfully.")
+	return nil
+}
+
 func (app *App) setConfigurationDefaults() {
 	app.Config.SetDefault("healthcheck.workingText", "WORKING")
+	app.Config.SetDefault("healthcheck.timeoutMilliseconds", 1000)
+	app.Config.SetDefault("healthcheck.shutdownDelaySeconds", 5)
 	app.Config.SetDefault("api.maxReadBufferSize", 32000)
+	app.Config.SetDefault("api.idempotency.ttlSeconds", 86400)
+	app.Config.SetDefault("api.donationLock.enabled", true)
+	app.Config.SetDefault("api.batch.maxDonations", 100)
+	app.Config.SetDefault("api.apiKeys.enabled", false)
+	app.Config.SetDefault("api.apiKeys.rotationGraceSeconds", 86400)
+	app.Config.SetDefault("api.rateLimit.enabled", false)
+	app.Config.SetDefault("api.rateLimit.forwardedForHeader", "")
+	app.Config.SetDefault("api.rateLimit.trustedProxies", 1)
+	app.Config.SetDefault("api.admin.port", 0)
+	app.Config.SetDefault("donationRequests.expirationHours", 720)
+	app.Config.SetDefault("donationRequests.expirer.enabled", true)
+	app.Config.SetDefault("donationRequests.expirer.intervalSeconds", 60)
+	app.Config.SetDefault("archive.maxAgeHours", 720)
+	app.Config.SetDefault("verification.minDonationAgeSeconds", 300)
+	app.Config.SetDefault("audit.defaultLimit", 50)
+
+	app.Config.SetDefault("metrics.enabled", true)
+
+	app.Config.SetDefault("tracing.exporters", "newrelic")
+	app.Config.SetDefault("tracing.opentelemetry.endpoint", "localhost:4318")
+	app.Config.SetDefault("tracing.opentelemetry.insecure", true)
+	app.Config.SetDefault("tracing.opentelemetry.serviceName", "donations")
+	app.Config.SetDefault("tracing.opentelemetry.sampleRatio", 1.0)
+
+	app.Config.SetDefault("grpc.enabled", true)
+	app.Config.SetDefault("grpc.port", 8889)
+
+	app.Config.SetDefault("webhooks.maxAttempts", 8)
+	app.Config.SetDefault("webhooks.backoffBaseSeconds", 10)
+	app.Config.SetDefault("webhooks.backoffMaxSeconds", 3600)
+	app.Config.SetDefault("webhooks.timeoutMilliseconds", 5000)
+	app.Config.SetDefault("webhooks.worker.enabled", true)
+	app.Config.SetDefault("webhooks.worker.intervalMilliseconds", 1000)
+	app.Config.SetDefault("webhooks.worker.batchSize", 100)
+
+	app.Config.SetDefault("stream.enabled", true)
+	app.Config.SetDefault("stream.historySize", 100)
+	app.Config.SetDefault("stream.historyTTLSeconds", 86400)
+	app.Config.SetDefault("stream.bufferSize", 100)
+	app.Config.SetDefault("stream.keepAliveSeconds", 15)
+
+	app.Config.SetDefault("events.publisher", "none")
+	app.Config.SetDefault("events.file.path", "./events.jsonl")
+	app.Config.SetDefault("events.http.url", "")
+	app.Config.SetDefault("events.http.timeoutMilliseconds", 1000)
+	app.Config.SetDefault("events.http.bufferSize", 10000)
+	app.Config.SetDefault("events.kafka.brokers", "localhost:9092")
+	app.Config.SetDefault("events.kafka.topic", "donations-events")
+	app.Config.SetDefault("events.kafka.bufferSize", 10000)
 
 	app.Config.SetDefault("mongo.host", "localhost")
 	app.Config.SetDefault("mongo.port", 27017)
 	app.Config.SetDefault("mongo.user", "")
 	app.Config.SetDefault("mongo.password", "")
 	app.Config.SetDefault("mongo.db", "donations")
+	app.Config.SetDefault("mongo.indexCheck", "warn")
+
+	app.Config.SetDefault("redis.maxIdle", 3)
+	app.Config.SetDefault("redis.maxActive", 0)
+	app.Config.SetDefault("redis.idleTimeoutSeconds", 240)
+	app.Config.SetDefault("redis.connectTimeoutMilliseconds", 1000)
+	app.Config.SetDefault("redis.readTimeoutMilliseconds", 1000)
+	app.Config.SetDefault("redis.writeTimeoutMilliseconds", 1000)
+	app.Config.SetDefault("redis.healthCheckIntervalSeconds", 10)
+	app.Config.SetDefault("redis.waitForConnection", false)
 }
 
 func (app *App) loadConfiguration() error {
@@ -172,12 +315,59 @@ func (app *App) OnErrorHandler(err error, stack []byte) {
 
 func (app *App) configureRedis() error {
 	redisURL := app.Config.GetString("redis.url")
+	maxIdle := app.Config.GetInt("redis.maxIdle")
+	maxActive := app.Config.GetInt("redis.maxActive")
+	idleTimeout := time.Duration(app.Config.GetInt("redis.idleTimeoutSeconds")) * time.Second
+	connectTimeout := time.Duration(app.Config.GetInt("redis.connectTimeoutMilliseconds")) * time.Millisecond
+	readTimeout := time.Duration(app.Config.GetInt("redis.readTimeoutMilliseconds")) * time.Millisecond
+	writeTimeout := time.Duration(app.Config.GetInt("redis.writeTimeoutMilliseconds")) * time.Millisecond
+	healthCheckInterval := time.Duration(app.Config.GetInt("redis.healthCheckIntervalSeconds")) * time.Second
 	l := app.Logger.With(
 		zap.String("operation", "configureRedis"),
 		zap.String("redis.url", app.Config.GetString("redis.url")),
 	)
 
-	conn, err := redis.DialURL(redisURL)
+	app.Redis = &redis.Pool{
+		MaxIdle:     maxIdle,
+		MaxActive:   maxActive,
+		IdleTimeout: idleTimeout,
+		Wait:        app.Config.GetBool("redis.waitForConnection"),
+		Dial: func() (redis.Conn, error) {
+			log.D(l, "Connecting to redis...")
+			conn, err := redis.DialURL(
+				redisURL,
+				redis.DialConnectTimeout(connectTimeout),
+				redis.DialReadTimeout(readTimeout),
+				redis.DialWriteTimeout(writeTimeout),
+			)
+			if err != nil {
+				log.E(l, "Failed to connect to redis.", func(cm log.CM) {
+					cm.Write(zap.Error(err))
+				})
+				return nil, err
+			}
+			return metrics.NewRedisConn(conn), nil
+		},
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			//Connections used recently are assumed to be healthy
+			if time.Since(t) < healthCheckInterval {
+				return nil
+			}
+			log.D(l, "Pinging redis...")
+			_, err := c.Do("PING")
+			if err != nil {
+				log.E(l, "Failed to ping redis.", func(cm log.CM) {
+					cm.Write(zap.Error(err))
+				})
+				return err
+			}
+			return nil
+		},
+	}
+
+	conn := app.Redis.Get()
+	defer conn.Close()
+	_, err := conn.Do("PING")
 	if err != nil {
 		log.E(l, "Failed to connect to redis.", func(cm log.CM) {
 			cm.Write(zap.Error(err))
@@ -185,59 +375,96 @@ func (app *App) configureRedis() error {
 		return err
 	}
 
-	app.Redis = conn
 	return nil
 }
 
 func (app *App) configureRedsync() error {
+	app.Redsync = redsync.New([]redsync.Pool{app.Redis})
+	return nil
+}
+
+func (app *App) configureClanStream() {
+	if !app.Config.GetBool("stream.enabled") {
+		app.Logger.Info("Clan streams are not enabled.", zap.String("operation", "configureClanStream"))
+		return
+	}
+
 	redisURL := app.Config.GetString("redis.url")
-	maxIdle := app.Config.GetInt("redis.maxIdle")
-	timeoutSeconds := app.Config.GetInt("redis.idleTimeoutSeconds")
+	connectTimeout := time.Duration(app.Config.GetInt("redis.connectTimeoutMilliseconds")) * time.Millisecond
+	app.Stream = stream.NewHub(
+		app.Redis,
+		func() (redis.Conn, error) {
+			//Subscriber connections wait for events, so they have no read timeout
+			return redis.DialURL(redisURL, redis.DialConnectTimeout(connectTimeout))
+		},
+		app.Config.GetInt("stream.historySize"),
+		time.Duration(app.Config.GetInt("stream.historyTTLSeconds"))*time.Second,
+		app.Config.GetInt("stream.bufferSize"),
+		app.Logger,
+	)
+}
+
+func (app *App) configureEventPublisher() error {
 	l := app.Logger.With(
-		zap.String("operation", "configureRedsync"),
-		zap.String("redis.url", app.Config.GetString("redis.url")),
+		zap.String("operation", "configureEventPublisher"),
+		zap.String("events.publisher", app.Config.GetString("events.publisher")),
 	)
 
-	app.Redsync = redsync.New([]redsync.Pool{
-		&redis.Pool{
-			MaxIdle:     maxIdle,
-			IdleTimeout: time.Duration(timeoutSeconds) * time.Second,
-			Dial: func() (redis.Conn, error) {
-				log.I(l, "Connecting to redis...")
-				conn, err := redis.DialURL(redisURL)
-				if err != nil {
-					log.E(l, "Failed to connect to redis.", func(cm log.CM) {
-						cm.Write(zap.Error(err))
-					})
-					return nil, err
-				}
-				return conn, nil
-			},
-			TestOnBorrow: func(c redis.Conn, t time.Time) error {
-				log.I(l, "Pinging redis...")
-				_, err := c.Do("PING")
-				if err != nil {
-					log.E(l, "Failed to ping redis.", func(cm log.CM) {
-						cm.Write(zap.Error(err))
-					})
-					return err
-				}
-				return nil
-			},
-		},
-	})
+	publisher, err := events.NewPublisher(app.Config, app.Logger)
+	if err != nil {
+		log.E(l, "Failed to configure event publisher.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return err
+	}
+	if app.Stream != nil {
+		publisher = events.NewMultiPublisher(publisher, app.Stream)
+	}
+	if app.Config.GetBool("metrics.enabled") {
+		publisher = events.NewMultiPublisher(publisher, metrics.NewEventCounter())
+	}
+	app.Events = publisher
+	models.SetEventPublisher(publisher)
 
+	l.Info("Configured event publisher successfully.")
 	return nil
 }
 
-//GetMutex returns a lock for a given name
-func (app *App) GetMutex(name string, retries, timeout int) *redsync.Mutex {
+//Mutex is a redsync lock that measures and traces the time waited to acquire it
+type Mutex struct {
+	*redsync.Mutex
+	kind string
+}
+
+//Lock acquires the lock in a span child of the span in ctx, measuring the time waited and failures per kind of lock
+func (m *Mutex) Lock(ctx context.Context) error {
+	_, span := tracing.StartSpan(ctx, fmt.Sprintf("redsync %s.lock", m.kind))
+	defer span.End()
+
+	start := time.Now()
+	err := m.Mutex.Lock()
+	metrics.LockWaitDuration.WithLabelValues(m.kind).Observe(metrics.Since(start))
+	if err != nil {
+		metrics.LockFailures.WithLabelValues(m.kind).Inc()
+		span.RecordError(err)
+	}
+	return err
+}
+
+//GetDonationRequestExpiration returns how long donation requests can receive donations before they expire
+func (app *App) GetDonationRequestExpiration() time.Duration {
+	return time.Duration(app.Config.GetInt("donationRequests.expirationHours")) * time.Hour
+}
+
+//GetMutex returns a lock for a given name. The kind of the lock in metrics is the prefix of
+//the name up to the first dash, such as Donate for Donate-<gameID>-<donationRequestID>.
+func (app *App) GetMutex(name string, retries, timeout int) *Mutex {
 	options := []redsync.Option{
 		redsync.SetTries(retries),
 		redsync.SetExpiry(time.Duration(timeout) * time.Second),
 	}
 	mutex := app.Redsync.NewMutex(name, options...)
-	return mutex
+	return &Mutex{Mutex: mutex, kind: strings.SplitN(name, "-", 2)[0]}
 }
 
 func (app *App) configureMongoDB() error {
@@ -258,18 +485,88 @@ func (app *App) configureMongoDB() error {
 	db := session.DB(app.Config.GetString("mongo.db"))
 	app.MongoSession = session
 	app.MongoDb = db
+	models.SetMongoOperationObserver(metrics.ObserveMongoOperation)
 
 	l.Info("Connected to MongoDb successfully.")
+
+	return app.checkMongoDBIndexes()
+}
+
+//checkMongoDBIndexes warns or fails, depending on mongo.indexCheck, when registered indexes are missing
+func (app *App) checkMongoDBIndexes() error {
+	indexCheck := app.Config.GetString("mongo.indexCheck")
+	l := app.Logger.With(
+		zap.String("operation", "checkMongoDBIndexes"),
+		zap.String("mongo.indexCheck", indexCheck),
+	)
+
+	if indexCheck == "off" {
+		return nil
+	}
+	if indexCheck != "warn" && indexCheck != "fail" {
+		return fmt.Errorf("Invalid mongo.indexCheck value '%s'. Valid values are off, warn and fail.", indexCheck)
+	}
+
+	missing, err := models.GetMissingIndexes(app.MongoDb, app.Logger)
+	if err != nil {
+		log.E(l, "Failed to check MongoDb indexes.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return err
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	keys := []string{}
+	for _, index := range missing {
+		keys = append(keys, fmt.Sprintf("%s(%s)", index.Collection, index.GetKey()))
+	}
+	message := "MongoDb indexes are missing. Run 'donations ensure-indexes' to create them."
+	if indexCheck == "fail" {
+		log.E(l, message, func(cm log.CM) {
+			cm.Write(zap.String("missing", strings.Join(keys, " ")))
+		})
+		return fmt.Errorf("%s Missing: %s.", message, strings.Join(keys, " "))
+	}
+
+	log.W(l, message, func(cm log.CM) {
+		cm.Write(zap.String("missing", strings.Join(keys, " ")))
+	})
 	return nil
 }
 
-// Start starts listening for web requests at specified host and port
-func (app *App) Start() {
+// Start starts listening for web requests at specified host and port.
+// It fails if the gRPC server cannot listen at its port.
+func (app *App) Start() error {
 	l := app.Logger.With(
 		zap.String("operation", "Start"),
 	)
 
 	l.Info("Starting Donations...", zap.String("host", app.Host), zap.Int("port", app.Port))
+	if app.Config.GetBool("grpc.enabled") {
+		err := app.startGRPC()
+		if err != nil {
+			return err
+		}
+	}
+	if app.Config.GetBool("webhooks.worker.enabled") {
+		l.Info("Starting webhook worker...")
+		app.Webhooks.Start()
+	}
+	if app.Config.GetBool("donationRequests.expirer.enabled") {
+		l.Info("Starting donation request expirer...")
+		app.startExpirer()
+	}
+	if app.Admin != nil {
+		err := app.startAdmin()
+		if err != nil {
+			return err
+		}
+	}
+
+	go app.stopOnSignal()
+	app.MarkReady()
 	if app.Background {
 		go func() {
 			app.App.Run(app.Engine)
@@ -277,11 +574,156 @@ func (app *App) Start() {
 	} else {
 		app.App.Run(app.Engine)
 	}
+	return nil
+}
+
+func (app *App) startGRPC() error {
+	l := app.Logger.With(
+		zap.String("operation", "startGRPC"),
+	)
+
+	addr := fmt.Sprintf("%s:%d", app.Host, app.Config.GetInt("grpc.port"))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.E(l, "Failed to listen for gRPC calls.", func(cm log.CM) {
+			cm.Write(zap.String("addr", addr), zap.Error(err))
+		})
+		return err
+	}
+
+	l.Info("Starting gRPC server...", zap.String("addr", addr))
+	go func() {
+		err := app.GRPC.Serve(listener)
+		if err != nil {
+			log.W(l, "gRPC server stopped.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+		}
+	}()
+	return nil
+}
+
+//startAdmin listens at the admin port before serving the admin routes in the background,
+//so the app fails to start if the port can't be used
+func (app *App) startAdmin() error {
+	l := app.Logger.With(
+		zap.String("operation", "startAdmin"),
+	)
+
+	addr := fmt.Sprintf("%s:%d", app.Host, app.Config.GetInt("api.admin.port"))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.E(l, "Failed to listen for admin requests.", func(cm log.CM) {
+			cm.Write(zap.String("addr", addr), zap.Error(err))
+		})
+		return err
+	}
+
+	l.Info("Starting admin listener...", zap.String("addr", addr))
+	app.AdminEngine = app.newEngineWithConfig(engine.Config{Address: addr, Listener: listener})
+	go func() {
+		err := app.Admin.Run(app.AdminEngine)
+		if err != nil {
+			log.W(l, "Admin listener stopped.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+		}
+	}()
+	return nil
+}
+
+//startExpirer expires donation requests every donationRequests.expirer.intervalSeconds until the app is stopped,
+//so the expired event is emitted as soon as they expire. Requests are only expired once, even with many instances.
+func (app *App) startExpirer() {
+	l := app.Logger.With(
+		zap.String("operation", "startExpirer"),
+	)
+
+	app.expirerStop = make(chan struct{})
+	app.expirerDone = make(chan struct{})
+	interval := time.Duration(app.Config.GetInt("donationRequests.expirer.intervalSeconds")) * time.Second
+
+	go func() {
+		defer close(app.expirerDone)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-app.expirerStop:
+				return
+			case <-ticker.C:
+				expired, err := models.ExpireDonationRequests(
+					"", app.GetDonationRequestExpiration(), &models.RealClock{}, app.MongoDb, app.Logger,
+				)
+				if err != nil {
+					log.E(l, "Failed to expire donation requests.", func(cm log.CM) {
+						cm.Write(zap.Error(err))
+					})
+				} else if expired > 0 {
+					log.I(l, "Donation requests expired.", func(cm log.CM) {
+						cm.Write(zap.Int("expired", expired))
+					})
+				}
+			}
+		}
+	}()
+}
+
+//stopExpirer waits for the current run of the expirer to finish and stops it
+func (app *App) stopExpirer() {
+	if app.expirerStop == nil {
+		return
+	}
+	close(app.expirerStop)
+	<-app.expirerDone
+	app.expirerStop = nil
+}
+
+//GetStatus returns whether the app is starting, ready or stopping
+func (app *App) GetStatus() string {
+	return appStatuses[atomic.LoadInt32(&app.status)]
+}
+
+//MarkReady makes the readiness healthcheck succeed, if dependencies are healthy
+func (app *App) MarkReady() {
+	atomic.StoreInt32(&app.status, appReady)
+}
+
+//MarkStopping makes the readiness healthcheck fail, so load balancers stop sending requests
+func (app *App) MarkStopping() {
+	atomic.StoreInt32(&app.status, appStopping)
+}
+
+//stopOnSignal fails the readiness healthcheck when the process is asked to stop, waits for
+//load balancers to notice it and stops the app
+func (app *App) stopOnSignal() {
+	l := app.Logger.With(
+		zap.String("operation", "stopOnSignal"),
+	)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-signals
+
+	delay := time.Duration(app.Config.GetInt("healthcheck.shutdownDelaySeconds")) * time.Second
+	l.Info("Stopping Donations...", zap.String("signal", sig.String()), zap.Duration("delay", delay))
+	app.MarkStopping()
+	time.Sleep(delay)
+
+	app.Stop()
+	os.Exit(0)
 }
 
 //Stop app running routines
 func (app *App) Stop() {
+	app.MarkStopping()
+	app.GRPC.Stop()
+	app.Webhooks.Stop()
+	app.stopExpirer()
+	app.Events.Close()
+	app.Tracer.Close()
 	app.MongoSession.Close()
+	app.Redis.Close()
 }
 
 func (app *App) configureApplication() error {
@@ -290,22 +732,80 @@ func (app *App) configureApplication() error {
 	)
 
 	l.Debug("Configuring Application...")
-	addr := fmt.Sprintf("%s:%d", app.Host, app.Port)
+	app.Engine = app.newEngine(fmt.Sprintf("%s:%d", app.Host, app.Port))
+	app.App = app.newRouter(false)
+	app.Admin = nil
+	app.AdminEngine = nil
+	admin := app.App
+
+	//with an admin port, admin routes are served by their own listener and middleware chain,
+	//so the public listener can be exposed without them
+	if adminPort := app.Config.GetInt("api.admin.port"); adminPort != 0 {
+		app.AdminEngine = app.newEngine(fmt.Sprintf("%s:%d", app.Host, adminPort))
+		app.Admin = app.newRouter(true)
+		admin = app.Admin
+		l.Info("Admin routes will be served at a separate port.", zap.Int("adminPort", adminPort))
+	}
+
+	app.configurePublicRoutes(app.App)
+	app.configureAdminRoutes(admin)
+
+	//metrics are internal, so they are served with the admin routes
+	if app.Config.GetBool("metrics.enabled") {
+		admin.Get("/metrics", MetricsHandler(app))
+	}
+
+	err := app.configureMongoDB()
+	if err != nil {
+		return err
+	}
+
+	err = app.configureRedis()
+	if err != nil {
+		return err
+	}
+
+	err = app.configureRedsync()
+	if err != nil {
+		return err
+	}
+
+	app.configureClanStream()
+
+	err = app.configureEventPublisher()
+	if err != nil {
+		return err
+	}
+
+	app.Webhooks = webhooks.NewWorker(app.Config, app.MongoDb, app.Logger)
+	app.GRPC = NewGRPCServer(app)
+
+	l.Debug("Application configured successfully.")
+
+	return nil
+}
+
+func (app *App) newEngine(addr string) engine.Server {
+	return app.newEngineWithConfig(engine.Config{Address: addr})
+}
+
+func (app *App) newEngineWithConfig(config engine.Config) engine.Server {
 	if app.Fast {
-		app.Engine = fasthttp.New(addr)
-	} else {
-		app.Engine = standard.New(addr)
+		return fasthttp.WithConfig(config)
 	}
-	app.App = echo.New()
-	a := app.App
+	return standard.WithConfig(config)
+}
+
+//newRouter returns an echo instance with the middleware chain shared by every route
+func (app *App) newRouter(adminListener bool) *echo.Echo {
+	a := echo.New()
 
 	_, w, _ := os.Pipe()
 	a.SetLogOutput(w)
 
-	basicAuthUser := app.Config.GetString("api.basicAuth.user")
-	if basicAuthUser != "" {
-		basicAuthPass := app.Config.GetString("api.basicAuth.pass")
-
+	//with API keys enabled, the basic auth credentials are checked by the API key middleware of each route
+	basicAuthUser, basicAuthPass := getBasicAuthCredentials(app, adminListener)
+	if basicAuthUser != "" && !app.Config.GetBool("api.apiKeys.enabled") {
 		a.Use(middleware.BasicAuth(func(username, password string) bool {
 			return username == basicAuthUser && password == basicAuthPass
 		}))
@@ -313,8 +813,9 @@ func (app *App) configureApplication() error {
 
 	a.Pre(middleware.RemoveTrailingSlash())
 
-	//NewRelicMiddleware has to stand out from all others
-	a.Use(NewNewRelicMiddleware(app, app.Logger).Serve)
+	//TracingMiddleware has to stand out from all others
+	a.Use(NewTracingMiddleware(app).Serve)
+	a.Use(NewMetricsMiddleware().Serve)
 
 	a.Use(NewRecoveryMiddleware(app.OnErrorHandler).Serve)
 	a.Use(NewVersionMiddleware().Serve)
@@ -323,27 +824,82 @@ func (app *App) configureApplication() error {
 	a.Use(NewBodyExtractionMiddleware().Serve)
 
 	a.Get("/healthcheck", HealthCheckHandler(app))
+	a.Get("/healthcheck/live", LivenessHandler(app))
+	a.Get("/healthcheck/ready", ReadinessHandler(app))
 
-	//Games Routes
-	//TODO: Get Game Details
-	a.Put("/games/:gameID", UpdateGameHandler(app))
+	return a
+}
 
-	//Items Routes
-	a.Put("/games/:gameID/items/:itemKey", UpsertItemHandler(app))
+//configurePublicRoutes mounts the gameplay routes, used by game clients and servers
+func (app *App) configurePublicRoutes(a *echo.Echo) {
+	client := NewAPIKeyMiddleware(app, models.APIKeyScopeClient).Serve
 
 	//Donation Requests routes
-	a.Post("/games/:gameID/donation-requests", CreateDonationRequestHandler(app))
+	a.Post(
+		"/games/:gameID/donation-requests",
+		CreateDonationRequestHandler(app),
+		client,
+		NewRateLimitMiddleware(app, "CreateDonationRequest").Serve,
+		NewIdempotencyMiddleware(app, "CreateDonationRequest", authorizeDonationRequestReplay).Serve,
+	)
 
 	//Donations routes
-	a.Post("/games/:gameID/donation-requests/:donationRequestID", CreateDonationHandler(app))
+	a.Post(
+		"/games/:gameID/donation-requests/:donationRequestID",
+		CreateDonationHandler(app),
+		client,
+		NewRateLimitMiddleware(app, "CreateDonation").Serve,
+		NewIdempotencyMiddleware(app, "CreateDonation", authorizeDonationReplay).Serve,
+	)
 
-	a.Get("/games/:gameID/donation-weight-by-clan", GetDonationWeightByClanHandler(app))
+	a.Post(
+		"/games/:gameID/donations/batch",
+		BatchDonationHandler(app),
+		client,
+		NewRateLimitMiddleware(app, "BatchDonation").Serve,
+		NewIdempotencyMiddleware(app, "BatchDonation", authorizeBatchDonationReplay).Serve,
+	)
 
-	app.configureMongoDB()
-	app.configureRedsync()
-	app.configureRedis()
+	a.Get("/games/:gameID/donation-requests/:donationRequestID", GetDonationRequestHandler(app), client)
 
-	l.Debug("Application configured successfully.")
+	a.Get("/games/:gameID/donation-weight-by-clan", GetDonationWeightByClanHandler(app), client)
 
-	return nil
+	//Clans routes
+	a.Get("/games/:gameID/clans/:clanID/stream", StreamClanHandler(app), client)
+}
+
+//configureAdminRoutes mounts the routes that configure games and manage their data
+func (app *App) configureAdminRoutes(a *echo.Echo) {
+	admin := NewAPIKeyMiddleware(app, models.APIKeyScopeAdmin).Serve
+
+	//Games Routes
+	//TODO: Get Game Details
+	a.Put("/games/:gameID", UpdateGameHandler(app), admin)
+	a.Get("/games/:gameID/history", GetGameHistoryHandler(app), admin)
+	a.Post("/games/:gameID/history/:version/rollback", RollbackGameHandler(app), admin)
+
+	//Items Routes
+	a.Put("/games/:gameID/items/:itemKey", UpsertItemHandler(app), admin)
+
+	//Players routes
+	a.Get("/games/:gameID/players/:playerID/export", ExportPlayerDataHandler(app), admin)
+	a.Delete("/games/:gameID/players/:playerID", ErasePlayerDataHandler(app), admin)
+
+	//Webhooks routes
+	a.Post("/games/:gameID/webhooks", CreateWebhookHandler(app), admin)
+	a.Get("/games/:gameID/webhooks", GetWebhooksHandler(app), admin)
+	a.Delete("/games/:gameID/webhooks/:webhookID", RemoveWebhookHandler(app), admin)
+	a.Get("/games/:gameID/webhooks/dead-letters", GetWebhookDeadLettersHandler(app), admin)
+	a.Post("/games/:gameID/webhooks/deliveries/:deliveryID/redeliver", RedeliverWebhookHandler(app), admin)
+
+	//API keys routes
+	a.Post("/games/:gameID/api-keys", CreateAPIKeyHandler(app), admin)
+	a.Get("/games/:gameID/api-keys", GetAPIKeysHandler(app), admin)
+	a.Post("/games/:gameID/api-keys/:keyID/rotate", RotateAPIKeyHandler(app), admin)
+	a.Delete("/games/:gameID/api-keys/:keyID", RevokeAPIKeyHandler(app), admin)
+
+	//Player authentication routes
+	a.Put("/games/:gameID/player-auth", SetPlayerAuthHandler(app), admin)
+	a.Get("/games/:gameID/player-auth", GetPlayerAuthHandler(app), admin)
+	a.Delete("/games/:gameID/player-auth", RemovePlayerAuthHandler(app), admin)
 }
@@ -3,6 +3,9 @@ package api_test
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/http"
+	"sync"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -32,6 +35,33 @@ var _ = Describe("App", func() {
 		})
 	})
 
+	Describe("App redis", func() {
+		It("should share a redis pool between goroutines", func() {
+			app, err := api.GetApp("127.0.0.1", 9999, GetConfPath(), false, logger, false, false)
+			Expect(err).NotTo(HaveOccurred())
+			defer app.Stop()
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 20)
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					conn := app.Redis.Get()
+					defer conn.Close()
+					_, err := conn.Do("PING")
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	})
+
 	Describe("App authentication", func() {
 		BeforeEach(func() {
 			var err error
@@ -40,6 +70,90 @@ var _ = Describe("App", func() {
 		})
 	})
 
+	Describe("App admin routes", func() {
+		It("should serve admin routes at a separate port", func() {
+			app := GetDefaultTestApp(logger)
+			defer app.Stop()
+			app.Config.Set("api.admin.port", 8891)
+			err := app.Configure()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(app.Admin).NotTo(BeNil())
+
+			game, err := GetTestGame(app.MongoDb, app.Logger, true)
+			Expect(err).NotTo(HaveOccurred())
+			payload := &api.UpdateGamePayload{
+				Name:                         "game",
+				DonationCooldownHours:        8,
+				DonationRequestCooldownHours: 24,
+			}
+			jsonPayload, err := payload.ToJSON()
+			Expect(err).NotTo(HaveOccurred())
+
+			status, _ := Put(app, fmt.Sprintf("/games/%s", game.ID), string(jsonPayload))
+			Expect(status).To(Equal(http.StatusNotFound))
+			status, body := PutAdmin(app, fmt.Sprintf("/games/%s", game.ID), string(jsonPayload))
+			Expect(status).To(Equal(http.StatusOK), body)
+
+			donationRequest := &api.CreateDonationRequestPayload{
+				Item:   GetFirstItem(game).Key,
+				Player: "player",
+				Clan:   "clan",
+			}
+			jsonPayload, err = donationRequest.ToJSON()
+			Expect(err).NotTo(HaveOccurred())
+			status, body = Post(app, fmt.Sprintf("/games/%s/donation-requests", game.ID), string(jsonPayload))
+			Expect(status).To(Equal(http.StatusOK), body)
+		})
+
+		It("should use the admin basic auth credentials only at the admin port", func() {
+			app := GetDefaultTestApp(logger)
+			defer app.Stop()
+			app.Config.Set("api.admin.port", 8891)
+			app.Config.Set("api.admin.basicAuth.user", "admin")
+			app.Config.Set("api.admin.basicAuth.pass", "secret")
+			err := app.Configure()
+			Expect(err).NotTo(HaveOccurred())
+
+			status, _ := GetAdmin(app, "/healthcheck")
+			Expect(status).To(Equal(http.StatusUnauthorized))
+			status, _ = Get(app, "/healthcheck")
+			Expect(status).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("App start", func() {
+		It("should fail if the gRPC server cannot listen", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			defer listener.Close()
+
+			app, err := api.GetApp("127.0.0.1", 9999, GetConfPath(), false, logger, true, false)
+			Expect(err).NotTo(HaveOccurred())
+			defer app.Stop()
+			app.Config.Set("grpc.enabled", true)
+			app.Config.Set("grpc.port", listener.Addr().(*net.TCPAddr).Port)
+
+			err = app.Start()
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail if the admin listener cannot listen", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			defer listener.Close()
+
+			app, err := api.GetApp("127.0.0.1", 9999, GetConfPath(), false, logger, true, false)
+			Expect(err).NotTo(HaveOccurred())
+			defer app.Stop()
+			app.Config.Set("api.admin.port", listener.Addr().(*net.TCPAddr).Port)
+			err = app.Configure()
+			Expect(err).NotTo(HaveOccurred())
+
+			err = app.Start()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	Describe("Error Handler", func() {
 		var sink *TestBuffer
 		BeforeEach(func() {
@@ -92,6 +206,16 @@ var _ = Describe("App", func() {
 			Expect(expected).To(Equal("WORKING"))
 		})
 
+		It("Should override configuration with options", func() {
+			app, err := api.GetApp(
+				"127.0.0.1", 9999, GetConfPath(), false, logger, false, false,
+				api.WithConfig("mongo.indexCheck", "off"),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			defer app.Stop()
+			Expect(app.Config.GetString("mongo.indexCheck")).To(Equal("off"))
+		})
+
 		It("Should faild if configuration file does not exist", func() {
 			app, err := api.GetApp("127.0.0.1", 9999, "../config/invalid.yaml", false, logger, false, false)
 			Expect(app).To(BeNil())
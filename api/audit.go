@@ -0,0 +1,177 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/models"
+
+	"github.com/labstack/echo"
+	"github.com/uber-go/zap"
+)
+
+//ActorHeader identifies who the caller is changing a game's configuration for. It is not authenticated,
+//so it is recorded in the audit log as the claimed actor.
+const ActorHeader = "X-Actor"
+
+//UnknownActor is recorded when the request has no API key nor operator basic auth credentials
+const UnknownActor = "unknown"
+
+//parseBasicAuth returns the user and password of a basic Authorization header
+func parseBasicAuth(auth string) (string, string, bool) {
+	if !strings.HasPrefix(auth, "Basic ") {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(auth[len("Basic "):])
+	if err != nil {
+		return "", "", false
+	}
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+//getAuthenticatedActor returns the name of the API key that authorized a request or, if the
+//credentials are the operator ones, the basic auth user
+func getAuthenticatedActor(app *App, key *models.APIKey, auth string) string {
+	if key != nil {
+		return key.Name
+	}
+	if isOperator(app, auth, true) || isOperator(app, auth, false) {
+		user, _, _ := parseBasicAuth(auth)
+		return user
+	}
+	return UnknownActor
+}
+
+//getActor returns the authenticated actor of a request
+func getActor(app *App, c echo.Context) string {
+	key, _ := c.Get("apiKey").(*models.APIKey)
+	return getAuthenticatedActor(app, key, c.Request().Header().Get("Authorization"))
+}
+
+//getClaimedActor returns the X-Actor header, who the caller says it acts for
+func getClaimedActor(c echo.Context) string {
+	return c.Request().Header().Get(ActorHeader)
+}
+
+//recordAudit records a change of a game. The change was already saved, so failures are
+//returned for the caller to fail the request and be alerted, since the history misses the change.
+func recordAudit(app *App, actor, claimedActor, action string, before, after *models.Game, l zap.Logger) error {
+	_, err := models.RecordAuditEntry(
+		after.ID, actor, claimedActor, action, before, after,
+		&models.RealClock{}, app.MongoDb, app.Logger,
+	)
+	if err != nil {
+		log.E(l, "Failed to record audit entry!", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return fmt.Errorf("The game was changed, but the change could not be recorded in its history: %s", err.Error())
+	}
+	return nil
+}
+
+//GetGameHistoryHandler is the handler responsible for listing the changes to a game's configuration
+func GetGameHistoryHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		gameID := c.Param("gameID")
+		l := app.Logger.With(
+			zap.String("source", "GetGameHistoryHandler"),
+			zap.String("operation", "GetGameHistory"),
+			zap.String("gameID", gameID),
+		)
+		c.Set("route", "GetGameHistory")
+
+		limit := app.Config.GetInt("audit.defaultLimit")
+		if value := c.QueryParam("limit"); value != "" {
+			var err error
+			limit, err = strconv.Atoi(value)
+			if err != nil || limit <= 0 {
+				return FailWith(400, "Invalid limit.", c)
+			}
+		}
+
+		var entries []*models.AuditEntry
+		err := WithSegment("model", c, func() error {
+			_, err := models.GetGameByID(gameID, app.MongoDb, app.Logger)
+			if err != nil {
+				return err
+			}
+
+			entries, err = models.GetAuditLog(gameID, limit, app.MongoDb, app.Logger)
+			return err
+		})
+		if _, ok := err.(*errors.DocumentNotFoundError); ok {
+			return FailWithError(404, err, c)
+		}
+		if err != nil {
+			log.E(l, "Failed to retrieve game history!", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{"history": entries})
+	}
+}
+
+//RollbackGameHandler is the handler responsible for restoring a game to a version of its history
+func RollbackGameHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		gameID := c.Param("gameID")
+		l := app.Logger.With(
+			zap.String("source", "RollbackGameHandler"),
+			zap.String("operation", "RollbackGame"),
+			zap.String("gameID", gameID),
+		)
+		c.Set("route", "RollbackGame")
+
+		version, err := strconv.Atoi(c.Param("version"))
+		if err != nil || version <= 0 {
+			return FailWith(400, "Invalid version.", c)
+		}
+
+		log.D(l, "Rolling back game...", func(cm log.CM) {
+			cm.Write(zap.Int("version", version))
+		})
+
+		var game *models.Game
+		err = WithSegment("model", c, func() error {
+			game, _, err = models.RollbackGame(
+				gameID, version, getActor(app, c), getClaimedActor(c),
+				&models.RealClock{}, app.MongoDb, app.Logger,
+			)
+			return err
+		})
+		if _, ok := err.(*errors.DocumentNotFoundError); ok {
+			return FailWithError(404, err, c)
+		}
+		if err != nil {
+			log.E(l, "Failed to roll back game!", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+
+		log.I(l, "Rolled back game successfully.", func(cm log.CM) {
+			cm.Write(zap.Int("version", version))
+		})
+
+		gameJSON, err := game.ToJSON()
+		if err != nil {
+			log.E(l, "Failed to marshal game!", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+
+		return c.String(http.StatusOK, string(gameJSON))
+	}
+}
@@ -0,0 +1,162 @@
+package api_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/api"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Audit Handler", func() {
+	var logger zap.Logger
+	var app *api.App
+	var game *models.Game
+
+	getHistory := func(query string) []*models.AuditEntry {
+		status, body := Get(app, fmt.Sprintf("/games/%s/history%s", game.ID, query))
+		Expect(status).To(Equal(http.StatusOK), body)
+
+		var result struct {
+			History []*models.AuditEntry `json:"history"`
+		}
+		err := json.Unmarshal([]byte(body), &result)
+		Expect(err).NotTo(HaveOccurred())
+		return result.History
+	}
+
+	updateGame := func(donationCooldownHours int, headers map[string]string) {
+		payload := &api.UpdateGamePayload{
+			Name:                         game.Name,
+			DonationCooldownHours:        donationCooldownHours,
+			DonationRequestCooldownHours: game.DonationRequestCooldownHours,
+		}
+		jsonPayload, err := payload.ToJSON()
+		Expect(err).NotTo(HaveOccurred())
+		status, body := PutWithHeaders(app, fmt.Sprintf("/games/%s", game.ID), string(jsonPayload), headers)
+		Expect(status).To(Equal(http.StatusOK), body)
+	}
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		app = GetDefaultTestApp(logger)
+
+		var err error
+		game, err = GetTestGame(app.MongoDb, app.Logger, true)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		app.Stop()
+	})
+
+	Describe("Game History", func() {
+		It("Should record game and item changes with their actor", func() {
+			app.Config.Set("api.basicAuth.user", "admin")
+			app.Config.Set("api.basicAuth.pass", "secret")
+			updateGame(game.DonationCooldownHours+1, map[string]string{"X-Actor": "jane"})
+
+			payload := &api.UpsertItemPayload{
+				Metadata:                          map[string]interface{}{"x": 1},
+				WeightPerDonation:                 5,
+				LimitOfItemsPerPlayerDonation:     2,
+				LimitOfItemsInEachDonationRequest: 6,
+			}
+			jsonPayload, err := payload.ToJSON()
+			Expect(err).NotTo(HaveOccurred())
+			auth := base64.StdEncoding.EncodeToString([]byte("admin:secret"))
+			status, body := PutWithHeaders(
+				app, fmt.Sprintf("/games/%s/items/item-1", game.ID), string(jsonPayload),
+				map[string]string{"Authorization": fmt.Sprintf("Basic %s", auth), "X-Actor": "john"},
+			)
+			Expect(status).To(Equal(http.StatusOK), body)
+
+			history := getHistory("")
+			Expect(history).To(HaveLen(2))
+
+			Expect(history[0].Version).To(Equal(2))
+			Expect(history[0].Action).To(Equal(models.AuditUpsertItem))
+			Expect(history[0].Actor).To(Equal("admin"))
+			Expect(history[0].ClaimedActor).To(Equal("john"))
+			Expect(history[0].Changes).To(HaveLen(1))
+			Expect(history[0].Changes[0].Field).To(Equal("items.item-1.weightPerDonation"))
+			Expect(history[0].Changes[0].Before).To(BeEquivalentTo(1))
+			Expect(history[0].Changes[0].After).To(BeEquivalentTo(5))
+
+			Expect(history[1].Version).To(Equal(1))
+			Expect(history[1].Action).To(Equal(models.AuditUpdateGame))
+			Expect(history[1].Actor).To(Equal(api.UnknownActor))
+			Expect(history[1].ClaimedActor).To(Equal("jane"))
+			Expect(history[1].Changes[0].Field).To(Equal("donationCooldownHours"))
+
+			Expect(getHistory("?limit=1")).To(HaveLen(1))
+		})
+
+		It("Should not record basic auth users that are not the operator as actors", func() {
+			auth := base64.StdEncoding.EncodeToString([]byte("admin:secret"))
+			updateGame(game.DonationCooldownHours+1, map[string]string{"Authorization": fmt.Sprintf("Basic %s", auth)})
+
+			history := getHistory("")
+			Expect(history).To(HaveLen(1))
+			Expect(history[0].Actor).To(Equal(api.UnknownActor))
+		})
+
+		It("Should not record requests that change nothing", func() {
+			updateGame(game.DonationCooldownHours, nil)
+			Expect(getHistory("")).To(BeEmpty())
+		})
+
+		It("Should fail with an invalid limit", func() {
+			status, body := Get(app, fmt.Sprintf("/games/%s/history?limit=invalid", game.ID))
+			Expect(status).To(Equal(http.StatusBadRequest))
+			Expect(body).To(ContainSubstring("Invalid limit"))
+		})
+
+		It("Should fail if the game does not exist", func() {
+			status, _ := Get(app, "/games/invalid-game/history")
+			Expect(status).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("Rollback Game", func() {
+		It("Should restore a version of the game", func() {
+			original := game.DonationCooldownHours
+			updateGame(original+1, nil)
+			updateGame(original+2, nil)
+
+			status, body := Post(app, fmt.Sprintf("/games/%s/history/1/rollback", game.ID), "")
+			Expect(status).To(Equal(http.StatusOK), body)
+
+			rGame, err := models.GetGameFromJSON([]byte(body))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rGame.DonationCooldownHours).To(Equal(original + 1))
+
+			history := getHistory("")
+			Expect(history).To(HaveLen(3))
+			Expect(history[0].Action).To(Equal(models.AuditRollback))
+			Expect(history[0].RollbackOf).To(Equal(1))
+			Expect(history[0].Actor).To(Equal(api.UnknownActor))
+		})
+
+		It("Should fail if the version does not exist", func() {
+			status, _ := Post(app, fmt.Sprintf("/games/%s/history/10/rollback", game.ID), "")
+			Expect(status).To(Equal(http.StatusNotFound))
+		})
+
+		It("Should fail with an invalid version", func() {
+			status, body := Post(app, fmt.Sprintf("/games/%s/history/invalid/rollback", game.ID), "")
+			Expect(status).To(Equal(http.StatusBadRequest))
+			Expect(body).To(ContainSubstring("Invalid version"))
+		})
+	})
+})
@@ -0,0 +1,268 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/models"
+
+	"github.com/labstack/echo"
+	"github.com/uber-go/zap"
+	"golang.org/x/net/context"
+)
+
+//Error codes of the donations in a batch
+const (
+	//BatchInvalidDonation means the donation has missing or invalid fields
+	BatchInvalidDonation = "invalidDonation"
+	//BatchDonationRequestNotFound means the donation request does not exist in the game
+	BatchDonationRequestNotFound = "donationRequestNotFound"
+	//BatchDonationRequestExpired means the donation request expired before being finished
+	BatchDonationRequestExpired = "donationRequestExpired"
+	//BatchDonationRequestLimitReached means the donation request can't accept the amount
+	BatchDonationRequestLimitReached = "donationRequestLimitReached"
+	//BatchPlayerLimitReached means the player can't donate the amount to the donation request
+	BatchPlayerLimitReached = "playerLimitReached"
+	//BatchDonationCooldown means the player reached the max weight of donations in the cooldown
+	BatchDonationCooldown = "donationCooldown"
+	//BatchPlayerAuthenticationFailed means the player token is not valid for the player of the donation
+	BatchPlayerAuthenticationFailed = "playerAuthenticationFailed"
+	//BatchConcurrentUpdate means the donation request kept being updated concurrently
+	BatchConcurrentUpdate = "concurrentUpdate"
+	//BatchInternalError means the donation failed unexpectedly and can be retried
+	BatchInternalError = "internalError"
+)
+
+//BatchDonationResult is the outcome of a donation in a batch
+type BatchDonationResult struct {
+	Index             int         `json:"index"`
+	DonationRequestID string      `json:"donationRequestID"`
+	Player            string      `json:"player"`
+	Success           bool        `json:"success"`
+	Code              string      `json:"code,omitempty"`
+	Reason            string      `json:"reason,omitempty"`
+	Type              string      `json:"type,omitempty"`
+	Details           interface{} `json:"details,omitempty"`
+}
+
+func (r *BatchDonationResult) fail(code string, err error) {
+	r.Success = false
+	r.Code = code
+	r.Reason = err.Error()
+	r.Type, r.Details = getErrorDetails(err)
+}
+
+//getBatchErrorCode maps the errors of DonationRequest.Donate to error codes
+func getBatchErrorCode(err error) string {
+	switch err.(type) {
+	case *errors.ParameterIsRequiredError:
+		return BatchInvalidDonation
+	case *errors.DocumentNotFoundError:
+		return BatchDonationRequestNotFound
+	case *errors.DonationRequestExpiredError:
+		return BatchDonationRequestExpired
+	case *errors.LimitOfItemsInDonationRequestReachedError:
+		return BatchDonationRequestLimitReached
+	case *errors.LimitOfItemsPerPlayerInDonationRequestReachedError:
+		return BatchPlayerLimitReached
+	case *errors.DonationCooldownViolatedError:
+		return BatchDonationCooldown
+	case *errors.DonationRequestConcurrentlyUpdatedError:
+		return BatchConcurrentUpdate
+	default:
+		return BatchInternalError
+	}
+}
+
+//donateBatchToRequest applies the donations of a batch to the same donation request,
+//holding its lock and loading it only once
+func donateBatchToRequest(
+	ctx context.Context, app *App, gameID, donationRequestID string,
+	entries []*BatchDonationEntry, results []*BatchDonationResult,
+	l zap.Logger,
+) {
+	failAll := func(code string, err error) {
+		for _, result := range results {
+			result.fail(code, err)
+		}
+	}
+
+	if app.Config.GetBool("api.donationLock.enabled") {
+		mutexID := fmt.Sprintf("Donate-%s-%s", gameID, donationRequestID)
+		mutex := app.GetMutex(mutexID, 32, 8) // Number of retries to get lock and Lock expiration
+		err := mutex.Lock(ctx)
+		if err != nil {
+			log.E(l, "Could not acquire lock after many retries.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			failAll(BatchInternalError, err)
+			return
+		}
+		defer mutex.Unlock()
+	}
+
+	donationRequest, err := models.GetDonationRequestByID(donationRequestID, app.MongoDb, app.Logger)
+	if err == nil && donationRequest.GameID != gameID {
+		err = errors.NewDocumentNotFoundError("donationRequest", donationRequestID)
+	}
+	if err == nil {
+		err = donationRequest.ValidateDonationRequestNotExpired(app.GetDonationRequestExpiration(), app.Logger)
+	}
+	if err != nil {
+		failAll(getBatchErrorCode(err), err)
+		return
+	}
+
+	donationRequest.Context = ctx
+	for i, entry := range entries {
+		//Donate only changes the donation request when the donation is saved,
+		//so it is reused by the next donations
+		err = donationRequest.Donate(entry.Player, entry.Amount, entry.MaxWeightPerPlayer, app.Redis, app.MongoDb, app.Logger)
+		if err != nil {
+			results[i].fail(getBatchErrorCode(err), err)
+			continue
+		}
+		results[i].Success = true
+	}
+}
+
+//BatchDonationHandler is the handler responsible for applying many donations at once.
+//Each donation is validated and applied independently, like in the Create Donation route.
+func BatchDonationHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		gameID := c.Param("gameID")
+		l := app.Logger.With(
+			zap.String("source", "BatchDonationHandler"),
+			zap.String("operation", "BatchDonation"),
+			zap.String("gameID", gameID),
+		)
+		c.Set("route", "BatchDonation")
+
+		log.D(l, "Creating batch of donations...")
+
+		var payload BatchDonationPayload
+		err := WithSegment("payload", c, func() error {
+			if err := LoadJSONPayload(&payload, c, l); err != nil {
+				log.E(l, "Invalid json payload!", func(cm log.CM) {
+					cm.Write(zap.Error(err))
+				})
+				return err
+			}
+
+			return nil
+		})
+		if err != nil {
+			return FailWithError(400, err, c)
+		}
+
+		maxDonations := app.Config.GetInt("api.batch.maxDonations")
+		if len(payload.Donations) > maxDonations {
+			return FailWith(400, fmt.Sprintf("A batch can't have more than %d donations.", maxDonations), c)
+		}
+
+		var playerAuth *models.PlayerAuthConfig
+		err = WithSegment("playerAuth", c, func() error {
+			var err error
+			playerAuth, err = getPlayerAuthConfig(app, gameID)
+			return err
+		})
+		if err != nil {
+			log.E(l, "Failed to get player authentication!", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+		playerToken := c.Request().Header().Get(PlayerTokenHeader)
+
+		results := make([]*BatchDonationResult, len(payload.Donations))
+		requestIDs := []string{}
+		indexes := map[string][]int{}
+		ensuredPlayers := map[string]error{}
+		authenticated := []int{}
+		players := []string{}
+
+		for i, entry := range payload.Donations {
+			results[i] = &BatchDonationResult{Index: i}
+			if entry == nil {
+				results[i].fail(BatchInvalidDonation, fmt.Errorf("donation is required"))
+				continue
+			}
+			results[i].DonationRequestID = entry.DonationRequestID
+			results[i].Player = entry.Player
+
+			if errs := entry.Validate(); len(errs) > 0 {
+				results[i].fail(BatchInvalidDonation, fmt.Errorf("%s", strings.Join(errs, ", ")))
+				continue
+			}
+
+			if playerAuth != nil {
+				//each donation can send the token of its player, so a batch can have many players
+				token := entry.PlayerToken
+				if token == "" {
+					token = playerToken
+				}
+				if _, err := playerAuth.AuthenticatePlayer(token, entry.Player); err != nil {
+					results[i].fail(BatchPlayerAuthenticationFailed, err)
+					continue
+				}
+			}
+
+			authenticated = append(authenticated, i)
+			players = append(players, entry.Player)
+		}
+
+		//players are only limited once authenticated, so a batch can't use the tokens of other players
+		if status, err := applyPlayerRateLimit(app, c, "BatchDonation", players...); err != nil {
+			return FailWithError(status, err, c)
+		}
+
+		for _, i := range authenticated {
+			entry := payload.Donations[i]
+			err, ok := ensuredPlayers[entry.Player]
+			if !ok {
+				err = models.EnsurePlayerExists(gameID, entry.Player, app.MongoDb, app.Logger)
+				ensuredPlayers[entry.Player] = err
+			}
+			if err != nil {
+				results[i].fail(BatchInternalError, err)
+				continue
+			}
+
+			if _, ok := indexes[entry.DonationRequestID]; !ok {
+				requestIDs = append(requestIDs, entry.DonationRequestID)
+			}
+			indexes[entry.DonationRequestID] = append(indexes[entry.DonationRequestID], i)
+		}
+
+		WithSegment("model", c, func() error {
+			for _, donationRequestID := range requestIDs {
+				entries := []*BatchDonationEntry{}
+				requestResults := []*BatchDonationResult{}
+				for _, i := range indexes[donationRequestID] {
+					entries = append(entries, payload.Donations[i])
+					requestResults = append(requestResults, results[i])
+				}
+				donateBatchToRequest(GetTraceContext(c), app, gameID, donationRequestID, entries, requestResults, l)
+			}
+			return nil
+		})
+
+		succeeded := 0
+		for _, result := range results {
+			if result.Success {
+				succeeded++
+			}
+		}
+		log.I(l, "Created batch of donations successfully.", func(cm log.CM) {
+			cm.Write(zap.Int("donations", len(results)), zap.Int("succeeded", succeeded))
+		})
+
+		return SucceedWith(map[string]interface{}{
+			"succeeded": succeeded,
+			"failed":    len(results) - succeeded,
+			"results":   results,
+		}, c)
+	}
+}
@@ -0,0 +1,296 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	uuid "github.com/satori/go.uuid"
+	"github.com/topfreegames/donations/api"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Batch Donation Handler", func() {
+	var logger zap.Logger
+	var app *api.App
+	var game *models.Game
+
+	type batchResponse struct {
+		Succeeded int                        `json:"succeeded"`
+		Failed    int                        `json:"failed"`
+		Results   []*api.BatchDonationResult `json:"results"`
+	}
+
+	postBatchWithHeaders := func(headers map[string]string, donations ...*api.BatchDonationEntry) (int, string) {
+		payload := &api.BatchDonationPayload{Donations: donations}
+		jsonPayload, err := payload.ToJSON()
+		Expect(err).NotTo(HaveOccurred())
+		return PostWithHeaders(app, fmt.Sprintf("/games/%s/donations/batch", game.ID), string(jsonPayload), headers)
+	}
+
+	postBatch := func(donations ...*api.BatchDonationEntry) (int, string) {
+		return postBatchWithHeaders(map[string]string{}, donations...)
+	}
+
+	getCodes := func(body string) []string {
+		var response batchResponse
+		err := json.Unmarshal([]byte(body), &response)
+		Expect(err).NotTo(HaveOccurred())
+		codes := []string{}
+		for _, r := range response.Results {
+			codes = append(codes, r.Code)
+		}
+		return codes
+	}
+
+	playerToken := func(player string) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub": player,
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}).SignedString([]byte("secret"))
+		Expect(err).NotTo(HaveOccurred())
+		return token
+	}
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		app = GetDefaultTestApp(logger)
+
+		var err error
+		game, err = GetTestGame(app.MongoDb, app.Logger, true)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		app.Stop()
+	})
+
+	Describe("Batch Donation", func() {
+		It("Should apply each donation independently", func() {
+			dr1, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+			dr2, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+			player := uuid.NewV4().String()
+
+			status, body := postBatch(
+				&api.BatchDonationEntry{DonationRequestID: dr1.ID, Player: player, Amount: 2, MaxWeightPerPlayer: 100},
+				&api.BatchDonationEntry{DonationRequestID: dr1.ID, Player: player, Amount: 1, MaxWeightPerPlayer: 100},
+				&api.BatchDonationEntry{DonationRequestID: dr2.ID, Player: player, Amount: 1, MaxWeightPerPlayer: 100},
+				&api.BatchDonationEntry{DonationRequestID: uuid.NewV4().String(), Player: player, Amount: 1, MaxWeightPerPlayer: 100},
+				&api.BatchDonationEntry{DonationRequestID: dr1.ID, Player: player, MaxWeightPerPlayer: 100},
+				&api.BatchDonationEntry{DonationRequestID: dr1.ID, Player: uuid.NewV4().String(), Amount: 5, MaxWeightPerPlayer: 100},
+			)
+			Expect(status).To(Equal(http.StatusOK), body)
+
+			var result batchResponse
+			err = json.Unmarshal([]byte(body), &result)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Succeeded).To(Equal(2))
+			Expect(result.Failed).To(Equal(4))
+			Expect(result.Results).To(HaveLen(6))
+
+			codes := []string{}
+			for i, r := range result.Results {
+				Expect(r.Index).To(Equal(i))
+				codes = append(codes, r.Code)
+			}
+			Expect(codes).To(Equal([]string{
+				"",
+				api.BatchPlayerLimitReached,
+				"",
+				api.BatchDonationRequestNotFound,
+				api.BatchInvalidDonation,
+				api.BatchDonationRequestLimitReached,
+			}))
+			Expect(result.Results[4].Reason).To(ContainSubstring("amount is required"))
+
+			dbDr, err := models.GetDonationRequestByID(dr1.ID, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dbDr.Donations).To(HaveLen(1))
+			Expect(dbDr.Donations[0].Amount).To(Equal(2))
+		})
+
+		It("Should fail without donations", func() {
+			status, body := postBatch()
+			Expect(status).To(Equal(http.StatusBadRequest))
+			Expect(body).To(ContainSubstring("donations is required"))
+		})
+
+		It("Should fail with too many donations", func() {
+			app.Config.Set("api.batch.maxDonations", 1)
+			entry := &api.BatchDonationEntry{DonationRequestID: "id", Player: "player", Amount: 1, MaxWeightPerPlayer: 1}
+			status, body := postBatch(entry, entry)
+			Expect(status).To(Equal(http.StatusBadRequest))
+			Expect(body).To(ContainSubstring("more than 1 donations"))
+		})
+
+		It("Should fail the donations to expired donation requests", func() {
+			dr, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = models.ExpireDonationRequests(
+				game.ID, time.Hour, &MockClock{Time: time.Now().UTC().Unix() + 7200}, app.MongoDb, app.Logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			status, body := postBatch(
+				&api.BatchDonationEntry{DonationRequestID: dr.ID, Player: uuid.NewV4().String(), Amount: 1, MaxWeightPerPlayer: 100},
+			)
+			Expect(status).To(Equal(http.StatusOK), body)
+			Expect(getCodes(body)).To(Equal([]string{api.BatchDonationRequestExpired}))
+		})
+
+		It("Should fail the donations to donation requests of other games", func() {
+			other, err := GetTestGame(app.MongoDb, app.Logger, true)
+			Expect(err).NotTo(HaveOccurred())
+			dr, err := GetTestDonationRequest(other, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			status, body := postBatch(
+				&api.BatchDonationEntry{DonationRequestID: dr.ID, Player: uuid.NewV4().String(), Amount: 1, MaxWeightPerPlayer: 100},
+			)
+			Expect(status).To(Equal(http.StatusOK), body)
+			Expect(getCodes(body)).To(Equal([]string{api.BatchDonationRequestNotFound}))
+
+			dbDr, err := models.GetDonationRequestByID(dr.ID, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dbDr.Donations).To(BeEmpty())
+		})
+
+		It("Should authenticate each donation with its own player token or the header", func() {
+			_, err := models.SetPlayerAuthConfig(
+				game.ID, models.PlayerAuthProviderJWT, "HS256", "secret",
+				&models.RealClock{}, app.MongoDb, app.Logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			dr, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			status, body := postBatchWithHeaders(
+				map[string]string{api.PlayerTokenHeader: playerToken("player-1")},
+				&api.BatchDonationEntry{DonationRequestID: dr.ID, Player: "player-1", Amount: 1, MaxWeightPerPlayer: 100},
+				&api.BatchDonationEntry{
+					DonationRequestID: dr.ID, Player: "player-2", Amount: 1, MaxWeightPerPlayer: 100,
+					PlayerToken: playerToken("player-2"),
+				},
+				&api.BatchDonationEntry{
+					DonationRequestID: dr.ID, Player: "player-3", Amount: 1, MaxWeightPerPlayer: 100,
+					PlayerToken: playerToken("player-2"),
+				},
+				&api.BatchDonationEntry{DonationRequestID: dr.ID, Player: "player-4", Amount: 1, MaxWeightPerPlayer: 100},
+			)
+			Expect(status).To(Equal(http.StatusOK), body)
+			Expect(getCodes(body)).To(Equal([]string{
+				"",
+				"",
+				api.BatchPlayerAuthenticationFailed,
+				api.BatchPlayerAuthenticationFailed,
+			}))
+
+			dbDr, err := models.GetDonationRequestByID(dr.ID, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dbDr.Donations).To(HaveLen(2))
+		})
+
+		It("Should replay repeated batches with their content type only to authenticated players", func() {
+			_, err := models.SetPlayerAuthConfig(
+				game.ID, models.PlayerAuthProviderJWT, "HS256", "secret",
+				&models.RealClock{}, app.MongoDb, app.Logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			dr, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			payload := &api.BatchDonationPayload{Donations: []*api.BatchDonationEntry{
+				&api.BatchDonationEntry{DonationRequestID: dr.ID, Player: "player-1", Amount: 1, MaxWeightPerPlayer: 100},
+				&api.BatchDonationEntry{DonationRequestID: dr.ID, Player: "player-2", Amount: 1, MaxWeightPerPlayer: 100},
+			}}
+			jsonPayload, err := payload.ToJSON()
+			Expect(err).NotTo(HaveOccurred())
+			key := uuid.NewV4().String()
+			ts := InitializeTestServer(app)
+			defer ts.Close()
+
+			post := func(token string) (*http.Response, string) {
+				req := GetRequest(app, ts, "POST", fmt.Sprintf("/games/%s/donations/batch", game.ID), bytes.NewBuffer(jsonPayload))
+				req.Header.Set(api.IdempotencyKeyHeader, key)
+				if token != "" {
+					req.Header.Set(api.PlayerTokenHeader, token)
+				}
+				res := PerformRequest(ts, req)
+				return res, string(ReadBody(res))
+			}
+
+			res, body := post(playerToken("player-1"))
+			Expect(res.StatusCode).To(Equal(http.StatusOK), body)
+			Expect(getCodes(body)).To(Equal([]string{"", api.BatchPlayerAuthenticationFailed}))
+			contentType := res.Header.Get("Content-Type")
+			Expect(contentType).To(ContainSubstring("application/json"))
+
+			res, replayed := post(playerToken("player-1"))
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(res.Header.Get(api.IdempotentReplayHeader)).To(Equal("true"))
+			Expect(res.Header.Get("Content-Type")).To(Equal(contentType))
+			Expect(replayed).To(Equal(body))
+
+			res, replayed = post("")
+			Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(res.Header.Get(api.IdempotentReplayHeader)).To(BeEmpty())
+			Expect(replayed).To(ContainSubstring("Authentication with provider jwt for user player-1 failed"))
+
+			dbDr, err := models.GetDonationRequestByID(dr.ID, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dbDr.Donations).To(HaveLen(1))
+		})
+
+		It("Should not exceed the limit of a donation request with concurrent batches", func() {
+			dr, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			var wg sync.WaitGroup
+			var mutex sync.Mutex
+			codes := []string{}
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer GinkgoRecover()
+					status, body := postBatch(
+						&api.BatchDonationEntry{DonationRequestID: dr.ID, Player: uuid.NewV4().String(), Amount: 1, MaxWeightPerPlayer: 100},
+					)
+					Expect(status).To(Equal(http.StatusOK), body)
+					mutex.Lock()
+					codes = append(codes, getCodes(body)...)
+					mutex.Unlock()
+				}()
+			}
+			wg.Wait()
+
+			succeeded := 0
+			for _, code := range codes {
+				if code == "" {
+					succeeded++
+					continue
+				}
+				Expect(code).To(Or(Equal(api.BatchDonationRequestLimitReached), Equal(api.BatchConcurrentUpdate)))
+			}
+
+			dbDr, err := models.GetDonationRequestByID(dr.ID, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dbDr.Donations).To(HaveLen(succeeded))
+			Expect(dbDr.GetDonationCount()).To(BeNumerically("<=", GetFirstItem(game).LimitOfItemsInEachDonationRequest))
+		})
+	})
+})
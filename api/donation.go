@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/topfreegames/donations/errors"
 	"github.com/topfreegames/donations/log"
 	"github.com/topfreegames/donations/models"
 
@@ -37,7 +38,15 @@ func CreateDonationRequestHandler(app *App) func(c echo.Context) error {
 			return nil
 		})
 		if err != nil {
-			return FailWith(400, err.Error(), c)
+			return FailWithError(400, err, c)
+		}
+
+		if status, err := authenticatePlayerOfRequest(app, c, gameID, payload.Player, &payload.Clan); err != nil {
+			return FailWithError(status, err, c)
+		}
+
+		if status, err := applyPlayerRateLimit(app, c, "CreateDonationRequest", payload.Player); err != nil {
+			return FailWithError(status, err, c)
 		}
 
 		var status int
@@ -58,7 +67,7 @@ func CreateDonationRequestHandler(app *App) func(c echo.Context) error {
 			err = WithSegment("DonationRequest", c, func() error {
 				mutexID := fmt.Sprintf("DonationRequest-%s-%s", gameID, payload.Player)
 				mutex := app.GetMutex(mutexID, 32, 8) // Number of retries to get lock and Lock expiration
-				err := mutex.Lock()
+				err := mutex.Lock(GetTraceContext(c))
 				if err != nil {
 					log.E(l, "Could not acquire lock after many retries.", func(cm log.CM) {
 						cm.Write(zap.Error(err))
@@ -73,6 +82,7 @@ func CreateDonationRequestHandler(app *App) func(c echo.Context) error {
 					payload.Player,
 					payload.Clan,
 				)
+				donationRequest.Context = GetTraceContext(c)
 				err = donationRequest.Create(app.MongoDb, app.Logger)
 				if err != nil {
 					status = 500
@@ -86,7 +96,7 @@ func CreateDonationRequestHandler(app *App) func(c echo.Context) error {
 			return nil
 		})
 		if err != nil {
-			return FailWith(status, err.Error(), c)
+			return FailWithError(status, err, c)
 		}
 
 		log.I(l, "Created new donation request successfully.", func(cm log.CM) {
@@ -105,7 +115,7 @@ func CreateDonationRequestHandler(app *App) func(c echo.Context) error {
 			return nil
 		})
 		if err != nil {
-			return FailWith(500, err.Error(), c)
+			return FailWithError(500, err, c)
 		}
 
 		return c.String(http.StatusOK, string(donationRequestJSON))
@@ -137,33 +147,54 @@ func CreateDonationHandler(app *App) func(c echo.Context) error {
 			return nil
 		})
 		if err != nil {
-			return FailWith(400, err.Error(), c)
+			return FailWithError(400, err, c)
+		}
+
+		if status, err := authenticatePlayerOfRequest(app, c, gameID, payload.Player, nil); err != nil {
+			return FailWithError(status, err, c)
+		}
+
+		if status, err := applyPlayerRateLimit(app, c, "CreateDonation", payload.Player); err != nil {
+			return FailWithError(status, err, c)
 		}
 
 		err = models.EnsurePlayerExists(gameID, payload.Player, app.MongoDb, app.Logger)
 		if err != nil {
-			return FailWith(500, err.Error(), c)
+			return FailWithError(500, err, c)
 		}
 
 		var donationRequest *models.DonationRequest
 		err = WithSegment("model", c, func() error {
-			mutexID := fmt.Sprintf("Donate-%s-%s", gameID, donationRequestID)
-			mutex := app.GetMutex(mutexID, 32, 8) // Number of retries to get lock and Lock expiration
-			err := mutex.Lock()
+			//Donation limits are enforced by optimistic concurrency in the model,
+			//the lock only serializes donations to the same donation request
+			if app.Config.GetBool("api.donationLock.enabled") {
+				mutexID := fmt.Sprintf("Donate-%s-%s", gameID, donationRequestID)
+				mutex := app.GetMutex(mutexID, 32, 8) // Number of retries to get lock and Lock expiration
+				err := mutex.Lock(GetTraceContext(c))
 
+				if err != nil {
+					log.E(l, "Could not acquire lock after many retries.", func(cm log.CM) {
+						cm.Write(zap.Error(err))
+					})
+					return err
+				}
+				defer mutex.Unlock()
+			}
+
+			donationRequest, err = models.GetDonationRequestByID(donationRequestID, app.MongoDb, app.Logger)
+			if err == nil && donationRequest.GameID != gameID {
+				err = errors.NewDocumentNotFoundError("donationRequest", donationRequestID)
+			}
 			if err != nil {
-				log.E(l, "Could not acquire lock after many retries.", func(cm log.CM) {
-					cm.Write(zap.Error(err))
-				})
 				return err
 			}
-			defer mutex.Unlock()
 
-			donationRequest, err = models.GetDonationRequestByID(donationRequestID, app.MongoDb, app.Logger)
+			err = donationRequest.ValidateDonationRequestNotExpired(app.GetDonationRequestExpiration(), app.Logger)
 			if err != nil {
 				return err
 			}
 
+			donationRequest.Context = GetTraceContext(c)
 			err = donationRequest.Donate(payload.Player, payload.Amount, payload.MaxWeightPerPlayer, app.Redis, app.MongoDb, app.Logger)
 			if err != nil {
 				return err
@@ -171,8 +202,17 @@ func CreateDonationHandler(app *App) func(c echo.Context) error {
 
 			return nil
 		})
+		if _, ok := err.(*errors.DocumentNotFoundError); ok {
+			return FailWithError(404, err, c)
+		}
+		if _, ok := err.(*errors.DonationRequestConcurrentlyUpdatedError); ok {
+			return FailWithError(http.StatusConflict, err, c)
+		}
+		if _, ok := err.(*errors.DonationRequestExpiredError); ok {
+			return FailWithError(http.StatusGone, err, c)
+		}
 		if err != nil {
-			return FailWith(500, err.Error(), c)
+			return FailWithError(500, err, c)
 		}
 
 		log.I(l, "Created new donation request successfully.", func(cm log.CM) {
@@ -180,13 +220,58 @@ func CreateDonationHandler(app *App) func(c echo.Context) error {
 		})
 
 		if err != nil {
-			return FailWith(500, err.Error(), c)
+			return FailWithError(500, err, c)
 		}
 
 		return c.String(http.StatusOK, "{\"success\":true}")
 	}
 }
 
+//GetDonationRequestHandler is the handler responsible for retrieving live or archived donation requests
+func GetDonationRequestHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		l := app.Logger.With(
+			zap.String("source", "GetDonationRequestHandler"),
+			zap.String("operation", "GetDonationRequest"),
+		)
+		c.Set("route", "GetDonationRequest")
+		gameID := c.Param("gameID")
+		donationRequestID := c.Param("donationRequestID")
+
+		log.D(l, "Getting donation request...")
+
+		var donationRequest *models.DonationRequest
+		err := WithSegment("model", c, func() error {
+			var err error
+			donationRequest, err = models.GetDonationRequestFromHistory(donationRequestID, app.MongoDb, app.Logger)
+			return err
+		})
+		if _, ok := err.(*errors.DocumentNotFoundError); ok || (err == nil && donationRequest.GameID != gameID) {
+			return FailWithError(404, errors.NewDocumentNotFoundError("donationRequest", donationRequestID), c)
+		}
+		if err != nil {
+			return FailWithError(500, err, c)
+		}
+
+		var donationRequestJSON []byte
+		err = WithSegment("serialization", c, func() error {
+			donationRequestJSON, err = donationRequest.ToJSON()
+			if err != nil {
+				log.E(l, "Failed to marshal donation request!", func(cm log.CM) {
+					cm.Write(zap.Error(err))
+				})
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return FailWithError(500, err, c)
+		}
+
+		return c.String(http.StatusOK, string(donationRequestJSON))
+	}
+}
+
 func toTime(val string) int64 {
 	var err error
 	var res int64
@@ -202,6 +287,20 @@ func toTime(val string) int64 {
 	return res
 }
 
+//getResetType returns the reset type of a daily, weekly or monthly type, or NoReset otherwise
+func getResetType(resetType string) models.ResetType {
+	switch resetType {
+	case "daily":
+		return models.DailyReset
+	case "weekly":
+		return models.WeeklyReset
+	case "monthly":
+		return models.MonthlyReset
+	default:
+		return models.NoReset
+	}
+}
+
 //GetDonationWeightByClanHandler is the handler responsible for creating donation requests
 func GetDonationWeightByClanHandler(app *App) func(c echo.Context) error {
 	return func(c echo.Context) error {
@@ -209,26 +308,15 @@ func GetDonationWeightByClanHandler(app *App) func(c echo.Context) error {
 			zap.String("source", "GetDonationWeightByClanHandler"),
 			zap.String("operation", "GetDonationWeightByClan"),
 		)
-		c.Set("route", "CreateDonation")
+		c.Set("route", "GetDonationWeightByClan")
 		gameID := c.Param("gameID")
 		clanID := c.QueryParam("clanID")
-		resetTypeStr := c.QueryParam("type")
-		var resetType models.ResetType
-		switch resetTypeStr {
-		case "daily":
-			resetType = models.DailyReset
-		case "weekly":
-			resetType = models.WeeklyReset
-		case "monthly":
-			resetType = models.MonthlyReset
-		default:
-			resetType = models.NoReset
-		}
+		resetType := getResetType(c.QueryParam("type"))
 
 		log.D(l, "Getting clan weight...")
 		weight, err := models.GetDonationWeightForClan(gameID, clanID, time.Now(), resetType, app.Redis, app.Logger)
 		if err != nil {
-			return FailWith(500, err.Error(), c)
+			return FailWithError(500, err, c)
 		}
 
 		return c.String(http.StatusOK, fmt.Sprintf("{\"success\":true, \"weight\": %d}", weight))
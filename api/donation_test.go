@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/topfreegames/donations/models"
 
@@ -65,6 +66,107 @@ var _ = Describe("Game Handler", func() {
 		})
 	})
 
+	Describe("Get Donation Request", func() {
+		Describe("Feature", func() {
+			It("Should respond with live donation request json", func() {
+				game, err := GetTestGame(app.MongoDb, app.Logger, true)
+				Expect(err).NotTo(HaveOccurred())
+
+				donationRequest, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				status, body := Get(app, fmt.Sprintf("/games/%s/donation-requests/%s", game.ID, donationRequest.ID))
+				Expect(status).To(Equal(http.StatusOK))
+
+				dr, err := models.GetDonationRequestFromJSON([]byte(body))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dr.ID).To(Equal(donationRequest.ID))
+				Expect(dr.GameID).To(Equal(game.ID))
+			})
+
+			It("Should respond with archived donation request json", func() {
+				game, err := GetTestGame(app.MongoDb, app.Logger, true)
+				Expect(err).NotTo(HaveOccurred())
+
+				donationRequest, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				clock := &MockClock{Time: time.Now().UTC().Unix() + 7200}
+				_, err = models.ExpireDonationRequests(game.ID, time.Hour, clock, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = models.ArchiveDonationRequests(game.ID, 0, false, clock, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				status, body := Get(app, fmt.Sprintf("/games/%s/donation-requests/%s", game.ID, donationRequest.ID))
+				Expect(status).To(Equal(http.StatusOK))
+
+				dr, err := models.GetDonationRequestFromJSON([]byte(body))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dr.ID).To(Equal(donationRequest.ID))
+			})
+
+			It("Should fail if donation request belongs to another game", func() {
+				game, err := GetTestGame(app.MongoDb, app.Logger, true)
+				Expect(err).NotTo(HaveOccurred())
+
+				donationRequest, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				status, _ := Get(app, fmt.Sprintf("/games/%s/donation-requests/%s", uuid.NewV4().String(), donationRequest.ID))
+				Expect(status).To(Equal(http.StatusNotFound))
+			})
+
+			It("Should fail if donation request does not exist", func() {
+				game, err := GetTestGame(app.MongoDb, app.Logger, true)
+				Expect(err).NotTo(HaveOccurred())
+
+				status, _ := Get(app, fmt.Sprintf("/games/%s/donation-requests/%s", game.ID, uuid.NewV4().String()))
+				Expect(status).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("Create Donation Request", func() {
+		Describe("Idempotency", func() {
+			It("Should create a single donation request for a repeated idempotency key", func() {
+				game, err := GetTestGame(app.MongoDb, app.Logger, true)
+				Expect(err).NotTo(HaveOccurred())
+
+				payload := &api.CreateDonationRequestPayload{
+					Player: uuid.NewV4().String(),
+					Item:   GetFirstItem(game).Key,
+					Clan:   uuid.NewV4().String(),
+				}
+				jsonPayload, err := payload.ToJSON()
+				Expect(err).NotTo(HaveOccurred())
+
+				headers := map[string]string{api.IdempotencyKeyHeader: uuid.NewV4().String()}
+				status, body := PostWithHeaders(
+					app,
+					fmt.Sprintf("/games/%s/donation-requests/", game.ID),
+					string(jsonPayload),
+					headers,
+				)
+				Expect(status).To(Equal(http.StatusOK))
+
+				status, replayed := PostWithHeaders(
+					app,
+					fmt.Sprintf("/games/%s/donation-requests/", game.ID),
+					string(jsonPayload),
+					headers,
+				)
+				Expect(status).To(Equal(http.StatusOK))
+				Expect(replayed).To(Equal(body))
+
+				count, err := models.GetDonationRequestsCollection(app.MongoDb).Find(map[string]interface{}{
+					"player": payload.Player,
+				}).Count()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(count).To(Equal(1))
+			})
+		})
+	})
+
 	Describe("Donate", func() {
 		Describe("Feature", func() {
 			It("Should respond with donation json after creation", func() {
@@ -121,6 +223,196 @@ var _ = Describe("Game Handler", func() {
 				Expect(body).To(Equal("{\"success\":true}"))
 			})
 
+			It("Should fail if the donation request belongs to another game", func() {
+				game, err := GetTestGame(app.MongoDb, app.Logger, true)
+				Expect(err).NotTo(HaveOccurred())
+
+				otherGame, err := GetTestGame(app.MongoDb, app.Logger, true)
+				Expect(err).NotTo(HaveOccurred())
+
+				donation, err := GetTestDonationRequest(otherGame, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				payload := &api.DonationPayload{
+					Player:             uuid.NewV4().String(),
+					Amount:             1,
+					MaxWeightPerPlayer: 50,
+				}
+				jsonPayload, err := payload.ToJSON()
+				Expect(err).NotTo(HaveOccurred())
+				status, _ := Post(
+					app,
+					fmt.Sprintf("/games/%s/donation-requests/%s/", game.ID, donation.ID),
+					string(jsonPayload),
+				)
+				Expect(status).To(Equal(http.StatusNotFound))
+
+				dbDonation, err := models.GetDonationRequestByID(donation.ID, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dbDonation.Donations).To(BeEmpty())
+			})
+
+			It("Should fail if the donation request does not exist", func() {
+				game, err := GetTestGame(app.MongoDb, app.Logger, true)
+				Expect(err).NotTo(HaveOccurred())
+
+				payload := &api.DonationPayload{
+					Player:             uuid.NewV4().String(),
+					Amount:             1,
+					MaxWeightPerPlayer: 50,
+				}
+				jsonPayload, err := payload.ToJSON()
+				Expect(err).NotTo(HaveOccurred())
+				status, _ := Post(
+					app,
+					fmt.Sprintf("/games/%s/donation-requests/%s/", game.ID, uuid.NewV4().String()),
+					string(jsonPayload),
+				)
+				Expect(status).To(Equal(http.StatusNotFound))
+			})
+
+			It("Should fail if the donation request expired", func() {
+				game, err := GetTestGame(app.MongoDb, app.Logger, true)
+				Expect(err).NotTo(HaveOccurred())
+
+				player, err := GetTestPlayer(game, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				donation, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				createdAt := time.Now().UTC().Add(-app.GetDonationRequestExpiration()).Unix() - 1
+				err = models.GetDonationRequestsCollection(app.MongoDb).UpdateId(
+					donation.ID,
+					map[string]interface{}{"$set": map[string]interface{}{"createdAt": createdAt}},
+				)
+				Expect(err).NotTo(HaveOccurred())
+
+				payload := &api.DonationPayload{
+					Player:             player.ID,
+					Amount:             1,
+					MaxWeightPerPlayer: 50,
+				}
+				jsonPayload, err := payload.ToJSON()
+				Expect(err).NotTo(HaveOccurred())
+				status, body := Post(
+					app,
+					fmt.Sprintf("/games/%s/donation-requests/%s/", game.ID, donation.ID),
+					string(jsonPayload),
+				)
+				Expect(status).To(Equal(http.StatusGone))
+				Expect(body).To(ContainSubstring("has expired"))
+			})
+
+			It("Should replay the original response for a repeated idempotency key", func() {
+				game, err := GetTestGame(app.MongoDb, app.Logger, true)
+				Expect(err).NotTo(HaveOccurred())
+
+				player, err := GetTestPlayer(game, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				donation, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				payload := &api.DonationPayload{
+					Player:             player.ID,
+					Amount:             1,
+					MaxWeightPerPlayer: 50,
+				}
+				jsonPayload, err := payload.ToJSON()
+				Expect(err).NotTo(HaveOccurred())
+
+				headers := map[string]string{api.IdempotencyKeyHeader: uuid.NewV4().String()}
+				for i := 0; i < 3; i++ {
+					status, body := PostWithHeaders(
+						app,
+						fmt.Sprintf("/games/%s/donation-requests/%s/", game.ID, donation.ID),
+						string(jsonPayload),
+						headers,
+					)
+					Expect(status).To(Equal(http.StatusOK))
+					Expect(body).To(Equal("{\"success\":true}"))
+				}
+
+				dbDonationRequest, err := models.GetDonationRequestByID(donation.ID, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dbDonationRequest.Donations).To(HaveLen(1))
+			})
+
+			It("Should fail if idempotency key is reused for another donation request", func() {
+				game, err := GetTestGame(app.MongoDb, app.Logger, true)
+				Expect(err).NotTo(HaveOccurred())
+
+				player, err := GetTestPlayer(game, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				donation, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				otherDonation, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				payload := &api.DonationPayload{
+					Player:             player.ID,
+					Amount:             1,
+					MaxWeightPerPlayer: 50,
+				}
+				jsonPayload, err := payload.ToJSON()
+				Expect(err).NotTo(HaveOccurred())
+
+				headers := map[string]string{api.IdempotencyKeyHeader: uuid.NewV4().String()}
+				status, _ := PostWithHeaders(
+					app,
+					fmt.Sprintf("/games/%s/donation-requests/%s/", game.ID, donation.ID),
+					string(jsonPayload),
+					headers,
+				)
+				Expect(status).To(Equal(http.StatusOK))
+
+				status, body := PostWithHeaders(
+					app,
+					fmt.Sprintf("/games/%s/donation-requests/%s/", game.ID, otherDonation.ID),
+					string(jsonPayload),
+					headers,
+				)
+				Expect(status).To(Equal(http.StatusUnprocessableEntity))
+				Expect(body).To(ContainSubstring("was already used for a different request to"))
+			})
+
+			It("Should fail if idempotency key is reused with another payload", func() {
+				game, err := GetTestGame(app.MongoDb, app.Logger, true)
+				Expect(err).NotTo(HaveOccurred())
+
+				player, err := GetTestPlayer(game, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				donation, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				headers := map[string]string{api.IdempotencyKeyHeader: uuid.NewV4().String()}
+				for amount, expected := range []int{http.StatusOK, http.StatusUnprocessableEntity} {
+					payload := &api.DonationPayload{
+						Player:             player.ID,
+						Amount:             amount + 1,
+						MaxWeightPerPlayer: 50,
+					}
+					jsonPayload, err := payload.ToJSON()
+					Expect(err).NotTo(HaveOccurred())
+
+					status, _ := PostWithHeaders(
+						app,
+						fmt.Sprintf("/games/%s/donation-requests/%s/", game.ID, donation.ID),
+						string(jsonPayload),
+						headers,
+					)
+					Expect(status).To(Equal(expected))
+				}
+
+				dbDonationRequest, err := models.GetDonationRequestByID(donation.ID, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dbDonationRequest.Donations).To(HaveLen(1))
+			})
+
 			It("Should not donate more than allowed", func() {
 				var wg sync.WaitGroup
 				results := []map[string]interface{}{}
@@ -174,6 +466,206 @@ var _ = Describe("Game Handler", func() {
 				Expect(ok).To(Equal(2))
 				Expect(failed).To(Equal(8))
 			})
+
+			It("Should not donate more than allowed without the donation lock", func() {
+				var wg sync.WaitGroup
+				var mutex sync.Mutex
+				results := []int{}
+
+				app.Config.Set("api.donationLock.enabled", false)
+				defer app.Config.Set("api.donationLock.enabled", true)
+
+				game, err := GetTestGame(app.MongoDb, app.Logger, true, map[string]interface{}{
+					"LimitOfItemsInEachDonationRequest": 2,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				donation, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				playerID := uuid.NewV4().String()
+				payload := &api.DonationPayload{
+					Player:             playerID,
+					Amount:             1,
+					MaxWeightPerPlayer: 50,
+				}
+				jsonPayload, err := payload.ToJSON()
+				Expect(err).NotTo(HaveOccurred())
+
+				for i := 0; i < 10; i++ {
+					wg.Add(1)
+
+					go func(index int) {
+						defer wg.Done()
+						status, _ := Post(
+							app,
+							fmt.Sprintf("/games/%s/donation-requests/%s/", game.ID, donation.ID),
+							string(jsonPayload),
+						)
+
+						mutex.Lock()
+						results = append(results, status)
+						mutex.Unlock()
+					}(i)
+				}
+
+				wg.Wait()
+
+				ok := 0
+				failed := 0
+				for _, status := range results {
+					if status == 200 {
+						ok++
+					} else {
+						failed++
+					}
+				}
+				Expect(ok).To(Equal(2))
+				Expect(failed).To(Equal(8))
+
+				dbDonationRequest, err := models.GetDonationRequestByID(donation.ID, app.MongoDb, app.Logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dbDonationRequest.Donations).To(HaveLen(2))
+				Expect(dbDonationRequest.FinishedAt).NotTo(BeEquivalentTo(0))
+			})
+		})
+	})
+
+	Describe("Rate Limit", func() {
+		createDonationRequestFrom := func(game *models.Game, forwardedFor string) (int, string) {
+			payload := &api.CreateDonationRequestPayload{
+				Player: uuid.NewV4().String(),
+				Item:   GetFirstItem(game).Key,
+				Clan:   uuid.NewV4().String(),
+			}
+			jsonPayload, err := payload.ToJSON()
+			Expect(err).NotTo(HaveOccurred())
+			url := fmt.Sprintf("/games/%s/donation-requests", game.ID)
+			return PostWithHeaders(app, url, string(jsonPayload), map[string]string{"X-Forwarded-For": forwardedFor})
+		}
+
+		createDonationRequest := func(game *models.Game) (int, string) {
+			return createDonationRequestFrom(game, "")
+		}
+
+		It("Should limit the donation requests of a game", func() {
+			app.Config.Set("api.rateLimit.enabled", true)
+			app.Config.Set("api.rateLimit.routes.CreateDonationRequest.game.capacity", 2)
+			app.Config.Set("api.rateLimit.routes.CreateDonationRequest.game.refillPerSecond", 0.01)
+
+			game, err := GetTestGame(app.MongoDb, app.Logger, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			for i := 0; i < 2; i++ {
+				status, body := createDonationRequest(game)
+				Expect(status).To(Equal(http.StatusOK), body)
+			}
+
+			status, body := createDonationRequest(game)
+			Expect(status).To(Equal(http.StatusTooManyRequests))
+			Expect(body).To(ContainSubstring("Rate limit per game of route CreateDonationRequest exceeded."))
+
+			other, err := GetTestGame(app.MongoDb, app.Logger, true)
+			Expect(err).NotTo(HaveOccurred())
+			status, body = createDonationRequest(other)
+			Expect(status).To(Equal(http.StatusOK), body)
+		})
+
+		It("Should not take tokens from the other limits of a request over one", func() {
+			app.Config.Set("api.rateLimit.enabled", true)
+			app.Config.Set("api.rateLimit.forwardedForHeader", "X-Forwarded-For")
+			app.Config.Set("api.rateLimit.routes.CreateDonationRequest.game.capacity", 2)
+			app.Config.Set("api.rateLimit.routes.CreateDonationRequest.game.refillPerSecond", 0.01)
+			app.Config.Set("api.rateLimit.routes.CreateDonationRequest.ip.capacity", 1)
+			app.Config.Set("api.rateLimit.routes.CreateDonationRequest.ip.refillPerSecond", 0.01)
+
+			game, err := GetTestGame(app.MongoDb, app.Logger, true)
+			Expect(err).NotTo(HaveOccurred())
+			//addresses are unique so that the buckets of previous runs are not used
+			client := uuid.NewV4().String()
+
+			status, body := createDonationRequestFrom(game, client)
+			Expect(status).To(Equal(http.StatusOK), body)
+
+			status, body = createDonationRequestFrom(game, client)
+			Expect(status).To(Equal(http.StatusTooManyRequests))
+			Expect(body).To(ContainSubstring("Rate limit per ip of route CreateDonationRequest exceeded."))
+
+			status, body = createDonationRequestFrom(game, uuid.NewV4().String())
+			Expect(status).To(Equal(http.StatusOK), body)
+
+			status, body = createDonationRequestFrom(game, uuid.NewV4().String())
+			Expect(status).To(Equal(http.StatusTooManyRequests))
+			Expect(body).To(ContainSubstring("Rate limit per game of route CreateDonationRequest exceeded."))
+		})
+
+		It("Should limit the IP appended by the trusted proxy to the forwarded for header", func() {
+			app.Config.Set("api.rateLimit.enabled", true)
+			app.Config.Set("api.rateLimit.forwardedForHeader", "X-Forwarded-For")
+			app.Config.Set("api.rateLimit.trustedProxies", 1)
+			app.Config.Set("api.rateLimit.routes.CreateDonationRequest.ip.capacity", 1)
+			app.Config.Set("api.rateLimit.routes.CreateDonationRequest.ip.refillPerSecond", 0.01)
+
+			game, err := GetTestGame(app.MongoDb, app.Logger, true)
+			Expect(err).NotTo(HaveOccurred())
+			client := uuid.NewV4().String()
+
+			status, body := createDonationRequestFrom(game, fmt.Sprintf("forged-0, %s", client))
+			Expect(status).To(Equal(http.StatusOK), body)
+
+			status, body = createDonationRequestFrom(game, fmt.Sprintf("forged-1, %s", client))
+			Expect(status).To(Equal(http.StatusTooManyRequests))
+			Expect(body).To(ContainSubstring("Rate limit per ip of route CreateDonationRequest exceeded."))
+
+			status, body = createDonationRequestFrom(game, uuid.NewV4().String())
+			Expect(status).To(Equal(http.StatusOK), body)
+		})
+
+		It("Should ignore the forwarded for header without trusted proxies", func() {
+			app.Config.Set("api.rateLimit.enabled", true)
+			app.Config.Set("api.rateLimit.forwardedForHeader", "X-Forwarded-For")
+			app.Config.Set("api.rateLimit.trustedProxies", 0)
+			app.Config.Set("api.rateLimit.routes.CreateDonationRequest.ip.capacity", 1)
+			app.Config.Set("api.rateLimit.routes.CreateDonationRequest.ip.refillPerSecond", 0.01)
+
+			game, err := GetTestGame(app.MongoDb, app.Logger, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			status, body := createDonationRequestFrom(game, uuid.NewV4().String())
+			Expect(status).To(Equal(http.StatusOK), body)
+
+			status, body = createDonationRequestFrom(game, uuid.NewV4().String())
+			Expect(status).To(Equal(http.StatusTooManyRequests))
+			Expect(body).To(ContainSubstring("Rate limit per ip of route CreateDonationRequest exceeded."))
+		})
+
+		It("Should limit each player of a batch once", func() {
+			app.Config.Set("api.rateLimit.enabled", true)
+			app.Config.Set("api.rateLimit.routes.BatchDonation.player.capacity", 1)
+			app.Config.Set("api.rateLimit.routes.BatchDonation.player.refillPerSecond", 0.01)
+
+			game, err := GetTestGame(app.MongoDb, app.Logger, true)
+			Expect(err).NotTo(HaveOccurred())
+			dr, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			player := uuid.NewV4().String()
+			payload := &api.BatchDonationPayload{
+				Donations: []*api.BatchDonationEntry{
+					&api.BatchDonationEntry{DonationRequestID: dr.ID, Player: player, Amount: 1, MaxWeightPerPlayer: 100},
+					&api.BatchDonationEntry{DonationRequestID: dr.ID, Player: player, Amount: 1, MaxWeightPerPlayer: 100},
+				},
+			}
+			jsonPayload, err := payload.ToJSON()
+			Expect(err).NotTo(HaveOccurred())
+
+			url := fmt.Sprintf("/games/%s/donations/batch", game.ID)
+			status, body := Post(app, url, string(jsonPayload))
+			Expect(status).To(Equal(http.StatusOK), body)
+
+			status, body = Post(app, url, string(jsonPayload))
+			Expect(status).To(Equal(http.StatusTooManyRequests))
+			Expect(body).To(ContainSubstring("Rate limit per player of route BatchDonation exceeded."))
 		})
 	})
 })
@@ -28,16 +28,17 @@ func UpdateGameHandler(app *App) func(c echo.Context) error {
 			log.E(l, "Invalid json payload!", func(cm log.CM) {
 				cm.Write(zap.Error(err))
 			})
-			return FailWith(400, err.Error(), c)
+			return FailWithError(400, err, c)
 		}
 
+		var before *models.Game
 		game, err := models.GetGameByID(gameID, app.MongoDb, app.Logger)
 		if err != nil {
 			if _, ok := err.(*errors.DocumentNotFoundError); !ok {
 				log.E(l, "Failed to retrieve game!", func(cm log.CM) {
 					cm.Write(zap.Error(err))
 				})
-				return FailWith(500, err.Error(), c)
+				return FailWithError(500, err, c)
 			}
 			log.D(l, "Game not found, creating new game...")
 			game = models.NewGame(
@@ -46,6 +47,7 @@ func UpdateGameHandler(app *App) func(c echo.Context) error {
 				payload.DonationRequestCooldownHours,
 			)
 		} else {
+			before = game.Clone()
 			game.Name = payload.Name
 			game.DonationCooldownHours = payload.DonationCooldownHours
 			game.DonationRequestCooldownHours = payload.DonationRequestCooldownHours
@@ -56,19 +58,24 @@ func UpdateGameHandler(app *App) func(c echo.Context) error {
 			log.E(l, "Failed to update game!", func(cm log.CM) {
 				cm.Write(zap.Error(err))
 			})
-			return FailWith(500, err.Error(), c)
+			return FailWithError(500, err, c)
 		}
 
 		log.I(l, "Updated game successfully.", func(cm log.CM) {
 			cm.Write(zap.String("ID", game.ID))
 		})
 
+		err = recordAudit(app, getActor(app, c), getClaimedActor(c), models.AuditUpdateGame, before, game, l)
+		if err != nil {
+			return FailWithError(500, err, c)
+		}
+
 		gameJSON, err := game.ToJSON()
 		if err != nil {
 			log.E(l, "Failed to marshal game!", func(cm log.CM) {
 				cm.Write(zap.Error(err))
 			})
-			return FailWith(500, err.Error(), c)
+			return FailWithError(500, err, c)
 		}
 
 		return c.String(http.StatusOK, string(gameJSON))
@@ -0,0 +1,675 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/metrics"
+	"github.com/topfreegames/donations/models"
+	"github.com/topfreegames/donations/rpc"
+	"github.com/topfreegames/donations/tracing"
+	"github.com/uber-go/zap"
+)
+
+//GRPCServer implements the gRPC service with the same operations as the HTTP routes
+type GRPCServer struct {
+	App *App
+}
+
+//NewGRPCServer returns a gRPC server with the donations service registered.
+//If basic auth is configured, calls must send it in the authorization metadata.
+//If API keys are enabled, calls must send an API key of the game in the x-api-key metadata instead.
+func NewGRPCServer(app *App) *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(newGRPCInterceptor(app)))
+	rpc.RegisterDonationsServer(server, &GRPCServer{App: app})
+	return server
+}
+
+func getMetadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromContext(ctx)
+	if !ok || len(md[key]) == 0 {
+		return ""
+	}
+	return md[key][0]
+}
+
+//metadataCarrier reads the trace context propagated in the metadata of a call
+type metadataCarrier metadata.MD
+
+func (m metadataCarrier) Get(key string) string {
+	values := m[strings.ToLower(key)]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (m metadataCarrier) Set(key, value string) {
+	m[strings.ToLower(key)] = []string{value}
+}
+
+func (m metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+//grpcAPIKey is the context key of the API key that authorized a call
+type grpcAPIKey struct{}
+
+//getGRPCActor returns the authenticated actor of a call
+func getGRPCActor(ctx context.Context, app *App) string {
+	key, _ := ctx.Value(grpcAPIKey{}).(*models.APIKey)
+	return getAuthenticatedActor(app, key, getMetadataValue(ctx, "authorization"))
+}
+
+//getGRPCClaimedActor returns the x-actor metadata, who the caller says it acts for
+func getGRPCClaimedActor(ctx context.Context) string {
+	return getMetadataValue(ctx, "x-actor")
+}
+
+//getGRPCGameID returns the game of a request, which API keys must belong to
+func getGRPCGameID(req interface{}) string {
+	switch r := req.(type) {
+	case *rpc.UpdateGameRequest:
+		return r.GameId
+	case *rpc.UpsertItemRequest:
+		return r.GameId
+	case *rpc.CreateDonationRequestRequest:
+		return r.GameId
+	case *rpc.DonateRequest:
+		return r.GameId
+	case *rpc.GetDonationRequestRequest:
+		return r.GameId
+	case *rpc.GetDonationWeightByClanRequest:
+		return r.GameId
+	}
+	return ""
+}
+
+//getGRPCScope returns the API key scope a method requires, like the scopes of the HTTP routes
+func getGRPCScope(method string) string {
+	switch method {
+	case "/donations.Donations/UpdateGame", "/donations.Donations/UpsertItem":
+		return models.APIKeyScopeAdmin
+	}
+	return models.APIKeyScopeClient
+}
+
+//grpcRoutes are the HTTP routes of the rate limited and idempotent methods, whose configuration they share
+var grpcRoutes = map[string]string{
+	"/donations.Donations/CreateDonationRequest": "CreateDonationRequest",
+	"/donations.Donations/CreateDonation":        "CreateDonation",
+}
+
+//getGRPCRateLimitIP returns the IP address of the client of a call
+func getGRPCRateLimitIP(ctx context.Context, app *App) string {
+	remoteAddress := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddress = p.Addr.String()
+	}
+	header := strings.ToLower(app.Config.GetString("api.rateLimit.forwardedForHeader"))
+	return getClientIP(app, getMetadataValue(ctx, header), remoteAddress)
+}
+
+//applyGRPCRateLimit takes a token from the buckets of a call, failing with ResourceExhausted and setting the
+//retry-after metadata if any of them is over its limit. If redis fails, the call is let through.
+func applyGRPCRateLimit(ctx context.Context, app *App, gameID, route string, buckets []*rateLimitBucket) error {
+	if !app.Config.GetBool("api.rateLimit.enabled") || len(buckets) == 0 {
+		return nil
+	}
+
+	ctx, span := tracing.StartSpan(ctx, "rateLimit")
+	defer span.End()
+
+	err := checkRateLimit(app, gameID, route, buckets)
+	if err != nil {
+		span.RecordError(err)
+		retryAfterSeconds := err.(*errors.RateLimitExceededError).RetryAfterSeconds
+		grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfterSeconds)))
+		return grpcError(err)
+	}
+	return nil
+}
+
+//applyGRPCRouteRateLimit applies the game and ip limits of the route of the method, like the RateLimitMiddleware
+func applyGRPCRouteRateLimit(ctx context.Context, app *App, method string, req interface{}) error {
+	route, ok := grpcRoutes[method]
+	if !ok || !app.Config.GetBool("api.rateLimit.enabled") {
+		return nil
+	}
+
+	gameID := getGRPCGameID(req)
+	buckets := append(
+		getRateLimitBuckets(app, route, "game", gameID),
+		getRateLimitBuckets(app, route, "ip", getGRPCRateLimitIP(ctx, app))...,
+	)
+	return applyGRPCRateLimit(ctx, app, gameID, route, buckets)
+}
+
+//applyGRPCPlayerRateLimit applies the player limit of the route of the method, like applyPlayerRateLimit.
+//It must only be called once the player is authenticated.
+func applyGRPCPlayerRateLimit(ctx context.Context, app *App, method, gameID, player string) error {
+	route := grpcRoutes[method]
+	buckets := getRateLimitBuckets(app, route, "player", fmt.Sprintf("%s:%s", gameID, player))
+	return applyGRPCRateLimit(ctx, app, gameID, route, buckets)
+}
+
+//withGRPCIdempotency runs call once for each idempotency-key metadata sent to the method of a game, like the
+//IdempotencyMiddleware, and returns its stored response to repeated calls with the same request within the TTL.
+//Only successful calls are stored, since failed ones did not donate and can be retried.
+func withGRPCIdempotency(
+	ctx context.Context, app *App, method, gameID string, req proto.Message,
+	call func() (*rpc.DonationRequest, error),
+) (*rpc.DonationRequest, error) {
+	key := getMetadataValue(ctx, strings.ToLower(IdempotencyKeyHeader))
+	if key == "" {
+		return call()
+	}
+
+	route := grpcRoutes[method]
+	ttl := app.Config.GetInt("api.idempotency.ttlSeconds")
+	clock := &models.RealClock{}
+	l := app.Logger.With(
+		zap.String("source", "GRPCServer"),
+		zap.String("operation", "withGRPCIdempotency"),
+		zap.String("gameID", gameID),
+		zap.String("route", route),
+		zap.String("idempotencyKey", key),
+	)
+
+	mutexID := fmt.Sprintf("Idempotency-%s-%s-%s", gameID, route, key)
+	mutex := app.GetMutex(mutexID, 32, 8) // Number of retries to get lock and Lock expiration
+	err := mutex.Lock(ctx)
+	if err != nil {
+		log.E(l, "Could not acquire lock after many retries.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return nil, grpcError(err)
+	}
+	defer mutex.Unlock()
+
+	requestBody, err := proto.Marshal(req)
+	if err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "%s", err.Error())
+	}
+	requestHash := models.GetIdempotentRequestHash(requestBody)
+
+	stored, err := models.GetIdempotentResponse(gameID, route, key, ttl, clock, app.MongoDb, app.Logger)
+	if err != nil {
+		if _, ok := err.(*errors.DocumentNotFoundError); !ok {
+			log.E(l, "Failed to retrieve idempotent response.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return nil, grpcError(err)
+		}
+	}
+
+	if stored != nil {
+		if stored.Path != method || stored.RequestHash != requestHash {
+			return nil, grpcError(&errors.IdempotencyKeyReusedError{Key: key, Path: stored.Path})
+		}
+		var res rpc.DonationRequest
+		err = json.Unmarshal([]byte(stored.Body), &res)
+		if err != nil {
+			return nil, grpcError(err)
+		}
+		log.D(l, "Replaying stored response.")
+		grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(IdempotentReplayHeader), "true"))
+		return &res, nil
+	}
+
+	res, err := call()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(res)
+	if err == nil {
+		err = models.SaveIdempotentResponse(
+			gameID, route, key, method, requestHash,
+			http.StatusOK, "application/json", string(body),
+			ttl, clock,
+			app.MongoDb, app.Logger,
+		)
+	}
+	if err != nil {
+		log.E(l, "Failed to store idempotent response.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+	}
+	return res, nil
+}
+
+func newGRPCInterceptor(app *App) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		l := app.Logger.With(
+			zap.String("source", "GRPCServer"),
+			zap.String("method", info.FullMethod),
+		)
+
+		if app.Config.GetBool("api.apiKeys.enabled") {
+			if !isOperator(app, getMetadataValue(ctx, "authorization"), false) {
+				key, err := authorizeAPIKey(
+					app, getMetadataValue(ctx, "x-api-key"),
+					getGRPCGameID(req), getGRPCScope(info.FullMethod),
+				)
+				switch err.(type) {
+				case nil:
+					ctx = context.WithValue(ctx, grpcAPIKey{}, key)
+				case *errors.InvalidAPIKeyError:
+					return nil, grpc.Errorf(codes.Unauthenticated, "%s", err.Error())
+				case *errors.APIKeyForbiddenError:
+					return nil, grpc.Errorf(codes.PermissionDenied, "%s", err.Error())
+				default:
+					return nil, grpc.Errorf(codes.Internal, "%s", err.Error())
+				}
+			}
+		} else if basicAuthUser := app.Config.GetString("api.basicAuth.user"); basicAuthUser != "" {
+			user, pass, ok := parseBasicAuth(getMetadataValue(ctx, "authorization"))
+			if !ok || user != basicAuthUser || pass != app.Config.GetString("api.basicAuth.pass") {
+				return nil, grpc.Errorf(codes.Unauthenticated, "Invalid credentials.")
+			}
+		}
+
+		md, _ := metadata.FromContext(ctx)
+		ctx, span := app.Tracer.StartTrace(tracing.NewContext(ctx, app.Tracer), info.FullMethod, metadataCarrier(md))
+		defer span.End()
+
+		start := time.Now()
+		var res interface{}
+		err := applyGRPCRouteRateLimit(ctx, app, info.FullMethod, req)
+		if err == nil {
+			res, err = handler(ctx, req)
+		}
+		metrics.GRPCDuration.WithLabelValues(info.FullMethod, grpc.Code(err).String()).Observe(metrics.Since(start))
+		if err != nil {
+			span.RecordError(err)
+			log.W(l, "Call failed.", func(cm log.CM) {
+				cm.Write(zap.Error(err), zap.Duration("latency", time.Now().Sub(start)))
+			})
+			return nil, err
+		}
+
+		log.D(l, "Call succeeded.", func(cm log.CM) {
+			cm.Write(zap.Duration("latency", time.Now().Sub(start)))
+		})
+		return res, nil
+	}
+}
+
+//grpcError maps model errors to gRPC status codes, like the HTTP handlers map them to status codes
+func grpcError(err error) error {
+	metrics.ObserveError(err)
+	switch err.(type) {
+	case *errors.DocumentNotFoundError:
+		return grpc.Errorf(codes.NotFound, "%s", err.Error())
+	case *errors.AuthenticationFailed:
+		return grpc.Errorf(codes.Unauthenticated, "%s", err.Error())
+	case *errors.ParameterIsRequiredError, *errors.ItemNotFoundInGameError:
+		return grpc.Errorf(codes.InvalidArgument, "%s", err.Error())
+	case *errors.LimitOfItemsInDonationRequestReachedError,
+		*errors.LimitOfItemsPerPlayerInDonationRequestReachedError,
+		*errors.DonationRequestCooldownViolatedError,
+		*errors.DonationCooldownViolatedError,
+		*errors.DonationRequestExpiredError:
+		return grpc.Errorf(codes.FailedPrecondition, "%s", err.Error())
+	case *errors.DonationRequestConcurrentlyUpdatedError:
+		return grpc.Errorf(codes.Aborted, "%s", err.Error())
+	case *errors.IdempotencyKeyReusedError:
+		return grpc.Errorf(codes.FailedPrecondition, "%s", err.Error())
+	case *errors.RateLimitExceededError:
+		return grpc.Errorf(codes.ResourceExhausted, "%s", err.Error())
+	default:
+		return grpc.Errorf(codes.Internal, "%s", err.Error())
+	}
+}
+
+func validateGRPCPayload(payload Validatable) error {
+	if errs := payload.Validate(); len(errs) > 0 {
+		return grpc.Errorf(codes.InvalidArgument, "%s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func toRPCItem(item *models.Item) (*rpc.Item, error) {
+	metadataJSON, err := json.Marshal(item.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	return &rpc.Item{
+		Key:                               item.Key,
+		Metadata:                          string(metadataJSON),
+		LimitOfItemsInEachDonationRequest: int32(item.LimitOfItemsInEachDonationRequest),
+		LimitOfItemsPerPlayerDonation:     int32(item.LimitOfItemsPerPlayerDonation),
+		WeightPerDonation:                 int32(item.WeightPerDonation),
+		UpdatedAt:                         item.UpdatedAt,
+	}, nil
+}
+
+func toRPCGame(game *models.Game) (*rpc.Game, error) {
+	items := map[string]*rpc.Item{}
+	for key, item := range game.Items {
+		rpcItem, err := toRPCItem(&item)
+		if err != nil {
+			return nil, err
+		}
+		items[key] = rpcItem
+	}
+	return &rpc.Game{
+		Id:                           game.ID,
+		Name:                         game.Name,
+		Items:                        items,
+		DonationCooldownHours:        int32(game.DonationCooldownHours),
+		DonationRequestCooldownHours: int32(game.DonationRequestCooldownHours),
+		UpdatedAt:                    game.UpdatedAt.Unix(),
+	}, nil
+}
+
+func toRPCDonationRequest(donationRequest *models.DonationRequest) *rpc.DonationRequest {
+	donations := []*rpc.Donation{}
+	for _, donation := range donationRequest.Donations {
+		donations = append(donations, &rpc.Donation{
+			Id:        donation.ID,
+			Player:    donation.Player,
+			Amount:    int32(donation.Amount),
+			Weight:    int32(donation.Weight),
+			CreatedAt: donation.CreatedAt,
+		})
+	}
+	return &rpc.DonationRequest{
+		Id:         donationRequest.ID,
+		GameId:     donationRequest.GameID,
+		Item:       donationRequest.Item,
+		Player:     donationRequest.Player,
+		Clan:       donationRequest.Clan,
+		Donations:  donations,
+		CreatedAt:  donationRequest.CreatedAt,
+		UpdatedAt:  donationRequest.UpdatedAt,
+		FinishedAt: donationRequest.FinishedAt,
+	}
+}
+
+//UpdateGame creates or updates a game
+func (s *GRPCServer) UpdateGame(ctx context.Context, req *rpc.UpdateGameRequest) (*rpc.Game, error) {
+	app := s.App
+	l := app.Logger.With(
+		zap.String("source", "GRPCServer"),
+		zap.String("operation", "UpdateGame"),
+		zap.String("gameID", req.GameId),
+	)
+
+	payload := &UpdateGamePayload{
+		Name:                         req.Name,
+		DonationCooldownHours:        int(req.DonationCooldownHours),
+		DonationRequestCooldownHours: int(req.DonationRequestCooldownHours),
+	}
+	if err := validateGRPCPayload(payload); err != nil {
+		return nil, err
+	}
+
+	var before *models.Game
+	game, err := models.GetGameByID(req.GameId, app.MongoDb, app.Logger)
+	if err != nil {
+		if _, ok := err.(*errors.DocumentNotFoundError); !ok {
+			return nil, grpcError(err)
+		}
+		game = models.NewGame(
+			payload.Name, req.GameId,
+			payload.DonationCooldownHours,
+			payload.DonationRequestCooldownHours,
+		)
+	} else {
+		before = game.Clone()
+		game.Name = payload.Name
+		game.DonationCooldownHours = payload.DonationCooldownHours
+		game.DonationRequestCooldownHours = payload.DonationRequestCooldownHours
+	}
+
+	err = game.Save(app.MongoDb, app.Logger)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	log.I(l, "Updated game successfully.")
+	err = recordAudit(app, getGRPCActor(ctx, app), getGRPCClaimedActor(ctx), models.AuditUpdateGame, before, game, l)
+	if err != nil {
+		return nil, grpc.Errorf(codes.Internal, "%s", err.Error())
+	}
+
+	return toRPCGame(game)
+}
+
+//UpsertItem creates or updates an item of a game
+func (s *GRPCServer) UpsertItem(ctx context.Context, req *rpc.UpsertItemRequest) (*rpc.Item, error) {
+	app := s.App
+	l := app.Logger.With(
+		zap.String("source", "GRPCServer"),
+		zap.String("operation", "UpsertItem"),
+		zap.String("gameID", req.GameId),
+		zap.String("itemKey", req.Key),
+	)
+
+	payload := &UpsertItemPayload{
+		WeightPerDonation:                 int(req.WeightPerDonation),
+		LimitOfItemsPerPlayerDonation:     int(req.LimitOfItemsPerPlayerDonation),
+		LimitOfItemsInEachDonationRequest: int(req.LimitOfItemsInEachDonationRequest),
+	}
+	if req.Metadata != "" {
+		err := json.Unmarshal([]byte(req.Metadata), &payload.Metadata)
+		if err != nil {
+			return nil, grpc.Errorf(codes.InvalidArgument, "metadata must be a JSON object: %s", err.Error())
+		}
+	}
+	if err := validateGRPCPayload(payload); err != nil {
+		return nil, err
+	}
+
+	game, err := models.GetGameByID(req.GameId, app.MongoDb, app.Logger)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	before := game.Clone()
+	item, err := game.AddItem(
+		req.Key, payload.Metadata,
+		payload.LimitOfItemsInEachDonationRequest,
+		payload.LimitOfItemsPerPlayerDonation,
+		payload.WeightPerDonation,
+		app.MongoDb, app.Logger,
+	)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	log.I(l, "Created/Updated item successfully.")
+	err = recordAudit(app, getGRPCActor(ctx, app), getGRPCClaimedActor(ctx), models.AuditUpsertItem, before, game, l)
+	if err != nil {
+		return nil, grpc.Errorf(codes.Internal, "%s", err.Error())
+	}
+
+	return toRPCItem(item)
+}
+
+//CreateDonationRequest creates a donation request
+func (s *GRPCServer) CreateDonationRequest(ctx context.Context, req *rpc.CreateDonationRequestRequest) (*rpc.DonationRequest, error) {
+	app := s.App
+	l := app.Logger.With(
+		zap.String("source", "GRPCServer"),
+		zap.String("operation", "CreateDonationRequest"),
+		zap.String("gameID", req.GameId),
+	)
+
+	payload := &CreateDonationRequestPayload{
+		Item:   req.Item,
+		Player: req.Player,
+		Clan:   req.Clan,
+	}
+	if err := validateGRPCPayload(payload); err != nil {
+		return nil, err
+	}
+
+	err := authenticatePlayer(app, req.GameId, payload.Player, &payload.Clan, getMetadataValue(ctx, "x-player-token"))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	method := "/donations.Donations/CreateDonationRequest"
+	return withGRPCIdempotency(ctx, app, method, req.GameId, req, func() (*rpc.DonationRequest, error) {
+		err := applyGRPCPlayerRateLimit(ctx, app, method, req.GameId, payload.Player)
+		if err != nil {
+			return nil, err
+		}
+		return s.createDonationRequest(ctx, req.GameId, payload, l)
+	})
+}
+
+func (s *GRPCServer) createDonationRequest(
+	ctx context.Context, gameID string, payload *CreateDonationRequestPayload, l zap.Logger,
+) (*rpc.DonationRequest, error) {
+	app := s.App
+	game, err := models.GetGameByID(gameID, app.MongoDb, app.Logger)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	mutexID := fmt.Sprintf("DonationRequest-%s-%s", gameID, payload.Player)
+	mutex := app.GetMutex(mutexID, 32, 8) // Number of retries to get lock and Lock expiration
+	err = mutex.Lock(ctx)
+	if err != nil {
+		log.E(l, "Could not acquire lock after many retries.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return nil, grpcError(err)
+	}
+	defer mutex.Unlock()
+
+	donationRequest := models.NewDonationRequest(game.ID, payload.Item, payload.Player, payload.Clan)
+	donationRequest.Context = ctx
+	err = donationRequest.Create(app.MongoDb, app.Logger)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	log.I(l, "Created new donation request successfully.", func(cm log.CM) {
+		cm.Write(zap.String("ID", donationRequest.ID))
+	})
+	return toRPCDonationRequest(donationRequest), nil
+}
+
+//CreateDonation donates to a donation request
+func (s *GRPCServer) CreateDonation(ctx context.Context, req *rpc.DonateRequest) (*rpc.DonationRequest, error) {
+	app := s.App
+	l := app.Logger.With(
+		zap.String("source", "GRPCServer"),
+		zap.String("operation", "CreateDonation"),
+		zap.String("gameID", req.GameId),
+		zap.String("donationRequestID", req.DonationRequestId),
+	)
+
+	payload := &DonationPayload{
+		Player:             req.Player,
+		Amount:             int(req.Amount),
+		MaxWeightPerPlayer: int(req.MaxWeightPerPlayer),
+	}
+	if err := validateGRPCPayload(payload); err != nil {
+		return nil, err
+	}
+
+	err := authenticatePlayer(app, req.GameId, payload.Player, nil, getMetadataValue(ctx, "x-player-token"))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	method := "/donations.Donations/CreateDonation"
+	return withGRPCIdempotency(ctx, app, method, req.GameId, req, func() (*rpc.DonationRequest, error) {
+		err := applyGRPCPlayerRateLimit(ctx, app, method, req.GameId, payload.Player)
+		if err != nil {
+			return nil, err
+		}
+		return s.donate(ctx, req, payload, l)
+	})
+}
+
+func (s *GRPCServer) donate(
+	ctx context.Context, req *rpc.DonateRequest, payload *DonationPayload, l zap.Logger,
+) (*rpc.DonationRequest, error) {
+	app := s.App
+	err := models.EnsurePlayerExists(req.GameId, payload.Player, app.MongoDb, app.Logger)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	if app.Config.GetBool("api.donationLock.enabled") {
+		mutexID := fmt.Sprintf("Donate-%s-%s", req.GameId, req.DonationRequestId)
+		mutex := app.GetMutex(mutexID, 32, 8) // Number of retries to get lock and Lock expiration
+		err = mutex.Lock(ctx)
+		if err != nil {
+			log.E(l, "Could not acquire lock after many retries.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return nil, grpcError(err)
+		}
+		defer mutex.Unlock()
+	}
+
+	donationRequest, err := models.GetDonationRequestByID(req.DonationRequestId, app.MongoDb, app.Logger)
+	if err == nil && donationRequest.GameID != req.GameId {
+		err = errors.NewDocumentNotFoundError("donationRequest", req.DonationRequestId)
+	}
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	err = donationRequest.ValidateDonationRequestNotExpired(app.GetDonationRequestExpiration(), app.Logger)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	donationRequest.Context = ctx
+	err = donationRequest.Donate(payload.Player, payload.Amount, payload.MaxWeightPerPlayer, app.Redis, app.MongoDb, app.Logger)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	log.I(l, "Created new donation successfully.")
+	return toRPCDonationRequest(donationRequest), nil
+}
+
+//GetDonationRequest returns a live or archived donation request
+func (s *GRPCServer) GetDonationRequest(ctx context.Context, req *rpc.GetDonationRequestRequest) (*rpc.DonationRequest, error) {
+	donationRequest, err := models.GetDonationRequestFromHistory(req.DonationRequestId, s.App.MongoDb, s.App.Logger)
+	if err == nil && donationRequest.GameID != req.GameId {
+		err = errors.NewDocumentNotFoundError("donationRequest", req.DonationRequestId)
+	}
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return toRPCDonationRequest(donationRequest), nil
+}
+
+//GetDonationWeightByClan returns the donation weight of a clan
+func (s *GRPCServer) GetDonationWeightByClan(ctx context.Context, req *rpc.GetDonationWeightByClanRequest) (*rpc.DonationWeight, error) {
+	weight, err := models.GetDonationWeightForClan(
+		req.GameId, req.ClanId, time.Now(), getResetType(req.Type), s.App.Redis, s.App.Logger,
+	)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &rpc.DonationWeight{Weight: int32(weight)}, nil
+}
@@ -0,0 +1,272 @@
+package api_test
+
+import (
+	"net"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	uuid "github.com/satori/go.uuid"
+	"github.com/topfreegames/donations/api"
+	"github.com/topfreegames/donations/models"
+	"github.com/topfreegames/donations/rpc"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("gRPC Server", func() {
+	var logger zap.Logger
+	var app *api.App
+	var game *models.Game
+	var conn *grpc.ClientConn
+	var client rpc.DonationsClient
+	var ctx context.Context
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		app = GetDefaultTestApp(logger)
+
+		var err error
+		game, err = GetTestGame(app.MongoDb, app.Logger, true)
+		Expect(err).NotTo(HaveOccurred())
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		go app.GRPC.Serve(listener)
+
+		conn, err = grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+		Expect(err).NotTo(HaveOccurred())
+		client = rpc.NewDonationsClient(conn)
+		ctx = context.Background()
+	})
+
+	AfterEach(func() {
+		conn.Close()
+		app.Stop()
+	})
+
+	Describe("Games and items", func() {
+		It("Should create a game and record the actor", func() {
+			gameID := uuid.NewV4().String()
+			ctx = metadata.NewContext(ctx, metadata.Pairs("x-actor", "jane"))
+			rGame, err := client.UpdateGame(ctx, &rpc.UpdateGameRequest{
+				GameId:                       gameID,
+				Name:                         "game",
+				DonationCooldownHours:        1,
+				DonationRequestCooldownHours: 2,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rGame.Id).To(Equal(gameID))
+			Expect(rGame.DonationRequestCooldownHours).To(BeEquivalentTo(2))
+
+			entries, err := models.GetAuditLog(gameID, 10, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Actor).To(Equal(api.UnknownActor))
+			Expect(entries[0].ClaimedActor).To(Equal("jane"))
+		})
+
+		It("Should fail to update a game with an invalid payload", func() {
+			_, err := client.UpdateGame(ctx, &rpc.UpdateGameRequest{GameId: game.ID})
+			Expect(grpc.Code(err)).To(Equal(codes.InvalidArgument))
+			Expect(grpc.ErrorDesc(err)).To(ContainSubstring("name is required"))
+		})
+
+		It("Should upsert an item", func() {
+			item, err := client.UpsertItem(ctx, &rpc.UpsertItemRequest{
+				GameId:                            game.ID,
+				Key:                               "sword",
+				Metadata:                          `{"x":1}`,
+				WeightPerDonation:                 1,
+				LimitOfItemsPerPlayerDonation:     2,
+				LimitOfItemsInEachDonationRequest: 3,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(item.Key).To(Equal("sword"))
+			Expect(item.Metadata).To(Equal(`{"x":1}`))
+
+			dbGame, err := models.GetGameByID(game.ID, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dbGame.Items).To(HaveKey("sword"))
+		})
+
+		It("Should fail to upsert an item of an unknown game", func() {
+			_, err := client.UpsertItem(ctx, &rpc.UpsertItemRequest{
+				GameId:                            uuid.NewV4().String(),
+				Key:                               "sword",
+				Metadata:                          `{"x":1}`,
+				WeightPerDonation:                 1,
+				LimitOfItemsPerPlayerDonation:     2,
+				LimitOfItemsInEachDonationRequest: 3,
+			})
+			Expect(grpc.Code(err)).To(Equal(codes.NotFound))
+		})
+	})
+
+	Describe("Donations", func() {
+		It("Should create a donation request and donate to it", func() {
+			dr, err := client.CreateDonationRequest(ctx, &rpc.CreateDonationRequestRequest{
+				GameId: game.ID,
+				Item:   GetFirstItem(game).Key,
+				Player: uuid.NewV4().String(),
+				Clan:   uuid.NewV4().String(),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dr.Id).NotTo(BeEmpty())
+
+			dr, err = client.CreateDonation(ctx, &rpc.DonateRequest{
+				GameId:             game.ID,
+				DonationRequestId:  dr.Id,
+				Player:             uuid.NewV4().String(),
+				Amount:             1,
+				MaxWeightPerPlayer: 100,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dr.Donations).To(HaveLen(1))
+
+			dr, err = client.GetDonationRequest(ctx, &rpc.GetDonationRequestRequest{
+				GameId:            game.ID,
+				DonationRequestId: dr.Id,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dr.Donations).To(HaveLen(1))
+
+			weight, err := client.GetDonationWeightByClan(ctx, &rpc.GetDonationWeightByClanRequest{
+				GameId: game.ID,
+				ClanId: dr.Clan,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(weight.Weight).To(BeEquivalentTo(1))
+		})
+
+		It("Should fail with a donation request of another game", func() {
+			dr, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = client.GetDonationRequest(ctx, &rpc.GetDonationRequestRequest{
+				GameId:            uuid.NewV4().String(),
+				DonationRequestId: dr.ID,
+			})
+			Expect(grpc.Code(err)).To(Equal(codes.NotFound))
+		})
+
+		It("Should replay donations sent with the same idempotency key", func() {
+			dr, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			req := &rpc.DonateRequest{
+				GameId:             game.ID,
+				DonationRequestId:  dr.ID,
+				Player:             uuid.NewV4().String(),
+				Amount:             1,
+				MaxWeightPerPlayer: 100,
+			}
+			ctx = metadata.NewContext(ctx, metadata.Pairs("idempotency-key", uuid.NewV4().String()))
+			first, err := client.CreateDonation(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(first.Donations).To(HaveLen(1))
+
+			var header metadata.MD
+			second, err := client.CreateDonation(ctx, req, grpc.Header(&header))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(second.Donations).To(HaveLen(1))
+			Expect(second.Donations[0].Id).To(Equal(first.Donations[0].Id))
+			Expect(header["idempotent-replayed"]).To(Equal([]string{"true"}))
+
+			dbDonationRequest, err := models.GetDonationRequestByID(dr.ID, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dbDonationRequest.Donations).To(HaveLen(1))
+
+			req.Amount = 2
+			_, err = client.CreateDonation(ctx, req)
+			Expect(grpc.Code(err)).To(Equal(codes.FailedPrecondition))
+		})
+	})
+
+	Describe("Rate limits", func() {
+		BeforeEach(func() {
+			app.Config.Set("api.rateLimit.enabled", true)
+		})
+
+		It("Should limit donation requests per game", func() {
+			app.Config.Set("api.rateLimit.routes.CreateDonationRequest.game.capacity", 1)
+			app.Config.Set("api.rateLimit.routes.CreateDonationRequest.game.refillPerSecond", 0.01)
+
+			req := &rpc.CreateDonationRequestRequest{
+				GameId: game.ID,
+				Item:   GetFirstItem(game).Key,
+				Player: uuid.NewV4().String(),
+				Clan:   uuid.NewV4().String(),
+			}
+			_, err := client.CreateDonationRequest(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+
+			var header metadata.MD
+			req.Player = uuid.NewV4().String()
+			_, err = client.CreateDonationRequest(ctx, req, grpc.Header(&header))
+			Expect(grpc.Code(err)).To(Equal(codes.ResourceExhausted))
+			Expect(header["retry-after"]).To(Equal([]string{"100"}))
+		})
+
+		It("Should limit donations per player", func() {
+			app.Config.Set("api.rateLimit.routes.CreateDonation.player.capacity", 1)
+			app.Config.Set("api.rateLimit.routes.CreateDonation.player.refillPerSecond", 0.01)
+
+			dr, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			req := &rpc.DonateRequest{
+				GameId:             game.ID,
+				DonationRequestId:  dr.ID,
+				Player:             uuid.NewV4().String(),
+				Amount:             1,
+				MaxWeightPerPlayer: 100,
+			}
+			_, err = client.CreateDonation(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = client.CreateDonation(ctx, req)
+			Expect(grpc.Code(err)).To(Equal(codes.ResourceExhausted))
+
+			req.Player = uuid.NewV4().String()
+			_, err = client.CreateDonation(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("API keys", func() {
+		It("Should require an API key of the game with the scope of the method", func() {
+			app.Config.Set("api.apiKeys.enabled", true)
+			key, token, err := models.CreateAPIKey(
+				game.ID, "server", []string{models.APIKeyScopeClient},
+				&models.RealClock{}, app.MongoDb, app.Logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			weightRequest := &rpc.GetDonationWeightByClanRequest{GameId: game.ID, ClanId: "clan-0"}
+			_, err = client.GetDonationWeightByClan(ctx, weightRequest)
+			Expect(grpc.Code(err)).To(Equal(codes.Unauthenticated))
+
+			ctx = metadata.NewContext(ctx, metadata.Pairs("x-api-key", token))
+			_, err = client.GetDonationWeightByClan(ctx, weightRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = client.UpdateGame(ctx, &rpc.UpdateGameRequest{
+				GameId:                       game.ID,
+				Name:                         "game",
+				DonationCooldownHours:        1,
+				DonationRequestCooldownHours: 2,
+			})
+			Expect(grpc.Code(err)).To(Equal(codes.PermissionDenied))
+			Expect(grpc.ErrorDesc(err)).To(ContainSubstring(key.ID))
+		})
+	})
+})
@@ -11,10 +11,98 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/labstack/echo"
 )
 
+//DependencyCheck is the result of checking a dependency of the app
+type DependencyCheck struct {
+	Healthy             bool   `json:"healthy"`
+	LatencyMilliseconds int64  `json:"latencyMilliseconds"`
+	Error               string `json:"error,omitempty"`
+}
+
+//Readiness is the state of the app and of each of its dependencies
+type Readiness struct {
+	Ready  bool                        `json:"ready"`
+	Status string                      `json:"status"`
+	Checks map[string]*DependencyCheck `json:"checks"`
+}
+
+//withTimeout returns the error of f, or a timeout error if f takes longer than timeout
+func withTimeout(timeout time.Duration, f func() error) error {
+	//buffered, so f can finish after the timeout without leaking the goroutine
+	result := make(chan error, 1)
+	go func() {
+		result <- f()
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("Timed out after %s.", timeout)
+	}
+}
+
+//pingMongo checks that MongoDB answers using a copy of the app session, so a broken socket is not reused
+func (app *App) pingMongo(timeout time.Duration) error {
+	return withTimeout(timeout, func() error {
+		session := app.MongoSession.Copy()
+		defer session.Close()
+		session.SetSyncTimeout(timeout)
+		session.SetSocketTimeout(timeout)
+		return session.Ping()
+	})
+}
+
+//pingRedis checks that redis answers
+func (app *App) pingRedis(timeout time.Duration) error {
+	return withTimeout(timeout, func() error {
+		conn := app.Redis.Get()
+		defer conn.Close()
+		_, err := conn.Do("PING")
+		return err
+	})
+}
+
+//CheckDependencies pings every dependency of the app concurrently, each with the configured timeout
+func (app *App) CheckDependencies() map[string]*DependencyCheck {
+	timeout := time.Duration(app.Config.GetInt("healthcheck.timeoutMilliseconds")) * time.Millisecond
+	pings := map[string]func(time.Duration) error{
+		"mongo": app.pingMongo,
+		"redis": app.pingRedis,
+	}
+
+	checks := map[string]*DependencyCheck{}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for name, ping := range pings {
+		wg.Add(1)
+		go func(name string, ping func(time.Duration) error) {
+			defer wg.Done()
+			start := time.Now()
+			err := ping(timeout)
+			check := &DependencyCheck{
+				Healthy:             err == nil,
+				LatencyMilliseconds: int64(time.Now().Sub(start) / time.Millisecond),
+			}
+			if err != nil {
+				check.Error = err.Error()
+			}
+
+			mutex.Lock()
+			checks[name] = check
+			mutex.Unlock()
+		}(name, ping)
+	}
+	wg.Wait()
+
+	return checks
+}
+
 //HealthCheckHandler is the handler responsible for validating that the app is still up
 func HealthCheckHandler(app *App) func(c echo.Context) error {
 	return func(c echo.Context) error {
@@ -22,14 +110,55 @@ func HealthCheckHandler(app *App) func(c echo.Context) error {
 
 		workingString := app.Config.GetString("healthcheck.workingText")
 
-		err := WithSegment("mongo", c, func() error {
+		var checks map[string]*DependencyCheck
+		WithSegment("dependencies", c, func() error {
+			checks = app.CheckDependencies()
 			return nil
 		})
-		if err != nil {
-			return FailWith(http.StatusInternalServerError, fmt.Sprintf("Error connecting to database: %s", err), c)
+		if check := checks["mongo"]; !check.Healthy {
+			return FailWith(http.StatusInternalServerError, fmt.Sprintf("Error connecting to database: %s", check.Error), c)
+		}
+		if check := checks["redis"]; !check.Healthy {
+			return FailWith(http.StatusInternalServerError, fmt.Sprintf("Error connecting to redis: %s", check.Error), c)
 		}
 
 		workingString = strings.TrimSpace(workingString)
 		return c.String(http.StatusOK, workingString)
 	}
 }
+
+//LivenessHandler is the handler responsible for validating that the process is able to serve requests.
+//It does not check dependencies, so an outage of MongoDB or redis does not restart every instance.
+func LivenessHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		c.Set("route", "Liveness")
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"alive":  true,
+			"status": app.GetStatus(),
+		})
+	}
+}
+
+//ReadinessHandler is the handler responsible for validating that the app should receive requests.
+//It fails while the app is starting or stopping, or if any dependency is not healthy.
+func ReadinessHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		c.Set("route", "Readiness")
+
+		readiness := &Readiness{Status: app.GetStatus()}
+		WithSegment("dependencies", c, func() error {
+			readiness.Checks = app.CheckDependencies()
+			return nil
+		})
+
+		readiness.Ready = readiness.Status == AppStatusReady
+		for _, check := range readiness.Checks {
+			readiness.Ready = readiness.Ready && check.Healthy
+		}
+
+		if !readiness.Ready {
+			return c.JSON(http.StatusServiceUnavailable, readiness)
+		}
+		return c.JSON(http.StatusOK, readiness)
+	}
+}
@@ -8,6 +8,7 @@
 package api_test
 
 import (
+	"encoding/json"
 	"net/http"
 
 	. "github.com/onsi/ginkgo"
@@ -48,4 +49,66 @@ var _ = Describe("Healthcheck API Handler", func() {
 			Expect(body).To(Equal("OTHERWORKING"))
 		})
 	})
+
+	Describe("Liveness and Readiness Handlers", func() {
+		var logger zap.Logger
+		var app *api.App
+
+		getReadiness := func() (int, *api.Readiness) {
+			status, body := Get(app, "/healthcheck/ready")
+			var readiness api.Readiness
+			err := json.Unmarshal([]byte(body), &readiness)
+			Expect(err).NotTo(HaveOccurred(), body)
+			return status, &readiness
+		}
+
+		BeforeEach(func() {
+			logger = zap.New(
+				zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+				zap.FatalLevel,
+			)
+
+			app = GetDefaultTestApp(logger)
+		})
+
+		AfterEach(func() {
+			app.Stop()
+		})
+
+		It("Should be alive while starting", func() {
+			status, body := Get(app, "/healthcheck/live")
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).To(ContainSubstring(`"alive":true`))
+			Expect(body).To(ContainSubstring(`"status":"starting"`))
+		})
+
+		It("Should not be ready while starting", func() {
+			status, readiness := getReadiness()
+			Expect(status).To(Equal(http.StatusServiceUnavailable))
+			Expect(readiness.Ready).To(BeFalse())
+			Expect(readiness.Status).To(Equal(api.AppStatusStarting))
+		})
+
+		It("Should be ready with healthy dependencies", func() {
+			app.MarkReady()
+
+			status, readiness := getReadiness()
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(readiness.Ready).To(BeTrue())
+			Expect(readiness.Status).To(Equal(api.AppStatusReady))
+			Expect(readiness.Checks).To(HaveLen(2))
+			Expect(readiness.Checks["mongo"].Healthy).To(BeTrue())
+			Expect(readiness.Checks["redis"].Healthy).To(BeTrue())
+		})
+
+		It("Should not be ready while stopping", func() {
+			app.MarkReady()
+			app.MarkStopping()
+
+			status, readiness := getReadiness()
+			Expect(status).To(Equal(http.StatusServiceUnavailable))
+			Expect(readiness.Ready).To(BeFalse())
+			Expect(readiness.Status).To(Equal(api.AppStatusStopping))
+		})
+	})
 })
@@ -14,13 +14,16 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo"
 	"github.com/mailru/easyjson/jlexer"
 	"github.com/mailru/easyjson/jwriter"
-	newrelic "github.com/newrelic/go-agent"
 	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/metrics"
+	"github.com/topfreegames/donations/tracing"
 	"github.com/uber-go/zap"
+	"golang.org/x/net/context"
 )
 
 //EasyJSONUnmarshaler describes a struct able to unmarshal json
@@ -40,19 +43,48 @@ func FailWith(status int, message string, c echo.Context) error {
 	return c.String(status, msg)
 }
 
-// SucceedWith sends payload to user with status 200
-func SucceedWith(payload map[string]interface{}, c echo.Context) error {
-	f := func() error {
-		payload["success"] = true
-		return c.JSON(http.StatusOK, payload)
+//failure is the response of errors of the errors package, with their type and fields, so clients
+//can tell them apart without parsing the reason
+type failure struct {
+	Success bool        `json:"success"`
+	Reason  string      `json:"reason"`
+	Type    string      `json:"type"`
+	Details interface{} `json:"details"`
+}
+
+//getErrorDetails returns the type of an error of the errors package, or an empty type for other errors
+func getErrorDetails(err error) (string, interface{}) {
+	errorType := metrics.GetErrorType(err)
+	if errorType == metrics.UnknownErrorType {
+		return "", nil
 	}
-	tx := GetTX(c)
-	if tx == nil {
-		return f()
+	return errorType, err
+}
+
+// FailWithError fails with the message of err, counting it in the errors metric by its type.
+// Errors of the errors package are sent with their type and fields.
+func FailWithError(status int, err error, c echo.Context) error {
+	metrics.ObserveError(err)
+	errorType, details := getErrorDetails(err)
+	if errorType == "" {
+		return FailWith(status, err.Error(), c)
+	}
+
+	msg, e := json.Marshal(&failure{Reason: err.Error(), Type: errorType, Details: details})
+	if e != nil {
+		return FailWith(status, err.Error(), c)
 	}
-	segment := newrelic.StartSegment(tx, "response-marshalling")
-	defer segment.End()
-	return f()
+	c.Set("text", string(msg))
+	return c.String(status, string(msg))
+}
+
+// SucceedWith sends payload to user with status 200
+func SucceedWith(payload map[string]interface{}, c echo.Context) error {
+	_, span := tracing.StartSpan(GetTraceContext(c), "response-marshalling")
+	defer span.End()
+
+	payload["success"] = true
+	return c.JSON(http.StatusOK, payload)
 }
 
 //LoadJSONPayload loads the JSON payload to the given struct validating all fields are not null
@@ -131,23 +163,43 @@ func GetRequestJSON(payloadStruct interface{}, c echo.Context) error {
 	return nil
 }
 
-//GetTX returns new relic transaction
-func GetTX(c echo.Context) newrelic.Transaction {
-	tx := c.Get("txn")
-	if tx == nil {
+//GetTraceContext returns the context with the current span of the request, to start child spans.
+//It returns nil outside of requests, which traces nothing.
+func GetTraceContext(c echo.Context) context.Context {
+	ctx, ok := c.Get("traceContext").(context.Context)
+	if !ok {
 		return nil
 	}
+	return ctx
+}
 
-	return tx.(newrelic.Transaction)
+//getRoute returns the route set in ctx by the handler
+func getRoute(c echo.Context) string {
+	if route, ok := c.Get("route").(string); ok {
+		return route
+	}
+	return "unknown"
 }
 
-//WithSegment adds a segment to new relic transaction
+//WithSegment runs f in a span child of the current span of the request and measures its latency.
+//Spans started by f with GetTraceContext are children of the segment.
 func WithSegment(name string, c echo.Context, f func() error) error {
-	tx := GetTX(c)
-	if tx == nil {
-		return f()
+	start := time.Now()
+	defer func() {
+		metrics.SegmentDuration.WithLabelValues(getRoute(c), name).Observe(metrics.Since(start))
+	}()
+
+	parent := GetTraceContext(c)
+	ctx, span := tracing.StartSpan(parent, name)
+	defer span.End()
+
+	if ctx != nil {
+		c.Set("traceContext", ctx)
+		defer c.Set("traceContext", parent)
+	}
+	err := f()
+	if err != nil {
+		span.RecordError(err)
 	}
-	segment := newrelic.StartSegment(tx, name)
-	defer segment.End()
-	return f()
+	return err
 }
@@ -36,12 +36,13 @@ func UpsertItemHandler(app *App) func(c echo.Context) error {
 			return nil
 		})
 		if err != nil {
-			return FailWith(400, err.Error(), c)
+			return FailWithError(400, err, c)
 		}
 
 		var status int
 		var item *models.Item
 		var game *models.Game
+		var before *models.Game
 
 		err = WithSegment("model", c, func() error {
 			err = WithSegment("Game", c, func() error {
@@ -55,6 +56,7 @@ func UpsertItemHandler(app *App) func(c echo.Context) error {
 				return err
 			}
 
+			before = game.Clone()
 			err = WithSegment("Item", c, func() error {
 				item, err = game.AddItem(
 					itemKey, payload.Metadata,
@@ -75,13 +77,18 @@ func UpsertItemHandler(app *App) func(c echo.Context) error {
 			return nil
 		})
 		if err != nil {
-			return FailWith(status, err.Error(), c)
+			return FailWithError(status, err, c)
 		}
 
 		log.I(l, "Created/Updated item successfully.", func(cm log.CM) {
 			cm.Write(zap.String("Key", item.Key))
 		})
 
+		err = recordAudit(app, getActor(app, c), getClaimedActor(c), models.AuditUpsertItem, before, game, l)
+		if err != nil {
+			return FailWithError(500, err, c)
+		}
+
 		var itemJSON []byte
 		err = WithSegment("serialization", c, func() error {
 			itemJSON, err = item.ToJSON()
@@ -94,7 +101,7 @@ func UpsertItemHandler(app *App) func(c echo.Context) error {
 			return nil
 		})
 		if err != nil {
-			return FailWith(500, err.Error(), c)
+			return FailWithError(500, err, c)
 		}
 
 		return c.String(http.StatusOK, string(itemJSON))
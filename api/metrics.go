@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/metrics"
+	"github.com/uber-go/zap"
+)
+
+//MetricsHandler is the handler responsible for exposing metrics to Prometheus
+func MetricsHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		l := app.Logger.With(
+			zap.String("source", "MetricsHandler"),
+			zap.String("operation", "Metrics"),
+		)
+		c.Set("route", "Metrics")
+
+		body, contentType, err := metrics.Gather()
+		if err != nil {
+			log.E(l, "Failed to gather metrics.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(http.StatusInternalServerError, err, c)
+		}
+
+		return c.Blob(http.StatusOK, contentType, body)
+	}
+}
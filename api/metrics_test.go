@@ -0,0 +1,72 @@
+package api_test
+
+import (
+	"fmt"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	uuid "github.com/satori/go.uuid"
+	"github.com/topfreegames/donations/api"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Metrics Handler", func() {
+	var logger zap.Logger
+	var app *api.App
+	var game *models.Game
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		app = GetDefaultTestApp(logger)
+
+		var err error
+		game, err = GetTestGame(app.MongoDb, app.Logger, true)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		app.Stop()
+	})
+
+	It("Should expose request, error, lock, redis and donation metrics", func() {
+		payload := &api.CreateDonationRequestPayload{
+			Player: uuid.NewV4().String(),
+			Item:   GetFirstItem(game).Key,
+			Clan:   uuid.NewV4().String(),
+		}
+		jsonPayload, err := payload.ToJSON()
+		Expect(err).NotTo(HaveOccurred())
+
+		status, body := Post(app, fmt.Sprintf("/games/%s/donation-requests", game.ID), string(jsonPayload))
+		Expect(status).To(Equal(http.StatusOK), body)
+		status, _ = Get(app, fmt.Sprintf("/games/%s/donation-requests/%s", game.ID, uuid.NewV4().String()))
+		Expect(status).To(Equal(http.StatusNotFound))
+
+		status, body = Get(app, "/metrics")
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(ContainSubstring(
+			`donations_http_request_duration_seconds_count{method="POST",route="CreateDonationRequest",status="200"}`,
+		))
+		Expect(body).To(ContainSubstring(`donations_errors_total{type="DocumentNotFoundError"}`))
+		Expect(body).To(ContainSubstring(`donations_segment_duration_seconds_count{route="CreateDonationRequest",segment="model"}`))
+		Expect(body).To(ContainSubstring(`donations_lock_wait_seconds_count{lock="DonationRequest"}`))
+		Expect(body).To(ContainSubstring(`donations_redis_command_duration_seconds_count{command="SET"}`))
+		Expect(body).To(ContainSubstring(fmt.Sprintf(`donations_donation_requests_created_total{game="%s"} 1`, game.ID)))
+	})
+
+	It("Should not serve metrics when disabled", func() {
+		app.Config.Set("metrics.enabled", false)
+		err := app.Configure()
+		Expect(err).NotTo(HaveOccurred())
+
+		status, _ := Get(app, "/metrics")
+		Expect(status).To(Equal(http.StatusNotFound))
+	})
+})
@@ -12,22 +12,47 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"runtime/debug"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/garyburd/redigo/redis"
 	"github.com/getsentry/raven-go"
 	"github.com/labstack/echo"
+	"github.com/labstack/echo/engine"
+	"github.com/topfreegames/donations/errors"
 	"github.com/topfreegames/donations/log"
 	"github.com/topfreegames/donations/metadata"
+	"github.com/topfreegames/donations/metrics"
+	"github.com/topfreegames/donations/models"
+	"github.com/topfreegames/donations/tracing"
 	"github.com/uber-go/zap"
+	"golang.org/x/net/context"
 )
 
+//bodyWriter copies the body of a response to a buffer. Server-sent event streams are not copied, since they
+//last as long as the client is connected.
+type bodyWriter struct {
+	io.Writer
+	res    engine.Response
+	buffer *bytes.Buffer
+}
+
+func (w *bodyWriter) Write(b []byte) (int, error) {
+	n, err := w.Writer.Write(b)
+	if !strings.HasPrefix(w.res.Header().Get(echo.HeaderContentType), "text/event-stream") {
+		w.buffer.Write(b[:n])
+	}
+	return n, err
+}
+
 func getBodyFromNext(c echo.Context, next echo.HandlerFunc) (string, error) {
 	res := c.Response()
-	rw := res.Writer()
 	buf := new(bytes.Buffer)
-	mw := io.MultiWriter(rw, buf)
-	res.SetWriter(mw)
+	res.SetWriter(&bodyWriter{Writer: res.Writer(), res: res, buffer: buf})
 
 	err := next(c)
 
@@ -261,36 +286,542 @@ func (l *LoggerMiddleware) Serve(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
-//NewNewRelicMiddleware returns the logger middleware
-func NewNewRelicMiddleware(app *App, theLogger zap.Logger) *NewRelicMiddleware {
-	l := &NewRelicMiddleware{App: app, Logger: theLogger}
-	return l
+//NewTracingMiddleware returns a new tracing middleware
+func NewTracingMiddleware(app *App) *TracingMiddleware {
+	return &TracingMiddleware{App: app}
 }
 
-//NewRelicMiddleware is responsible for logging to Zap all requests
-type NewRelicMiddleware struct {
-	App    *App
-	Logger zap.Logger
+//TracingMiddleware starts the root span of each request with the tracer of the app,
+//continuing the trace propagated in the request headers
+type TracingMiddleware struct {
+	App *App
 }
 
 // Serve serves the middleware
-func (nr *NewRelicMiddleware) Serve(next echo.HandlerFunc) echo.HandlerFunc {
+func (t *TracingMiddleware) Serve(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		method := c.Request().Method()
 		route := fmt.Sprintf("%s %s", method, c.Path())
-		txn := nr.App.NewRelic.StartTransaction(route, nil, nil)
-		c.Set("txn", txn)
+		ctx := tracing.NewContext(context.Background(), t.App.Tracer)
+		ctx, span := t.App.Tracer.StartTrace(ctx, route, c.Request().Header())
+		span.SetAttribute("http.method", method)
+		span.SetAttribute("http.route", c.Path())
+		c.Set("traceContext", ctx)
 		defer func() {
-			c.Set("txn", nil)
-			txn.End()
+			c.Set("traceContext", nil)
+			span.End()
 		}()
 
 		err := next(c)
+		span.SetAttribute("http.status_code", c.Response().Status())
+		if err != nil {
+			span.RecordError(err)
+			return err
+		}
+
+		return nil
+	}
+}
+
+//NewMetricsMiddleware returns a new metrics middleware
+func NewMetricsMiddleware() *MetricsMiddleware {
+	return &MetricsMiddleware{}
+}
+
+//MetricsMiddleware measures the latency of requests per route context value, method and status
+type MetricsMiddleware struct{}
+
+// Serve serves the middleware
+func (m *MetricsMiddleware) Serve(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+		metrics.RequestDuration.WithLabelValues(
+			getRoute(c),
+			c.Request().Method(),
+			strconv.Itoa(c.Response().Status()),
+		).Observe(metrics.Since(start))
+		return err
+	}
+}
+
+//IdempotencyKeyHeader is the header clients use to make retries of a request safe
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+//IdempotentReplayHeader is set in responses replayed from a previous request
+const IdempotentReplayHeader = "Idempotent-Replayed"
+
+//IdempotentReplayAuthorizer authorizes a repeated request before its stored response is replayed,
+//returning the status and error to fail with if it is not authorized
+type IdempotentReplayAuthorizer func(app *App, c echo.Context, stored *models.IdempotentResponse) (int, error)
+
+//NewIdempotencyMiddleware returns a new idempotency middleware for the given route.
+//authorizeReplay authenticates repeated requests like the handler of the route does, and can be nil.
+func NewIdempotencyMiddleware(app *App, route string, authorizeReplay IdempotentReplayAuthorizer) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		App:             app,
+		Route:           route,
+		AuthorizeReplay: authorizeReplay,
+	}
+}
+
+//IdempotencyMiddleware stores the outcome of requests sent with an idempotency key
+//and returns it again for repeated requests within the configured TTL
+type IdempotencyMiddleware struct {
+	App             *App
+	Route           string
+	AuthorizeReplay IdempotentReplayAuthorizer
+}
+
+// Serve serves the middleware
+func (i *IdempotencyMiddleware) Serve(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key := c.Request().Header().Get(IdempotencyKeyHeader)
+		if key == "" {
+			return next(c)
+		}
+
+		gameID := c.Param("gameID")
+		path := c.Request().URL().Path()
+		ttl := i.App.Config.GetInt("api.idempotency.ttlSeconds")
+		clock := &models.RealClock{}
+		l := i.App.Logger.With(
+			zap.String("source", "IdempotencyMiddleware"),
+			zap.String("operation", "Serve"),
+			zap.String("gameID", gameID),
+			zap.String("route", i.Route),
+			zap.String("idempotencyKey", key),
+		)
+
+		mutexID := fmt.Sprintf("Idempotency-%s-%s-%s", gameID, i.Route, key)
+		mutex := i.App.GetMutex(mutexID, 32, 8) // Number of retries to get lock and Lock expiration
+		err := mutex.Lock(GetTraceContext(c))
 		if err != nil {
-			txn.NoticeError(err)
+			log.E(l, "Could not acquire lock after many retries.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+		defer mutex.Unlock()
+
+		var stored *models.IdempotentResponse
+		err = WithSegment("idempotency", c, func() error {
+			stored, err = models.GetIdempotentResponse(gameID, i.Route, key, ttl, clock, i.App.MongoDb, i.App.Logger)
+			if err != nil {
+				if _, ok := err.(*errors.DocumentNotFoundError); ok {
+					return nil
+				}
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			log.E(l, "Failed to retrieve idempotent response.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+
+		requestBody, err := GetRequestBody(c)
+		if err != nil {
+			return FailWith(400, err.Error(), c)
+		}
+		requestHash := models.GetIdempotentRequestHash(requestBody)
+
+		if stored != nil {
+			if stored.Path != path || stored.RequestHash != requestHash {
+				err := &errors.IdempotencyKeyReusedError{Key: key, Path: stored.Path}
+				return FailWithError(http.StatusUnprocessableEntity, err, c)
+			}
+			if i.AuthorizeReplay != nil {
+				if status, err := i.AuthorizeReplay(i.App, c, stored); err != nil {
+					return FailWithError(status, err, c)
+				}
+			}
+			log.D(l, "Replaying stored response.")
+			c.Response().Header().Set(IdempotentReplayHeader, "true")
+			if stored.ContentType == "" {
+				return c.String(stored.Status, stored.Body)
+			}
+			c.Response().Header().Set(echo.HeaderContentType, stored.ContentType)
+			c.Response().WriteHeader(stored.Status)
+			_, err = c.Response().Write([]byte(stored.Body))
+			return err
+		}
+
+		body, err := getBodyFromNext(c, next)
+		status := c.Response().Status()
+		contentType := c.Response().Header().Get(echo.HeaderContentType)
+
+		//server failures are not stored so that clients can retry them, and neither are authentication
+		//and rate limit failures, which depend on the credentials sent and the time and not on the request
+		if err != nil || status > 499 || status == http.StatusUnauthorized || status == http.StatusForbidden ||
+			status == http.StatusTooManyRequests {
+			return err
+		}
+
+		err = WithSegment("idempotency", c, func() error {
+			return models.SaveIdempotentResponse(
+				gameID, i.Route, key, path, requestHash,
+				status, contentType, body,
+				ttl, clock,
+				i.App.MongoDb, i.App.Logger,
+			)
+		})
+		if err != nil {
+			log.E(l, "Failed to store idempotent response.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+		}
+
+		return nil
+	}
+}
+
+//APIKeyHeader is the header clients use to send the API key of a game
+const APIKeyHeader = "X-Api-Key"
+
+//getBasicAuthCredentials returns the basic auth credentials of the operator. The admin listener uses
+//api.admin.basicAuth, if configured, and the public listener and gRPC use api.basicAuth.
+func getBasicAuthCredentials(app *App, adminListener bool) (string, string) {
+	if adminListener && app.Config.GetString("api.admin.basicAuth.user") != "" {
+		return app.Config.GetString("api.admin.basicAuth.user"), app.Config.GetString("api.admin.basicAuth.pass")
+	}
+	return app.Config.GetString("api.basicAuth.user"), app.Config.GetString("api.basicAuth.pass")
+}
+
+//isOperator returns true if the basic auth credentials are the operator ones, which can access every game
+func isOperator(app *App, auth string, adminListener bool) bool {
+	basicAuthUser, basicAuthPass := getBasicAuthCredentials(app, adminListener)
+	if basicAuthUser == "" {
+		return false
+	}
+	user, pass, ok := parseBasicAuth(auth)
+	return ok && user == basicAuthUser && pass == basicAuthPass
+}
+
+//authorizeAPIKey returns the API key of the token if it was granted the scope in the game
+func authorizeAPIKey(app *App, token, gameID, scope string) (*models.APIKey, error) {
+	if token == "" {
+		return nil, &errors.InvalidAPIKeyError{}
+	}
+	key, err := models.AuthenticateAPIKey(token, &models.RealClock{}, app.MongoDb, app.Logger)
+	if err != nil {
+		return nil, err
+	}
+	if key.GameID != gameID || !key.HasScope(scope) {
+		return nil, &errors.APIKeyForbiddenError{KeyID: key.ID, GameID: gameID, Scope: scope}
+	}
+	return key, nil
+}
+
+//NewAPIKeyMiddleware returns a new API key middleware that requires the given scope
+func NewAPIKeyMiddleware(app *App, scope string) *APIKeyMiddleware {
+	return &APIKeyMiddleware{
+		App:   app,
+		Scope: scope,
+	}
+}
+
+//APIKeyMiddleware requires an API key of the game in the :gameID route param when API keys are enabled.
+//The global basic auth credentials, if configured, are still accepted for every game.
+type APIKeyMiddleware struct {
+	App   *App
+	Scope string
+}
+
+// Serve serves the middleware
+func (a *APIKeyMiddleware) Serve(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !a.App.Config.GetBool("api.apiKeys.enabled") {
+			return next(c)
+		}
+		adminListener := a.Scope == models.APIKeyScopeAdmin && a.App.Admin != nil
+		if isOperator(a.App, c.Request().Header().Get("Authorization"), adminListener) {
+			return next(c)
+		}
+
+		gameID := c.Param("gameID")
+		l := a.App.Logger.With(
+			zap.String("source", "APIKeyMiddleware"),
+			zap.String("operation", "Serve"),
+			zap.String("gameID", gameID),
+			zap.String("scope", a.Scope),
+		)
+
+		var key *models.APIKey
+		err := WithSegment("apiKey", c, func() error {
+			var err error
+			key, err = authorizeAPIKey(a.App, c.Request().Header().Get(APIKeyHeader), gameID, a.Scope)
 			return err
+		})
+		switch err.(type) {
+		case nil:
+		case *errors.InvalidAPIKeyError:
+			log.D(l, "Invalid API key.")
+			return FailWithError(http.StatusUnauthorized, err, c)
+		case *errors.APIKeyForbiddenError:
+			log.D(l, "API key can't access the route.", func(cm log.CM) {
+				cm.Write(zap.String("keyID", err.(*errors.APIKeyForbiddenError).KeyID))
+			})
+			return FailWithError(http.StatusForbidden, err, c)
+		default:
+			log.E(l, "Failed to authenticate API key.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
 		}
 
+		c.Set("apiKey", key)
+		return next(c)
+	}
+}
+
+//RateLimits are the keys a route can be limited by. Each one has its own token bucket, configured in
+//api.rateLimit.routes.<route>.<limit>.capacity and refillPerSecond. Limits without a capacity are not applied.
+//The game and ip limits are applied before the request is handled, and the player limit once its player is
+//authenticated, so that a request can't use the tokens of other players.
+var RateLimits = []string{"game", "player", "ip"}
+
+//rateLimitScript takes a token from each bucket in KEYS, refilling them for the time passed since they were
+//last used. ARGV has the current time followed by the capacity and refill per second of each bucket. Tokens are
+//only taken if every bucket has one. It returns whether they were taken, the index of the bucket that had to
+//wait the longest for a token (starting at 1, or 0 if allowed) and the milliseconds until that token.
+var rateLimitScript = redis.NewScript(-1, `
+local now = tonumber(ARGV[1])
+local tokens = {}
+local denied = 0
+local retryAfter = 0
+
+for i, key in ipairs(KEYS) do
+  local capacity = tonumber(ARGV[i * 2])
+  local refillPerSecond = tonumber(ARGV[i * 2 + 1])
+  local bucket = redis.call("HMGET", key, "tokens", "updatedAt")
+  local available = tonumber(bucket[1]) or capacity
+  local updatedAt = tonumber(bucket[2]) or now
+  tokens[i] = math.min(capacity, available + math.max(0, now - updatedAt) * refillPerSecond / 1000)
+
+  if tokens[i] < 1 then
+    local wait = math.ceil((1 - tokens[i]) * 1000 / refillPerSecond)
+    if wait > retryAfter then
+      denied = i
+      retryAfter = wait
+    end
+  end
+end
+
+if denied > 0 then
+  return {0, denied, retryAfter}
+end
+
+for i, key in ipairs(KEYS) do
+  local capacity = tonumber(ARGV[i * 2])
+  local refillPerSecond = tonumber(ARGV[i * 2 + 1])
+  redis.call("HMSET", key, "tokens", tokens[i] - 1, "updatedAt", now)
+  redis.call("PEXPIRE", key, math.ceil(capacity * 1000 / refillPerSecond) + 1000)
+end
+return {1, 0, 0}
+`)
+
+//rateLimitBucket is the token bucket of a limit of a route for a game, player or IP
+type rateLimitBucket struct {
+	Limit           string
+	Key             string
+	Capacity        int
+	RefillPerSecond float64
+}
+
+//getRateLimitBuckets returns the buckets of the limit of the route for each key, or none if the limit is not configured
+func getRateLimitBuckets(app *App, route, limit string, keys ...string) []*rateLimitBucket {
+	config := fmt.Sprintf("api.rateLimit.routes.%s.%s", route, limit)
+	capacity := app.Config.GetInt(fmt.Sprintf("%s.capacity", config))
+	refillPerSecond := app.Config.GetFloat64(fmt.Sprintf("%s.refillPerSecond", config))
+	if capacity <= 0 || refillPerSecond <= 0 {
+		return []*rateLimitBucket{}
+	}
+
+	buckets := []*rateLimitBucket{}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		buckets = append(buckets, &rateLimitBucket{
+			Limit:           limit,
+			Key:             key,
+			Capacity:        capacity,
+			RefillPerSecond: refillPerSecond,
+		})
+	}
+	return buckets
+}
+
+//takeRateLimit takes a token from every bucket of the route in a single script, so that no token is taken
+//unless all of them allow the request. If not, it returns the bucket to wait for and for how many milliseconds.
+func takeRateLimit(app *App, route string, buckets []*rateLimitBucket) (bool, *rateLimitBucket, int, error) {
+	conn := app.Redis.Get()
+	defer conn.Close()
+
+	keys := make([]interface{}, 0, len(buckets))
+	args := []interface{}{time.Now().UnixNano() / int64(time.Millisecond)}
+	for _, bucket := range buckets {
+		keys = append(keys, fmt.Sprintf("rate-limit:%s:%s:%s", route, bucket.Limit, bucket.Key))
+		args = append(args, bucket.Capacity, bucket.RefillPerSecond)
+	}
+
+	result, err := redis.Ints(rateLimitScript.Do(conn, append(append([]interface{}{len(keys)}, keys...), args...)...))
+	if err != nil {
+		return false, nil, 0, err
+	}
+	if result[0] == 1 {
+		return true, nil, 0, nil
+	}
+	return false, buckets[result[1]-1], result[2], nil
+}
+
+//checkRateLimit takes a token from the buckets of a request to a route of a game, returning a
+//RateLimitExceededError if any of them is over its limit. If redis fails, the request is let through.
+func checkRateLimit(app *App, gameID, route string, buckets []*rateLimitBucket) error {
+	l := app.Logger.With(
+		zap.String("source", "rateLimit"),
+		zap.String("operation", "checkRateLimit"),
+		zap.String("gameID", gameID),
+		zap.String("route", route),
+	)
+
+	allowed, denied, retryAfter, err := takeRateLimit(app, route, buckets)
+	if err != nil {
+		log.E(l, "Failed to check rate limit, letting request through.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
 		return nil
 	}
+
+	if !allowed {
+		retryAfterSeconds := (retryAfter + 999) / 1000
+		log.I(l, "Rate limit exceeded.", func(cm log.CM) {
+			cm.Write(
+				zap.String("limit", denied.Limit),
+				zap.String("key", denied.Key),
+				zap.Int("retryAfterSeconds", retryAfterSeconds),
+			)
+		})
+		return &errors.RateLimitExceededError{
+			Route:             route,
+			Limit:             denied.Limit,
+			RetryAfterSeconds: retryAfterSeconds,
+		}
+	}
+
+	log.D(l, "Rate limit allowed request.", func(cm log.CM) {
+		cm.Write(zap.Int("buckets", len(buckets)))
+	})
+	return nil
+}
+
+//applyRateLimit takes a token from the buckets of the request, returning 429 and setting the Retry-After
+//header if any of them is over its limit. If redis fails, the request is let through.
+func applyRateLimit(app *App, c echo.Context, route string, buckets []*rateLimitBucket) (int, error) {
+	if !app.Config.GetBool("api.rateLimit.enabled") || len(buckets) == 0 {
+		return http.StatusOK, nil
+	}
+
+	err := WithSegment("rateLimit", c, func() error {
+		return checkRateLimit(app, c.Param("gameID"), route, buckets)
+	})
+	if err != nil {
+		retryAfterSeconds := err.(*errors.RateLimitExceededError).RetryAfterSeconds
+		c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+		return http.StatusTooManyRequests, err
+	}
+
+	return http.StatusOK, nil
+}
+
+//applyPlayerRateLimit applies the player limit of the route to the players of a request, which are only unique
+//in their game. It must only be called once the players are authenticated.
+func applyPlayerRateLimit(app *App, c echo.Context, route string, players ...string) (int, error) {
+	keys := []string{}
+	for _, player := range players {
+		if player != "" {
+			keys = append(keys, fmt.Sprintf("%s:%s", c.Param("gameID"), player))
+		}
+	}
+	return applyRateLimit(app, c, route, getRateLimitBuckets(app, route, "player", keys...))
+}
+
+//getRateLimitIP returns the IP address of the client of an HTTP request
+func getRateLimitIP(app *App, c echo.Context) string {
+	header := app.Config.GetString("api.rateLimit.forwardedForHeader")
+	forwardedFor := ""
+	if header != "" {
+		forwardedFor = c.Request().Header().Get(header)
+	}
+	return getClientIP(app, forwardedFor, c.Request().RemoteAddress())
+}
+
+//getClientIP returns the IP address of the client, without its port. If api.rateLimit.forwardedForHeader
+//is set, the address is read from the forwardedFor value of that header instead, skipping the
+//api.rateLimit.trustedProxies addresses appended to it by the proxies in front of the API, since the
+//addresses before them can be forged by clients. Without trusted proxies the whole header can be forged,
+//so it is ignored.
+func getClientIP(app *App, forwardedFor, remoteAddress string) string {
+	trustedProxies := app.Config.GetInt("api.rateLimit.trustedProxies")
+	if app.Config.GetString("api.rateLimit.forwardedForHeader") != "" && trustedProxies > 0 {
+		addresses := []string{}
+		for _, address := range strings.Split(forwardedFor, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				addresses = append(addresses, address)
+			}
+		}
+		if len(addresses) > 0 {
+			index := len(addresses) - trustedProxies
+			if index < 0 {
+				index = 0
+			}
+			return addresses[index]
+		}
+	}
+
+	if host, _, err := net.SplitHostPort(remoteAddress); err == nil {
+		return host
+	}
+	return remoteAddress
+}
+
+//NewRateLimitMiddleware returns a new rate limit middleware for the given route
+func NewRateLimitMiddleware(app *App, route string) *RateLimitMiddleware {
+	return &RateLimitMiddleware{
+		App:   app,
+		Route: route,
+	}
+}
+
+//RateLimitMiddleware limits the requests to a route per game and IP with token buckets stored in redis,
+//so that the limits are shared by every instance of the API. Requests over a limit fail with 429 and a
+//Retry-After header. If redis fails, requests are let through. The player limit is applied by the
+//handlers with applyPlayerRateLimit, after the player is authenticated.
+type RateLimitMiddleware struct {
+	App   *App
+	Route string
+}
+
+// Serve serves the middleware
+func (r *RateLimitMiddleware) Serve(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !r.App.Config.GetBool("api.rateLimit.enabled") {
+			return next(c)
+		}
+
+		buckets := append(
+			getRateLimitBuckets(r.App, r.Route, "game", c.Param("gameID")),
+			getRateLimitBuckets(r.App, r.Route, "ip", getRateLimitIP(r.App, c))...,
+		)
+		if status, err := applyRateLimit(r.App, c, r.Route, buckets); err != nil {
+			return FailWithError(status, err, c)
+		}
+
+		return next(c)
+	}
 }
@@ -4,9 +4,11 @@ package api
 
 import (
 	"fmt"
+	"net/url"
 
 	"github.com/mailru/easyjson/jwriter"
 	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/models"
 	"github.com/uber-go/zap"
 )
 
@@ -147,6 +149,54 @@ func (dp *DonationPayload) ToJSON() ([]byte, error) {
 	return w.BuildBytes()
 }
 
+//BatchDonationEntry maps each donation of the Batch Donations route
+type BatchDonationEntry struct {
+	DonationRequestID  string `json:"donationRequestID"`
+	Player             string `json:"player"`
+	Amount             int    `json:"amount"`
+	MaxWeightPerPlayer int    `json:"maxWeightPerPlayer"`
+	PlayerToken        string `json:"playerToken,omitempty"`
+}
+
+//Validate all the required fields for a donation in a batch
+func (bde *BatchDonationEntry) Validate() []string {
+	v := NewValidation()
+	v.validateRequiredString("donationRequestID", bde.DonationRequestID)
+	v.validateCustom("donation", func() []string {
+		return (&DonationPayload{
+			Player:             bde.Player,
+			Amount:             bde.Amount,
+			MaxWeightPerPlayer: bde.MaxWeightPerPlayer,
+		}).Validate()
+	})
+	return v.Errors()
+}
+
+//BatchDonationPayload maps the payload for the Batch Donations route
+type BatchDonationPayload struct {
+	Donations []*BatchDonationEntry `json:"donations"`
+}
+
+//Validate all the required fields for a batch of donations. Each donation is validated
+//independently, so an invalid donation does not fail the others.
+func (bdp *BatchDonationPayload) Validate() []string {
+	v := NewValidation()
+	v.validateCustom("donations", func() []string {
+		if len(bdp.Donations) == 0 {
+			return []string{"donations is required"}
+		}
+		return []string{}
+	})
+	return v.Errors()
+}
+
+//ToJSON returns the payload as JSON
+func (bdp *BatchDonationPayload) ToJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	bdp.MarshalEasyJSON(&w)
+	return w.BuildBytes()
+}
+
 //UpsertItemPayload maps the payload for the Upsert Item route
 type UpsertItemPayload struct {
 	Metadata                          map[string]interface{} `json:"metadata"`
@@ -171,3 +221,114 @@ func (uip *UpsertItemPayload) ToJSON() ([]byte, error) {
 	uip.MarshalEasyJSON(&w)
 	return w.BuildBytes()
 }
+
+//CreateWebhookPayload maps the payload for the Create Webhook route
+type CreateWebhookPayload struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+//Validate all the required fields for creating a webhook
+func (cwp *CreateWebhookPayload) Validate() []string {
+	v := NewValidation()
+	v.validateRequiredString("url", cwp.URL)
+	v.validateCustom("url", func() []string {
+		if cwp.URL == "" {
+			return []string{}
+		}
+		u, err := url.Parse(cwp.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return []string{"url must be an absolute http or https url"}
+		}
+		return []string{}
+	})
+	v.validateCustom("events", func() []string {
+		if len(cwp.Events) == 0 {
+			return []string{"events is required"}
+		}
+		errors := []string{}
+		for _, event := range cwp.Events {
+			if !models.IsValidWebhookEvent(event) {
+				errors = append(errors, fmt.Sprintf("%s is not a valid event", event))
+			}
+		}
+		return errors
+	})
+	return v.Errors()
+}
+
+//ToJSON returns the payload as JSON
+func (cwp *CreateWebhookPayload) ToJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	cwp.MarshalEasyJSON(&w)
+	return w.BuildBytes()
+}
+
+//CreateAPIKeyPayload maps the payload for the Create API Key route
+type CreateAPIKeyPayload struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+//Validate all the required fields for creating an API key
+func (cakp *CreateAPIKeyPayload) Validate() []string {
+	v := NewValidation()
+	v.validateRequiredString("name", cakp.Name)
+	v.validateCustom("scopes", func() []string {
+		if len(cakp.Scopes) == 0 {
+			return []string{"scopes is required"}
+		}
+		errors := []string{}
+		for _, scope := range cakp.Scopes {
+			if !models.IsValidAPIKeyScope(scope) {
+				errors = append(errors, fmt.Sprintf("%s is not a valid scope", scope))
+			}
+		}
+		return errors
+	})
+	return v.Errors()
+}
+
+//ToJSON returns the payload as JSON
+func (cakp *CreateAPIKeyPayload) ToJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	cakp.MarshalEasyJSON(&w)
+	return w.BuildBytes()
+}
+
+//SetPlayerAuthPayload maps the payload for the Set Player Auth route
+type SetPlayerAuthPayload struct {
+	Provider  string `json:"provider"`
+	Algorithm string `json:"algorithm"`
+	Key       string `json:"key"`
+}
+
+//Validate all the required fields for requiring player tokens
+func (spap *SetPlayerAuthPayload) Validate() []string {
+	v := NewValidation()
+	v.validateRequiredString("provider", spap.Provider)
+	v.validateRequiredString("algorithm", spap.Algorithm)
+	v.validateRequiredString("key", spap.Key)
+	v.validateCustom("algorithm", func() []string {
+		if spap.Algorithm == "" {
+			return []string{}
+		}
+		if !models.IsValidPlayerAuthAlgorithm(spap.Algorithm) {
+			return []string{fmt.Sprintf("%s is not a valid algorithm", spap.Algorithm)}
+		}
+		config := &models.PlayerAuthConfig{Algorithm: spap.Algorithm, Key: spap.Key}
+		if spap.Key != "" && config.ValidateKey() != nil {
+			return []string{fmt.Sprintf("key is not a valid %s key", spap.Algorithm)}
+		}
+		return []string{}
+	})
+	return v.Errors()
+}
+
+//ToJSON returns the payload as JSON
+func (spap *SetPlayerAuthPayload) ToJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	spap.MarshalEasyJSON(&w)
+	return w.BuildBytes()
+}
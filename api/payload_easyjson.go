@@ -346,3 +346,403 @@ func (v Validation) MarshalEasyJSON(w *jwriter.Writer) {
 func (v *Validation) UnmarshalEasyJSON(l *jlexer.Lexer) {
 	easyjsonA8a797f8DecodeGithubComTopfreegamesDonationsApi4(l, v)
 }
+func easyjsonA8a797f8DecodeGithubComTopfreegamesDonationsApi5(in *jlexer.Lexer, out *CreateWebhookPayload) {
+	if in.IsNull() {
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "url":
+			out.URL = string(in.String())
+		case "events":
+			if in.IsNull() {
+				in.Skip()
+				out.Events = nil
+			} else {
+				in.Delim('[')
+				if !in.IsDelim(']') {
+					out.Events = make([]string, 0, 4)
+				} else {
+					out.Events = []string{}
+				}
+				for !in.IsDelim(']') {
+					var v4 string
+					v4 = string(in.String())
+					out.Events = append(out.Events, v4)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "secret":
+			out.Secret = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+}
+func easyjsonA8a797f8EncodeGithubComTopfreegamesDonationsApi5(out *jwriter.Writer, in CreateWebhookPayload) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"url\":")
+	out.String(string(in.URL))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"events\":")
+	if in.Events == nil {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v5, v6 := range in.Events {
+			if v5 > 0 {
+				out.RawByte(',')
+			}
+			out.String(string(v6))
+		}
+		out.RawByte(']')
+	}
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"secret\":")
+	out.String(string(in.Secret))
+	out.RawByte('}')
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v CreateWebhookPayload) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonA8a797f8EncodeGithubComTopfreegamesDonationsApi5(w, v)
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *CreateWebhookPayload) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonA8a797f8DecodeGithubComTopfreegamesDonationsApi5(l, v)
+}
+func easyjsonA8a797f8DecodeGithubComTopfreegamesDonationsApi6(in *jlexer.Lexer, out *BatchDonationEntry) {
+	if in.IsNull() {
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "donationRequestID":
+			out.DonationRequestID = string(in.String())
+		case "player":
+			out.Player = string(in.String())
+		case "amount":
+			out.Amount = int(in.Int())
+		case "maxWeightPerPlayer":
+			out.MaxWeightPerPlayer = int(in.Int())
+		case "playerToken":
+			out.PlayerToken = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+}
+func easyjsonA8a797f8EncodeGithubComTopfreegamesDonationsApi6(out *jwriter.Writer, in BatchDonationEntry) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"donationRequestID\":")
+	out.String(string(in.DonationRequestID))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"player\":")
+	out.String(string(in.Player))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"amount\":")
+	out.Int(int(in.Amount))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"maxWeightPerPlayer\":")
+	out.Int(int(in.MaxWeightPerPlayer))
+	if in.PlayerToken != "" {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"playerToken\":")
+		out.String(string(in.PlayerToken))
+	}
+	out.RawByte('}')
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v BatchDonationEntry) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonA8a797f8EncodeGithubComTopfreegamesDonationsApi6(w, v)
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *BatchDonationEntry) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonA8a797f8DecodeGithubComTopfreegamesDonationsApi6(l, v)
+}
+func easyjsonA8a797f8DecodeGithubComTopfreegamesDonationsApi7(in *jlexer.Lexer, out *BatchDonationPayload) {
+	if in.IsNull() {
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "donations":
+			if in.IsNull() {
+				in.Skip()
+				out.Donations = nil
+			} else {
+				in.Delim('[')
+				if !in.IsDelim(']') {
+					out.Donations = make([]*BatchDonationEntry, 0, 8)
+				} else {
+					out.Donations = []*BatchDonationEntry{}
+				}
+				for !in.IsDelim(']') {
+					var v7 *BatchDonationEntry
+					if in.IsNull() {
+						in.Skip()
+						v7 = nil
+					} else {
+						if v7 == nil {
+							v7 = new(BatchDonationEntry)
+						}
+						(*v7).UnmarshalEasyJSON(in)
+					}
+					out.Donations = append(out.Donations, v7)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+}
+func easyjsonA8a797f8EncodeGithubComTopfreegamesDonationsApi7(out *jwriter.Writer, in BatchDonationPayload) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"donations\":")
+	if in.Donations == nil {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v8, v9 := range in.Donations {
+			if v8 > 0 {
+				out.RawByte(',')
+			}
+			if v9 == nil {
+				out.RawString("null")
+			} else {
+				(*v9).MarshalEasyJSON(out)
+			}
+		}
+		out.RawByte(']')
+	}
+	out.RawByte('}')
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v BatchDonationPayload) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonA8a797f8EncodeGithubComTopfreegamesDonationsApi7(w, v)
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *BatchDonationPayload) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonA8a797f8DecodeGithubComTopfreegamesDonationsApi7(l, v)
+}
+func easyjsonA8a797f8DecodeGithubComTopfreegamesDonationsApi8(in *jlexer.Lexer, out *CreateAPIKeyPayload) {
+	if in.IsNull() {
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "name":
+			out.Name = string(in.String())
+		case "scopes":
+			if in.IsNull() {
+				in.Skip()
+				out.Scopes = nil
+			} else {
+				in.Delim('[')
+				if !in.IsDelim(']') {
+					out.Scopes = make([]string, 0, 4)
+				} else {
+					out.Scopes = []string{}
+				}
+				for !in.IsDelim(']') {
+					var v10 string
+					v10 = string(in.String())
+					out.Scopes = append(out.Scopes, v10)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+}
+func easyjsonA8a797f8EncodeGithubComTopfreegamesDonationsApi8(out *jwriter.Writer, in CreateAPIKeyPayload) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"name\":")
+	out.String(string(in.Name))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"scopes\":")
+	if in.Scopes == nil {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v11, v12 := range in.Scopes {
+			if v11 > 0 {
+				out.RawByte(',')
+			}
+			out.String(string(v12))
+		}
+		out.RawByte(']')
+	}
+	out.RawByte('}')
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v CreateAPIKeyPayload) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonA8a797f8EncodeGithubComTopfreegamesDonationsApi8(w, v)
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *CreateAPIKeyPayload) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonA8a797f8DecodeGithubComTopfreegamesDonationsApi8(l, v)
+}
+func easyjsonA8a797f8DecodeGithubComTopfreegamesDonationsApi9(in *jlexer.Lexer, out *SetPlayerAuthPayload) {
+	if in.IsNull() {
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "provider":
+			out.Provider = string(in.String())
+		case "algorithm":
+			out.Algorithm = string(in.String())
+		case "key":
+			out.Key = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+}
+func easyjsonA8a797f8EncodeGithubComTopfreegamesDonationsApi9(out *jwriter.Writer, in SetPlayerAuthPayload) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"provider\":")
+	out.String(string(in.Provider))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"algorithm\":")
+	out.String(string(in.Algorithm))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"key\":")
+	out.String(string(in.Key))
+	out.RawByte('}')
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v SetPlayerAuthPayload) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonA8a797f8EncodeGithubComTopfreegamesDonationsApi9(w, v)
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *SetPlayerAuthPayload) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonA8a797f8DecodeGithubComTopfreegamesDonationsApi9(l, v)
+}
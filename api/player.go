@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/models"
+
+	"github.com/labstack/echo"
+	"github.com/uber-go/zap"
+)
+
+//ExportPlayerDataHandler is the handler responsible for exporting everything stored about a player
+func ExportPlayerDataHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		gameID := c.Param("gameID")
+		playerID := c.Param("playerID")
+		l := app.Logger.With(
+			zap.String("source", "ExportPlayerDataHandler"),
+			zap.String("operation", "ExportPlayerData"),
+			zap.String("gameID", gameID),
+			zap.String("playerID", playerID),
+		)
+		c.Set("route", "ExportPlayerData")
+
+		log.D(l, "Exporting player data...")
+		var export *models.PlayerDataExport
+		err := WithSegment("model", c, func() error {
+			var err error
+			export, err = models.ExportPlayerData(gameID, playerID, &models.RealClock{}, app.Redis, app.MongoDb, app.Logger)
+			return err
+		})
+		if err != nil {
+			log.E(l, "Failed to export player data!", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+
+		log.I(l, "Exported player data successfully.")
+		return c.JSON(http.StatusOK, export)
+	}
+}
+
+//ErasePlayerDataHandler is the handler responsible for erasing a player from a game
+func ErasePlayerDataHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		gameID := c.Param("gameID")
+		playerID := c.Param("playerID")
+		l := app.Logger.With(
+			zap.String("source", "ErasePlayerDataHandler"),
+			zap.String("operation", "ErasePlayerData"),
+			zap.String("gameID", gameID),
+			zap.String("playerID", playerID),
+		)
+		c.Set("route", "ErasePlayerData")
+
+		log.D(l, "Erasing player data...")
+		var result *models.PlayerErasureResult
+		err := WithSegment("model", c, func() error {
+			var err error
+			result, err = models.ErasePlayerData(gameID, playerID, app.Redis, app.MongoDb, app.Logger)
+			if err != nil || app.Stream == nil {
+				return err
+			}
+			result.StreamMessages, err = app.Stream.ErasePlayer(gameID, playerID, result.Pseudonym)
+			return err
+		})
+		if err != nil {
+			log.E(l, "Failed to erase player data!", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+
+		log.I(l, "Erased player data successfully.")
+		return c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/models"
+
+	"github.com/labstack/echo"
+	"github.com/uber-go/zap"
+)
+
+//PlayerTokenHeader is the header with the signed token of the player creating a donation request or donating
+const PlayerTokenHeader = "X-Player-Token"
+
+//getPlayerAuthConfig returns the player authentication config of a game, or nil if the game does not require player tokens
+func getPlayerAuthConfig(app *App, gameID string) (*models.PlayerAuthConfig, error) {
+	config, err := models.GetPlayerAuthConfig(gameID, app.MongoDb, app.Logger)
+	if _, ok := err.(*errors.DocumentNotFoundError); ok {
+		return nil, nil
+	}
+	return config, err
+}
+
+//authenticatePlayer verifies that the token was signed for the player when the game requires player tokens.
+//If clan is not nil, the clan of the token must also be clan.
+func authenticatePlayer(app *App, gameID, player string, clan *string, token string) error {
+	config, err := getPlayerAuthConfig(app, gameID)
+	if err != nil || config == nil {
+		return err
+	}
+	claims, err := config.AuthenticatePlayer(token, player)
+	if err != nil {
+		return err
+	}
+	if clan != nil && claims.Clan != *clan {
+		return errors.NewAuthenticationFailed(config.Provider, player, "")
+	}
+	return nil
+}
+
+//authenticatePlayerOfRequest is authenticatePlayer for the player token header, returning the status of failures
+func authenticatePlayerOfRequest(app *App, c echo.Context, gameID, player string, clan *string) (int, error) {
+	err := WithSegment("playerAuth", c, func() error {
+		return authenticatePlayer(app, gameID, player, clan, c.Request().Header().Get(PlayerTokenHeader))
+	})
+	if _, ok := err.(*errors.AuthenticationFailed); ok {
+		return http.StatusUnauthorized, err
+	}
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+//authorizeDonationRequestReplay authenticates the player of a repeated donation request before its stored
+//response is replayed. Payloads the handler rejects before authenticating the player are replayed as they are.
+func authorizeDonationRequestReplay(app *App, c echo.Context, stored *models.IdempotentResponse) (int, error) {
+	var payload CreateDonationRequestPayload
+	if err := LoadJSONPayload(&payload, c, app.Logger); err != nil {
+		return http.StatusOK, nil
+	}
+	return authenticatePlayerOfRequest(app, c, c.Param("gameID"), payload.Player, &payload.Clan)
+}
+
+//authorizeDonationReplay authenticates the player of a repeated donation before its stored response is replayed
+func authorizeDonationReplay(app *App, c echo.Context, stored *models.IdempotentResponse) (int, error) {
+	var payload DonationPayload
+	if err := LoadJSONPayload(&payload, c, app.Logger); err != nil {
+		return http.StatusOK, nil
+	}
+	return authenticatePlayerOfRequest(app, c, c.Param("gameID"), payload.Player, nil)
+}
+
+//authorizeBatchDonationReplay authenticates the players of a repeated batch before its stored response is replayed.
+//Donations that failed authentication in the stored response are not authenticated again, since their results
+//don't disclose anything, but every other donation must still have a valid token for its player.
+func authorizeBatchDonationReplay(app *App, c echo.Context, stored *models.IdempotentResponse) (int, error) {
+	var payload BatchDonationPayload
+	if err := LoadJSONPayload(&payload, c, app.Logger); err != nil || stored.Status != http.StatusOK {
+		return http.StatusOK, nil
+	}
+
+	var playerAuth *models.PlayerAuthConfig
+	err := WithSegment("playerAuth", c, func() error {
+		var err error
+		playerAuth, err = getPlayerAuthConfig(app, c.Param("gameID"))
+		return err
+	})
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if playerAuth == nil {
+		return http.StatusOK, nil
+	}
+
+	var response struct {
+		Results []*BatchDonationResult `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(stored.Body), &response); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	playerToken := c.Request().Header().Get(PlayerTokenHeader)
+	for i, entry := range payload.Donations {
+		if entry == nil || len(entry.Validate()) > 0 {
+			continue
+		}
+		if i < len(response.Results) && response.Results[i].Code == BatchPlayerAuthenticationFailed {
+			continue
+		}
+		token := entry.PlayerToken
+		if token == "" {
+			token = playerToken
+		}
+		if _, err := playerAuth.AuthenticatePlayer(token, entry.Player); err != nil {
+			return http.StatusUnauthorized, err
+		}
+	}
+	return http.StatusOK, nil
+}
+
+//SetPlayerAuthHandler is the handler responsible for making a game require player tokens
+func SetPlayerAuthHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		gameID := c.Param("gameID")
+		l := app.Logger.With(
+			zap.String("source", "SetPlayerAuthHandler"),
+			zap.String("operation", "SetPlayerAuth"),
+			zap.String("gameID", gameID),
+		)
+		c.Set("route", "SetPlayerAuth")
+
+		var payload SetPlayerAuthPayload
+		err := WithSegment("payload", c, func() error {
+			if err := LoadJSONPayload(&payload, c, l); err != nil {
+				log.E(l, "Invalid json payload!", func(cm log.CM) {
+					cm.Write(zap.Error(err))
+				})
+				return err
+			}
+
+			return nil
+		})
+		if err != nil {
+			return FailWithError(400, err, c)
+		}
+
+		var config *models.PlayerAuthConfig
+		err = WithSegment("model", c, func() error {
+			_, err := models.GetGameByID(gameID, app.MongoDb, app.Logger)
+			if err != nil {
+				return err
+			}
+
+			config, err = models.SetPlayerAuthConfig(
+				gameID, payload.Provider, payload.Algorithm, payload.Key,
+				&models.RealClock{}, app.MongoDb, app.Logger,
+			)
+			return err
+		})
+		if _, ok := err.(*errors.DocumentNotFoundError); ok {
+			return FailWithError(404, err, c)
+		}
+		if _, ok := err.(*errors.AuthenticationProviderNotSupported); ok {
+			return FailWithError(400, err, c)
+		}
+		if err != nil {
+			log.E(l, "Failed to set player authentication!", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+
+		log.I(l, "Set player authentication successfully.")
+		return c.JSON(http.StatusOK, config)
+	}
+}
+
+//GetPlayerAuthHandler is the handler responsible for returning how a game authenticates players, without its key
+func GetPlayerAuthHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		gameID := c.Param("gameID")
+		l := app.Logger.With(
+			zap.String("source", "GetPlayerAuthHandler"),
+			zap.String("operation", "GetPlayerAuth"),
+			zap.String("gameID", gameID),
+		)
+		c.Set("route", "GetPlayerAuth")
+
+		var config *models.PlayerAuthConfig
+		err := WithSegment("model", c, func() error {
+			var err error
+			config, err = models.GetPlayerAuthConfig(gameID, app.MongoDb, app.Logger)
+			return err
+		})
+		if _, ok := err.(*errors.DocumentNotFoundError); ok {
+			return FailWithError(404, err, c)
+		}
+		if err != nil {
+			log.E(l, "Failed to get player authentication!", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+
+		return c.JSON(http.StatusOK, config)
+	}
+}
+
+//RemovePlayerAuthHandler is the handler responsible for making a game stop requiring player tokens
+func RemovePlayerAuthHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		gameID := c.Param("gameID")
+		l := app.Logger.With(
+			zap.String("source", "RemovePlayerAuthHandler"),
+			zap.String("operation", "RemovePlayerAuth"),
+			zap.String("gameID", gameID),
+		)
+		c.Set("route", "RemovePlayerAuth")
+
+		err := WithSegment("model", c, func() error {
+			return models.RemovePlayerAuthConfig(gameID, app.MongoDb, app.Logger)
+		})
+		if _, ok := err.(*errors.DocumentNotFoundError); ok {
+			return FailWithError(404, err, c)
+		}
+		if err != nil {
+			log.E(l, "Failed to remove player authentication!", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+
+		log.I(l, "Removed player authentication successfully.")
+		return c.String(http.StatusOK, "{\"success\":true}")
+	}
+}
@@ -0,0 +1,241 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/api"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Player Auth Handler", func() {
+	var logger zap.Logger
+	var app *api.App
+	var game *models.Game
+
+	playerToken := func(player, clan string) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub":  player,
+			"clan": clan,
+			"exp":  time.Now().Add(time.Hour).Unix(),
+		}).SignedString([]byte("secret"))
+		Expect(err).NotTo(HaveOccurred())
+		return token
+	}
+
+	createDonationRequest := func(player, clan string, headers map[string]string) (int, string) {
+		payload := &api.CreateDonationRequestPayload{Item: GetFirstItem(game).Key, Player: player, Clan: clan}
+		jsonPayload, err := payload.ToJSON()
+		Expect(err).NotTo(HaveOccurred())
+		url := fmt.Sprintf("/games/%s/donation-requests", game.ID)
+		return PostWithHeaders(app, url, string(jsonPayload), headers)
+	}
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		app = GetDefaultTestApp(logger)
+
+		var err error
+		game, err = GetTestGame(app.MongoDb, app.Logger, true)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		app.Stop()
+	})
+
+	Describe("Set Player Auth", func() {
+		It("Should require player tokens without returning the key", func() {
+			payload := &api.SetPlayerAuthPayload{Provider: "jwt", Algorithm: "HS256", Key: "secret"}
+			jsonPayload, err := payload.ToJSON()
+			Expect(err).NotTo(HaveOccurred())
+
+			status, body := Put(app, fmt.Sprintf("/games/%s/player-auth", game.ID), string(jsonPayload))
+			Expect(status).To(Equal(http.StatusOK), body)
+			Expect(body).NotTo(ContainSubstring("secret"))
+
+			status, body = Get(app, fmt.Sprintf("/games/%s/player-auth", game.ID))
+			Expect(status).To(Equal(http.StatusOK), body)
+			var config models.PlayerAuthConfig
+			err = json.Unmarshal([]byte(body), &config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Algorithm).To(Equal("HS256"))
+			Expect(config.Key).To(BeEmpty())
+
+			status, _ = Delete(app, fmt.Sprintf("/games/%s/player-auth", game.ID), "")
+			Expect(status).To(Equal(http.StatusOK))
+			status, _ = Get(app, fmt.Sprintf("/games/%s/player-auth", game.ID))
+			Expect(status).To(Equal(http.StatusNotFound))
+		})
+
+		It("Should fail with unsupported providers and invalid keys", func() {
+			payload := &api.SetPlayerAuthPayload{Provider: "facebook", Algorithm: "HS256", Key: "secret"}
+			jsonPayload, err := payload.ToJSON()
+			Expect(err).NotTo(HaveOccurred())
+
+			status, body := Put(app, fmt.Sprintf("/games/%s/player-auth", game.ID), string(jsonPayload))
+			Expect(status).To(Equal(http.StatusBadRequest))
+			Expect(body).To(ContainSubstring("Provider facebook is not supported."))
+
+			payload = &api.SetPlayerAuthPayload{Provider: "jwt", Algorithm: "RS256", Key: "secret"}
+			jsonPayload, err = payload.ToJSON()
+			Expect(err).NotTo(HaveOccurred())
+
+			status, body = Put(app, fmt.Sprintf("/games/%s/player-auth", game.ID), string(jsonPayload))
+			Expect(status).To(Equal(http.StatusBadRequest))
+			Expect(body).To(ContainSubstring("key is not a valid RS256 key"))
+		})
+	})
+
+	Describe("Player tokens", func() {
+		BeforeEach(func() {
+			_, err := models.SetPlayerAuthConfig(
+				game.ID, models.PlayerAuthProviderJWT, "HS256", "secret",
+				&models.RealClock{}, app.MongoDb, app.Logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should require the token of the player and clan to create donation requests", func() {
+			status, _ := createDonationRequest("player-0", "clan-0", map[string]string{})
+			Expect(status).To(Equal(http.StatusUnauthorized))
+
+			status, _ = createDonationRequest("player-0", "clan-0", map[string]string{
+				api.PlayerTokenHeader: playerToken("player-0", "clan-1"),
+			})
+			Expect(status).To(Equal(http.StatusUnauthorized))
+
+			token := playerToken("player-1", "clan-0")
+			status, body := createDonationRequest("player-0", "clan-0", map[string]string{
+				api.PlayerTokenHeader: token,
+			})
+			Expect(status).To(Equal(http.StatusUnauthorized))
+			Expect(body).NotTo(ContainSubstring(token))
+
+			status, body = createDonationRequest("player-0", "clan-0", map[string]string{
+				api.PlayerTokenHeader: playerToken("player-0", "clan-0"),
+			})
+			Expect(status).To(Equal(http.StatusOK), body)
+		})
+
+		It("Should only take tokens from the rate limit of a player once it is authenticated", func() {
+			app.Config.Set("api.rateLimit.enabled", true)
+			app.Config.Set("api.rateLimit.routes.CreateDonationRequest.player.capacity", 1)
+			app.Config.Set("api.rateLimit.routes.CreateDonationRequest.player.refillPerSecond", 0.01)
+
+			for i := 0; i < 2; i++ {
+				status, _ := createDonationRequest("player-0", "clan-0", map[string]string{
+					api.PlayerTokenHeader: playerToken("player-1", "clan-0"),
+				})
+				Expect(status).To(Equal(http.StatusUnauthorized))
+			}
+
+			status, body := createDonationRequest("player-0", "clan-0", map[string]string{
+				api.PlayerTokenHeader: playerToken("player-0", "clan-0"),
+			})
+			Expect(status).To(Equal(http.StatusOK), body)
+
+			status, body = createDonationRequest("player-0", "clan-0", map[string]string{
+				api.PlayerTokenHeader: playerToken("player-0", "clan-0"),
+			})
+			Expect(status).To(Equal(http.StatusTooManyRequests))
+			Expect(body).To(ContainSubstring("Rate limit per player of route CreateDonationRequest exceeded."))
+		})
+
+		It("Should not store authentication failures of idempotent requests", func() {
+			status, _ := createDonationRequest("player-0", "clan-0", map[string]string{
+				api.IdempotencyKeyHeader: "key",
+			})
+			Expect(status).To(Equal(http.StatusUnauthorized))
+
+			status, body := createDonationRequest("player-0", "clan-0", map[string]string{
+				api.IdempotencyKeyHeader: "key",
+				api.PlayerTokenHeader:    playerToken("player-0", "clan-0"),
+			})
+			Expect(status).To(Equal(http.StatusOK), body)
+		})
+
+		It("Should only replay idempotent requests to the authenticated player", func() {
+			status, body := createDonationRequest("player-0", "clan-0", map[string]string{
+				api.IdempotencyKeyHeader: "key",
+				api.PlayerTokenHeader:    playerToken("player-0", "clan-0"),
+			})
+			Expect(status).To(Equal(http.StatusOK), body)
+
+			status, replayed := createDonationRequest("player-0", "clan-0", map[string]string{
+				api.IdempotencyKeyHeader: "key",
+				api.PlayerTokenHeader:    playerToken("player-1", "clan-0"),
+			})
+			Expect(status).To(Equal(http.StatusUnauthorized))
+			Expect(replayed).NotTo(ContainSubstring(body))
+
+			status, replayed = createDonationRequest("player-0", "clan-0", map[string]string{
+				api.IdempotencyKeyHeader: "key",
+				api.PlayerTokenHeader:    playerToken("player-0", "clan-0"),
+			})
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(replayed).To(Equal(body))
+		})
+
+		It("Should require the token of the donor to donate", func() {
+			donationRequest, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			payload := &api.DonationPayload{Player: "player-1", Amount: 1, MaxWeightPerPlayer: 10}
+			jsonPayload, err := payload.ToJSON()
+			Expect(err).NotTo(HaveOccurred())
+			url := fmt.Sprintf("/games/%s/donation-requests/%s", game.ID, donationRequest.ID)
+
+			status, body := PostWithHeaders(app, url, string(jsonPayload), map[string]string{
+				api.PlayerTokenHeader: playerToken("player-2", ""),
+			})
+			Expect(status).To(Equal(http.StatusUnauthorized))
+			Expect(body).To(ContainSubstring("Authentication with provider jwt for user player-1 failed"))
+
+			status, body = PostWithHeaders(app, url, string(jsonPayload), map[string]string{
+				api.PlayerTokenHeader: playerToken("player-1", ""),
+			})
+			Expect(status).To(Equal(http.StatusOK), body)
+		})
+
+		It("Should fail the donations of other players in batches", func() {
+			dr, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			payload := &api.BatchDonationPayload{
+				Donations: []*api.BatchDonationEntry{
+					&api.BatchDonationEntry{DonationRequestID: dr.ID, Player: "player-1", Amount: 1, MaxWeightPerPlayer: 10},
+					&api.BatchDonationEntry{DonationRequestID: dr.ID, Player: "player-2", Amount: 1, MaxWeightPerPlayer: 10},
+				},
+			}
+			jsonPayload, err := payload.ToJSON()
+			Expect(err).NotTo(HaveOccurred())
+
+			url := fmt.Sprintf("/games/%s/donations/batch", game.ID)
+			status, body := PostWithHeaders(app, url, string(jsonPayload), map[string]string{
+				api.PlayerTokenHeader: playerToken("player-1", ""),
+			})
+			Expect(status).To(Equal(http.StatusOK), body)
+
+			var response struct {
+				Succeeded int                        `json:"succeeded"`
+				Results   []*api.BatchDonationResult `json:"results"`
+			}
+			err = json.Unmarshal([]byte(body), &response)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.Succeeded).To(Equal(1))
+			Expect(response.Results[1].Code).To(Equal(api.BatchPlayerAuthenticationFailed))
+		})
+	})
+})
@@ -0,0 +1,83 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/api"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Player Handler", func() {
+	var logger zap.Logger
+	var app *api.App
+	var game *models.Game
+	var player *models.Player
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		app = GetDefaultTestApp(logger)
+
+		var err error
+		game, err = GetTestGame(app.MongoDb, app.Logger, true)
+		Expect(err).NotTo(HaveOccurred())
+
+		player, err = GetTestPlayer(game, app.MongoDb, app.Logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		dr, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = dr.Donate(player.ID, 1, 100, app.Redis, app.MongoDb, app.Logger)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		app.Stop()
+	})
+
+	Describe("Export Player Data", func() {
+		It("Should respond with player data", func() {
+			status, body := Get(app, fmt.Sprintf("/games/%s/players/%s/export", game.ID, player.ID))
+			Expect(status).To(Equal(http.StatusOK))
+
+			var export models.PlayerDataExport
+			err := json.Unmarshal([]byte(body), &export)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(export.GameID).To(Equal(game.ID))
+			Expect(export.PlayerID).To(Equal(player.ID))
+			Expect(export.Donations).To(HaveLen(1))
+		})
+	})
+
+	Describe("Erase Player Data", func() {
+		It("Should erase player data", func() {
+			status, body := Delete(app, fmt.Sprintf("/games/%s/players/%s", game.ID, player.ID), "")
+			Expect(status).To(Equal(http.StatusOK))
+
+			var result models.PlayerErasureResult
+			err := json.Unmarshal([]byte(body), &result)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.PlayersRemoved).To(Equal(1))
+			Expect(result.Donations).To(Equal(1))
+
+			status, body = Get(app, fmt.Sprintf("/games/%s/players/%s/export", game.ID, player.ID))
+			Expect(status).To(Equal(http.StatusOK))
+
+			var export models.PlayerDataExport
+			err = json.Unmarshal([]byte(body), &export)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(export.Player).To(BeNil())
+			Expect(export.Donations).To(BeEmpty())
+		})
+	})
+})
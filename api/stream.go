@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/stream"
+
+	"github.com/labstack/echo"
+	"github.com/uber-go/zap"
+)
+
+//TruncatedStreamEvent is sent when events after the client cursor are not in the clan history anymore
+const TruncatedStreamEvent = "stream.truncated"
+
+func writeStreamMessage(w io.Writer, message *stream.Message) error {
+	data, err := json.Marshal(message.Event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", message.Cursor, message.Event.Type, data)
+	return err
+}
+
+func getStreamCursor(c echo.Context) (int64, error) {
+	//Last-Event-ID is sent by EventSource clients when reconnecting
+	value := c.Request().Header().Get("Last-Event-ID")
+	if value == "" {
+		value = c.QueryParam("cursor")
+	}
+	if value == "" {
+		return -1, nil
+	}
+	cursor, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || cursor < 0 {
+		return 0, fmt.Errorf("Invalid cursor '%s'.", value)
+	}
+	return cursor, nil
+}
+
+//StreamClanHandler is the handler responsible for streaming the donation events of a clan as server-sent events
+func StreamClanHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		gameID := c.Param("gameID")
+		clanID := c.Param("clanID")
+		l := app.Logger.With(
+			zap.String("source", "StreamClanHandler"),
+			zap.String("operation", "StreamClan"),
+			zap.String("gameID", gameID),
+			zap.String("clanID", clanID),
+		)
+		c.Set("route", "StreamClan")
+
+		if app.Stream == nil {
+			return FailWith(404, "Clan streams are not enabled.", c)
+		}
+
+		res := c.Response()
+		flusher, ok := res.(http.Flusher)
+		if !ok {
+			return FailWith(501, "Clan streams require the standard engine.", c)
+		}
+		var closed <-chan bool
+		if notifier, ok := res.(http.CloseNotifier); ok {
+			closed = notifier.CloseNotify()
+		}
+
+		cursor, err := getStreamCursor(c)
+		if err != nil {
+			return FailWithError(400, err, c)
+		}
+
+		sub, err := app.Stream.Subscribe(gameID, clanID, cursor)
+		if err != nil {
+			log.E(l, "Failed to subscribe to clan stream!", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+		defer sub.Close()
+
+		log.D(l, "Streaming clan events...", func(cm log.CM) {
+			cm.Write(zap.Int64("cursor", cursor), zap.Int("replay", len(sub.Replay)))
+		})
+
+		res.Header().Set("Content-Type", "text/event-stream")
+		res.Header().Set("Cache-Control", "no-cache")
+		res.Header().Set("Connection", "keep-alive")
+		res.WriteHeader(http.StatusOK)
+
+		if sub.Truncated {
+			_, err = fmt.Fprintf(res, "event: %s\ndata: {}\n\n", TruncatedStreamEvent)
+			if err != nil {
+				return nil
+			}
+		}
+
+		last := cursor
+		for _, message := range sub.Replay {
+			if err = writeStreamMessage(res, message); err != nil {
+				return nil
+			}
+			last = message.Cursor
+		}
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(time.Duration(app.Config.GetInt("stream.keepAliveSeconds")) * time.Second)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case message, ok := <-sub.Messages:
+				//The client fell behind or the hub was disconnected, so it must resume from its cursor
+				if !ok {
+					return nil
+				}
+				//Already replayed from the history
+				if message.Cursor <= last {
+					continue
+				}
+				err = writeStreamMessage(res, message)
+				last = message.Cursor
+			case <-keepAlive.C:
+				_, err = fmt.Fprint(res, ": keep-alive\n\n")
+			case <-closed:
+				return nil
+			}
+			if err != nil {
+				return nil
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package api_test
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/api"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Stream Handler", func() {
+	var logger zap.Logger
+	var app *api.App
+	var game *models.Game
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		app = GetDefaultTestApp(logger)
+
+		var err error
+		game, err = GetTestGame(app.MongoDb, app.Logger, true)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		app.Stop()
+	})
+
+	Describe("Stream Clan", func() {
+		It("Should stream donation events of the clan", func() {
+			dr, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			ts := InitializeTestServer(app)
+			defer ts.Close()
+
+			req := GetRequest(app, ts, "GET", fmt.Sprintf("/games/%s/clans/%s/stream?cursor=0", game.ID, dr.Clan), nil)
+			res := PerformRequest(ts, req)
+			defer res.Body.Close()
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(res.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+
+			lines := make(chan string, 100)
+			go func() {
+				defer GinkgoRecover()
+				scanner := bufio.NewScanner(res.Body)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+				close(lines)
+			}()
+
+			Eventually(lines).Should(Receive(Equal("id: 1")))
+			Eventually(lines).Should(Receive(Equal(fmt.Sprintf("event: %s", models.DonationRequestCreatedEvent))))
+			Eventually(lines).Should(Receive(ContainSubstring(dr.ID)))
+
+			player, err := GetTestPlayer(game, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+			err = dr.Donate(player.ID, 1, 100, app.Redis, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			var line string
+			Eventually(lines).Should(Receive(&line))
+			for line == "" || strings.HasPrefix(line, ":") {
+				Eventually(lines).Should(Receive(&line))
+			}
+			Expect(line).To(Equal("id: 2"))
+			Eventually(lines).Should(Receive(Equal(fmt.Sprintf("event: %s", models.DonationCreatedEvent))))
+		})
+
+		It("Should fail with an invalid cursor", func() {
+			status, body := Get(app, fmt.Sprintf("/games/%s/clans/clan/stream?cursor=invalid", game.ID))
+			Expect(status).To(Equal(http.StatusBadRequest))
+			Expect(body).To(ContainSubstring("Invalid cursor"))
+		})
+	})
+})
@@ -0,0 +1,73 @@
+package api_test
+
+import (
+	"fmt"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/api"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/topfreegames/donations/tracing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Tracing", func() {
+	var logger zap.Logger
+	var app *api.App
+	var game *models.Game
+	var exporter *tracing.InMemoryExporter
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		app = GetDefaultTestApp(logger)
+		exporter = tracing.NewInMemoryExporter()
+		app.Tracer = tracing.NewOpenTelemetryTracerWithProcessor(tracing.NewSimpleSpanProcessor(exporter), 1)
+
+		var err error
+		game, err = GetTestGame(app.MongoDb, app.Logger, true)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		app.Stop()
+	})
+
+	It("Should trace donations with the propagated trace context", func() {
+		donationRequest, err := GetTestDonationRequest(game, app.MongoDb, app.Logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		payload := &api.DonationPayload{Player: "player-1", Amount: 1, MaxWeightPerPlayer: 10}
+		jsonPayload, err := payload.ToJSON()
+		Expect(err).NotTo(HaveOccurred())
+
+		traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+		url := fmt.Sprintf("/games/%s/donation-requests/%s", game.ID, donationRequest.ID)
+		status, body := PostWithHeaders(app, url, string(jsonPayload), map[string]string{
+			"traceparent": fmt.Sprintf("00-%s-00f067aa0ba902b7-01", traceID),
+		})
+		Expect(status).To(Equal(http.StatusOK), body)
+
+		spans := map[string]*tracing.SpanData{}
+		for _, span := range exporter.GetSpans() {
+			Expect(span.SpanContext.TraceID.String()).To(Equal(traceID))
+			spans[span.Name] = span
+		}
+
+		Expect(spans).To(HaveKey("POST /games/:gameID/donation-requests/:donationRequestID"))
+		Expect(spans).To(HaveKey("model"))
+		request := spans["POST /games/:gameID/donation-requests/:donationRequestID"]
+		Expect(request.Parent.SpanID.String()).To(Equal("00f067aa0ba902b7"))
+		model := spans["model"]
+		Expect(model.Parent.SpanID).To(Equal(request.SpanContext.SpanID))
+		for _, name := range []string{"redsync Donate.lock", "mongodb donations.insert", "redis incrby"} {
+			Expect(spans).To(HaveKey(name))
+			Expect(spans[name].Parent.SpanID).To(Equal(model.SpanContext.SpanID))
+		}
+	})
+})
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/models"
+
+	"github.com/labstack/echo"
+	"github.com/uber-go/zap"
+)
+
+//WebhookWithSecret is returned when a webhook is created, the only time its secret is available
+type WebhookWithSecret struct {
+	*models.Webhook
+	Secret string `json:"secret"`
+}
+
+//CreateWebhookHandler is the handler responsible for subscribing webhooks to the events of a game
+func CreateWebhookHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		gameID := c.Param("gameID")
+		l := app.Logger.With(
+			zap.String("source", "CreateWebhookHandler"),
+			zap.String("operation", "CreateWebhook"),
+			zap.String("gameID", gameID),
+		)
+		c.Set("route", "CreateWebhook")
+
+		log.D(l, "Creating webhook...")
+
+		var payload CreateWebhookPayload
+		err := WithSegment("payload", c, func() error {
+			if err := LoadJSONPayload(&payload, c, l); err != nil {
+				log.E(l, "Invalid json payload!", func(cm log.CM) {
+					cm.Write(zap.Error(err))
+				})
+				return err
+			}
+
+			return nil
+		})
+		if err != nil {
+			return FailWithError(400, err, c)
+		}
+
+		var webhook *models.Webhook
+		err = WithSegment("model", c, func() error {
+			_, err := models.GetGameByID(gameID, app.MongoDb, app.Logger)
+			if err != nil {
+				return err
+			}
+
+			webhook, err = models.CreateWebhook(
+				gameID, payload.URL, payload.Events, payload.Secret,
+				&models.RealClock{}, app.MongoDb, app.Logger,
+			)
+			return err
+		})
+		if _, ok := err.(*errors.DocumentNotFoundError); ok {
+			return FailWithError(404, err, c)
+		}
+		if err != nil {
+			log.E(l, "Failed to create webhook!", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+
+		log.I(l, "Created webhook successfully.", func(cm log.CM) {
+			cm.Write(zap.String("ID", webhook.ID))
+		})
+		return c.JSON(http.StatusOK, &WebhookWithSecret{Webhook: webhook, Secret: webhook.Secret})
+	}
+}
+
+//GetWebhooksHandler is the handler responsible for listing the webhooks of a game
+func GetWebhooksHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		gameID := c.Param("gameID")
+		l := app.Logger.With(
+			zap.String("source", "GetWebhooksHandler"),
+			zap.String("operation", "GetWebhooks"),
+			zap.String("gameID", gameID),
+		)
+		c.Set("route", "GetWebhooks")
+
+		var webhooks []*models.Webhook
+		err := WithSegment("model", c, func() error {
+			var err error
+			webhooks, err = models.GetWebhooks(gameID, app.MongoDb, app.Logger)
+			return err
+		})
+		if err != nil {
+			log.E(l, "Failed to get webhooks!", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+
+		return c.JSON(http.StatusOK, webhooks)
+	}
+}
+
+//RemoveWebhookHandler is the handler responsible for removing a webhook of a game
+func RemoveWebhookHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		gameID := c.Param("gameID")
+		webhookID := c.Param("webhookID")
+		l := app.Logger.With(
+			zap.String("source", "RemoveWebhookHandler"),
+			zap.String("operation", "RemoveWebhook"),
+			zap.String("gameID", gameID),
+			zap.String("webhookID", webhookID),
+		)
+		c.Set("route", "RemoveWebhook")
+
+		err := WithSegment("model", c, func() error {
+			return models.RemoveWebhook(gameID, webhookID, app.MongoDb, app.Logger)
+		})
+		if _, ok := err.(*errors.DocumentNotFoundError); ok {
+			return FailWithError(404, err, c)
+		}
+		if err != nil {
+			log.E(l, "Failed to remove webhook!", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+
+		log.I(l, "Removed webhook successfully.")
+		return c.String(http.StatusOK, "{\"success\":true}")
+	}
+}
+
+//GetWebhookDeadLettersHandler is the handler responsible for listing the deliveries of a game that failed too many times
+func GetWebhookDeadLettersHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		gameID := c.Param("gameID")
+		l := app.Logger.With(
+			zap.String("source", "GetWebhookDeadLettersHandler"),
+			zap.String("operation", "GetWebhookDeadLetters"),
+			zap.String("gameID", gameID),
+		)
+		c.Set("route", "GetWebhookDeadLetters")
+
+		var deliveries []*models.WebhookDelivery
+		err := WithSegment("model", c, func() error {
+			var err error
+			deliveries, err = models.GetDeadWebhookDeliveries(gameID, app.MongoDb, app.Logger)
+			return err
+		})
+		if err != nil {
+			log.E(l, "Failed to get webhook dead letters!", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+
+		return c.JSON(http.StatusOK, deliveries)
+	}
+}
+
+//RedeliverWebhookHandler is the handler responsible for scheduling a webhook delivery to be sent again
+func RedeliverWebhookHandler(app *App) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		gameID := c.Param("gameID")
+		deliveryID := c.Param("deliveryID")
+		l := app.Logger.With(
+			zap.String("source", "RedeliverWebhookHandler"),
+			zap.String("operation", "RedeliverWebhook"),
+			zap.String("gameID", gameID),
+			zap.String("deliveryID", deliveryID),
+		)
+		c.Set("route", "RedeliverWebhook")
+
+		err := WithSegment("model", c, func() error {
+			return models.RedeliverWebhookDelivery(gameID, deliveryID, &models.RealClock{}, app.MongoDb, app.Logger)
+		})
+		if _, ok := err.(*errors.DocumentNotFoundError); ok {
+			return FailWithError(404, err, c)
+		}
+		if err != nil {
+			log.E(l, "Failed to redeliver webhook!", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return FailWithError(500, err, c)
+		}
+
+		log.I(l, "Scheduled webhook redelivery successfully.")
+		return c.String(http.StatusOK, "{\"success\":true}")
+	}
+}
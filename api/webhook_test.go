@@ -0,0 +1,163 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/api"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Webhook Handler", func() {
+	var logger zap.Logger
+	var app *api.App
+	var game *models.Game
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		app = GetDefaultTestApp(logger)
+
+		var err error
+		game, err = GetTestGame(app.MongoDb, app.Logger, true)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		app.Stop()
+	})
+
+	Describe("Create Webhook", func() {
+		It("Should create a webhook", func() {
+			payload := &api.CreateWebhookPayload{
+				URL:    "http://localhost:8080/hook",
+				Events: []string{models.DonationCreatedEvent, models.DonationRequestFinishedEvent},
+			}
+			jsonPayload, err := payload.ToJSON()
+			Expect(err).NotTo(HaveOccurred())
+
+			status, body := Post(app, fmt.Sprintf("/games/%s/webhooks", game.ID), string(jsonPayload))
+			Expect(status).To(Equal(http.StatusOK), body)
+
+			var webhook api.WebhookWithSecret
+			err = json.Unmarshal([]byte(body), &webhook)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(webhook.ID).NotTo(BeEmpty())
+			Expect(webhook.Secret).NotTo(BeEmpty())
+			Expect(webhook.Events).To(Equal(payload.Events))
+
+			status, body = Get(app, fmt.Sprintf("/games/%s/webhooks", game.ID))
+			Expect(status).To(Equal(http.StatusOK))
+
+			var webhooks []*models.Webhook
+			err = json.Unmarshal([]byte(body), &webhooks)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(webhooks).To(HaveLen(1))
+			Expect(webhooks[0].ID).To(Equal(webhook.ID))
+			Expect(body).NotTo(ContainSubstring(webhook.Secret))
+			Expect(body).NotTo(ContainSubstring(`"secret"`))
+		})
+
+		It("Should fail with invalid events or url", func() {
+			payload := &api.CreateWebhookPayload{
+				URL:    "localhost/hook",
+				Events: []string{"invalid.event"},
+			}
+			jsonPayload, err := payload.ToJSON()
+			Expect(err).NotTo(HaveOccurred())
+
+			status, body := Post(app, fmt.Sprintf("/games/%s/webhooks", game.ID), string(jsonPayload))
+			Expect(status).To(Equal(http.StatusBadRequest))
+			Expect(body).To(ContainSubstring("invalid.event is not a valid event"))
+			Expect(body).To(ContainSubstring("url must be an absolute http or https url"))
+		})
+
+		It("Should fail if game does not exist", func() {
+			payload := &api.CreateWebhookPayload{
+				URL:    "http://localhost:8080/hook",
+				Events: []string{models.AllEvents},
+			}
+			jsonPayload, err := payload.ToJSON()
+			Expect(err).NotTo(HaveOccurred())
+
+			status, _ := Post(app, "/games/invalid-game/webhooks", string(jsonPayload))
+			Expect(status).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("Remove Webhook", func() {
+		It("Should remove a webhook", func() {
+			webhook, err := models.CreateWebhook(
+				game.ID, "http://localhost:8080/hook", []string{models.AllEvents}, "",
+				&models.RealClock{}, app.MongoDb, app.Logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			status, _ := Delete(app, fmt.Sprintf("/games/%s/webhooks/%s", game.ID, webhook.ID), "")
+			Expect(status).To(Equal(http.StatusOK))
+
+			status, _ = Delete(app, fmt.Sprintf("/games/%s/webhooks/%s", game.ID, webhook.ID), "")
+			Expect(status).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("Dead Letters", func() {
+		var delivery *models.WebhookDelivery
+
+		BeforeEach(func() {
+			webhook, err := models.CreateWebhook(
+				game.ID, "http://localhost:8080/hook", []string{models.AllEvents}, "",
+				&models.RealClock{}, app.MongoDb, app.Logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = models.DispatchWebhookEvent(game.ID, models.DonationCreatedEvent, []byte("{}"), &models.RealClock{}, app.MongoDb, app.Logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = models.GetWebhookDeliveriesCollection(app.MongoDb).Find(bson.M{"webhookID": webhook.ID}).One(&delivery)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = models.MarkWebhookDeliveryFailed(
+				delivery, fmt.Errorf("failed"), 1, time.Second, time.Second, &models.RealClock{}, app.MongoDb,
+			)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should list dead deliveries", func() {
+			status, body := Get(app, fmt.Sprintf("/games/%s/webhooks/dead-letters", game.ID))
+			Expect(status).To(Equal(http.StatusOK))
+
+			var deliveries []*models.WebhookDelivery
+			err := json.Unmarshal([]byte(body), &deliveries)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deliveries).To(HaveLen(1))
+			Expect(deliveries[0].ID).To(Equal(delivery.ID))
+			Expect(deliveries[0].LastError).To(Equal("failed"))
+		})
+
+		It("Should redeliver dead deliveries", func() {
+			status, _ := Post(app, fmt.Sprintf("/games/%s/webhooks/deliveries/%s/redeliver", game.ID, delivery.ID), "")
+			Expect(status).To(Equal(http.StatusOK))
+
+			status, body := Get(app, fmt.Sprintf("/games/%s/webhooks/dead-letters", game.ID))
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).To(MatchJSON("[]"))
+		})
+
+		It("Should fail to redeliver unknown deliveries", func() {
+			status, _ := Post(app, fmt.Sprintf("/games/%s/webhooks/deliveries/invalid-id/redeliver", game.ID), "")
+			Expect(status).To(Equal(http.StatusNotFound))
+		})
+	})
+})
@@ -0,0 +1,495 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+//IdempotencyKeyHeader is the header that makes retries of donation requests and donations safe
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+//APIKeyHeader is the header with the API key of a game, when API keys are enabled
+const APIKeyHeader = "X-Api-Key"
+
+//PlayerTokenHeader is the header with the signed token of the player, when the game requires player tokens
+const PlayerTokenHeader = "X-Player-Token"
+
+//ActorHeader identifies who is changing a game's configuration in the game history
+const ActorHeader = "X-Actor"
+
+//Client is a client of the donations HTTP API. Failed responses are returned as errors package types
+//when the API fails with one of them, or as *APIError otherwise.
+type Client struct {
+	//URL of the API, without trailing slash
+	URL string
+
+	//User and Password are sent as basic auth when User is not empty
+	User     string
+	Password string
+
+	//APIKey is the token of an API key of the game, sent when not empty
+	APIKey string
+
+	//PlayerToken is the signed token of the player creating donation requests and donating, sent when not empty
+	PlayerToken string
+
+	//Actor is recorded in the game history for changes made by this client
+	Actor string
+
+	//MaxRetries is the number of times a request is sent again after a network error or a 5xx response.
+	//Only requests that are safe to repeat are retried: POST requests are retried only if they accept an
+	//idempotency key, which is generated once and sent in every attempt.
+	MaxRetries int
+
+	//RetryInterval is the wait before the first retry and is doubled at each retry
+	RetryInterval time.Duration
+
+	HTTPClient *http.Client
+}
+
+//NewClient returns a client of the API in url
+func NewClient(url string) *Client {
+	return &Client{
+		URL:           strings.TrimRight(url, "/"),
+		MaxRetries:    3,
+		RetryInterval: 100 * time.Millisecond,
+		HTTPClient:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+//WithPlayerToken returns a copy of the client that sends the token of a player, so a client
+//can be shared by the requests of many players
+func (c *Client) WithPlayerToken(token string) *Client {
+	copied := *c
+	copied.PlayerToken = token
+	return &copied
+}
+
+//BatchDonation is a donation sent in a batch. PlayerToken is the token of its player, if the game requires
+//player tokens and the donation is not of the player of the client token.
+type BatchDonation struct {
+	DonationRequestID  string `json:"donationRequestID"`
+	Player             string `json:"player"`
+	Amount             int    `json:"amount"`
+	MaxWeightPerPlayer int    `json:"maxWeightPerPlayer"`
+	PlayerToken        string `json:"playerToken,omitempty"`
+}
+
+//BatchDonationResult is the outcome of a donation in a batch
+type BatchDonationResult struct {
+	Index             int             `json:"index"`
+	DonationRequestID string          `json:"donationRequestID"`
+	Player            string          `json:"player"`
+	Success           bool            `json:"success"`
+	Code              string          `json:"code,omitempty"`
+	Reason            string          `json:"reason,omitempty"`
+	Type              string          `json:"type,omitempty"`
+	Details           json.RawMessage `json:"details,omitempty"`
+}
+
+//Err returns the error of a failed donation, decoded like the errors of the other routes
+func (r *BatchDonationResult) Err() error {
+	if r.Success {
+		return nil
+	}
+	return decodeFailure(http.StatusOK, r.Reason, r.Type, r.Details)
+}
+
+//BatchDonationResponse is the outcome of a batch of donations
+type BatchDonationResponse struct {
+	Succeeded int                    `json:"succeeded"`
+	Failed    int                    `json:"failed"`
+	Results   []*BatchDonationResult `json:"results"`
+}
+
+//escape a value to be used as a path segment
+func escape(value string) string {
+	return strings.Replace(url.QueryEscape(value), "+", "%20", -1)
+}
+
+func (c *Client) newRequest(method, path string, body []byte, headers map[string]string) (*http.Request, error) {
+	var reader *bytes.Reader
+	if body == nil {
+		reader = bytes.NewReader([]byte{})
+	} else {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("%s%s", c.URL, path), reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.User != "" {
+		req.SetBasicAuth(c.User, c.Password)
+	}
+	if c.APIKey != "" {
+		req.Header.Set(APIKeyHeader, c.APIKey)
+	}
+	if c.PlayerToken != "" {
+		req.Header.Set(PlayerTokenHeader, c.PlayerToken)
+	}
+	if c.Actor != "" {
+		req.Header.Set(ActorHeader, c.Actor)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	return req, nil
+}
+
+//send performs a single attempt of the request, returning whether it can be retried if it fails
+func (c *Client) send(method, path string, body []byte, headers map[string]string) ([]byte, bool, error) {
+	req, err := c.newRequest(method, path, body, headers)
+	if err != nil {
+		return nil, false, err
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, true, err
+	}
+	if res.StatusCode != http.StatusOK {
+		err = DecodeError(res.StatusCode, data)
+		return nil, isRetryable(err), err
+	}
+	return data, false, nil
+}
+
+//do sends the request, retrying it if retryable is true. If idempotent is true,
+//an idempotency key is sent and the request is always retryable.
+func (c *Client) do(method, path string, payload interface{}, retryable, idempotent bool, result interface{}) error {
+	var body []byte
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	headers := map[string]string{}
+	if idempotent {
+		headers[IdempotencyKeyHeader] = uuid.NewV4().String()
+		retryable = true
+	}
+
+	for attempt := 0; ; attempt++ {
+		data, canRetry, err := c.send(method, path, body, headers)
+		if err != nil {
+			if !retryable || !canRetry || attempt >= c.MaxRetries {
+				return err
+			}
+			time.Sleep(c.RetryInterval << uint(attempt))
+			continue
+		}
+
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(data, result)
+	}
+}
+
+//Healthcheck returns an error if the API or its database is not working
+func (c *Client) Healthcheck() error {
+	return c.do("GET", "/healthcheck", nil, true, false, nil)
+}
+
+//UpdateGame creates or updates a game
+func (c *Client) UpdateGame(gameID, name string, donationCooldownHours, donationRequestCooldownHours int) (*Game, error) {
+	payload := map[string]interface{}{
+		"name":                         name,
+		"donationCooldownHours":        donationCooldownHours,
+		"donationRequestCooldownHours": donationRequestCooldownHours,
+	}
+	var game Game
+	err := c.do("PUT", fmt.Sprintf("/games/%s", escape(gameID)), payload, true, false, &game)
+	if err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+//GetGameHistory returns up to limit versions of a game, newest first. The API default is used if limit is 0.
+func (c *Client) GetGameHistory(gameID string, limit int) ([]*AuditEntry, error) {
+	path := fmt.Sprintf("/games/%s/history", escape(gameID))
+	if limit > 0 {
+		path = fmt.Sprintf("%s?limit=%d", path, limit)
+	}
+	var result struct {
+		History []*AuditEntry `json:"history"`
+	}
+	err := c.do("GET", path, nil, true, false, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result.History, nil
+}
+
+//RollbackGame restores a game to a version of its history
+func (c *Client) RollbackGame(gameID string, version int) (*Game, error) {
+	var game Game
+	err := c.do("POST", fmt.Sprintf("/games/%s/history/%d/rollback", escape(gameID), version), nil, false, false, &game)
+	if err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+//UpsertItem creates or updates an item of a game
+func (c *Client) UpsertItem(
+	gameID, key string, metadata map[string]interface{},
+	weightPerDonation,
+	limitOfItemsPerPlayerDonation,
+	limitOfItemsInEachDonationRequest int,
+) (*Item, error) {
+	payload := map[string]interface{}{
+		"metadata":                          metadata,
+		"weightPerDonation":                 weightPerDonation,
+		"limitOfItemsPerPlayerDonation":     limitOfItemsPerPlayerDonation,
+		"limitOfItemsInEachDonationRequest": limitOfItemsInEachDonationRequest,
+	}
+	var item Item
+	err := c.do("PUT", fmt.Sprintf("/games/%s/items/%s", escape(gameID), escape(key)), payload, true, false, &item)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+//CreateDonationRequest creates a donation request of an item for a player of a clan
+func (c *Client) CreateDonationRequest(gameID, item, player, clan string) (*DonationRequest, error) {
+	payload := map[string]interface{}{
+		"item":   item,
+		"player": player,
+		"clan":   clan,
+	}
+	var donationRequest DonationRequest
+	err := c.do("POST", fmt.Sprintf("/games/%s/donation-requests", escape(gameID)), payload, true, true, &donationRequest)
+	if err != nil {
+		return nil, err
+	}
+	return &donationRequest, nil
+}
+
+//GetDonationRequest returns a live or archived donation request
+func (c *Client) GetDonationRequest(gameID, donationRequestID string) (*DonationRequest, error) {
+	var donationRequest DonationRequest
+	path := fmt.Sprintf("/games/%s/donation-requests/%s", escape(gameID), escape(donationRequestID))
+	err := c.do("GET", path, nil, true, false, &donationRequest)
+	if err != nil {
+		return nil, err
+	}
+	return &donationRequest, nil
+}
+
+//Donate donates amount items of a player to a donation request
+func (c *Client) Donate(gameID, donationRequestID, player string, amount, maxWeightPerPlayer int) error {
+	payload := map[string]interface{}{
+		"player":             player,
+		"amount":             amount,
+		"maxWeightPerPlayer": maxWeightPerPlayer,
+	}
+	path := fmt.Sprintf("/games/%s/donation-requests/%s", escape(gameID), escape(donationRequestID))
+	return c.do("POST", path, payload, true, true, nil)
+}
+
+//BatchDonate donates to many donation requests at once. Each donation succeeds or fails independently.
+func (c *Client) BatchDonate(gameID string, donations []*BatchDonation) (*BatchDonationResponse, error) {
+	payload := map[string]interface{}{"donations": donations}
+	var response BatchDonationResponse
+	err := c.do("POST", fmt.Sprintf("/games/%s/donations/batch", escape(gameID)), payload, true, true, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+//GetDonationWeightByClan returns the donation weight of a clan. resetType is daily, weekly,
+//monthly or empty for the weight of all time.
+func (c *Client) GetDonationWeightByClan(gameID, clanID, resetType string) (int, error) {
+	query := url.Values{}
+	query.Set("clanID", clanID)
+	if resetType != "" {
+		query.Set("type", resetType)
+	}
+	var result struct {
+		Weight int `json:"weight"`
+	}
+	path := fmt.Sprintf("/games/%s/donation-weight-by-clan?%s", escape(gameID), query.Encode())
+	err := c.do("GET", path, nil, true, false, &result)
+	if err != nil {
+		return 0, err
+	}
+	return result.Weight, nil
+}
+
+//ExportPlayerData returns everything stored about a player in a game
+func (c *Client) ExportPlayerData(gameID, playerID string) (*PlayerDataExport, error) {
+	var export PlayerDataExport
+	err := c.do("GET", fmt.Sprintf("/games/%s/players/%s/export", escape(gameID), escape(playerID)), nil, true, false, &export)
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+//ErasePlayerData erases a player from a game
+func (c *Client) ErasePlayerData(gameID, playerID string) (*PlayerErasureResult, error) {
+	var result PlayerErasureResult
+	err := c.do("DELETE", fmt.Sprintf("/games/%s/players/%s", escape(gameID), escape(playerID)), nil, true, false, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+//WebhookWithSecret is a webhook returned when it is created, the only time its secret is available
+type WebhookWithSecret struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+//CreateWebhook subscribes an url to events of a game. A random secret is generated if secret is empty.
+func (c *Client) CreateWebhook(gameID, webhookURL string, events []string, secret string) (*WebhookWithSecret, error) {
+	payload := map[string]interface{}{
+		"url":    webhookURL,
+		"events": events,
+		"secret": secret,
+	}
+	var webhook WebhookWithSecret
+	err := c.do("POST", fmt.Sprintf("/games/%s/webhooks", escape(gameID)), payload, false, false, &webhook)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+//GetWebhooks returns the webhooks of a game, without their secrets
+func (c *Client) GetWebhooks(gameID string) ([]*Webhook, error) {
+	webhooks := []*Webhook{}
+	err := c.do("GET", fmt.Sprintf("/games/%s/webhooks", escape(gameID)), nil, true, false, &webhooks)
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+//RemoveWebhook removes a webhook of a game
+func (c *Client) RemoveWebhook(gameID, webhookID string) error {
+	return c.do("DELETE", fmt.Sprintf("/games/%s/webhooks/%s", escape(gameID), escape(webhookID)), nil, true, false, nil)
+}
+
+//GetWebhookDeadLetters returns the deliveries of a game that failed too many times
+func (c *Client) GetWebhookDeadLetters(gameID string) ([]*WebhookDelivery, error) {
+	deliveries := []*WebhookDelivery{}
+	err := c.do("GET", fmt.Sprintf("/games/%s/webhooks/dead-letters", escape(gameID)), nil, true, false, &deliveries)
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+//RedeliverWebhook schedules a delivery to be sent again as soon as possible
+func (c *Client) RedeliverWebhook(gameID, deliveryID string) error {
+	path := fmt.Sprintf("/games/%s/webhooks/deliveries/%s/redeliver", escape(gameID), escape(deliveryID))
+	return c.do("POST", path, nil, true, false, nil)
+}
+
+//APIKeyWithToken is an API key returned when it is created or rotated, the only time its token is available
+type APIKeyWithToken struct {
+	APIKey
+	Token string `json:"token"`
+}
+
+//CreateAPIKey creates an API key of a game with the given scopes
+func (c *Client) CreateAPIKey(gameID, name string, scopes []string) (*APIKeyWithToken, error) {
+	payload := map[string]interface{}{
+		"name":   name,
+		"scopes": scopes,
+	}
+	var key APIKeyWithToken
+	err := c.do("POST", fmt.Sprintf("/games/%s/api-keys", escape(gameID)), payload, false, false, &key)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+//GetAPIKeys returns the API keys of a game, without their tokens
+func (c *Client) GetAPIKeys(gameID string) ([]*APIKey, error) {
+	keys := []*APIKey{}
+	err := c.do("GET", fmt.Sprintf("/games/%s/api-keys", escape(gameID)), nil, true, false, &keys)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+//RotateAPIKey replaces an API key of a game. The replaced key keeps working for graceSeconds,
+//or for the grace period configured in the API if graceSeconds is negative.
+func (c *Client) RotateAPIKey(gameID, keyID string, graceSeconds int) (*APIKeyWithToken, error) {
+	path := fmt.Sprintf("/games/%s/api-keys/%s/rotate", escape(gameID), escape(keyID))
+	if graceSeconds >= 0 {
+		path = fmt.Sprintf("%s?graceSeconds=%d", path, graceSeconds)
+	}
+	var key APIKeyWithToken
+	err := c.do("POST", path, nil, false, false, &key)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+//RevokeAPIKey removes an API key of a game, so its token stops working immediately
+func (c *Client) RevokeAPIKey(gameID, keyID string) error {
+	return c.do("DELETE", fmt.Sprintf("/games/%s/api-keys/%s", escape(gameID), escape(keyID)), nil, true, false, nil)
+}
+
+//SetPlayerAuth makes a game require player tokens signed with the algorithm (HS256, RS256 or ES256)
+//and verified with the key, which is the shared secret or the PEM encoded public key
+func (c *Client) SetPlayerAuth(gameID, algorithm, key string) (*PlayerAuthConfig, error) {
+	payload := map[string]interface{}{
+		"provider":  PlayerAuthProviderJWT,
+		"algorithm": algorithm,
+		"key":       key,
+	}
+	var config PlayerAuthConfig
+	err := c.do("PUT", fmt.Sprintf("/games/%s/player-auth", escape(gameID)), payload, true, false, &config)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+//GetPlayerAuth returns how a game authenticates players, without its key
+func (c *Client) GetPlayerAuth(gameID string) (*PlayerAuthConfig, error) {
+	var config PlayerAuthConfig
+	err := c.do("GET", fmt.Sprintf("/games/%s/player-auth", escape(gameID)), nil, true, false, &config)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+//RemovePlayerAuth makes a game stop requiring player tokens
+func (c *Client) RemovePlayerAuth(gameID string) error {
+	return c.do("DELETE", fmt.Sprintf("/games/%s/player-auth", escape(gameID)), nil, true, false, nil)
+}
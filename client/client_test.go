@@ -0,0 +1,110 @@
+package client_test
+
+import (
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	uuid "github.com/satori/go.uuid"
+	"github.com/topfreegames/donations/api"
+	"github.com/topfreegames/donations/client"
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Client", func() {
+	var logger zap.Logger
+	var app *api.App
+	var ts *httptest.Server
+	var c *client.Client
+	var game *models.Game
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		app = GetDefaultTestApp(logger)
+		ts = InitializeTestServer(app)
+		c = client.NewClient(ts.URL)
+		c.Actor = "client-test"
+
+		var err error
+		game, err = GetTestGame(app.MongoDb, app.Logger, true)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		ts.Close()
+		app.Stop()
+	})
+
+	Describe("Games and items", func() {
+		It("Should update games and items and record the actor", func() {
+			updated, err := c.UpdateGame(game.ID, "new name", 1, 2)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.Name).To(Equal("new name"))
+			Expect(updated.DonationRequestCooldownHours).To(Equal(2))
+
+			item, err := c.UpsertItem(game.ID, "new-item", map[string]interface{}{"x": 1}, 1, 2, 3)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(item.LimitOfItemsInEachDonationRequest).To(Equal(3))
+
+			history, err := c.GetGameHistory(game.ID, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(history).To(HaveLen(2))
+			Expect(history[0].Action).To(Equal(models.AuditUpsertItem))
+			Expect(history[0].Actor).To(Equal("client-test"))
+		})
+
+		It("Should return the errors package types of failures", func() {
+			_, err := c.GetGameHistory(uuid.NewV4().String(), 0)
+			Expect(err).To(BeAssignableToTypeOf(&errors.DocumentNotFoundError{}))
+
+			_, err = c.UpdateGame(game.ID, "", 1, 2)
+			Expect(err).To(BeAssignableToTypeOf(&client.APIError{}))
+			Expect(err.(*client.APIError).StatusCode).To(Equal(400))
+		})
+	})
+
+	Describe("Donations", func() {
+		It("Should create donation requests and donate to them", func() {
+			donationRequest, err := c.CreateDonationRequest(game.ID, GetFirstItem(game).Key, "player-0", "clan-0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(donationRequest.ID).NotTo(BeEmpty())
+
+			Expect(c.Donate(game.ID, donationRequest.ID, "player-1", 1, 10)).To(Succeed())
+
+			err = c.Donate(game.ID, donationRequest.ID, "player-1", 2, 10)
+			Expect(err).To(BeAssignableToTypeOf(&errors.LimitOfItemsPerPlayerInDonationRequestReachedError{}))
+
+			response, err := c.BatchDonate(game.ID, []*client.BatchDonation{
+				&client.BatchDonation{DonationRequestID: donationRequest.ID, Player: "player-2", Amount: 1, MaxWeightPerPlayer: 10},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.Succeeded).To(Equal(1))
+
+			donationRequest, err = c.GetDonationRequest(game.ID, donationRequest.ID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(donationRequest.Donations).To(HaveLen(2))
+
+			weight, err := c.GetDonationWeightByClan(game.ID, "clan-0", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(weight).To(Equal(2))
+		})
+
+		It("Should fail for donation requests that do not exist", func() {
+			_, err := c.GetDonationRequest(game.ID, uuid.NewV4().String())
+			Expect(err).To(BeAssignableToTypeOf(&errors.DocumentNotFoundError{}))
+		})
+	})
+
+	Describe("Healthcheck", func() {
+		It("Should succeed", func() {
+			Expect(c.Healthcheck()).To(Succeed())
+		})
+	})
+})
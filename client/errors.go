@@ -0,0 +1,85 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/topfreegames/donations/errors"
+)
+
+//APIError is returned when the API fails without a type of the errors package
+type APIError struct {
+	StatusCode int
+	Reason     string
+}
+
+//Error string
+func (err APIError) Error() string {
+	return fmt.Sprintf("Request failed with status %d: %s", err.StatusCode, err.Reason)
+}
+
+const failurePrefix = `{"success":false,"reason":"`
+const failureSuffix = `"}`
+
+//errorTypes return a new error of each type of the errors package, by the type sent by the API
+var errorTypes = map[string]func() error{
+	"DocumentNotFoundError":                              func() error { return &errors.DocumentNotFoundError{} },
+	"ParameterIsRequiredError":                           func() error { return &errors.ParameterIsRequiredError{} },
+	"ItemNotFoundInGameError":                            func() error { return &errors.ItemNotFoundInGameError{} },
+	"LimitOfItemsInDonationRequestReachedError":          func() error { return &errors.LimitOfItemsInDonationRequestReachedError{} },
+	"LimitOfItemsPerPlayerInDonationRequestReachedError": func() error { return &errors.LimitOfItemsPerPlayerInDonationRequestReachedError{} },
+	"DonationRequestCooldownViolatedError":               func() error { return &errors.DonationRequestCooldownViolatedError{} },
+	"DonationCooldownViolatedError":                      func() error { return &errors.DonationCooldownViolatedError{} },
+	"DonationRequestConcurrentlyUpdatedError":            func() error { return &errors.DonationRequestConcurrentlyUpdatedError{} },
+	"DonationRequestExpiredError":                        func() error { return &errors.DonationRequestExpiredError{} },
+	"GameConcurrentlyUpdatedError":                       func() error { return &errors.GameConcurrentlyUpdatedError{} },
+	"IdempotencyKeyReusedError":                          func() error { return &errors.IdempotencyKeyReusedError{} },
+	"AuthenticationFailed":                               func() error { return &errors.AuthenticationFailed{} },
+	"AuthenticationProviderNotSupported":                 func() error { return &errors.AuthenticationProviderNotSupported{} },
+	"InvalidAPIKeyError":                                 func() error { return &errors.InvalidAPIKeyError{} },
+	"APIKeyForbiddenError":                               func() error { return &errors.APIKeyForbiddenError{} },
+	"RateLimitExceededError":                             func() error { return &errors.RateLimitExceededError{} },
+}
+
+//DecodeError returns the error of a failed response. Failures with a type of the errors package are
+//decoded into that type, any other failure is returned as an *APIError.
+func DecodeError(statusCode int, body []byte) error {
+	var failure struct {
+		Reason  string          `json:"reason"`
+		Type    string          `json:"type"`
+		Details json.RawMessage `json:"details"`
+	}
+	reason := strings.TrimSpace(string(body))
+	if err := json.Unmarshal(body, &failure); err == nil && failure.Reason != "" {
+		return decodeFailure(statusCode, failure.Reason, failure.Type, failure.Details)
+	} else if strings.HasPrefix(reason, failurePrefix) && strings.HasSuffix(reason, failureSuffix) {
+		//FailWith does not escape the reason, so reasons with quotes are not valid JSON
+		reason = reason[len(failurePrefix) : len(reason)-len(failureSuffix)]
+	}
+	return &APIError{StatusCode: statusCode, Reason: reason}
+}
+
+//decodeFailure returns an error of the errors package type of a failure, with its details, or an *APIError
+func decodeFailure(statusCode int, reason, errorType string, details json.RawMessage) error {
+	if newError, ok := errorTypes[errorType]; ok {
+		err := newError()
+		if len(details) == 0 || json.Unmarshal(details, err) == nil {
+			return err
+		}
+	}
+	return &APIError{StatusCode: statusCode, Reason: reason}
+}
+
+//isRetryable returns true if a failed response can succeed when the request is sent again.
+//Errors with a type in the errors package are returned again by the API, except concurrent updates.
+func isRetryable(err error) bool {
+	switch e := err.(type) {
+	case *APIError:
+		return e.StatusCode >= 500
+	case *errors.DonationRequestConcurrentlyUpdatedError:
+		return true
+	default:
+		return false
+	}
+}
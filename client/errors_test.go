@@ -0,0 +1,71 @@
+package client_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/client"
+	"github.com/topfreegames/donations/errors"
+)
+
+var _ = Describe("Errors", func() {
+	failure := func(err error) []byte {
+		data, e := json.Marshal(map[string]interface{}{
+			"success": false,
+			"reason":  err.Error(),
+			"type":    reflect.TypeOf(err).Elem().Name(),
+			"details": err,
+		})
+		Expect(e).NotTo(HaveOccurred())
+		return data
+	}
+
+	It("Should decode the errors package types by the type of the failure", func() {
+		errs := []error{
+			errors.NewDocumentNotFoundError("games", "game-id"),
+			&errors.ParameterIsRequiredError{Parameter: "Player", Model: "Donation"},
+			&errors.ItemNotFoundInGameError{ItemKey: "item-0", GameID: "game-id"},
+			&errors.LimitOfItemsInDonationRequestReachedError{},
+			&errors.LimitOfItemsPerPlayerInDonationRequestReachedError{},
+			&errors.DonationRequestCooldownViolatedError{},
+			&errors.DonationCooldownViolatedError{},
+			&errors.DonationRequestConcurrentlyUpdatedError{DonationRequestID: "request-id"},
+			&errors.DonationRequestExpiredError{DonationRequestID: "request-id"},
+			&errors.GameConcurrentlyUpdatedError{GameID: "game-id"},
+			&errors.IdempotencyKeyReusedError{Key: "key", Path: "/games/game-id/donation-requests"},
+			errors.NewAuthenticationFailed("jwt", "player-id", ""),
+			errors.NewAuthenticationProviderNotSupported("oauth"),
+			&errors.InvalidAPIKeyError{},
+			&errors.APIKeyForbiddenError{KeyID: "key-id", GameID: "game-id", Scope: "admin"},
+			&errors.RateLimitExceededError{Route: "CreateDonation", Limit: "player", RetryAfterSeconds: 2},
+		}
+		for _, err := range errs {
+			decoded := client.DecodeError(http.StatusInternalServerError, failure(err))
+			Expect(decoded).To(BeAssignableToTypeOf(err))
+			Expect(decoded).To(Equal(err))
+		}
+	})
+
+	It("Should return failures without a type as api errors", func() {
+		err := client.DecodeError(http.StatusBadRequest, []byte(`{"success":false,"reason":"name is required"}`))
+		Expect(err).To(Equal(&client.APIError{StatusCode: http.StatusBadRequest, Reason: "name is required"}))
+
+		reason := errors.NewDocumentNotFoundError("games", "game-id").Error()
+		err = client.DecodeError(http.StatusNotFound, []byte(`{"success":false,"reason":"`+reason+`"}`))
+		Expect(err).To(Equal(&client.APIError{StatusCode: http.StatusNotFound, Reason: reason}))
+
+		err = client.DecodeError(http.StatusNotFound, []byte(`{"success":false,"reason":"`+reason+`","type":"Unknown"}`))
+		Expect(err).To(Equal(&client.APIError{StatusCode: http.StatusNotFound, Reason: reason}))
+	})
+
+	It("Should decode reasons that are not valid JSON", func() {
+		err := client.DecodeError(http.StatusBadRequest, []byte(`{"success":false,"reason":"invalid "quoted" value"}`))
+		Expect(err).To(Equal(&client.APIError{StatusCode: http.StatusBadRequest, Reason: `invalid "quoted" value`}))
+
+		err = client.DecodeError(http.StatusBadGateway, []byte("Bad Gateway\n"))
+		Expect(err).To(Equal(&client.APIError{StatusCode: http.StatusBadGateway, Reason: "Bad Gateway"}))
+	})
+})
@@ -0,0 +1,518 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/topfreegames/donations/errors"
+)
+
+//FakeServer is an in-memory implementation of the donations API for tests of services that use the client.
+//It supports the game, item, donation request, donation, batch donation, donation weight and healthcheck
+//routes, with the same limits, cooldowns, idempotency keys and errors of the API. Other routes fail with 501.
+type FakeServer struct {
+	*httptest.Server
+
+	//Clock is the time of donation requests and donations. Replace it to test cooldowns.
+	Clock Clock
+
+	mutex               sync.Mutex
+	games               map[string]*Game
+	donationRequests    map[string]*DonationRequest
+	clanWeights         map[string]int
+	idempotentResponses map[string]*storedFakeResponse
+	failures            []*fakeResponse
+}
+
+type fakeResponse struct {
+	status   int
+	body     string
+	replayed bool
+}
+
+type storedFakeResponse struct {
+	path        string
+	requestHash string
+	response    *fakeResponse
+}
+
+//NewFakeServer starts a fake server. It must be closed with Close.
+func NewFakeServer() *FakeServer {
+	s := &FakeServer{
+		Clock:               &RealClock{},
+		games:               map[string]*Game{},
+		donationRequests:    map[string]*DonationRequest{},
+		clanWeights:         map[string]int{},
+		idempotentResponses: map[string]*storedFakeResponse{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+//NewClient returns a client of the fake server that does not wait between retries
+func (s *FakeServer) NewClient() *Client {
+	c := NewClient(s.URL)
+	c.RetryInterval = 0
+	return c
+}
+
+//FailNext makes the next request fail with status and reason before being processed
+func (s *FakeServer) FailNext(status int, reason string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.failures = append(s.failures, &fakeResponse{status: status, body: fakeFailure(reason)})
+}
+
+//FailNextWithError makes the next request fail with status and an error of the errors package before being processed
+func (s *FakeServer) FailNextWithError(status int, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.failures = append(s.failures, &fakeResponse{status: status, body: fakeErrorFailure(err)})
+}
+
+//SetGame creates or replaces a game and its items
+func (s *FakeServer) SetGame(game *Game) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.games[game.ID] = game.Clone()
+}
+
+//GetGame returns a game or nil if it does not exist
+func (s *FakeServer) GetGame(gameID string) *Game {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	game, ok := s.games[gameID]
+	if !ok {
+		return nil
+	}
+	return game.Clone()
+}
+
+//GetDonationRequests returns the donation requests of a game
+func (s *FakeServer) GetDonationRequests(gameID string) []*DonationRequest {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	donationRequests := []*DonationRequest{}
+	for _, donationRequest := range s.donationRequests {
+		if donationRequest.GameID == gameID {
+			clone := *donationRequest
+			donationRequests = append(donationRequests, &clone)
+		}
+	}
+	return donationRequests
+}
+
+//fakeFailure returns the body sent by FailWith
+func fakeFailure(reason string) string {
+	return fmt.Sprintf(`{"success":false,"reason":"%s"}`, reason)
+}
+
+//getFakeErrorType returns the type the API sends for an error of the errors package, or an empty type
+func getFakeErrorType(err error) string {
+	t := reflect.TypeOf(err)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if _, ok := errorTypes[t.Name()]; !ok {
+		return ""
+	}
+	return t.Name()
+}
+
+//fakeErrorFailure returns the body sent by FailWithError, with the type and fields of errors of the errors package
+func fakeErrorFailure(err error) string {
+	errorType := getFakeErrorType(err)
+	if errorType == "" {
+		return fakeFailure(err.Error())
+	}
+	data, e := json.Marshal(map[string]interface{}{
+		"success": false,
+		"reason":  err.Error(),
+		"type":    errorType,
+		"details": err,
+	})
+	if e != nil {
+		return fakeFailure(err.Error())
+	}
+	return string(data)
+}
+
+func fakeJSON(value interface{}) *fakeResponse {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return &fakeResponse{status: http.StatusInternalServerError, body: fakeFailure(err.Error())}
+	}
+	return &fakeResponse{status: http.StatusOK, body: string(data)}
+}
+
+//fakeError returns the status the API uses for the error
+func fakeError(err error) *fakeResponse {
+	status := http.StatusInternalServerError
+	switch err.(type) {
+	case *errors.DocumentNotFoundError:
+		status = http.StatusNotFound
+	case *errors.IdempotencyKeyReusedError:
+		status = http.StatusUnprocessableEntity
+	}
+	return &fakeResponse{status: status, body: fakeErrorFailure(err)}
+}
+
+//fakeRequired returns the 400 response of a payload with missing fields, or nil
+func fakeRequired(fields map[string]bool, names ...string) *fakeResponse {
+	missing := []string{}
+	for _, name := range names {
+		if !fields[name] {
+			missing = append(missing, fmt.Sprintf("%s is required", name))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return &fakeResponse{status: http.StatusBadRequest, body: fakeFailure(strings.Join(missing, ", "))}
+}
+
+func (s *FakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	response := s.handle(r, body)
+	w.Header().Set("Content-Type", "application/json")
+	if response.replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
+	w.WriteHeader(response.status)
+	w.Write([]byte(response.body))
+}
+
+func (s *FakeServer) handle(r *http.Request, body []byte) *fakeResponse {
+	if len(s.failures) > 0 {
+		failure := s.failures[0]
+		s.failures = s.failures[1:]
+		return failure
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	route := fmt.Sprintf("%s %d", r.Method, len(parts))
+	if len(parts) == 1 && parts[0] == "healthcheck" && r.Method == "GET" {
+		return &fakeResponse{status: http.StatusOK, body: "WORKING"}
+	}
+	if len(parts) < 2 || parts[0] != "games" {
+		return &fakeResponse{status: http.StatusNotImplemented, body: fakeFailure("Route is not supported by the fake server.")}
+	}
+
+	gameID := parts[1]
+	switch {
+	case route == "PUT 2":
+		return s.updateGame(gameID, body)
+	case route == "PUT 4" && parts[2] == "items":
+		return s.upsertItem(gameID, parts[3], body)
+	case route == "POST 3" && parts[2] == "donation-requests":
+		return s.idempotent(r, body, gameID, func() *fakeResponse { return s.createDonationRequest(gameID, body) })
+	case route == "GET 4" && parts[2] == "donation-requests":
+		return s.getDonationRequest(gameID, parts[3])
+	case route == "POST 4" && parts[2] == "donation-requests":
+		return s.idempotent(r, body, gameID, func() *fakeResponse { return s.donate(gameID, parts[3], body) })
+	case route == "POST 4" && parts[2] == "donations" && parts[3] == "batch":
+		return s.idempotent(r, body, gameID, func() *fakeResponse { return s.batchDonate(gameID, body) })
+	case route == "GET 3" && parts[2] == "donation-weight-by-clan":
+		weight := s.clanWeights[fmt.Sprintf("%s/%s", gameID, r.URL.Query().Get("clanID"))]
+		return &fakeResponse{status: http.StatusOK, body: fmt.Sprintf(`{"success":true, "weight": %d}`, weight)}
+	default:
+		return &fakeResponse{status: http.StatusNotImplemented, body: fakeFailure("Route is not supported by the fake server.")}
+	}
+}
+
+//idempotent stores the response of requests with an idempotency key and replays it for the same key
+func (s *FakeServer) idempotent(r *http.Request, body []byte, gameID string, handle func() *fakeResponse) *fakeResponse {
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if key == "" {
+		return handle()
+	}
+
+	storeKey := fmt.Sprintf("%s/%s", gameID, key)
+	hash := sha256.Sum256(body)
+	requestHash := hex.EncodeToString(hash[:])
+	if stored, ok := s.idempotentResponses[storeKey]; ok {
+		if stored.path != r.URL.Path || stored.requestHash != requestHash {
+			return fakeError(&errors.IdempotencyKeyReusedError{Key: key, Path: stored.path})
+		}
+		return &fakeResponse{status: stored.response.status, body: stored.response.body, replayed: true}
+	}
+
+	response := handle()
+	if response.status < 500 {
+		s.idempotentResponses[storeKey] = &storedFakeResponse{path: r.URL.Path, requestHash: requestHash, response: response}
+	}
+	return response
+}
+
+//decode the payload, returning the fields that have non zero values
+func decodeFakePayload(body []byte, payload interface{}) (map[string]bool, *fakeResponse) {
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, &fakeResponse{status: http.StatusBadRequest, body: fakeFailure(err.Error())}
+	}
+	if err := json.Unmarshal(body, payload); err != nil {
+		return nil, &fakeResponse{status: http.StatusBadRequest, body: fakeFailure(err.Error())}
+	}
+
+	present := map[string]bool{}
+	for key, value := range fields {
+		switch v := value.(type) {
+		case string:
+			present[key] = v != ""
+		case float64:
+			present[key] = v != 0
+		case map[string]interface{}:
+			present[key] = len(v) > 0
+		default:
+			present[key] = v != nil
+		}
+	}
+	return present, nil
+}
+
+func (s *FakeServer) updateGame(gameID string, body []byte) *fakeResponse {
+	var payload struct {
+		Name                         string `json:"name"`
+		DonationCooldownHours        int    `json:"donationCooldownHours"`
+		DonationRequestCooldownHours int    `json:"donationRequestCooldownHours"`
+	}
+	fields, failure := decodeFakePayload(body, &payload)
+	if failure != nil {
+		return failure
+	}
+	if failure = fakeRequired(fields, "name", "donationCooldownHours", "donationRequestCooldownHours"); failure != nil {
+		return failure
+	}
+
+	game, ok := s.games[gameID]
+	if !ok {
+		game = &Game{ID: gameID, Items: map[string]Item{}}
+		s.games[gameID] = game
+	}
+	game.Name = payload.Name
+	game.DonationCooldownHours = payload.DonationCooldownHours
+	game.DonationRequestCooldownHours = payload.DonationRequestCooldownHours
+	game.UpdatedAt = s.Clock.GetUTCTime()
+	return fakeJSON(game)
+}
+
+func (s *FakeServer) upsertItem(gameID, key string, body []byte) *fakeResponse {
+	var payload struct {
+		Metadata                          map[string]interface{} `json:"metadata"`
+		WeightPerDonation                 int                    `json:"weightPerDonation"`
+		LimitOfItemsPerPlayerDonation     int                    `json:"limitOfItemsPerPlayerDonation"`
+		LimitOfItemsInEachDonationRequest int                    `json:"limitOfItemsInEachDonationRequest"`
+	}
+	fields, failure := decodeFakePayload(body, &payload)
+	if failure != nil {
+		return failure
+	}
+	failure = fakeRequired(
+		fields, "metadata", "weightPerDonation",
+		"limitOfItemsPerPlayerDonation", "limitOfItemsInEachDonationRequest",
+	)
+	if failure != nil {
+		return failure
+	}
+
+	game, ok := s.games[gameID]
+	if !ok {
+		return fakeError(errors.NewDocumentNotFoundError("games", gameID))
+	}
+	item := &Item{
+		Key:                               key,
+		Metadata:                          payload.Metadata,
+		WeightPerDonation:                 payload.WeightPerDonation,
+		LimitOfItemsPerPlayerDonation:     payload.LimitOfItemsPerPlayerDonation,
+		LimitOfItemsInEachDonationRequest: payload.LimitOfItemsInEachDonationRequest,
+		UpdatedAt:                         s.Clock.GetUTCTime().Unix(),
+	}
+	game.Items[key] = *item
+	return fakeJSON(item)
+}
+
+func (s *FakeServer) createDonationRequest(gameID string, body []byte) *fakeResponse {
+	var payload struct {
+		Item   string `json:"item"`
+		Player string `json:"player"`
+		Clan   string `json:"clan"`
+	}
+	fields, failure := decodeFakePayload(body, &payload)
+	if failure != nil {
+		return failure
+	}
+	if failure = fakeRequired(fields, "item", "player", "clan"); failure != nil {
+		return failure
+	}
+
+	game, ok := s.games[gameID]
+	if !ok {
+		return fakeError(errors.NewDocumentNotFoundError("games", gameID))
+	}
+	if _, ok := game.Items[payload.Item]; !ok {
+		return fakeError(&errors.ItemNotFoundInGameError{ItemKey: payload.Item, GameID: gameID})
+	}
+
+	now := s.Clock.GetUTCTime()
+	cooldownStart := now.Add(-time.Duration(game.DonationRequestCooldownHours) * time.Hour).Unix()
+	for _, other := range s.donationRequests {
+		if other.GameID == gameID && other.Player == payload.Player && other.CreatedAt >= cooldownStart {
+			return fakeError(&errors.DonationRequestCooldownViolatedError{
+				GameID: gameID, ItemKey: payload.Item, Time: now.Unix(),
+			})
+		}
+	}
+
+	donationRequest := &DonationRequest{
+		ID:        uuid.NewV4().String(),
+		GameID:    gameID,
+		Item:      payload.Item,
+		Player:    payload.Player,
+		Clan:      payload.Clan,
+		Donations: []Donation{},
+		CreatedAt: now.Unix(),
+	}
+	s.donationRequests[donationRequest.ID] = donationRequest
+	return fakeJSON(donationRequest)
+}
+
+func (s *FakeServer) getDonationRequest(gameID, donationRequestID string) *fakeResponse {
+	donationRequest, ok := s.donationRequests[donationRequestID]
+	if !ok || donationRequest.GameID != gameID {
+		return fakeError(errors.NewDocumentNotFoundError("donationRequest", donationRequestID))
+	}
+	return fakeJSON(donationRequest)
+}
+
+func (s *FakeServer) donate(gameID, donationRequestID string, body []byte) *fakeResponse {
+	var payload BatchDonation
+	fields, failure := decodeFakePayload(body, &payload)
+	if failure != nil {
+		return failure
+	}
+	if failure = fakeRequired(fields, "player", "amount", "maxWeightPerPlayer"); failure != nil {
+		return failure
+	}
+
+	payload.DonationRequestID = donationRequestID
+	if err := s.applyDonation(gameID, &payload); err != nil {
+		return fakeError(err)
+	}
+	return &fakeResponse{status: http.StatusOK, body: `{"success":true}`}
+}
+
+func (s *FakeServer) batchDonate(gameID string, body []byte) *fakeResponse {
+	var payload struct {
+		Donations []*BatchDonation `json:"donations"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return &fakeResponse{status: http.StatusBadRequest, body: fakeFailure(err.Error())}
+	}
+	if len(payload.Donations) == 0 {
+		return &fakeResponse{status: http.StatusBadRequest, body: fakeFailure("donations is required")}
+	}
+
+	response := &BatchDonationResponse{Results: []*BatchDonationResult{}}
+	for i, donation := range payload.Donations {
+		result := &BatchDonationResult{
+			Index:             i,
+			DonationRequestID: donation.DonationRequestID,
+			Player:            donation.Player,
+			Success:           true,
+		}
+		if err := s.applyDonation(gameID, donation); err != nil {
+			result.Success = false
+			result.Reason = err.Error()
+			if result.Type = getFakeErrorType(err); result.Type != "" {
+				result.Details, _ = json.Marshal(err)
+			}
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+		response.Results = append(response.Results, result)
+	}
+	return fakeJSON(response)
+}
+
+func (s *FakeServer) applyDonation(gameID string, donation *BatchDonation) error {
+	if donation.Player == "" {
+		return &errors.ParameterIsRequiredError{Parameter: "Player", Model: "Donation"}
+	}
+	if donation.Amount <= 0 {
+		return &errors.ParameterIsRequiredError{Parameter: "Amount", Model: "Donation"}
+	}
+
+	donationRequest, ok := s.donationRequests[donation.DonationRequestID]
+	if !ok || donationRequest.GameID != gameID {
+		return errors.NewDocumentNotFoundError("donationRequest", donation.DonationRequestID)
+	}
+	game := s.games[gameID]
+	item := game.Items[donationRequest.Item]
+
+	if donationRequest.GetDonationCount()+donation.Amount > item.LimitOfItemsInEachDonationRequest {
+		return &errors.LimitOfItemsInDonationRequestReachedError{
+			GameID: gameID, DonationRequestID: donationRequest.ID, ItemKey: donationRequest.Item, Amount: donation.Amount,
+		}
+	}
+	current := donationRequest.GetDonationCountForPlayer(donation.Player)
+	if current+donation.Amount > item.LimitOfItemsPerPlayerDonation {
+		return &errors.LimitOfItemsPerPlayerInDonationRequestReachedError{
+			GameID: gameID, DonationRequestID: donationRequest.ID, ItemKey: donationRequest.Item,
+			Amount: donation.Amount, Player: donation.Player, CurrentDonationCount: current,
+		}
+	}
+
+	now := s.Clock.GetUTCTime()
+	cooldownStart := now.Add(-time.Duration(game.DonationCooldownHours) * time.Hour).Unix()
+	weight := 0
+	for _, other := range s.donationRequests {
+		for _, d := range other.Donations {
+			if d.GameID == gameID && d.Player == donation.Player && d.CreatedAt >= cooldownStart {
+				weight += d.Weight
+			}
+		}
+	}
+	if weight > 0 && weight >= donation.MaxWeightPerPlayer {
+		return &errors.DonationCooldownViolatedError{
+			GameID: gameID, PlayerID: donation.Player,
+			TotalWeightForPeriod: weight, MaxWeightForPerior: donation.MaxWeightPerPlayer,
+		}
+	}
+
+	donationRequest.Donations = append(donationRequest.Donations, Donation{
+		ID:                uuid.NewV4().String(),
+		GameID:            gameID,
+		Clan:              donationRequest.Clan,
+		Player:            donation.Player,
+		DonationRequestID: donationRequest.ID,
+		Amount:            donation.Amount,
+		Weight:            item.WeightPerDonation,
+		CreatedAt:         now.Unix(),
+	})
+	donationRequest.UpdatedAt = now.Unix()
+	donationRequest.Version++
+	if donationRequest.GetDonationCount() >= item.LimitOfItemsInEachDonationRequest {
+		donationRequest.FinishedAt = now.Unix()
+	}
+	s.clanWeights[fmt.Sprintf("%s/%s", gameID, donationRequest.Clan)] += item.WeightPerDonation
+	return nil
+}
@@ -0,0 +1,154 @@
+package client_test
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	uuid "github.com/satori/go.uuid"
+	"github.com/topfreegames/donations/client"
+	"github.com/topfreegames/donations/errors"
+	. "github.com/topfreegames/donations/testing"
+)
+
+var _ = Describe("Fake Server", func() {
+	var server *client.FakeServer
+	var c *client.Client
+	var gameID string
+
+	BeforeEach(func() {
+		server = client.NewFakeServer()
+		c = server.NewClient()
+		gameID = uuid.NewV4().String()
+
+		_, err := c.UpdateGame(gameID, "game", 8, 24)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.UpsertItem(gameID, "item-0", map[string]interface{}{"x": 1}, 1, 2, 3)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Games and items", func() {
+		It("Should create games and items", func() {
+			game := server.GetGame(gameID)
+			Expect(game.Name).To(Equal("game"))
+			Expect(game.DonationRequestCooldownHours).To(Equal(24))
+			Expect(game.Items["item-0"].LimitOfItemsInEachDonationRequest).To(Equal(3))
+		})
+
+		It("Should fail with the reason of the API for missing fields", func() {
+			_, err := c.UpdateGame(gameID, "", 8, 0)
+			Expect(err).To(Equal(&client.APIError{
+				StatusCode: http.StatusBadRequest,
+				Reason:     "name is required, donationRequestCooldownHours is required",
+			}))
+		})
+
+		It("Should fail for items of games that do not exist", func() {
+			_, err := c.UpsertItem("other-game", "item-0", map[string]interface{}{"x": 1}, 1, 2, 3)
+			Expect(err).To(Equal(errors.NewDocumentNotFoundError("games", "other-game")))
+		})
+	})
+
+	Describe("Donations", func() {
+		It("Should create donation requests and donate to them", func() {
+			donationRequest, err := c.CreateDonationRequest(gameID, "item-0", "player-0", "clan-0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(donationRequest.ID).NotTo(BeEmpty())
+
+			Expect(c.Donate(gameID, donationRequest.ID, "player-1", 2, 10)).To(Succeed())
+			Expect(c.Donate(gameID, donationRequest.ID, "player-2", 1, 10)).To(Succeed())
+
+			donationRequest, err = c.GetDonationRequest(gameID, donationRequest.ID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(donationRequest.Donations).To(HaveLen(2))
+			Expect(donationRequest.FinishedAt).NotTo(BeZero())
+
+			weight, err := c.GetDonationWeightByClan(gameID, "clan-0", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(weight).To(Equal(2))
+		})
+
+		It("Should enforce the limits and cooldowns of the API", func() {
+			donationRequest, err := c.CreateDonationRequest(gameID, "item-0", "player-0", "clan-0")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = c.CreateDonationRequest(gameID, "item-0", "player-0", "clan-0")
+			Expect(err).To(BeAssignableToTypeOf(&errors.DonationRequestCooldownViolatedError{}))
+
+			_, err = c.CreateDonationRequest(gameID, "item-1", "player-1", "clan-0")
+			Expect(err).To(Equal(&errors.ItemNotFoundInGameError{ItemKey: "item-1", GameID: gameID}))
+
+			err = c.Donate(gameID, donationRequest.ID, "player-1", 3, 10)
+			Expect(err).To(BeAssignableToTypeOf(&errors.LimitOfItemsPerPlayerInDonationRequestReachedError{}))
+
+			Expect(c.Donate(gameID, donationRequest.ID, "player-1", 1, 1)).To(Succeed())
+			err = c.Donate(gameID, donationRequest.ID, "player-1", 1, 1)
+			Expect(err).To(BeAssignableToTypeOf(&errors.DonationCooldownViolatedError{}))
+
+			server.Clock = &MockClock{Time: server.Clock.GetUTCTime().Unix() + 9*3600}
+			Expect(c.Donate(gameID, donationRequest.ID, "player-1", 1, 1)).To(Succeed())
+
+			err = c.Donate(gameID, donationRequest.ID, "player-2", 2, 10)
+			Expect(err).To(BeAssignableToTypeOf(&errors.LimitOfItemsInDonationRequestReachedError{}))
+		})
+
+		It("Should apply batches of donations independently", func() {
+			donationRequest, err := c.CreateDonationRequest(gameID, "item-0", "player-0", "clan-0")
+			Expect(err).NotTo(HaveOccurred())
+
+			response, err := c.BatchDonate(gameID, []*client.BatchDonation{
+				&client.BatchDonation{DonationRequestID: donationRequest.ID, Player: "player-1", Amount: 1, MaxWeightPerPlayer: 10},
+				&client.BatchDonation{DonationRequestID: "invalid-id", Player: "player-1", Amount: 1, MaxWeightPerPlayer: 10},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.Succeeded).To(Equal(1))
+			Expect(response.Failed).To(Equal(1))
+			Expect(response.Results[0].Err()).NotTo(HaveOccurred())
+			Expect(response.Results[1].Err()).To(Equal(errors.NewDocumentNotFoundError("donationRequest", "invalid-id")))
+		})
+	})
+
+	Describe("Retries", func() {
+		It("Should retry failed donations with the same idempotency key", func() {
+			donationRequest, err := c.CreateDonationRequest(gameID, "item-0", "player-0", "clan-0")
+			Expect(err).NotTo(HaveOccurred())
+
+			server.FailNext(http.StatusServiceUnavailable, "unavailable")
+			server.FailNext(http.StatusInternalServerError, "Could not acquire lock")
+			Expect(c.Donate(gameID, donationRequest.ID, "player-1", 1, 10)).To(Succeed())
+
+			donationRequest, err = c.GetDonationRequest(gameID, donationRequest.ID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(donationRequest.Donations).To(HaveLen(1))
+		})
+
+		It("Should give up after the maximum retries", func() {
+			c.MaxRetries = 1
+			server.FailNext(http.StatusInternalServerError, "first")
+			server.FailNext(http.StatusInternalServerError, "second")
+
+			_, err := c.CreateDonationRequest(gameID, "item-0", "player-0", "clan-0")
+			Expect(err).To(Equal(&client.APIError{StatusCode: http.StatusInternalServerError, Reason: "second"}))
+			Expect(server.GetDonationRequests(gameID)).To(BeEmpty())
+		})
+
+		It("Should not retry errors the API returns again", func() {
+			server.FailNextWithError(http.StatusInternalServerError, &errors.DonationRequestCooldownViolatedError{GameID: gameID})
+
+			_, err := c.CreateDonationRequest(gameID, "item-0", "player-0", "clan-0")
+			Expect(err).To(BeAssignableToTypeOf(&errors.DonationRequestCooldownViolatedError{}))
+			Expect(server.GetDonationRequests(gameID)).To(BeEmpty())
+		})
+
+		It("Should not retry requests without idempotency keys", func() {
+			server.FailNext(http.StatusInternalServerError, "unexpected")
+
+			_, err := c.RollbackGame(gameID, 1)
+			Expect(err).To(Equal(&client.APIError{StatusCode: http.StatusInternalServerError, Reason: "unexpected"}))
+		})
+	})
+})
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//TruncatedStreamEvent is received when events after the cursor are not in the clan history anymore,
+//so the clan state should be reloaded
+const TruncatedStreamEvent = "stream.truncated"
+
+//StreamClan calls handler with each donation event of a clan, in order, until handler returns an error
+//or the API closes the stream. cursor is the last received cursor, or -1 to receive only new events.
+//When the API closes the stream, nil is returned and the clan should be streamed again from the
+//cursor of the last message.
+func (c *Client) StreamClan(gameID, clanID string, cursor int64, handler func(message *StreamMessage) error) error {
+	path := fmt.Sprintf("/games/%s/clans/%s/stream", escape(gameID), escape(clanID))
+	if cursor >= 0 {
+		path = fmt.Sprintf("%s?cursor=%d", path, cursor)
+	}
+
+	req, err := c.newRequest("GET", path, nil, map[string]string{"Accept": "text/event-stream"})
+	if err != nil {
+		return err
+	}
+
+	//The stream is long lived, so the timeout of the client is not used
+	httpClient := &http.Client{Transport: c.HTTPClient.Transport}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		data, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		return DecodeError(res.StatusCode, data)
+	}
+
+	var eventType, data string
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if eventType == "" {
+				continue
+			}
+			message := &StreamMessage{Cursor: cursor, Event: &StreamEvent{Type: eventType}}
+			if eventType != TruncatedStreamEvent {
+				err = json.Unmarshal([]byte(data), message.Event)
+				if err != nil {
+					return err
+				}
+			}
+			eventType, data = "", ""
+			if err = handler(message); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			//keep-alive
+		case strings.HasPrefix(line, "id: "):
+			cursor, err = strconv.ParseInt(strings.TrimPrefix(line, "id: "), 10, 64)
+			if err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,215 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+//The types of this file are the responses of the API. They are declared here, instead of using the
+//models package, so services that use the client don't depend on the datastores of the API.
+
+//PlayerAuthProviderJWT is the provider of games that require player tokens signed as JWTs
+const PlayerAuthProviderJWT = "jwt"
+
+//Clock returns the current time
+type Clock interface {
+	GetUTCTime() time.Time
+}
+
+//RealClock returns the current time as UTC Date
+type RealClock struct{}
+
+//GetUTCTime returns the current time as UTC Date
+func (r *RealClock) GetUTCTime() time.Time {
+	return time.Now().UTC()
+}
+
+//Game is a game and its items
+type Game struct {
+	ID                           string          `json:"id"`
+	Name                         string          `json:"name"`
+	Items                        map[string]Item `json:"items"`
+	DonationCooldownHours        int             `json:"donationCooldownHours"`
+	DonationRequestCooldownHours int             `json:"donationRequestCooldownHours"`
+	UpdatedAt                    time.Time       `json:"updatedAt"`
+}
+
+//Clone returns a copy of the game that does not share its items
+func (g *Game) Clone() *Game {
+	clone := *g
+	clone.Items = map[string]Item{}
+	for key, item := range g.Items {
+		clone.Items[key] = item
+	}
+	return &clone
+}
+
+//Item is an item that can be donated in a game
+type Item struct {
+	Key                               string                 `json:"item"`
+	Metadata                          map[string]interface{} `json:"metadata"`
+	LimitOfItemsInEachDonationRequest int                    `json:"limitOfItemsInEachDonationRequest"`
+	LimitOfItemsPerPlayerDonation     int                    `json:"limitOfItemsPerPlayerDonation"`
+	WeightPerDonation                 int                    `json:"weightPerDonation"`
+	UpdatedAt                         int64                  `json:"updatedAt"`
+}
+
+//DonationRequest is a request of a player for items of its clan
+type DonationRequest struct {
+	ID         string     `json:"id"`
+	Item       string     `json:"item"`
+	Player     string     `json:"player"`
+	Clan       string     `json:"clan"`
+	GameID     string     `json:"gameID"`
+	Donations  []Donation `json:"donations"`
+	CreatedAt  int64      `json:"createdAt"`
+	UpdatedAt  int64      `json:"updatedAt"`
+	FinishedAt int64      `json:"finishedAt"`
+	ExpiredAt  int64      `json:"expiredAt"`
+	Version    int        `json:"version"`
+}
+
+//GetDonationCount returns the total amount of donations
+func (d *DonationRequest) GetDonationCount() int {
+	sum := 0
+	for i := 0; i < len(d.Donations); i++ {
+		sum += d.Donations[i].Amount
+	}
+	return sum
+}
+
+//GetDonationCountForPlayer returns the total amount of donations for a given player ID
+func (d *DonationRequest) GetDonationCountForPlayer(playerID string) int {
+	sum := 0
+	for i := 0; i < len(d.Donations); i++ {
+		if d.Donations[i].Player == playerID {
+			sum += d.Donations[i].Amount
+		}
+	}
+	return sum
+}
+
+//Donation is a donation of a player to a donation request
+type Donation struct {
+	ID                string `json:"id"`
+	GameID            string `json:"gameID"`
+	Clan              string `json:"clan"`
+	Player            string `json:"player"`
+	DonationRequestID string `json:"donationRequestID"`
+	Amount            int    `json:"amount"`
+	Weight            int    `json:"weight"`
+	CreatedAt         int64  `json:"createdAt"`
+}
+
+//AuditChange is a field of a game changed by an audit entry
+type AuditChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+//AuditEntry is a version of the history of a game
+type AuditEntry struct {
+	ID           string         `json:"id"`
+	GameID       string         `json:"gameID"`
+	Version      int            `json:"version"`
+	Actor        string         `json:"actor"`
+	ClaimedActor string         `json:"claimedActor,omitempty"`
+	Action       string         `json:"action"`
+	RollbackOf   int            `json:"rollbackOf,omitempty"`
+	Changes      []*AuditChange `json:"changes"`
+	Before       *Game          `json:"before"`
+	After        *Game          `json:"after"`
+	CreatedAt    int64          `json:"createdAt"`
+}
+
+//Player is the donation window of a player
+type Player struct {
+	GameID              string `json:"gameID"`
+	ID                  string `json:"id"`
+	DonationWindowStart int64  `json:"donationWindowStart"`
+}
+
+//PlayerDataExport is everything stored about a player in a game
+type PlayerDataExport struct {
+	GameID           string             `json:"gameID"`
+	PlayerID         string             `json:"playerID"`
+	ExportedAt       int64              `json:"exportedAt"`
+	Player           *Player            `json:"player"`
+	DonationRequests []*DonationRequest `json:"donationRequests"`
+	Donations        []*Donation        `json:"donations"`
+	DonationWeights  map[string]int     `json:"donationWeights"`
+}
+
+//PlayerErasureResult is what was erased or pseudonymized of a player
+type PlayerErasureResult struct {
+	GameID              string `json:"gameID"`
+	PlayerID            string `json:"playerID"`
+	Pseudonym           string `json:"pseudonym"`
+	PlayersRemoved      int    `json:"playersRemoved"`
+	DonationRequests    int    `json:"donationRequests"`
+	EmbeddedDonations   int    `json:"embeddedDonations"`
+	Donations           int    `json:"donations"`
+	DonationWeightKeys  int    `json:"donationWeightKeys"`
+	IdempotentResponses int    `json:"idempotentResponses"`
+	WebhookDeliveries   int    `json:"webhookDeliveries"`
+	StreamMessages      int    `json:"streamMessages"`
+}
+
+//Webhook is an url subscribed to events of a game
+type Webhook struct {
+	ID        string   `json:"id"`
+	GameID    string   `json:"gameID"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	CreatedAt int64    `json:"createdAt"`
+}
+
+//WebhookDelivery is an event sent to a webhook
+type WebhookDelivery struct {
+	ID            string `json:"id"`
+	GameID        string `json:"gameID"`
+	WebhookID     string `json:"webhookID"`
+	URL           string `json:"url"`
+	Event         string `json:"event"`
+	Payload       string `json:"payload"`
+	Status        string `json:"status"`
+	Attempts      int    `json:"attempts"`
+	NextAttemptAt int64  `json:"nextAttemptAt"`
+	LastError     string `json:"lastError,omitempty"`
+	CreatedAt     int64  `json:"createdAt"`
+	DeliveredAt   int64  `json:"deliveredAt,omitempty"`
+}
+
+//APIKey is an API key of a game, without its token
+type APIKey struct {
+	ID        string   `json:"id"`
+	GameID    string   `json:"gameID"`
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	CreatedAt int64    `json:"createdAt"`
+	ExpiresAt int64    `json:"expiresAt,omitempty"`
+}
+
+//PlayerAuthConfig is how a game authenticates players, without its key
+type PlayerAuthConfig struct {
+	GameID    string `json:"gameID"`
+	Provider  string `json:"provider"`
+	Algorithm string `json:"algorithm"`
+	UpdatedAt int64  `json:"updatedAt"`
+}
+
+//StreamEvent is a donation event of a clan stream
+type StreamEvent struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"event"`
+	GameID    string          `json:"gameID"`
+	CreatedAt int64           `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+//StreamMessage is an event of a clan stream and its cursor
+type StreamMessage struct {
+	Cursor int64
+	Event  *StreamEvent
+}
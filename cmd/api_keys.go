@@ -0,0 +1,195 @@
+// donations
+// https://github.com/topfreegames/donations
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2016 Top Free Games <backend@tfgco.com>
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/topfreegames/donations/api"
+	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/models"
+	"github.com/uber-go/zap"
+)
+
+var apiKeyGameID string
+var apiKeyID string
+var apiKeyName string
+var apiKeyScopes []string
+var apiKeyGraceSeconds int
+
+// apiKeysCmd represents the api-keys command
+var apiKeysCmd = &cobra.Command{
+	Use:   "api-keys",
+	Short: "creates, lists, rotates and revokes the API keys of a game",
+	Long: `Manages the API keys clients use to access the routes of a game when
+api.apiKeys.enabled is true. Tokens are only printed when a key is created or
+rotated, since only their hashes are stored.`,
+}
+
+// apiKeysCreateCmd represents the api-keys create command
+var apiKeysCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "creates an API key of a game",
+	Long: `Creates an API key named --name for the game specified with --game,
+granted the scopes in --scopes (admin and/or client), and prints its token.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runAPIKeyCommand("apiKeysCreateCmd", func(app *api.App, l zap.Logger) error {
+			if apiKeyName == "" {
+				return fmt.Errorf("The name of the key must be specified with --name.")
+			}
+			for _, scope := range apiKeyScopes {
+				if !models.IsValidAPIKeyScope(scope) {
+					return fmt.Errorf("%s is not a valid scope.", scope)
+				}
+			}
+
+			_, err := models.GetGameByID(apiKeyGameID, app.MongoDb, l)
+			if err != nil {
+				return err
+			}
+
+			key, token, err := models.CreateAPIKey(
+				apiKeyGameID, apiKeyName, apiKeyScopes,
+				&models.RealClock{}, app.MongoDb, l,
+			)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Created API key %s (%s).\n", key.ID, strings.Join(key.Scopes, ","))
+			fmt.Printf("Token: %s\n", token)
+			return nil
+		})
+	},
+}
+
+// apiKeysListCmd represents the api-keys list command
+var apiKeysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "lists the API keys of a game",
+	Long:  `Lists the API keys of the game specified with --game, without their tokens.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runAPIKeyCommand("apiKeysListCmd", func(app *api.App, l zap.Logger) error {
+			keys, err := models.GetAPIKeys(apiKeyGameID, app.MongoDb, l)
+			if err != nil {
+				return err
+			}
+
+			for _, key := range keys {
+				expires := ""
+				if key.ExpiresAt > 0 {
+					expires = fmt.Sprintf(" expires at %s", time.Unix(key.ExpiresAt, 0).UTC().Format(time.RFC3339))
+				}
+				fmt.Printf("%s %s (%s)%s\n", key.ID, key.Name, strings.Join(key.Scopes, ","), expires)
+			}
+			return nil
+		})
+	},
+}
+
+// apiKeysRotateCmd represents the api-keys rotate command
+var apiKeysRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "replaces an API key of a game",
+	Long: `Creates a new key with the name and scopes of the key specified with --key
+and prints its token. The replaced key keeps working for --grace-seconds, so
+clients can switch to the new token without downtime.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runAPIKeyCommand("apiKeysRotateCmd", func(app *api.App, l zap.Logger) error {
+			graceSeconds := apiKeyGraceSeconds
+			if graceSeconds < 0 {
+				graceSeconds = app.Config.GetInt("api.apiKeys.rotationGraceSeconds")
+			}
+
+			key, token, err := models.RotateAPIKey(
+				apiKeyGameID, apiKeyID, graceSeconds,
+				&models.RealClock{}, app.MongoDb, l,
+			)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Rotated API key %s into %s. The old key expires in %d seconds.\n", apiKeyID, key.ID, graceSeconds)
+			fmt.Printf("Token: %s\n", token)
+			return nil
+		})
+	},
+}
+
+// apiKeysRevokeCmd represents the api-keys revoke command
+var apiKeysRevokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "revokes an API key of a game",
+	Long:  `Removes the key specified with --key, so its token stops working immediately.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runAPIKeyCommand("apiKeysRevokeCmd", func(app *api.App, l zap.Logger) error {
+			err := models.RevokeAPIKey(apiKeyGameID, apiKeyID, app.MongoDb, l)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Revoked API key %s.\n", apiKeyID)
+			return nil
+		})
+	},
+}
+
+func runAPIKeyCommand(source string, run func(app *api.App, l zap.Logger) error) {
+	l := getCommandLogger()
+	cmdL := l.With(
+		zap.String("source", source),
+		zap.String("operation", "Run"),
+		zap.String("gameID", apiKeyGameID),
+	)
+
+	if apiKeyGameID == "" {
+		log.E(cmdL, "The game must be specified with --game.")
+		os.Exit(1)
+	}
+
+	app, err := getCommandApp(l)
+	if err != nil {
+		log.E(cmdL, "Application failed to start.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		os.Exit(1)
+	}
+	defer app.Stop()
+
+	err = run(app, l)
+	if err != nil {
+		log.E(cmdL, "Failed to run api-keys command.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		os.Exit(1)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(apiKeysCmd)
+	apiKeysCmd.AddCommand(apiKeysCreateCmd)
+	apiKeysCmd.AddCommand(apiKeysListCmd)
+	apiKeysCmd.AddCommand(apiKeysRotateCmd)
+	apiKeysCmd.AddCommand(apiKeysRevokeCmd)
+
+	apiKeysCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "./config/default.yaml", "Configuration path")
+	apiKeysCmd.PersistentFlags().StringVarP(&apiKeyGameID, "game", "g", "", "ID of the game")
+	apiKeysCreateCmd.Flags().StringVarP(&apiKeyName, "name", "n", "", "Name of the key")
+	apiKeysCreateCmd.Flags().StringSliceVarP(
+		&apiKeyScopes, "scopes", "s", []string{models.APIKeyScopeClient}, "Scopes of the key (admin and/or client)",
+	)
+	apiKeysRotateCmd.Flags().StringVarP(&apiKeyID, "key", "k", "", "ID of the key")
+	apiKeysRotateCmd.Flags().IntVar(
+		&apiKeyGraceSeconds, "grace-seconds", -1, "Seconds the replaced key keeps working (defaults to api.apiKeys.rotationGraceSeconds)",
+	)
+	apiKeysRevokeCmd.Flags().StringVarP(&apiKeyID, "key", "k", "", "ID of the key")
+}
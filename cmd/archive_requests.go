@@ -0,0 +1,103 @@
+// donations
+// https://github.com/topfreegames/donations
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2016 Top Free Games <backend@tfgco.com>
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/models"
+	"github.com/uber-go/zap"
+)
+
+var archiveRequestsGameID string
+var archiveRequestsMaxAgeHours int
+var archiveRequestsDryRun bool
+
+// archiveRequestsCmd represents the archive-requests command
+var archiveRequestsCmd = &cobra.Command{
+	Use:   "archive-requests",
+	Short: "moves old donation requests to the archive",
+	Long: `Moves the donation requests that were not updated for longer than
+archive.maxAgeHours (finished requests and requests that expired without
+being finished, after donationRequests.expirationHours) from the requests
+collection to the archivedRequests collection.
+
+Requests are expired by the API as they expire. Requests that expired
+while no API instance was running are expired before archiving, and are
+archived once they are not updated for archive.maxAgeHours.
+
+Archived donation requests can still be retrieved through the API and are
+still counted in the donation weight of players.
+
+Use --dry-run to only count the donation requests that would be archived.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		l := getCommandLogger()
+		cmdL := l.With(
+			zap.String("source", "archiveRequestsCmd"),
+			zap.String("operation", "Run"),
+			zap.String("gameID", archiveRequestsGameID),
+			zap.Bool("dryRun", archiveRequestsDryRun),
+		)
+
+		app, err := getCommandApp(l)
+		if err != nil {
+			log.E(cmdL, "Application failed to start.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			os.Exit(1)
+		}
+		defer app.Stop()
+
+		maxAgeHours := archiveRequestsMaxAgeHours
+		if maxAgeHours == 0 {
+			maxAgeHours = app.Config.GetInt("archive.maxAgeHours")
+		}
+
+		expired := 0
+		if !archiveRequestsDryRun {
+			expired, err = models.ExpireDonationRequests(
+				archiveRequestsGameID, app.GetDonationRequestExpiration(), &models.RealClock{}, app.MongoDb, l,
+			)
+			if err != nil {
+				log.E(cmdL, "Failed to expire donation requests.", func(cm log.CM) {
+					cm.Write(zap.Error(err))
+				})
+				os.Exit(1)
+			}
+		}
+
+		result, err := models.ArchiveDonationRequests(
+			archiveRequestsGameID,
+			time.Duration(maxAgeHours)*time.Hour,
+			archiveRequestsDryRun,
+			&models.RealClock{},
+			app.MongoDb, l,
+		)
+		if err != nil {
+			log.E(cmdL, "Failed to archive donation requests.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			os.Exit(1)
+		}
+
+		fmt.Printf("expired: %d archived: %d skipped: %d\n", expired, result.Archived, result.Skipped)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(archiveRequestsCmd)
+
+	archiveRequestsCmd.Flags().StringVarP(&configFile, "config", "c", "./config/default.yaml", "Configuration path")
+	archiveRequestsCmd.Flags().StringVarP(&archiveRequestsGameID, "game", "g", "", "Only archive donation requests for this game")
+	archiveRequestsCmd.Flags().IntVarP(&archiveRequestsMaxAgeHours, "max-age-hours", "a", 0, "Overrides archive.maxAgeHours")
+	archiveRequestsCmd.Flags().BoolVarP(&archiveRequestsDryRun, "dry-run", "n", false, "Only count the donation requests to archive")
+}
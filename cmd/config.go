@@ -0,0 +1,235 @@
+// donations
+// https://github.com/topfreegames/donations
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2016 Top Free Games <backend@tfgco.com>
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/topfreegames/donations/api"
+	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/models"
+	"github.com/uber-go/zap"
+)
+
+var gameConfigGameID string
+var gameConfigFile string
+var gameConfigFormat string
+var gameConfigActor string
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "exports, imports and diffs game configuration files",
+	Long: `Keeps the configuration of a game and its items in YAML or JSON files.
+The format is detected from the file extension (.yaml, .yml or .json) and
+can be overridden with --format.`,
+}
+
+// configExportCmd represents the config export command
+var configExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "writes the configuration of a game to a file",
+	Long: `Writes the configuration and items of the game specified with --game
+to the file specified with --file, or to the standard output if no file is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runGameConfigCommand("configExportCmd", func(app *api.App, l zap.Logger) error {
+			if gameConfigGameID == "" {
+				return fmt.Errorf("The game must be specified with --game.")
+			}
+
+			game, err := models.GetGameByID(gameConfigGameID, app.MongoDb, l)
+			if err != nil {
+				return err
+			}
+
+			data, err := models.NewGameConfig(game).Marshal(getGameConfigFormat())
+			if err != nil {
+				return err
+			}
+
+			if gameConfigFile == "" || gameConfigFile == "-" {
+				_, err = os.Stdout.Write(data)
+				return err
+			}
+			return ioutil.WriteFile(gameConfigFile, data, 0644)
+		})
+	},
+}
+
+// configDiffCmd represents the config diff command
+var configDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "shows the differences between a configuration file and the database",
+	Long: `Prints the changes that importing the file specified with --file would make
+to the game in the database, without applying them.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runGameConfigCommand("configDiffCmd", func(app *api.App, l zap.Logger) error {
+			config, err := readGameConfig()
+			if err != nil {
+				return err
+			}
+
+			changes, err := models.DiffGameConfig(config, &models.RealClock{}, app.MongoDb, l)
+			if err != nil {
+				return err
+			}
+
+			printGameConfigChanges(config.ID, changes)
+			return nil
+		})
+	},
+}
+
+// configImportCmd represents the config import command
+var configImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "applies a configuration file to the database",
+	Long: `Replaces the configuration and items of a game with the ones in the file
+specified with --file, creating the game if it does not exist. Items missing from
+the file are removed from the game. The game is written at once and the import
+fails if the game is changed while it is being applied.
+
+The changes are recorded in the game history on behalf of --actor.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runGameConfigCommand("configImportCmd", func(app *api.App, l zap.Logger) error {
+			config, err := readGameConfig()
+			if err != nil {
+				return err
+			}
+
+			changes, err := models.ApplyGameConfig(config, gameConfigActor, &models.RealClock{}, app.MongoDb, l)
+			if err != nil {
+				return err
+			}
+
+			printGameConfigChanges(config.ID, changes)
+			return nil
+		})
+	},
+}
+
+//getGameConfigFormat returns the format of the file from --format or from its extension
+func getGameConfigFormat() string {
+	if gameConfigFormat != "" {
+		return gameConfigFormat
+	}
+	if strings.ToLower(filepath.Ext(gameConfigFile)) == ".json" {
+		return models.GameConfigJSON
+	}
+	return models.GameConfigYAML
+}
+
+func readGameConfig() (*models.GameConfig, error) {
+	if gameConfigFile == "" {
+		return nil, fmt.Errorf("The configuration file must be specified with --file.")
+	}
+
+	data, err := ioutil.ReadFile(gameConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := models.GetGameConfigFromBytes(data, getGameConfigFormat())
+	if err != nil {
+		return nil, err
+	}
+
+	if gameConfigGameID != "" && config.ID == "" {
+		config.ID = gameConfigGameID
+	}
+	if gameConfigGameID != "" && config.ID != gameConfigGameID {
+		return nil, fmt.Errorf("The file configures game %s, not %s.", config.ID, gameConfigGameID)
+	}
+
+	if errs := config.Validate(); len(errs) > 0 {
+		return nil, fmt.Errorf("Invalid configuration file: %s.", strings.Join(errs, ", "))
+	}
+	return config, nil
+}
+
+func formatGameConfigValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+func printGameConfigChanges(gameID string, changes []*models.AuditChange) {
+	if len(changes) == 0 {
+		fmt.Printf("Game %s is up to date.\n", gameID)
+		return
+	}
+
+	for _, change := range changes {
+		switch {
+		case change.Before == nil:
+			fmt.Printf("+ %s: %s\n", change.Field, formatGameConfigValue(change.After))
+		case change.After == nil:
+			fmt.Printf("- %s: %s\n", change.Field, formatGameConfigValue(change.Before))
+		default:
+			fmt.Printf(
+				"~ %s: %s -> %s\n", change.Field,
+				formatGameConfigValue(change.Before), formatGameConfigValue(change.After),
+			)
+		}
+	}
+}
+
+func runGameConfigCommand(source string, run func(app *api.App, l zap.Logger) error) {
+	l := getCommandLogger()
+	cmdL := l.With(
+		zap.String("source", source),
+		zap.String("operation", "Run"),
+		zap.String("gameID", gameConfigGameID),
+		zap.String("file", gameConfigFile),
+	)
+
+	app, err := getCommandApp(l)
+	if err != nil {
+		log.E(cmdL, "Application failed to start.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		os.Exit(1)
+	}
+	defer app.Stop()
+
+	err = run(app, l)
+	if err != nil {
+		log.E(cmdL, "Failed to run config command.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		os.Exit(1)
+	}
+}
+
+func getDefaultGameConfigActor() string {
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return "cli"
+}
+
+func init() {
+	RootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configExportCmd)
+	configCmd.AddCommand(configImportCmd)
+	configCmd.AddCommand(configDiffCmd)
+
+	configCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "./config/default.yaml", "Configuration path")
+	configCmd.PersistentFlags().StringVarP(&gameConfigGameID, "game", "g", "", "ID of the game")
+	configCmd.PersistentFlags().StringVarP(&gameConfigFile, "file", "f", "", "Path of the game configuration file")
+	configCmd.PersistentFlags().StringVar(&gameConfigFormat, "format", "", "Format of the game configuration file (yaml or json)")
+	configImportCmd.Flags().StringVarP(&gameConfigActor, "actor", "a", getDefaultGameConfigActor(), "Actor recorded in the game history")
+}
@@ -0,0 +1,71 @@
+// donations
+// https://github.com/topfreegames/donations
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2016 Top Free Games <backend@tfgco.com>
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/models"
+	"github.com/uber-go/zap"
+)
+
+var ensureIndexesDryRun bool
+
+// ensureIndexesCmd represents the ensure-indexes command
+var ensureIndexesCmd = &cobra.Command{
+	Use:   "ensure-indexes",
+	Short: "creates the indexes donations needs in mongodb",
+	Long: `Creates in background the MongoDB indexes used by donations queries
+that do not exist yet. Each created index is printed to stdout.
+
+Use --dry-run to only print the missing indexes.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		l := getCommandLogger()
+		cmdL := l.With(
+			zap.String("source", "ensureIndexesCmd"),
+			zap.String("operation", "Run"),
+			zap.Bool("dryRun", ensureIndexesDryRun),
+		)
+
+		app, err := getCommandApp(l)
+		if err != nil {
+			log.E(cmdL, "Application failed to start.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			os.Exit(1)
+		}
+		defer app.Stop()
+
+		var indexes []*models.CollectionIndex
+		if ensureIndexesDryRun {
+			indexes, err = models.GetMissingIndexes(app.MongoDb, l)
+		} else {
+			indexes, err = models.EnsureIndexes(app.MongoDb, l)
+		}
+		if err != nil {
+			log.E(cmdL, "Failed to ensure indexes.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			os.Exit(1)
+		}
+
+		for _, index := range indexes {
+			fmt.Printf("%s %s\n", index.Collection, index.GetKey())
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(ensureIndexesCmd)
+
+	ensureIndexesCmd.Flags().StringVarP(&configFile, "config", "c", "./config/default.yaml", "Configuration path")
+	ensureIndexesCmd.Flags().BoolVarP(&ensureIndexesDryRun, "dry-run", "n", false, "Only print the missing indexes")
+}
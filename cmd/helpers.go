@@ -0,0 +1,45 @@
+// donations
+// https://github.com/topfreegames/donations
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2016 Top Free Games <backend@tfgco.com>
+
+package cmd
+
+import (
+	"github.com/topfreegames/donations/api"
+	"github.com/uber-go/zap"
+)
+
+func getCommandLogger() zap.Logger {
+	ll := zap.ErrorLevel
+	switch Verbose {
+	case 1:
+		ll = zap.WarnLevel
+	case 2:
+		ll = zap.InfoLevel
+	case 3:
+		ll = zap.DebugLevel
+	}
+
+	return zap.New(
+		zap.NewJSONEncoder(),
+		ll,
+	)
+}
+
+//getCommandApp returns an application connected to mongo and redis to be used by maintenance commands.
+//The startup index check is disabled, since these commands are the ones used to fix missing indexes.
+func getCommandApp(l zap.Logger) (*api.App, error) {
+	return api.GetApp(
+		"0.0.0.0",
+		0,
+		configFile,
+		Verbose > 2,
+		l,
+		false,
+		false,
+		api.WithConfig("mongo.indexCheck", "off"),
+	)
+}
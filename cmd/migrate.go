@@ -0,0 +1,140 @@
+// donations
+// https://github.com/topfreegames/donations
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2016 Top Free Games <backend@tfgco.com>
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/topfreegames/donations/api"
+	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/migrations"
+	"github.com/uber-go/zap"
+)
+
+var migrateTarget int
+var migrateSteps int
+var migrateAll bool
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "manages mongodb migrations",
+	Long: `Applies, reverts and lists the MongoDB migrations of donations.
+Applied migrations are recorded in the migrations collection.`,
+}
+
+// migrateUpCmd represents the migrate up command
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "applies pending migrations",
+	Long: `Applies, in version order, every migration that has not been applied yet.
+Use --to to stop at a given version.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runMigrateCommand("migrateUpCmd", func(app *api.App, l zap.Logger) ([]*migrations.Migration, error) {
+			return migrations.ApplyMigrations(migrations.GetMigrations(), migrateTarget, app.MongoDb, l)
+		})
+	},
+}
+
+// migrateDownCmd represents the migrate down command
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "reverts applied migrations",
+	Long: `Reverts, in reverse version order, the last applied migration.
+Use --steps to revert more migrations or --all to revert every one of them.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		steps := migrateSteps
+		if migrateAll {
+			steps = 0
+		}
+		runMigrateCommand("migrateDownCmd", func(app *api.App, l zap.Logger) ([]*migrations.Migration, error) {
+			return migrations.RevertMigrations(migrations.GetMigrations(), steps, app.MongoDb, l)
+		})
+	},
+}
+
+// migrateStatusCmd represents the migrate status command
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "lists migrations and whether they were applied",
+	Run: func(cmd *cobra.Command, args []string) {
+		l := getCommandLogger()
+		cmdL := l.With(
+			zap.String("source", "migrateStatusCmd"),
+			zap.String("operation", "Run"),
+		)
+
+		app, err := getCommandApp(l)
+		if err != nil {
+			log.E(cmdL, "Application failed to start.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			os.Exit(1)
+		}
+		defer app.Stop()
+
+		status, err := migrations.GetMigrationStatus(migrations.GetMigrations(), app.MongoDb)
+		if err != nil {
+			log.E(cmdL, "Failed to get migration status.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			os.Exit(1)
+		}
+
+		for _, s := range status {
+			appliedAt := "pending"
+			if s.Applied {
+				appliedAt = time.Unix(s.AppliedAt, 0).UTC().Format(time.RFC3339)
+			}
+			fmt.Printf("%04d %-20s %s\n", s.Version, appliedAt, s.Description)
+		}
+	},
+}
+
+func runMigrateCommand(source string, run func(app *api.App, l zap.Logger) ([]*migrations.Migration, error)) {
+	l := getCommandLogger()
+	cmdL := l.With(
+		zap.String("source", source),
+		zap.String("operation", "Run"),
+	)
+
+	app, err := getCommandApp(l)
+	if err != nil {
+		log.E(cmdL, "Application failed to start.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		os.Exit(1)
+	}
+	defer app.Stop()
+
+	done, err := run(app, l)
+	for _, migration := range done {
+		fmt.Printf("%04d %s\n", migration.Version, migration.Description)
+	}
+	if err != nil {
+		log.E(cmdL, "Failed to run migrations.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		os.Exit(1)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(migrateCmd)
+	migrateCmd.AddCommand(migrateUpCmd)
+	migrateCmd.AddCommand(migrateDownCmd)
+	migrateCmd.AddCommand(migrateStatusCmd)
+
+	migrateCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "./config/default.yaml", "Configuration path")
+	migrateUpCmd.Flags().IntVarP(&migrateTarget, "to", "t", 0, "Only apply migrations up to this version")
+	migrateDownCmd.Flags().IntVarP(&migrateSteps, "steps", "s", 1, "Number of migrations to revert")
+	migrateDownCmd.Flags().BoolVarP(&migrateAll, "all", "a", false, "Revert all applied migrations")
+}
@@ -0,0 +1,102 @@
+// donations
+// https://github.com/topfreegames/donations
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2016 Top Free Games <backend@tfgco.com>
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/models"
+	"github.com/uber-go/zap"
+)
+
+var rebuildWeightsGameID string
+var rebuildWeightsClanID string
+var rebuildWeightsDryRun bool
+
+// rebuildWeightsCmd represents the rebuild-weights command
+var rebuildWeightsCmd = &cobra.Command{
+	Use:   "rebuild-weights",
+	Short: "rebuilds donation weights in redis from mongodb",
+	Long: `Recomputes the all-time and the still-live daily, weekly and monthly
+donation weight counters of clans and players from the donations stored
+in MongoDB and writes them to Redis.
+
+Use --dry-run to only print the differences between Redis and MongoDB.
+When --clan is specified, only the counters for that clan are rebuilt.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		l := getCommandLogger()
+		cmdL := l.With(
+			zap.String("source", "rebuildWeightsCmd"),
+			zap.String("operation", "Run"),
+			zap.String("gameID", rebuildWeightsGameID),
+			zap.String("clanID", rebuildWeightsClanID),
+			zap.Bool("dryRun", rebuildWeightsDryRun),
+		)
+
+		if rebuildWeightsClanID != "" && rebuildWeightsGameID == "" {
+			log.E(cmdL, "The game must be specified when rebuilding weights for a clan.")
+			os.Exit(1)
+		}
+
+		app, err := getCommandApp(l)
+		if err != nil {
+			log.E(cmdL, "Application failed to start.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			os.Exit(1)
+		}
+		defer app.Stop()
+
+		gameIDs := []string{rebuildWeightsGameID}
+		if rebuildWeightsGameID == "" {
+			gameIDs, err = models.GetGameIDs(app.MongoDb)
+			if err != nil {
+				log.E(cmdL, "Failed to list games.", func(cm log.CM) {
+					cm.Write(zap.Error(err))
+				})
+				os.Exit(1)
+			}
+		}
+
+		clock := &models.RealClock{}
+		for _, gameID := range gameIDs {
+			diffs, err := models.RebuildDonationWeights(
+				gameID, rebuildWeightsClanID, rebuildWeightsDryRun,
+				clock, app.Redis, app.MongoDb, l,
+			)
+			if err != nil {
+				log.E(cmdL, "Failed to rebuild donation weights.", func(cm log.CM) {
+					cm.Write(zap.String("game", gameID), zap.Error(err))
+				})
+				os.Exit(1)
+			}
+
+			for _, diff := range diffs {
+				fmt.Printf("%s %d -> %d\n", diff.Key, diff.Current, diff.Expected)
+			}
+
+			action := "rebuilt"
+			if rebuildWeightsDryRun {
+				action = "would be rebuilt"
+			}
+			fmt.Printf("game %s: %d counters %s\n", gameID, len(diffs), action)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(rebuildWeightsCmd)
+
+	rebuildWeightsCmd.Flags().StringVarP(&configFile, "config", "c", "./config/default.yaml", "Configuration path")
+	rebuildWeightsCmd.Flags().StringVarP(&rebuildWeightsGameID, "game", "g", "", "Only rebuild weights for this game")
+	rebuildWeightsCmd.Flags().StringVarP(&rebuildWeightsClanID, "clan", "l", "", "Only rebuild weights for this clan (requires --game)")
+	rebuildWeightsCmd.Flags().BoolVarP(&rebuildWeightsDryRun, "dry-run", "n", false, "Only print the differences, without changing redis")
+}
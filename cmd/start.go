@@ -19,6 +19,8 @@ import (
 
 var host string
 var port int
+var grpcPort int
+var adminPort int
 var debug bool
 var quiet bool
 var fast bool
@@ -51,6 +53,15 @@ environment variables to override configuration keys.`,
 			zap.Bool("debug", debug),
 		)
 
+		//admin routes are mounted when the application is configured, so the ports are set as options
+		options := []api.AppOption{}
+		if adminPort != 0 {
+			options = append(options, api.WithConfig("api.admin.port", adminPort))
+		}
+		if grpcPort != 0 {
+			options = append(options, api.WithConfig("grpc.port", grpcPort))
+		}
+
 		log.D(cmdL, "Creating application...")
 		app, err := api.GetApp(
 			host,
@@ -60,6 +71,7 @@ environment variables to override configuration keys.`,
 			l,
 			false,
 			fast,
+			options...,
 		)
 
 		if err != nil {
@@ -71,7 +83,13 @@ environment variables to override configuration keys.`,
 		log.D(cmdL, "Application created successfully.")
 
 		log.I(cmdL, fmt.Sprintf("Application started successfully at %s:%d", host, port))
-		app.Start()
+		err = app.Start()
+		if err != nil {
+			log.E(cmdL, "Application failed to start.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			os.Exit(1)
+		}
 	},
 }
 
@@ -80,6 +98,8 @@ func init() {
 
 	startCmd.Flags().StringVarP(&host, "bind", "b", "0.0.0.0", "Host to bind donations to")
 	startCmd.Flags().IntVarP(&port, "port", "p", 8888, "Port to bind donations to")
+	startCmd.Flags().IntVarP(&grpcPort, "grpc-port", "g", 0, "Port to bind the gRPC server to (defaults to the grpc.port configuration)")
+	startCmd.Flags().IntVarP(&adminPort, "admin-port", "a", 0, "Port to serve the admin routes at, apart from the gameplay routes (defaults to the api.admin.port configuration)")
 	startCmd.Flags().StringVarP(&configFile, "config", "c", "./config/default.yaml", "Configuration path")
 	startCmd.Flags().BoolVarP(&debug, "debug", "d", false, "Debug mode")
 	startCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Quiet mode (log level error)")
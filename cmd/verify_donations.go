@@ -0,0 +1,108 @@
+// donations
+// https://github.com/topfreegames/donations
+//
+// Licensed under the MIT license:
+// http://www.opensource.org/licenses/mit-license
+// Copyright © 2016 Top Free Games <backend@tfgco.com>
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/models"
+	"github.com/uber-go/zap"
+)
+
+var verifyDonationsGameID string
+var verifyDonationsRepair bool
+var verifyDonationsMinAgeSeconds int
+
+// verifyDonationsCmd represents the verify-donations command
+var verifyDonationsCmd = &cobra.Command{
+	Use:   "verify-donations",
+	Short: "verifies donation requests against the donations collection",
+	Long: `Compares the donations embedded in each donation request with the ones
+in the donations collection and validates each request's finishedAt
+against its item limit.
+
+Each discrepancy found is written to stdout as a JSON object per line.
+The command exits with status 2 if discrepancies were found and not repaired.
+
+Use --repair to fix the discrepancies. Donations that exist in a single
+place are leftovers of failed donations and are removed. Run
+rebuild-weights afterwards to fix the donation weights in redis.
+
+Donations newer than --min-age-seconds (defaults to
+verification.minDonationAgeSeconds) may still be being written, so they
+are skipped. Use --min-age-seconds 0 to verify every donation.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		//the exit code is returned so the app is stopped before exiting
+		if code := runVerifyDonations(cmd); code != 0 {
+			os.Exit(code)
+		}
+	},
+}
+
+//runVerifyDonations verifies the donations and returns the exit code of the command
+func runVerifyDonations(cmd *cobra.Command) int {
+	l := getCommandLogger()
+	cmdL := l.With(
+		zap.String("source", "verifyDonationsCmd"),
+		zap.String("operation", "Run"),
+		zap.String("gameID", verifyDonationsGameID),
+		zap.Bool("repair", verifyDonationsRepair),
+	)
+
+	app, err := getCommandApp(l)
+	if err != nil {
+		log.E(cmdL, "Application failed to start.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return 1
+	}
+	defer app.Stop()
+
+	minAgeSeconds := app.Config.GetInt("verification.minDonationAgeSeconds")
+	if cmd.Flags().Changed("min-age-seconds") {
+		minAgeSeconds = verifyDonationsMinAgeSeconds
+	}
+
+	found := 0
+	encoder := json.NewEncoder(os.Stdout)
+	err = models.VerifyDonationRequests(
+		verifyDonationsGameID, verifyDonationsRepair, minAgeSeconds,
+		&models.RealClock{}, app.MongoDb, l,
+		func(discrepancy *models.DonationDiscrepancy) error {
+			found++
+			return encoder.Encode(discrepancy)
+		},
+	)
+	if err != nil {
+		log.E(cmdL, "Failed to verify donations.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return 1
+	}
+
+	log.I(cmdL, "Donations verified.", func(cm log.CM) {
+		cm.Write(zap.Int("discrepancies", found))
+	})
+
+	if found > 0 && !verifyDonationsRepair {
+		return 2
+	}
+	return 0
+}
+
+func init() {
+	RootCmd.AddCommand(verifyDonationsCmd)
+
+	verifyDonationsCmd.Flags().StringVarP(&configFile, "config", "c", "./config/default.yaml", "Configuration path")
+	verifyDonationsCmd.Flags().StringVarP(&verifyDonationsGameID, "game", "g", "", "Only verify donation requests for this game")
+	verifyDonationsCmd.Flags().BoolVarP(&verifyDonationsRepair, "repair", "r", false, "Repair the discrepancies found")
+	verifyDonationsCmd.Flags().IntVarP(&verifyDonationsMinAgeSeconds, "min-age-seconds", "m", 0, "Overrides verification.minDonationAgeSeconds")
+}
@@ -19,9 +19,11 @@ func (err AuthenticationProviderNotSupported) Error() string {
 	return fmt.Sprintf("Provider %s is not supported.", err.Provider)
 }
 
-//AuthenticationFailed happens when an authentication provider does not recognize the access token
+//AuthenticationFailed happens when an authentication provider does not recognize the access token.
+//The token is a credential, so it is not part of the error message nor of its JSON.
 type AuthenticationFailed struct {
-	Provider, UserID, Token string
+	Provider, UserID string
+	Token            string `json:"-"`
 }
 
 //NewAuthenticationFailed creates a new error
@@ -36,9 +38,50 @@ func NewAuthenticationFailed(provider, userID, token string) *AuthenticationFail
 //Error string
 func (err AuthenticationFailed) Error() string {
 	return fmt.Sprintf(
-		"Authentication with provider %s for user %s failed (Access Token: %s).",
+		"Authentication with provider %s for user %s failed.",
 		err.Provider,
 		err.UserID,
-		err.Token,
+	)
+}
+
+//IdempotencyKeyReusedError happens when an idempotency key is sent again for a different request
+type IdempotencyKeyReusedError struct {
+	Key, Path string
+}
+
+//Error string
+func (err IdempotencyKeyReusedError) Error() string {
+	return fmt.Sprintf("Idempotency key %s was already used for a different request to %s.", err.Key, err.Path)
+}
+
+//InvalidAPIKeyError happens when an API key does not exist, has expired or has a wrong secret
+type InvalidAPIKeyError struct{}
+
+//Error string
+func (err InvalidAPIKeyError) Error() string {
+	return "Invalid API key."
+}
+
+//APIKeyForbiddenError happens when an API key is used for another game or without the required scope
+type APIKeyForbiddenError struct {
+	KeyID, GameID, Scope string
+}
+
+//Error string
+func (err APIKeyForbiddenError) Error() string {
+	return fmt.Sprintf("API key %s can't access the %s scope of game %s.", err.KeyID, err.Scope, err.GameID)
+}
+
+//RateLimitExceededError happens when a route receives more requests than its limit for a game, player or IP
+type RateLimitExceededError struct {
+	Route, Limit      string
+	RetryAfterSeconds int
+}
+
+//Error string
+func (err RateLimitExceededError) Error() string {
+	return fmt.Sprintf(
+		"Rate limit per %s of route %s exceeded. Retry after %d seconds.",
+		err.Limit, err.Route, err.RetryAfterSeconds,
 	)
 }
@@ -97,3 +97,37 @@ type DonationCooldownViolatedError struct {
 func (err DonationCooldownViolatedError) Error() string {
 	return "This player can't donate so soon."
 }
+
+//DonationRequestConcurrentlyUpdatedError happens when a donation request
+//is updated by someone else while a donation is being saved
+type DonationRequestConcurrentlyUpdatedError struct {
+	DonationRequestID string
+	Version           int
+}
+
+//Error string
+func (err DonationRequestConcurrentlyUpdatedError) Error() string {
+	return fmt.Sprintf("Donation request %s was updated concurrently.", err.DonationRequestID)
+}
+
+//DonationRequestExpiredError happens when a donation is made to a donation request
+//that was not finished before it expired
+type DonationRequestExpiredError struct {
+	DonationRequestID string
+}
+
+//Error string
+func (err DonationRequestExpiredError) Error() string {
+	return fmt.Sprintf("Donation request %s has expired.", err.DonationRequestID)
+}
+
+//GameConcurrentlyUpdatedError happens when a game is changed by someone else
+//while a configuration file is being applied to it
+type GameConcurrentlyUpdatedError struct {
+	GameID string
+}
+
+//Error string
+func (err GameConcurrentlyUpdatedError) Error() string {
+	return fmt.Sprintf("Game %s was updated concurrently.", err.GameID)
+}
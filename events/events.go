@@ -0,0 +1,111 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/spf13/viper"
+	"github.com/uber-go/zap"
+)
+
+//Event represents a change in a game, such as a donation
+type Event struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"event"`
+	GameID    string          `json:"gameID"`
+	CreatedAt int64           `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+//NewEvent returns a new event with a random id. data must be valid JSON.
+func NewEvent(eventType, gameID string, createdAt time.Time, data []byte) *Event {
+	return &Event{
+		ID:        uuid.NewV4().String(),
+		Type:      eventType,
+		GameID:    gameID,
+		CreatedAt: createdAt.Unix(),
+		Data:      json.RawMessage(data),
+	}
+}
+
+//EventPublisher sends events to analytics pipelines
+type EventPublisher interface {
+	Publish(event *Event) error
+	Close() error
+}
+
+//NoopPublisher discards all events
+type NoopPublisher struct{}
+
+//Publish discards the event
+func (p *NoopPublisher) Publish(event *Event) error {
+	return nil
+}
+
+//Close does nothing
+func (p *NoopPublisher) Close() error {
+	return nil
+}
+
+//NewPublisher returns the publisher configured with the events.* keys of config
+func NewPublisher(config *viper.Viper, logger zap.Logger) (EventPublisher, error) {
+	publisher := config.GetString("events.publisher")
+	switch publisher {
+	case "", "none":
+		return &NoopPublisher{}, nil
+	case "file":
+		return NewFilePublisher(config.GetString("events.file.path"))
+	case "http":
+		return NewHTTPPublisher(
+			config.GetString("events.http.url"),
+			time.Duration(config.GetInt("events.http.timeoutMilliseconds"))*time.Millisecond,
+			config.GetInt("events.http.bufferSize"),
+			logger,
+		)
+	case "kafka":
+		return NewKafkaPublisher(
+			strings.Split(config.GetString("events.kafka.brokers"), ","),
+			config.GetString("events.kafka.topic"),
+			config.GetInt("events.kafka.bufferSize"),
+			logger,
+		)
+	}
+	return nil, fmt.Errorf("Invalid events.publisher value '%s'. Valid values are none, file, http and kafka.", publisher)
+}
+
+//MultiPublisher publishes events to many publishers
+type MultiPublisher struct {
+	Publishers []EventPublisher
+}
+
+//NewMultiPublisher returns a publisher that publishes events to all the given publishers
+func NewMultiPublisher(publishers ...EventPublisher) *MultiPublisher {
+	return &MultiPublisher{Publishers: publishers}
+}
+
+//Publish publishes the event to all publishers, returning the first error
+func (p *MultiPublisher) Publish(event *Event) error {
+	var firstErr error
+	for _, publisher := range p.Publishers {
+		err := publisher.Publish(event)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+//Close closes all publishers, returning the first error
+func (p *MultiPublisher) Close() error {
+	var firstErr error
+	for _, publisher := range p.Publishers {
+		err := publisher.Close()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,138 @@
+package events_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	"github.com/topfreegames/donations/events"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Event Publishers", func() {
+	var logger zap.Logger
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+	})
+
+	Describe("Configuring publishers", func() {
+		It("Should discard events by default", func() {
+			publisher, err := events.NewPublisher(viper.New(), logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(publisher).To(BeAssignableToTypeOf(&events.NoopPublisher{}))
+		})
+
+		It("Should fail with an invalid publisher", func() {
+			config := viper.New()
+			config.Set("events.publisher", "invalid")
+			_, err := events.NewPublisher(config, logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid events.publisher value 'invalid'"))
+		})
+
+		It("Should fail without http url or kafka topic", func() {
+			config := viper.New()
+			config.Set("events.publisher", "http")
+			_, err := events.NewPublisher(config, logger)
+			Expect(err).To(HaveOccurred())
+
+			config.Set("events.publisher", "kafka")
+			_, err = events.NewPublisher(config, logger)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("File publisher", func() {
+		var path string
+
+		BeforeEach(func() {
+			file, err := ioutil.TempFile("", "donations-events")
+			Expect(err).NotTo(HaveOccurred())
+			path = file.Name()
+			file.Close()
+		})
+
+		AfterEach(func() {
+			os.Remove(path)
+		})
+
+		It("Should append events as JSON lines", func() {
+			publisher, err := events.NewFilePublisher(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = publisher.Publish(events.NewEvent("donation.created", "game", time.Unix(100, 0), []byte(`{"amount":1}`)))
+			Expect(err).NotTo(HaveOccurred())
+			err = publisher.Publish(events.NewEvent("donation.created", "game", time.Unix(101, 0), []byte(`{"amount":2}`)))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(publisher.Close()).To(Succeed())
+
+			data, err := ioutil.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+			Expect(lines).To(HaveLen(2))
+
+			var event events.Event
+			err = json.Unmarshal([]byte(lines[1]), &event)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(event.Type).To(Equal("donation.created"))
+			Expect(event.GameID).To(Equal("game"))
+			Expect(event.CreatedAt).To(BeEquivalentTo(101))
+			Expect(string(event.Data)).To(Equal(`{"amount":2}`))
+		})
+	})
+
+	Describe("HTTP publisher", func() {
+		var server *httptest.Server
+		var received []*events.Event
+		var lock sync.Mutex
+
+		BeforeEach(func() {
+			received = []*events.Event{}
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var event events.Event
+				err := json.NewDecoder(r.Body).Decode(&event)
+				Expect(err).NotTo(HaveOccurred())
+				lock.Lock()
+				received = append(received, &event)
+				lock.Unlock()
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("Should post events to the url", func() {
+			publisher, err := events.NewHTTPPublisher(server.URL, time.Second, 10, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = publisher.Publish(events.NewEvent("donation.created", "game", time.Unix(100, 0), []byte(`{}`)))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(publisher.Close()).To(Succeed())
+
+			Expect(received).To(HaveLen(1))
+			Expect(received[0].Type).To(Equal("donation.created"))
+		})
+
+		It("Should fail to publish after closed", func() {
+			publisher, err := events.NewHTTPPublisher(server.URL, time.Second, 10, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(publisher.Close()).To(Succeed())
+
+			err = publisher.Publish(events.NewEvent("donation.created", "game", time.Unix(100, 0), []byte(`{}`)))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,43 @@
+package events
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+//FilePublisher appends events to a file, one JSON document per line
+type FilePublisher struct {
+	file *os.File
+	lock sync.Mutex
+}
+
+//NewFilePublisher returns a publisher that appends events to the file at path, creating it if needed
+func NewFilePublisher(path string) (*FilePublisher, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FilePublisher{file: file}, nil
+}
+
+//Publish appends the event to the file
+func (p *FilePublisher) Publish(event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	_, err = p.file.Write(data)
+	return err
+}
+
+//Close closes the file
+func (p *FilePublisher) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.file.Close()
+}
@@ -0,0 +1,113 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/topfreegames/donations/log"
+	"github.com/uber-go/zap"
+)
+
+//HTTPPublisher posts events as JSON to an url. Events are queued and sent in background,
+//so publishing does not slow down donations.
+type HTTPPublisher struct {
+	URL    string
+	Client *http.Client
+	Logger zap.Logger
+
+	queue  chan *Event
+	done   chan struct{}
+	lock   sync.RWMutex
+	closed bool
+}
+
+//NewHTTPPublisher returns a publisher that posts events to url, queueing up to bufferSize events
+func NewHTTPPublisher(url string, timeout time.Duration, bufferSize int, logger zap.Logger) (*HTTPPublisher, error) {
+	if url == "" {
+		return nil, fmt.Errorf("events.http.url is required by the http event publisher.")
+	}
+
+	p := &HTTPPublisher{
+		URL:    url,
+		Client: &http.Client{Timeout: timeout},
+		Logger: logger,
+		queue:  make(chan *Event, bufferSize),
+		done:   make(chan struct{}),
+	}
+	go p.run()
+	return p, nil
+}
+
+//Publish queues the event. It fails if the queue is full or the publisher is closed.
+func (p *HTTPPublisher) Publish(event *Event) error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.closed {
+		return fmt.Errorf("Event publisher is closed.")
+	}
+
+	select {
+	case p.queue <- event:
+		return nil
+	default:
+		return fmt.Errorf("Event queue is full, event %s was dropped.", event.ID)
+	}
+}
+
+//Close sends the queued events and stops the publisher
+func (p *HTTPPublisher) Close() error {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.queue)
+	p.lock.Unlock()
+
+	<-p.done
+	return nil
+}
+
+func (p *HTTPPublisher) run() {
+	defer close(p.done)
+	l := p.Logger.With(
+		zap.String("source", "HTTPPublisher"),
+		zap.String("operation", "run"),
+		zap.String("url", p.URL),
+	)
+
+	for event := range p.queue {
+		err := p.send(event)
+		if err != nil {
+			log.E(l, "Failed to publish event.", func(cm log.CM) {
+				cm.Write(zap.String("eventID", event.ID), zap.Error(err))
+			})
+		}
+	}
+}
+
+func (p *HTTPPublisher) send(event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	res, err := p.Client.Post(p.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("Event sink responded with status %d.", res.StatusCode)
+	}
+	return nil
+}
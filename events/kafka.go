@@ -0,0 +1,117 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/topfreegames/donations/log"
+	"github.com/uber-go/zap"
+)
+
+//KafkaPublisher produces events to a Kafka topic, keyed by game so the events of a game are ordered.
+//Events are queued and handed to the producer in background, so publishing does not block
+//donations while the brokers are unavailable.
+type KafkaPublisher struct {
+	Topic    string
+	producer sarama.AsyncProducer
+	queue    chan *Event
+	done     chan struct{}
+	lock     sync.RWMutex
+	closed   bool
+}
+
+//NewKafkaPublisher returns a publisher that produces events to topic, queueing up to bufferSize events
+func NewKafkaPublisher(brokers []string, topic string, bufferSize int, logger zap.Logger) (*KafkaPublisher, error) {
+	if topic == "" {
+		return nil, fmt.Errorf("events.kafka.topic is required by the kafka event publisher.")
+	}
+
+	config := sarama.NewConfig()
+	config.Producer.Return.Errors = true
+	producer, err := sarama.NewAsyncProducer(brokers, config)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &KafkaPublisher{
+		Topic:    topic,
+		producer: producer,
+		queue:    make(chan *Event, bufferSize),
+		done:     make(chan struct{}),
+	}
+
+	l := logger.With(
+		zap.String("source", "KafkaPublisher"),
+		zap.String("operation", "produce"),
+		zap.String("topic", topic),
+	)
+	errorsDone := make(chan struct{})
+	go func() {
+		defer close(errorsDone)
+		for err := range producer.Errors() {
+			log.E(l, "Failed to publish event.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+		}
+	}()
+	go func() {
+		defer close(p.done)
+		p.run(l)
+		producer.AsyncClose()
+		<-errorsDone
+	}()
+
+	return p, nil
+}
+
+//Publish queues the event. It fails if the queue is full or the publisher is closed.
+func (p *KafkaPublisher) Publish(event *Event) error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.closed {
+		return fmt.Errorf("Event publisher is closed.")
+	}
+
+	select {
+	case p.queue <- event:
+		return nil
+	default:
+		return fmt.Errorf("Event queue is full, event %s was dropped.", event.ID)
+	}
+}
+
+//Close produces the queued events and closes the producer
+func (p *KafkaPublisher) Close() error {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.queue)
+	p.lock.Unlock()
+
+	<-p.done
+	return nil
+}
+
+//run hands the queued events to the producer until the queue is closed
+func (p *KafkaPublisher) run(l zap.Logger) {
+	for event := range p.queue {
+		data, err := json.Marshal(event)
+		if err != nil {
+			log.E(l, "Failed to encode event.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			continue
+		}
+
+		p.producer.Input() <- &sarama.ProducerMessage{
+			Topic: p.Topic,
+			Key:   sarama.StringEncoder(event.GameID),
+			Value: sarama.ByteEncoder(data),
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/topfreegames/donations/events"
+	"github.com/topfreegames/donations/models"
+)
+
+const namespace = "donations"
+
+//errorsPackage is the package of the errors counted by their type name
+const errorsPackage = "github.com/topfreegames/donations/errors"
+
+//UnknownErrorType labels errors that don't come from the errors package, such as database failures
+const UnknownErrorType = "unknown"
+
+var (
+	//RequestDuration is the latency of HTTP requests per route context value, method and status
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	//GRPCDuration is the latency of gRPC calls per method and status code
+	GRPCDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_call_duration_seconds",
+		Help:      "Latency of gRPC calls.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	//Errors counts the errors returned to clients per type
+	Errors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "errors_total",
+		Help:      "Errors returned to clients.",
+	}, []string{"type"})
+
+	//SegmentDuration is the latency of the segments of a route, such as the MongoDB operations of model segments
+	SegmentDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "segment_duration_seconds",
+		Help:      "Latency of the segments of HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "segment"})
+
+	//RedisCommandDuration is the latency of redis commands
+	RedisCommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "redis_command_duration_seconds",
+		Help:      "Latency of redis commands.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"command"})
+
+	//MongoOperationDuration is the latency of the MongoDB operations of the models per collection and operation
+	MongoOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mongo_operation_duration_seconds",
+		Help:      "Latency of MongoDB operations.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"collection", "operation"})
+
+	//LockWaitDuration is the time waited to acquire redsync locks per kind of lock
+	LockWaitDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "lock_wait_seconds",
+		Help:      "Time waited to acquire locks.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"lock"})
+
+	//LockFailures counts the locks that could not be acquired after all retries
+	LockFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lock_failures_total",
+		Help:      "Locks that could not be acquired.",
+	}, []string{"lock"})
+
+	//DonationRequestsCreated counts the donation requests created per game
+	DonationRequestsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "donation_requests_created_total",
+		Help:      "Donation requests created.",
+	}, []string{"game"})
+
+	//DonationsCreated counts the donations per game
+	DonationsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "donations_total",
+		Help:      "Donations to donation requests.",
+	}, []string{"game"})
+
+	//WeightDonated counts the weight of the donations per game
+	WeightDonated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "weight_donated_total",
+		Help:      "Weight of the donations to donation requests.",
+	}, []string{"game"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		RequestDuration,
+		GRPCDuration,
+		Errors,
+		SegmentDuration,
+		RedisCommandDuration,
+		MongoOperationDuration,
+		LockWaitDuration,
+		LockFailures,
+		DonationRequestsCreated,
+		DonationsCreated,
+		WeightDonated,
+	)
+}
+
+//Since returns the seconds elapsed since start, the unit of every histogram
+func Since(start time.Time) float64 {
+	return time.Now().Sub(start).Seconds()
+}
+
+//GetErrorType returns the name of the type of an error of the errors package, or UnknownErrorType
+func GetErrorType(err error) string {
+	t := reflect.TypeOf(err)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.PkgPath() != errorsPackage {
+		return UnknownErrorType
+	}
+	return t.Name()
+}
+
+//ObserveMongoOperation observes the latency of a MongoDB operation. It is a models.MongoOperationObserver.
+func ObserveMongoOperation(collection, operation string, duration time.Duration) {
+	MongoOperationDuration.WithLabelValues(collection, operation).Observe(duration.Seconds())
+}
+
+//ObserveError counts an error returned to a client
+func ObserveError(err error) {
+	Errors.WithLabelValues(GetErrorType(err)).Inc()
+}
+
+//Gather returns the registered metrics in the Prometheus text format and its content type
+func Gather() ([]byte, string, error) {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		return nil, "", err
+	}
+
+	buf := new(bytes.Buffer)
+	encoder := expfmt.NewEncoder(buf, expfmt.FmtText)
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			return nil, "", err
+		}
+	}
+	return buf.Bytes(), string(expfmt.FmtText), nil
+}
+
+//EventCounter counts the business events emitted by the models. It is an events.EventPublisher,
+//so it counts the changes made through any API.
+type EventCounter struct{}
+
+//NewEventCounter returns a new event counter
+func NewEventCounter() *EventCounter {
+	return &EventCounter{}
+}
+
+//Publish counts the event
+func (p *EventCounter) Publish(event *events.Event) error {
+	switch event.Type {
+	case models.DonationRequestCreatedEvent:
+		DonationRequestsCreated.WithLabelValues(event.GameID).Inc()
+	case models.DonationCreatedEvent:
+		var donation struct {
+			Weight int `json:"weight"`
+		}
+		if err := json.Unmarshal(event.Data, &donation); err != nil {
+			return err
+		}
+		DonationsCreated.WithLabelValues(event.GameID).Inc()
+		WeightDonated.WithLabelValues(event.GameID).Add(float64(donation.Weight))
+	}
+	return nil
+}
+
+//Close does nothing
+func (p *EventCounter) Close() error {
+	return nil
+}
+
+//redisConn measures the latency of the commands sent with Do
+type redisConn struct {
+	redis.Conn
+}
+
+//NewRedisConn returns a connection that measures the latency of its commands
+func NewRedisConn(conn redis.Conn) redis.Conn {
+	return &redisConn{Conn: conn}
+}
+
+//Do sends a command and measures its latency. Flushes of pipelined commands, sent without a command, are not measured.
+func (c *redisConn) Do(command string, args ...interface{}) (interface{}, error) {
+	if command == "" {
+		return c.Conn.Do(command, args...)
+	}
+	start := time.Now()
+	reply, err := c.Conn.Do(command, args...)
+	RedisCommandDuration.WithLabelValues(strings.ToUpper(command)).Observe(Since(start))
+	return reply, err
+}
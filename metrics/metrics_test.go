@@ -0,0 +1,61 @@
+package metrics_test
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	uuid "github.com/satori/go.uuid"
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/events"
+	"github.com/topfreegames/donations/metrics"
+	"github.com/topfreegames/donations/models"
+)
+
+var _ = Describe("Metrics", func() {
+	Describe("Error types", func() {
+		It("Should name errors of the errors package by their type", func() {
+			Expect(metrics.GetErrorType(&errors.DonationCooldownViolatedError{})).To(Equal("DonationCooldownViolatedError"))
+			Expect(metrics.GetErrorType(errors.NewDocumentNotFoundError("games", "id"))).To(Equal("DocumentNotFoundError"))
+		})
+
+		It("Should not name other errors", func() {
+			Expect(metrics.GetErrorType(fmt.Errorf("failed"))).To(Equal(metrics.UnknownErrorType))
+		})
+	})
+
+	Describe("Event counter", func() {
+		It("Should count donation requests, donations and weight per game", func() {
+			gameID := uuid.NewV4().String()
+			counter := metrics.NewEventCounter()
+
+			err := counter.Publish(events.NewEvent(models.DonationRequestCreatedEvent, gameID, time.Now(), []byte(`{}`)))
+			Expect(err).NotTo(HaveOccurred())
+			for i := 0; i < 2; i++ {
+				err = counter.Publish(events.NewEvent(models.DonationCreatedEvent, gameID, time.Now(), []byte(`{"weight":15}`)))
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			body, contentType, err := metrics.Gather()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contentType).To(HavePrefix("text/plain"))
+			Expect(string(body)).To(ContainSubstring(fmt.Sprintf(`donations_donation_requests_created_total{game="%s"} 1`, gameID)))
+			Expect(string(body)).To(ContainSubstring(fmt.Sprintf(`donations_donations_total{game="%s"} 2`, gameID)))
+			Expect(string(body)).To(ContainSubstring(fmt.Sprintf(`donations_weight_donated_total{game="%s"} 30`, gameID)))
+		})
+	})
+
+	Describe("MongoDB operations", func() {
+		It("Should observe the latency per collection and operation", func() {
+			collection := uuid.NewV4().String()
+			metrics.ObserveMongoOperation(collection, "find", 10*time.Millisecond)
+
+			body, _, err := metrics.Gather()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(ContainSubstring(fmt.Sprintf(
+				`donations_mongo_operation_duration_seconds_count{collection="%s",operation="find"} 1`, collection,
+			)))
+		})
+	})
+})
@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"github.com/topfreegames/donations/models"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+)
+
+var createIndexesIndexes = []*models.CollectionIndex{
+	&models.CollectionIndex{
+		Collection: "requests",
+		Index:      mgo.Index{Key: []string{"player", "createdAt"}, Background: true},
+	},
+	&models.CollectionIndex{
+		Collection: "requests",
+		Index:      mgo.Index{Key: []string{"donations.player", "donations.createdAt"}, Background: true},
+	},
+	&models.CollectionIndex{
+		Collection: "requests",
+		Index:      mgo.Index{Key: []string{"gameID"}, Background: true},
+	},
+	&models.CollectionIndex{
+		Collection: "donations",
+		Index:      mgo.Index{Key: []string{"donationRequestID"}, Background: true},
+	},
+	&models.CollectionIndex{
+		Collection: "donations",
+		Index:      mgo.Index{Key: []string{"gameID", "clan"}, Background: true},
+	},
+	&models.CollectionIndex{
+		Collection: "players",
+		Index:      mgo.Index{Key: []string{"gameID"}, Background: true},
+	},
+	models.IdempotentResponsesTTLIndex,
+}
+
+func init() {
+	Register(&Migration{
+		Version:     1,
+		Description: "Create the indexes used by donations queries",
+		Up: func(db *mgo.Database, logger zap.Logger) error {
+			_, err := models.EnsureCollectionIndexes(createIndexesIndexes, db, logger)
+			return err
+		},
+		Down: func(db *mgo.Database, logger zap.Logger) error {
+			return models.DropCollectionIndexes(createIndexesIndexes, db, logger)
+		},
+	})
+}
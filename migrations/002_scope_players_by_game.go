@@ -0,0 +1,146 @@
+package migrations
+
+import (
+	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/models"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var playerScopedIndexes = []*models.CollectionIndex{
+	&models.CollectionIndex{
+		Collection: "requests",
+		Index:      mgo.Index{Key: []string{"gameID", "player", "createdAt"}, Background: true},
+	},
+	&models.CollectionIndex{
+		Collection: "requests",
+		Index:      mgo.Index{Key: []string{"gameID", "donations.player", "donations.createdAt"}, Background: true},
+	},
+	&models.CollectionIndex{
+		Collection: "players",
+		Index:      mgo.Index{Key: []string{"gameID", "playerID"}, Unique: true, Background: true},
+	},
+}
+
+var playerUnscopedIndexes = []*models.CollectionIndex{
+	&models.CollectionIndex{
+		Collection: "requests",
+		Index:      mgo.Index{Key: []string{"player", "createdAt"}, Background: true},
+	},
+	&models.CollectionIndex{
+		Collection: "requests",
+		Index:      mgo.Index{Key: []string{"donations.player", "donations.createdAt"}, Background: true},
+	},
+	&models.CollectionIndex{
+		Collection: "requests",
+		Index:      mgo.Index{Key: []string{"gameID"}, Background: true},
+	},
+	&models.CollectionIndex{
+		Collection: "players",
+		Index:      mgo.Index{Key: []string{"gameID"}, Background: true},
+	},
+}
+
+//scopePlayersByGame copies the player id, previously stored as _id, to the playerID field.
+//Players that shared a document between games keep the game of their last update.
+func scopePlayersByGame(db *mgo.Database, logger zap.Logger) error {
+	players := models.GetPlayersCollection(db)
+
+	iter := players.Find(bson.M{"playerID": bson.M{"$exists": false}}).Iter()
+	var player bson.M
+	for iter.Next(&player) {
+		id, ok := player["_id"].(string)
+		if ok {
+			err := players.UpdateId(id, bson.M{"$set": bson.M{"playerID": id}})
+			if err != nil {
+				iter.Close()
+				return err
+			}
+		}
+		player = bson.M{}
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	err := models.DropCollectionIndexes(playerUnscopedIndexes, db, logger)
+	if err != nil {
+		return err
+	}
+
+	_, err = models.EnsureCollectionIndexes(playerScopedIndexes, db, logger)
+	return err
+}
+
+//unscopePlayersByGame keys players by their id again. When the same player id exists
+//in more than one game only one of the documents can be kept, the others are removed.
+func unscopePlayersByGame(db *mgo.Database, logger zap.Logger) error {
+	l := logger.With(
+		zap.String("source", "migrations"),
+		zap.String("operation", "unscopePlayersByGame"),
+	)
+
+	err := models.DropCollectionIndexes(playerScopedIndexes, db, logger)
+	if err != nil {
+		return err
+	}
+
+	players := models.GetPlayersCollection(db)
+	removed := 0
+	iter := players.Find(bson.M{"_id": bson.M{"$type": 7}}).Iter()
+	var player bson.M
+	for iter.Next(&player) {
+		objectID := player["_id"]
+		id, _ := player["playerID"].(string)
+
+		count, err := players.FindId(id).Count()
+		if err != nil {
+			iter.Close()
+			return err
+		}
+		if count == 0 && id != "" {
+			player["_id"] = id
+			err = players.Insert(player)
+			if err != nil {
+				iter.Close()
+				return err
+			}
+		} else {
+			removed++
+		}
+
+		err = players.RemoveId(objectID)
+		if err != nil {
+			iter.Close()
+			return err
+		}
+		player = bson.M{}
+	}
+	if err = iter.Close(); err != nil {
+		return err
+	}
+
+	if removed > 0 {
+		log.W(l, "Players that existed in more than one game were removed.", func(cm log.CM) {
+			cm.Write(zap.Int("removed", removed))
+		})
+	}
+
+	_, err = players.UpdateAll(nil, bson.M{"$unset": bson.M{"playerID": ""}})
+	if err != nil {
+		return err
+	}
+
+	_, err = models.EnsureCollectionIndexes(playerUnscopedIndexes, db, logger)
+	return err
+}
+
+func init() {
+	Register(&Migration{
+		Version:     2,
+		Description: "Scope players and player queries by game",
+		Up:          scopePlayersByGame,
+		Down:        unscopePlayersByGame,
+	})
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"github.com/topfreegames/donations/models"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+)
+
+var archiveIndexes = []*models.CollectionIndex{
+	&models.CollectionIndex{
+		Collection: "requests",
+		Index:      mgo.Index{Key: []string{"updatedAt"}, Background: true},
+	},
+	&models.CollectionIndex{
+		Collection: "archivedRequests",
+		Index:      mgo.Index{Key: []string{"gameID", "donations.player", "donations.createdAt"}, Background: true},
+	},
+}
+
+func init() {
+	Register(&Migration{
+		Version:     3,
+		Description: "Create the indexes used by archived donation requests",
+		Up: func(db *mgo.Database, logger zap.Logger) error {
+			_, err := models.EnsureCollectionIndexes(archiveIndexes, db, logger)
+			return err
+		},
+		Down: func(db *mgo.Database, logger zap.Logger) error {
+			return models.DropCollectionIndexes(archiveIndexes, db, logger)
+		},
+	})
+}
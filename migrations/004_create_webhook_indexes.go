@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"github.com/topfreegames/donations/models"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+)
+
+var webhookIndexes = []*models.CollectionIndex{
+	&models.CollectionIndex{
+		Collection: "webhooks",
+		Index:      mgo.Index{Key: []string{"gameID", "events"}, Background: true},
+	},
+	&models.CollectionIndex{
+		Collection: "webhookDeliveries",
+		Index:      mgo.Index{Key: []string{"status", "nextAttemptAt"}, Background: true},
+	},
+	&models.CollectionIndex{
+		Collection: "webhookDeliveries",
+		Index:      mgo.Index{Key: []string{"gameID", "status", "createdAt"}, Background: true},
+	},
+}
+
+func init() {
+	Register(&Migration{
+		Version:     4,
+		Description: "Create the indexes used by webhooks",
+		Up: func(db *mgo.Database, logger zap.Logger) error {
+			_, err := models.EnsureCollectionIndexes(webhookIndexes, db, logger)
+			return err
+		},
+		Down: func(db *mgo.Database, logger zap.Logger) error {
+			return models.DropCollectionIndexes(webhookIndexes, db, logger)
+		},
+	})
+}
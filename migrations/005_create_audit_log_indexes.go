@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"github.com/topfreegames/donations/models"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+)
+
+var auditLogIndexes = []*models.CollectionIndex{
+	&models.CollectionIndex{
+		Collection: "auditLog",
+		Index:      mgo.Index{Key: []string{"gameID", "version"}, Unique: true, Background: true},
+	},
+}
+
+func init() {
+	Register(&Migration{
+		Version:     5,
+		Description: "Create the indexes used by the audit log",
+		Up: func(db *mgo.Database, logger zap.Logger) error {
+			_, err := models.EnsureCollectionIndexes(auditLogIndexes, db, logger)
+			return err
+		},
+		Down: func(db *mgo.Database, logger zap.Logger) error {
+			return models.DropCollectionIndexes(auditLogIndexes, db, logger)
+		},
+	})
+}
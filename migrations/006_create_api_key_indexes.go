@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"github.com/topfreegames/donations/models"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+)
+
+var apiKeyIndexes = []*models.CollectionIndex{
+	&models.CollectionIndex{
+		Collection: "apiKeys",
+		Index:      mgo.Index{Key: []string{"gameID", "createdAt"}, Background: true},
+	},
+}
+
+func init() {
+	Register(&Migration{
+		Version:     6,
+		Description: "Create the indexes used by API keys",
+		Up: func(db *mgo.Database, logger zap.Logger) error {
+			_, err := models.EnsureCollectionIndexes(apiKeyIndexes, db, logger)
+			return err
+		},
+		Down: func(db *mgo.Database, logger zap.Logger) error {
+			return models.DropCollectionIndexes(apiKeyIndexes, db, logger)
+		},
+	})
+}
@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"github.com/topfreegames/donations/models"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+)
+
+var expirationIndexes = []*models.CollectionIndex{
+	&models.CollectionIndex{
+		Collection: "requests",
+		Index:      mgo.Index{Key: []string{"createdAt"}, Background: true},
+	},
+}
+
+func init() {
+	Register(&Migration{
+		Version:     7,
+		Description: "Create the index used to expire donation requests",
+		Up: func(db *mgo.Database, logger zap.Logger) error {
+			_, err := models.EnsureCollectionIndexes(expirationIndexes, db, logger)
+			return err
+		},
+		Down: func(db *mgo.Database, logger zap.Logger) error {
+			return models.DropCollectionIndexes(expirationIndexes, db, logger)
+		},
+	})
+}
@@ -0,0 +1,234 @@
+package migrations
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/topfreegames/donations/log"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+//Migration represents a versioned change to the database schema or documents
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(db *mgo.Database, logger zap.Logger) error
+	Down        func(db *mgo.Database, logger zap.Logger) error
+}
+
+//AppliedMigration represents a migration recorded in the migrations collection
+type AppliedMigration struct {
+	Version     int    `json:"version" bson:"_id"`
+	Description string `json:"description" bson:"description"`
+	AppliedAt   int64  `json:"appliedAt" bson:"appliedAt"`
+}
+
+//MigrationStatus represents whether a known migration has been applied
+type MigrationStatus struct {
+	Version     int    `json:"version"`
+	Description string `json:"description"`
+	Applied     bool   `json:"applied"`
+	AppliedAt   int64  `json:"appliedAt,omitempty"`
+}
+
+type byVersion []*Migration
+
+func (m byVersion) Len() int           { return len(m) }
+func (m byVersion) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
+func (m byVersion) Less(i, j int) bool { return m[i].Version < m[j].Version }
+
+var registered = []*Migration{}
+
+//Register adds a migration to the list of known migrations. Migrations register themselves in init.
+func Register(migration *Migration) {
+	for _, m := range registered {
+		if m.Version == migration.Version {
+			panic(fmt.Sprintf("Migration %d was already registered.", migration.Version))
+		}
+	}
+	registered = append(registered, migration)
+}
+
+//GetMigrations returns the registered migrations sorted by version
+func GetMigrations() []*Migration {
+	migrations := make([]*Migration, len(registered))
+	copy(migrations, registered)
+	sort.Sort(byVersion(migrations))
+	return migrations
+}
+
+//GetMigrationsCollection to update or query applied migrations
+func GetMigrationsCollection(db *mgo.Database) *mgo.Collection {
+	return db.C("migrations")
+}
+
+//GetAppliedMigrations returns the migrations recorded as applied, indexed by version
+func GetAppliedMigrations(db *mgo.Database) (map[int]*AppliedMigration, error) {
+	var applied []AppliedMigration
+	err := GetMigrationsCollection(db).Find(nil).All(&applied)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[int]*AppliedMigration{}
+	for i := range applied {
+		result[applied[i].Version] = &applied[i]
+	}
+	return result, nil
+}
+
+//GetMigrationStatus returns, for each of the given migrations, whether it has been applied
+func GetMigrationStatus(migrations []*Migration, db *mgo.Database) ([]*MigrationStatus, error) {
+	applied, err := GetAppliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	status := []*MigrationStatus{}
+	for _, migration := range migrations {
+		s := &MigrationStatus{
+			Version:     migration.Version,
+			Description: migration.Description,
+		}
+		if a, ok := applied[migration.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = a.AppliedAt
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+//ApplyMigrations applies, in version order, the given migrations that have not been applied yet.
+//If target is greater than zero, migrations with versions greater than target are not applied.
+func ApplyMigrations(migrations []*Migration, target int, db *mgo.Database, logger zap.Logger) ([]*Migration, error) {
+	l := logger.With(
+		zap.String("source", "migrations"),
+		zap.String("operation", "ApplyMigrations"),
+		zap.Int("target", target),
+	)
+
+	applied, err := GetAppliedMigrations(db)
+	if err != nil {
+		log.E(l, "Failed to get applied migrations.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return nil, err
+	}
+
+	sorted := make([]*Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Sort(byVersion(sorted))
+
+	done := []*Migration{}
+	for _, migration := range sorted {
+		if target > 0 && migration.Version > target {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		ml := l.With(
+			zap.Int("version", migration.Version),
+			zap.String("description", migration.Description),
+		)
+		log.I(ml, "Applying migration...")
+		err = migration.Up(db, logger)
+		if err != nil {
+			log.E(ml, "Failed to apply migration.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return done, err
+		}
+
+		err = GetMigrationsCollection(db).Insert(&AppliedMigration{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedAt:   time.Now().UTC().Unix(),
+		})
+		if err != nil {
+			log.E(ml, "Failed to record migration.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return done, err
+		}
+
+		done = append(done, migration)
+		log.I(ml, "Migration applied successfully.")
+	}
+
+	return done, nil
+}
+
+//RevertMigrations reverts, in reverse version order, the last steps applied migrations.
+//If steps is zero or less, every applied migration is reverted.
+func RevertMigrations(migrations []*Migration, steps int, db *mgo.Database, logger zap.Logger) ([]*Migration, error) {
+	l := logger.With(
+		zap.String("source", "migrations"),
+		zap.String("operation", "RevertMigrations"),
+		zap.Int("steps", steps),
+	)
+
+	applied, err := GetAppliedMigrations(db)
+	if err != nil {
+		log.E(l, "Failed to get applied migrations.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return nil, err
+	}
+
+	known := map[int]*Migration{}
+	for _, migration := range migrations {
+		known[migration.Version] = migration
+	}
+
+	versions := []int{}
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	done := []*Migration{}
+	for _, version := range versions {
+		if steps > 0 && len(done) == steps {
+			break
+		}
+
+		migration, ok := known[version]
+		if !ok {
+			err = fmt.Errorf("Migration %d was applied but is unknown to this version of donations.", version)
+			log.E(l, err.Error())
+			return done, err
+		}
+
+		ml := l.With(
+			zap.Int("version", migration.Version),
+			zap.String("description", migration.Description),
+		)
+		log.I(ml, "Reverting migration...")
+		err = migration.Down(db, logger)
+		if err != nil {
+			log.E(ml, "Failed to revert migration.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return done, err
+		}
+
+		err = GetMigrationsCollection(db).Remove(bson.M{"_id": version})
+		if err != nil {
+			log.E(ml, "Failed to remove migration record.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return done, err
+		}
+
+		done = append(done, migration)
+		log.I(ml, "Migration reverted successfully.")
+	}
+
+	return done, nil
+}
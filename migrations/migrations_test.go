@@ -0,0 +1,209 @@
+package migrations_test
+
+import (
+	"fmt"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	uuid "github.com/satori/go.uuid"
+	"github.com/topfreegames/donations/migrations"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Migrations", func() {
+	var logger zap.Logger
+	var session *mgo.Session
+	var db *mgo.Database
+	var calls []string
+	var testMigrations []*migrations.Migration
+
+	getTestMigration := func(version int) *migrations.Migration {
+		return &migrations.Migration{
+			Version:     version,
+			Description: fmt.Sprintf("Test migration %d", version),
+			Up: func(db *mgo.Database, logger zap.Logger) error {
+				calls = append(calls, fmt.Sprintf("up-%d", version))
+				return nil
+			},
+			Down: func(db *mgo.Database, logger zap.Logger) error {
+				calls = append(calls, fmt.Sprintf("down-%d", version))
+				return nil
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		session, db = GetTestMongoDB()
+		_, err := migrations.GetMigrationsCollection(db).RemoveAll(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		calls = []string{}
+		testMigrations = []*migrations.Migration{
+			getTestMigration(3),
+			getTestMigration(1),
+			getTestMigration(2),
+		}
+	})
+
+	AfterEach(func() {
+		session.Close()
+		session = nil
+		db = nil
+	})
+
+	Describe("Applying migrations", func() {
+		It("Should apply pending migrations in order", func() {
+			done, err := migrations.ApplyMigrations(testMigrations, 0, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(HaveLen(3))
+			Expect(calls).To(Equal([]string{"up-1", "up-2", "up-3"}))
+
+			applied, err := migrations.GetAppliedMigrations(db)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(applied).To(HaveLen(3))
+			Expect(applied[2].Description).To(Equal("Test migration 2"))
+			Expect(applied[2].AppliedAt).To(BeNumerically(">", 0))
+		})
+
+		It("Should not apply migrations twice", func() {
+			_, err := migrations.ApplyMigrations(testMigrations, 0, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			done, err := migrations.ApplyMigrations(testMigrations, 0, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeEmpty())
+			Expect(calls).To(HaveLen(3))
+		})
+
+		It("Should apply migrations up to target version", func() {
+			done, err := migrations.ApplyMigrations(testMigrations, 2, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(HaveLen(2))
+			Expect(calls).To(Equal([]string{"up-1", "up-2"}))
+		})
+
+		It("Should stop at the first failed migration", func() {
+			testMigrations[2].Up = func(db *mgo.Database, logger zap.Logger) error {
+				return fmt.Errorf("failed")
+			}
+
+			done, err := migrations.ApplyMigrations(testMigrations, 0, db, logger)
+			Expect(err).To(HaveOccurred())
+			Expect(done).To(HaveLen(1))
+			Expect(calls).To(Equal([]string{"up-1"}))
+
+			applied, err := migrations.GetAppliedMigrations(db)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(applied).To(HaveLen(1))
+		})
+	})
+
+	Describe("Reverting migrations", func() {
+		It("Should revert the last applied migration", func() {
+			_, err := migrations.ApplyMigrations(testMigrations, 0, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			done, err := migrations.RevertMigrations(testMigrations, 1, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(HaveLen(1))
+			Expect(done[0].Version).To(Equal(3))
+
+			applied, err := migrations.GetAppliedMigrations(db)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(applied).To(HaveLen(2))
+			Expect(applied).NotTo(HaveKey(3))
+		})
+
+		It("Should revert all applied migrations in reverse order", func() {
+			_, err := migrations.ApplyMigrations(testMigrations, 0, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			done, err := migrations.RevertMigrations(testMigrations, 0, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(HaveLen(3))
+			Expect(calls).To(Equal([]string{"up-1", "up-2", "up-3", "down-3", "down-2", "down-1"}))
+
+			applied, err := migrations.GetAppliedMigrations(db)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(applied).To(BeEmpty())
+		})
+
+		It("Should fail to revert an unknown migration", func() {
+			_, err := migrations.ApplyMigrations(testMigrations, 0, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = migrations.RevertMigrations(testMigrations[1:], 1, db, logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Migration 3 was applied but is unknown"))
+		})
+	})
+
+	Describe("Migration status", func() {
+		It("Should return whether each migration was applied", func() {
+			_, err := migrations.ApplyMigrations(testMigrations, 1, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			status, err := migrations.GetMigrationStatus(migrations.GetMigrations(), db)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).NotTo(BeEmpty())
+			Expect(status[0].Version).To(Equal(1))
+			Expect(status[0].Applied).To(BeTrue())
+		})
+	})
+
+	Describe("Scope players by game migration", func() {
+		It("Should key existing players by game and player id", func() {
+			gameID := uuid.NewV4().String()
+			playerID := uuid.NewV4().String()
+			err := models.GetPlayersCollection(db).Insert(bson.M{
+				"_id":                 playerID,
+				"gameID":              gameID,
+				"donationWindowStart": 100,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = migrations.ApplyMigrations(migrations.GetMigrations(), 2, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			player, err := models.GetPlayerByID(gameID, playerID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(player.DonationWindowStart).To(Equal(int64(100)))
+
+			err = models.UpdateDonationWindowStart(uuid.NewV4().String(), playerID, 200, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			player, err = models.GetPlayerByID(gameID, playerID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(player.DonationWindowStart).To(Equal(int64(100)))
+		})
+	})
+
+	Describe("Registered migrations", func() {
+		It("Should apply and revert all registered migrations", func() {
+			done, err := migrations.ApplyMigrations(migrations.GetMigrations(), 0, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(HaveLen(len(migrations.GetMigrations())))
+
+			missing, err := models.GetMissingIndexes(db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(missing).To(BeEmpty())
+
+			done, err = migrations.RevertMigrations(migrations.GetMigrations(), 0, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(HaveLen(len(migrations.GetMigrations())))
+
+			_, err = migrations.ApplyMigrations(migrations.GetMigrations(), 0, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,227 @@
+package models
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/log"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	//APIKeyScopeAdmin allows changing the configuration of a game (game, items, webhooks, players, api keys)
+	APIKeyScopeAdmin = "admin"
+	//APIKeyScopeClient allows the gameplay routes of a game (donation requests and donations)
+	APIKeyScopeClient = "client"
+)
+
+//APIKeyScopes are the scopes an API key can be granted
+var APIKeyScopes = []string{APIKeyScopeAdmin, APIKeyScopeClient}
+
+//APIKey grants access to the routes of a single game. Only the hash of its secret is stored,
+//the token is returned once when the key is created.
+type APIKey struct {
+	ID         string   `json:"id" bson:"_id"`
+	GameID     string   `json:"gameID" bson:"gameID"`
+	Name       string   `json:"name" bson:"name"`
+	Scopes     []string `json:"scopes" bson:"scopes"`
+	SecretHash string   `json:"-" bson:"secretHash"`
+	CreatedAt  int64    `json:"createdAt" bson:"createdAt"`
+	ExpiresAt  int64    `json:"expiresAt,omitempty" bson:"expiresAt,omitempty"`
+}
+
+//IsValidAPIKeyScope returns true if API keys can be granted the scope
+func IsValidAPIKeyScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+//HasScope returns true if the key was granted the scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+//IsExpired returns true if the key was rotated and its grace period is over
+func (k *APIKey) IsExpired(clock Clock) bool {
+	return k.ExpiresAt > 0 && k.ExpiresAt <= clock.GetUTCTime().Unix()
+}
+
+//GetAPIKeysCollection to update or query API keys
+func GetAPIKeysCollection(db *mgo.Database) *mgo.Collection {
+	return db.C("apiKeys")
+}
+
+func hashAPIKeySecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+func newAPIKey(gameID, name string, scopes []string, clock Clock) (*APIKey, string, error) {
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := &APIKey{
+		ID:         uuid.NewV4().String(),
+		GameID:     gameID,
+		Name:       name,
+		Scopes:     scopes,
+		SecretHash: hashAPIKeySecret(secret),
+		CreatedAt:  clock.GetUTCTime().Unix(),
+	}
+	return key, fmt.Sprintf("%s.%s", key.ID, secret), nil
+}
+
+//CreateAPIKey creates a key of a game with the given scopes and returns it with its token
+func CreateAPIKey(gameID, name string, scopes []string, clock Clock, db *mgo.Database, logger zap.Logger) (*APIKey, string, error) {
+	l := logger.With(
+		zap.String("source", "APIKeyModel"),
+		zap.String("operation", "CreateAPIKey"),
+		zap.String("gameID", gameID),
+		zap.String("name", name),
+	)
+
+	key, token, err := newAPIKey(gameID, name, scopes, clock)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = observeMongo("apiKeys", "insert", func() error {
+		return GetAPIKeysCollection(db).Insert(key)
+	})
+	if err != nil {
+		log.E(l, "Failed to create API key.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return nil, "", err
+	}
+
+	log.D(l, "API key created successfully.", func(cm log.CM) {
+		cm.Write(zap.String("keyID", key.ID))
+	})
+	return key, token, nil
+}
+
+//GetAPIKeys returns the API keys of a game, including rotated keys still in their grace period
+func GetAPIKeys(gameID string, db *mgo.Database, logger zap.Logger) ([]*APIKey, error) {
+	keys := []*APIKey{}
+	err := observeMongo("apiKeys", "find", func() error {
+		return GetAPIKeysCollection(db).Find(bson.M{"gameID": gameID}).Sort("createdAt").All(&keys)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+//GetAPIKeyByID retrieves an API key of a game by its id
+func GetAPIKeyByID(gameID, id string, db *mgo.Database, logger zap.Logger) (*APIKey, error) {
+	var key APIKey
+	err := observeMongo("apiKeys", "find", func() error {
+		return GetAPIKeysCollection(db).Find(bson.M{"_id": id, "gameID": gameID}).One(&key)
+	})
+	if err != nil {
+		if err.Error() == NotFoundString {
+			return nil, errors.NewDocumentNotFoundError("apiKeys", id)
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+//RevokeAPIKey removes an API key of a game, so its token stops working immediately
+func RevokeAPIKey(gameID, id string, db *mgo.Database, logger zap.Logger) error {
+	err := observeMongo("apiKeys", "remove", func() error {
+		return GetAPIKeysCollection(db).Remove(bson.M{"_id": id, "gameID": gameID})
+	})
+	if err != nil {
+		if err.Error() == NotFoundString {
+			return errors.NewDocumentNotFoundError("apiKeys", id)
+		}
+		return err
+	}
+	return nil
+}
+
+//RotateAPIKey creates a new key with the name and scopes of an existing one and makes the
+//existing key expire after graceSeconds, so clients can switch tokens without downtime
+func RotateAPIKey(gameID, id string, graceSeconds int, clock Clock, db *mgo.Database, logger zap.Logger) (*APIKey, string, error) {
+	l := logger.With(
+		zap.String("source", "APIKeyModel"),
+		zap.String("operation", "RotateAPIKey"),
+		zap.String("gameID", gameID),
+		zap.String("keyID", id),
+	)
+
+	current, err := GetAPIKeyByID(gameID, id, db, logger)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key, token, err := CreateAPIKey(gameID, current.Name, current.Scopes, clock, db, logger)
+	if err != nil {
+		return nil, "", err
+	}
+
+	expiresAt := clock.GetUTCTime().Unix() + int64(graceSeconds)
+	if current.ExpiresAt > 0 && current.ExpiresAt < expiresAt {
+		expiresAt = current.ExpiresAt
+	}
+	err = observeMongo("apiKeys", "update", func() error {
+		return GetAPIKeysCollection(db).UpdateId(id, bson.M{"$set": bson.M{"expiresAt": expiresAt}})
+	})
+	if err != nil {
+		log.E(l, "Failed to expire rotated API key.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return nil, "", err
+	}
+
+	log.D(l, "API key rotated successfully.", func(cm log.CM) {
+		cm.Write(zap.String("newKeyID", key.ID), zap.Int64("expiresAt", expiresAt))
+	})
+	return key, token, nil
+}
+
+//AuthenticateAPIKey returns the API key of a token, failing with InvalidAPIKeyError if the key
+//does not exist, has expired or the secret does not match
+func AuthenticateAPIKey(token string, clock Clock, db *mgo.Database, logger zap.Logger) (*APIKey, error) {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return nil, &errors.InvalidAPIKeyError{}
+	}
+
+	var key APIKey
+	err := observeMongo("apiKeys", "find", func() error {
+		return GetAPIKeysCollection(db).FindId(parts[0]).One(&key)
+	})
+	if err != nil {
+		if err.Error() == NotFoundString {
+			return nil, &errors.InvalidAPIKeyError{}
+		}
+		return nil, err
+	}
+
+	hash := hashAPIKeySecret(parts[1])
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(key.SecretHash)) != 1 || key.IsExpired(clock) {
+		return nil, &errors.InvalidAPIKeyError{}
+	}
+	return &key, nil
+}
@@ -0,0 +1,126 @@
+package models_test
+
+import (
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("API Key Model", func() {
+	var logger zap.Logger
+	var session *mgo.Session
+	var db *mgo.Database
+	var game *models.Game
+	var clock *MockClock
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		session, db = GetTestMongoDB()
+		clock = &MockClock{Time: time.Now().UTC().Unix()}
+
+		var err error
+		game, err = GetTestGame(db, logger, true)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		session.Close()
+	})
+
+	Describe("Create and authenticate", func() {
+		It("Should authenticate the token of a key", func() {
+			key, token, err := models.CreateAPIKey(game.ID, "server", []string{models.APIKeyScopeClient}, clock, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key.SecretHash).NotTo(BeEmpty())
+			Expect(token).To(HavePrefix(key.ID + "."))
+			Expect(token).NotTo(ContainSubstring(key.SecretHash))
+
+			authenticated, err := models.AuthenticateAPIKey(token, clock, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(authenticated.ID).To(Equal(key.ID))
+			Expect(authenticated.GameID).To(Equal(game.ID))
+			Expect(authenticated.HasScope(models.APIKeyScopeClient)).To(BeTrue())
+			Expect(authenticated.HasScope(models.APIKeyScopeAdmin)).To(BeFalse())
+		})
+
+		It("Should fail for invalid tokens", func() {
+			key, _, err := models.CreateAPIKey(game.ID, "server", []string{models.APIKeyScopeClient}, clock, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, token := range []string{"", "invalid", key.ID, key.ID + ".wrong-secret", "unknown.secret"} {
+				_, err = models.AuthenticateAPIKey(token, clock, db, logger)
+				Expect(err).To(Equal(&errors.InvalidAPIKeyError{}), token)
+			}
+		})
+	})
+
+	Describe("Rotate", func() {
+		It("Should keep the old key working during the grace period", func() {
+			key, token, err := models.CreateAPIKey(game.ID, "server", []string{models.APIKeyScopeAdmin}, clock, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			rotated, rotatedToken, err := models.RotateAPIKey(game.ID, key.ID, 60, clock, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rotated.ID).NotTo(Equal(key.ID))
+			Expect(rotated.Name).To(Equal("server"))
+			Expect(rotated.Scopes).To(Equal([]string{models.APIKeyScopeAdmin}))
+
+			_, err = models.AuthenticateAPIKey(token, clock, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = models.AuthenticateAPIKey(rotatedToken, clock, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			clock.Time += 60
+			_, err = models.AuthenticateAPIKey(token, clock, db, logger)
+			Expect(err).To(Equal(&errors.InvalidAPIKeyError{}))
+			_, err = models.AuthenticateAPIKey(rotatedToken, clock, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			keys, err := models.GetAPIKeys(game.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keys).To(HaveLen(2))
+			for _, k := range keys {
+				if k.ID == key.ID {
+					Expect(k.ExpiresAt).To(Equal(clock.Time))
+				} else {
+					Expect(k.ExpiresAt).To(BeZero())
+				}
+			}
+		})
+
+		It("Should fail for keys of other games", func() {
+			key, _, err := models.CreateAPIKey(game.ID, "server", []string{models.APIKeyScopeAdmin}, clock, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, _, err = models.RotateAPIKey("other-game", key.ID, 60, clock, db, logger)
+			Expect(err).To(Equal(errors.NewDocumentNotFoundError("apiKeys", key.ID)))
+		})
+	})
+
+	Describe("Revoke", func() {
+		It("Should stop the token from working", func() {
+			key, token, err := models.CreateAPIKey(game.ID, "server", []string{models.APIKeyScopeClient}, clock, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = models.RevokeAPIKey(game.ID, key.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = models.AuthenticateAPIKey(token, clock, db, logger)
+			Expect(err).To(Equal(&errors.InvalidAPIKeyError{}))
+
+			err = models.RevokeAPIKey(game.ID, key.ID, db, logger)
+			Expect(err).To(Equal(errors.NewDocumentNotFoundError("apiKeys", key.ID)))
+		})
+	})
+})
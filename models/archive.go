@@ -0,0 +1,199 @@
+package models
+
+import (
+	"time"
+
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/log"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+//ArchiveResult represents the outcome of an archive run
+type ArchiveResult struct {
+	Archived int `json:"archived"`
+	Skipped  int `json:"skipped"`
+}
+
+//GetArchivedDonationRequestsCollection to query archived donation requests
+func GetArchivedDonationRequestsCollection(db *mgo.Database) *mgo.Collection {
+	return db.C("archivedRequests")
+}
+
+//GetDonationRequestFromHistory retrieves a donation request by its id, looking in the archive
+//if it is not a live donation request anymore
+func GetDonationRequestFromHistory(id string, db *mgo.Database, logger zap.Logger) (*DonationRequest, error) {
+	donationRequest, err := GetDonationRequestByID(id, db, logger)
+	if _, ok := err.(*errors.DocumentNotFoundError); !ok {
+		return donationRequest, err
+	}
+
+	var archived DonationRequest
+	err = observeMongo("archivedRequests", "find", func() error {
+		return GetArchivedDonationRequestsCollection(db).FindId(id).One(&archived)
+	})
+	if err != nil {
+		if err.Error() == NotFoundString {
+			return nil, errors.NewDocumentNotFoundError("donationRequest", id)
+		}
+		return nil, err
+	}
+	archived.Clock = &RealClock{}
+	return &archived, nil
+}
+
+//ExpireDonationRequests marks the donation requests (optionally only the ones for a game) that were not finished
+//within expiration of their creation as expired and emits their expired event. Expired donation requests are
+//archived once they are not updated for archive.maxAgeHours. It returns how many requests were expired.
+func ExpireDonationRequests(
+	gameID string, expiration time.Duration, clock Clock, db *mgo.Database, logger zap.Logger,
+) (int, error) {
+	l := logger.With(
+		zap.String("source", "ArchiveModel"),
+		zap.String("operation", "ExpireDonationRequests"),
+		zap.String("gameID", gameID),
+	)
+	if expiration <= 0 {
+		return 0, nil
+	}
+
+	now := clock.GetUTCTime()
+	query := bson.M{
+		"finishedAt": bson.M{"$exists": false},
+		"expiredAt":  bson.M{"$exists": false},
+		"createdAt":  bson.M{"$lte": now.Add(-expiration).Unix()},
+	}
+	if gameID != "" {
+		query["gameID"] = gameID
+	}
+
+	requests := GetDonationRequestsCollection(db)
+	expired := 0
+	iter := requests.Find(query).Iter()
+	var donationRequest DonationRequest
+	for iter.Next(&donationRequest) {
+		//Only expire the request if nobody donated to it since it was read, as the donation may have finished it
+		err := requests.Update(
+			bson.M{"_id": donationRequest.ID, "version": donationRequest.getVersionQuery()},
+			bson.M{
+				"$set": bson.M{"expiredAt": now.Unix(), "updatedAt": now.Unix()},
+				"$inc": bson.M{"version": 1},
+			},
+		)
+		if err == nil {
+			expired++
+			donationRequest.ExpiredAt = now.Unix()
+			donationRequest.UpdatedAt = now.Unix()
+			donationRequest.Version++
+			emitEvent(donationRequest.GameID, DonationRequestExpiredEvent, donationRequest.ToJSON, clock, db, l)
+		} else if err != mgo.ErrNotFound {
+			iter.Close()
+			log.E(l, "Failed to expire donation request.", func(cm log.CM) {
+				cm.Write(zap.String("donationRequestID", donationRequest.ID), zap.Error(err))
+			})
+			return expired, err
+		}
+
+		donationRequest = DonationRequest{}
+	}
+
+	if err := iter.Close(); err != nil {
+		log.E(l, "Failed to expire donation requests.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return expired, err
+	}
+
+	log.D(l, "Donation requests expired successfully.", func(cm log.CM) {
+		cm.Write(zap.Int("expired", expired))
+	})
+	return expired, nil
+}
+
+//ArchiveDonationRequests moves the donation requests (optionally only the ones for a game) that
+//were not updated for longer than maxAge to the archive. Those are the requests that were finished
+//and the ones marked as expired by ExpireDonationRequests. Requests that were not expired are not
+//archived, since they can still receive donations. If dryRun is true, requests are only counted.
+func ArchiveDonationRequests(
+	gameID string, maxAge time.Duration, dryRun bool,
+	clock Clock, db *mgo.Database, logger zap.Logger,
+) (*ArchiveResult, error) {
+	l := logger.With(
+		zap.String("source", "ArchiveModel"),
+		zap.String("operation", "ArchiveDonationRequests"),
+		zap.String("gameID", gameID),
+		zap.Bool("dryRun", dryRun),
+	)
+
+	now := clock.GetUTCTime()
+	query := bson.M{
+		"$or": []bson.M{
+			bson.M{"finishedAt": bson.M{"$exists": true}},
+			bson.M{"expiredAt": bson.M{"$exists": true}},
+		},
+		"updatedAt": bson.M{"$lte": now.Add(-maxAge).Unix()},
+	}
+	if gameID != "" {
+		query["gameID"] = gameID
+	}
+
+	requests := GetDonationRequestsCollection(db)
+	archive := GetArchivedDonationRequestsCollection(db)
+	result := &ArchiveResult{}
+
+	iter := requests.Find(query).Iter()
+	var donationRequest bson.M
+	for iter.Next(&donationRequest) {
+		if dryRun {
+			result.Archived++
+			donationRequest = bson.M{}
+			continue
+		}
+
+		id := donationRequest["_id"]
+		donationRequest["archivedAt"] = now.Unix()
+		_, err := archive.UpsertId(id, donationRequest)
+		if err != nil {
+			iter.Close()
+			log.E(l, "Failed to archive donation request.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return result, err
+		}
+
+		//Only remove the request if nobody donated to it since it was read
+		err = requests.Remove(bson.M{"_id": id, "version": donationRequest["version"]})
+		if err == mgo.ErrNotFound {
+			result.Skipped++
+			err = archive.RemoveId(id)
+		} else if err == nil {
+			result.Archived++
+		}
+		if err != nil {
+			iter.Close()
+			log.E(l, "Failed to remove archived donation request.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return result, err
+		}
+
+		donationRequest = bson.M{}
+	}
+
+	if err := iter.Close(); err != nil {
+		log.E(l, "Failed to archive donation requests.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return result, err
+	}
+
+	log.I(l, "Donation requests archived successfully.", func(cm log.CM) {
+		cm.Write(
+			zap.Int("archived", result.Archived),
+			zap.Int("skipped", result.Skipped),
+		)
+	})
+
+	return result, nil
+}
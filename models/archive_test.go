@@ -0,0 +1,189 @@
+package models_test
+
+import (
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/garyburd/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Archive Model", func() {
+	var logger zap.Logger
+	var session *mgo.Session
+	var db *mgo.Database
+	var r *redis.Pool
+	var game *models.Game
+	var player *models.Player
+	var dr *models.DonationRequest
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		session, db = GetTestMongoDB()
+		r = GetTestRedis()
+
+		var err error
+		game, err = GetTestGame(db, logger, true, map[string]interface{}{
+			"LimitOfItemsInEachDonationRequest": 2,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		player, err = GetTestPlayer(game, db, logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		dr, err = GetTestDonationRequest(game, db, logger)
+		Expect(err).NotTo(HaveOccurred())
+		dr.Clock = &MockClock{Time: 100}
+
+		err = dr.Donate(player.ID, 2, 100, r, db, logger)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		session.Close()
+		session = nil
+		db = nil
+	})
+
+	Describe("Archiving donation requests", func() {
+		It("Should archive donation requests older than max age", func() {
+			result, err := models.ArchiveDonationRequests(game.ID, time.Hour, false, &MockClock{Time: 3800}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Archived).To(Equal(1))
+			Expect(result.Skipped).To(Equal(0))
+
+			_, err = models.GetDonationRequestByID(dr.ID, db, logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("not found"))
+
+			archived, err := models.GetDonationRequestFromHistory(dr.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(archived.ID).To(Equal(dr.ID))
+			Expect(archived.FinishedAt).To(BeEquivalentTo(100))
+			Expect(archived.Donations).To(HaveLen(1))
+		})
+
+		It("Should not archive recently updated donation requests", func() {
+			result, err := models.ArchiveDonationRequests(game.ID, time.Hour, false, &MockClock{Time: 200}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Archived).To(Equal(0))
+
+			_, err = models.GetDonationRequestByID(dr.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should only archive unfinished donation requests after they are expired", func() {
+			unfinished, err := GetTestDonationRequest(game, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			unfinished.Clock = &MockClock{Time: 100}
+			err = unfinished.Donate(player.ID, 1, 100, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			now := time.Now().UTC().Unix()
+			result, err := models.ArchiveDonationRequests(game.ID, time.Hour, false, &MockClock{Time: now + 49*3600}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Archived).To(Equal(1))
+
+			_, err = models.GetDonationRequestByID(unfinished.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			expired, err := models.ExpireDonationRequests(game.ID, 48*time.Hour, &MockClock{Time: now + 49*3600}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(expired).To(Equal(1))
+
+			//expired requests are archived when they are not updated for max age after expiring
+			result, err = models.ArchiveDonationRequests(game.ID, time.Hour, false, &MockClock{Time: now + 49*3600}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Archived).To(Equal(0))
+
+			result, err = models.ArchiveDonationRequests(game.ID, time.Hour, false, &MockClock{Time: now + 50*3600}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Archived).To(Equal(1))
+
+			archived, err := models.GetDonationRequestFromHistory(unfinished.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(archived.ExpiredAt).To(Equal(now + 49*3600))
+		})
+	})
+
+	Describe("Expiring donation requests", func() {
+		It("Should only expire unfinished donation requests once", func() {
+			unfinished, err := GetTestDonationRequest(game, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			now := time.Now().UTC().Unix()
+			expired, err := models.ExpireDonationRequests(game.ID, 48*time.Hour, &MockClock{Time: now}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(expired).To(Equal(0))
+
+			expired, err = models.ExpireDonationRequests(game.ID, 48*time.Hour, &MockClock{Time: now + 49*3600}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(expired).To(Equal(1))
+
+			expired, err = models.ExpireDonationRequests(game.ID, 48*time.Hour, &MockClock{Time: now + 50*3600}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(expired).To(Equal(0))
+
+			donationRequest, err := models.GetDonationRequestByID(unfinished.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(donationRequest.ExpiredAt).To(Equal(now + 49*3600))
+			Expect(donationRequest.IsExpired(0)).To(BeTrue())
+
+			finished, err := models.GetDonationRequestByID(dr.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(finished.ExpiredAt).To(BeEquivalentTo(0))
+		})
+
+		It("Should only count donation requests in dry run", func() {
+			result, err := models.ArchiveDonationRequests(game.ID, time.Hour, true, &MockClock{Time: 3800}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Archived).To(Equal(1))
+
+			_, err = models.GetDonationRequestByID(dr.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			count, err := models.GetArchivedDonationRequestsCollection(db).FindId(dr.ID).Count()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(count).To(Equal(0))
+		})
+	})
+
+	Describe("Reading archived donation requests", func() {
+		It("Should include archived donations in the player donation weight", func() {
+			_, err := models.ArchiveDonationRequests(game.ID, time.Hour, false, &MockClock{Time: 3800}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			weight, err := models.GetDonationWeightForPlayer(game.ID, player.ID, 0, 3800, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(weight).To(Equal(dr.Donations[0].Weight))
+		})
+
+		It("Should count donation requests being archived only once in the player donation weight", func() {
+			var donationRequest bson.M
+			err := models.GetDonationRequestsCollection(db).FindId(dr.ID).One(&donationRequest)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = models.GetArchivedDonationRequestsCollection(db).UpsertId(dr.ID, donationRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			weight, err := models.GetDonationWeightForPlayer(game.ID, player.ID, 0, 3800, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(weight).To(Equal(dr.Donations[0].Weight))
+		})
+
+		It("Should fail to get a donation request that does not exist", func() {
+			_, err := models.GetDonationRequestFromHistory("invalid-id", db, logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("not found"))
+		})
+	})
+})
@@ -0,0 +1,294 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/log"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	//AuditUpdateGame is the action of updating a game's configuration
+	AuditUpdateGame = "updateGame"
+	//AuditUpsertItem is the action of creating or updating an item of a game
+	AuditUpsertItem = "upsertItem"
+	//AuditRollback is the action of restoring a game to a previous version
+	AuditRollback = "rollback"
+	//AuditImportConfig is the action of applying a configuration file to a game
+	AuditImportConfig = "importConfig"
+)
+
+//Number of times a version is retried when another change of the same game takes it first
+const maxAuditVersionAttempts = 5
+
+//AuditChange is a single field that changed in a game's configuration.
+//Before is nil for created fields and After is nil for removed fields.
+type AuditChange struct {
+	Field  string      `json:"field" bson:"field"`
+	Before interface{} `json:"before" bson:"before"`
+	After  interface{} `json:"after" bson:"after"`
+}
+
+//AuditEntry records a change to a game's configuration. Version increases with each change of the game
+//and After is the game as it was in that version.
+type AuditEntry struct {
+	ID      string `json:"id" bson:"_id"`
+	GameID  string `json:"gameID" bson:"gameID"`
+	Version int    `json:"version" bson:"version"`
+	//Actor is the authenticated identity that made the change, an operator or an API key
+	Actor string `json:"actor" bson:"actor"`
+	//ClaimedActor is who the caller said it acted for. Unlike Actor, it is not authenticated.
+	ClaimedActor string         `json:"claimedActor,omitempty" bson:"claimedActor,omitempty"`
+	Action       string         `json:"action" bson:"action"`
+	RollbackOf   int            `json:"rollbackOf,omitempty" bson:"rollbackOf,omitempty"`
+	Changes      []*AuditChange `json:"changes" bson:"changes"`
+	Before       *Game          `json:"before" bson:"before"`
+	After        *Game          `json:"after" bson:"after"`
+	CreatedAt    int64          `json:"createdAt" bson:"createdAt"`
+}
+
+//GetAuditLogCollection to update or query the audit log
+func GetAuditLogCollection(db *mgo.Database) *mgo.Collection {
+	return db.C("auditLog")
+}
+
+func sameJSON(a, b interface{}) bool {
+	//Metadata read from mongo has bson types, so values are compared by their JSON representation
+	aJSON, aErr := json.Marshal(a)
+	bJSON, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && string(aJSON) == string(bJSON)
+}
+
+func diffItems(key string, before, after *Item) []*AuditChange {
+	field := fmt.Sprintf("items.%s", key)
+	if before == nil || after == nil {
+		var b, a interface{}
+		if before != nil {
+			b = before
+		}
+		if after != nil {
+			a = after
+		}
+		return []*AuditChange{&AuditChange{Field: field, Before: b, After: a}}
+	}
+
+	changes := []*AuditChange{}
+	add := func(name string, b, a interface{}) {
+		if !sameJSON(b, a) {
+			changes = append(changes, &AuditChange{Field: fmt.Sprintf("%s.%s", field, name), Before: b, After: a})
+		}
+	}
+	add("metadata", before.Metadata, after.Metadata)
+	add("limitOfItemsInEachDonationRequest", before.LimitOfItemsInEachDonationRequest, after.LimitOfItemsInEachDonationRequest)
+	add("limitOfItemsPerPlayerDonation", before.LimitOfItemsPerPlayerDonation, after.LimitOfItemsPerPlayerDonation)
+	add("weightPerDonation", before.WeightPerDonation, after.WeightPerDonation)
+	return changes
+}
+
+//DiffGames returns the configuration fields that changed between two versions of a game.
+//before is nil if the game was created. Update timestamps are not considered changes.
+func DiffGames(before, after *Game) []*AuditChange {
+	if before == nil {
+		before = &Game{Items: map[string]Item{}}
+	}
+
+	changes := []*AuditChange{}
+	add := func(name string, b, a interface{}) {
+		if !sameJSON(b, a) {
+			changes = append(changes, &AuditChange{Field: name, Before: b, After: a})
+		}
+	}
+	add("name", before.Name, after.Name)
+	add("donationCooldownHours", before.DonationCooldownHours, after.DonationCooldownHours)
+	add("donationRequestCooldownHours", before.DonationRequestCooldownHours, after.DonationRequestCooldownHours)
+
+	keys := []string{}
+	for key := range before.Items {
+		keys = append(keys, key)
+	}
+	for key := range after.Items {
+		if _, ok := before.Items[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var b, a *Item
+		if item, ok := before.Items[key]; ok {
+			b = &item
+		}
+		if item, ok := after.Items[key]; ok {
+			a = &item
+		}
+		changes = append(changes, diffItems(key, b, a)...)
+	}
+	return changes
+}
+
+func getLastAuditVersion(gameID string, db *mgo.Database) (int, error) {
+	var last AuditEntry
+	err := observeMongo("auditLog", "find", func() error {
+		return GetAuditLogCollection(db).Find(bson.M{"gameID": gameID}).Sort("-version").One(&last)
+	})
+	if err == mgo.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return last.Version, nil
+}
+
+//RecordAuditEntry records the change of a game made by actor, on behalf of claimedActor if not empty,
+//as its next version. before is nil if the game was created. Returns nil if nothing changed.
+func RecordAuditEntry(
+	gameID, actor, claimedActor, action string,
+	before, after *Game,
+	clock Clock, db *mgo.Database, logger zap.Logger,
+) (*AuditEntry, error) {
+	return recordAuditEntry(gameID, actor, claimedActor, action, 0, before, after, clock, db, logger)
+}
+
+func recordAuditEntry(
+	gameID, actor, claimedActor, action string, rollbackOf int,
+	before, after *Game,
+	clock Clock, db *mgo.Database, logger zap.Logger,
+) (*AuditEntry, error) {
+	l := logger.With(
+		zap.String("source", "AuditModel"),
+		zap.String("operation", "RecordAuditEntry"),
+		zap.String("gameID", gameID),
+		zap.String("action", action),
+		zap.String("actor", actor),
+	)
+
+	changes := DiffGames(before, after)
+	if len(changes) == 0 {
+		log.D(l, "Game did not change, skipping audit entry.")
+		return nil, nil
+	}
+
+	entry := &AuditEntry{
+		ID:           uuid.NewV4().String(),
+		GameID:       gameID,
+		Actor:        actor,
+		ClaimedActor: claimedActor,
+		Action:       action,
+		RollbackOf:   rollbackOf,
+		Changes:      changes,
+		Before:       before,
+		After:        after,
+		CreatedAt:    clock.GetUTCTime().Unix(),
+	}
+
+	var err error
+	for attempt := 0; attempt < maxAuditVersionAttempts; attempt++ {
+		var last int
+		last, err = getLastAuditVersion(gameID, db)
+		if err != nil {
+			break
+		}
+		entry.Version = last + 1
+
+		//The unique index on gameID and version fails the insert if another change took the version
+		err = observeMongo("auditLog", "insert", func() error {
+			return GetAuditLogCollection(db).Insert(entry)
+		})
+		if err == nil || !mgo.IsDup(err) {
+			break
+		}
+	}
+	if err != nil {
+		log.E(l, "Failed to record audit entry.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return nil, err
+	}
+
+	log.D(l, "Audit entry recorded successfully.", func(cm log.CM) {
+		cm.Write(zap.Int("version", entry.Version), zap.Int("changes", len(changes)))
+	})
+	return entry, nil
+}
+
+//GetAuditLog returns up to limit audit entries of a game, newest first
+func GetAuditLog(gameID string, limit int, db *mgo.Database, logger zap.Logger) ([]*AuditEntry, error) {
+	entries := []*AuditEntry{}
+	err := observeMongo("auditLog", "find", func() error {
+		return GetAuditLogCollection(db).Find(bson.M{"gameID": gameID}).Sort("-version").Limit(limit).All(&entries)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+//GetAuditEntry retrieves a version of a game from the audit log
+func GetAuditEntry(gameID string, version int, db *mgo.Database, logger zap.Logger) (*AuditEntry, error) {
+	var entry AuditEntry
+	err := observeMongo("auditLog", "find", func() error {
+		return GetAuditLogCollection(db).Find(bson.M{"gameID": gameID, "version": version}).One(&entry)
+	})
+	if err != nil {
+		if err.Error() == NotFoundString {
+			return nil, errors.NewDocumentNotFoundError("auditLog", strconv.Itoa(version))
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+//RollbackGame restores the configuration and items of a game to the given version. The rollback
+//is recorded in the audit log as a new version.
+func RollbackGame(
+	gameID string, version int, actor, claimedActor string,
+	clock Clock, db *mgo.Database, logger zap.Logger,
+) (*Game, *AuditEntry, error) {
+	l := logger.With(
+		zap.String("source", "AuditModel"),
+		zap.String("operation", "RollbackGame"),
+		zap.String("gameID", gameID),
+		zap.Int("version", version),
+	)
+
+	target, err := GetAuditEntry(gameID, version, db, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	game, err := GetGameByID(gameID, db, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	before := game.Clone()
+
+	game.Name = target.After.Name
+	game.DonationCooldownHours = target.After.DonationCooldownHours
+	game.DonationRequestCooldownHours = target.After.DonationRequestCooldownHours
+	game.Items = map[string]Item{}
+	for key, item := range target.After.Items {
+		game.Items[key] = item
+	}
+
+	log.D(l, "Rolling back game...")
+	err = game.Save(db, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entry, err := recordAuditEntry(gameID, actor, claimedActor, AuditRollback, version, before, game.Clone(), clock, db, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.I(l, "Game rolled back successfully.")
+	return game, entry, nil
+}
@@ -0,0 +1,165 @@
+package models_test
+
+import (
+	mgo "gopkg.in/mgo.v2"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Audit Model", func() {
+	var logger zap.Logger
+	var session *mgo.Session
+	var db *mgo.Database
+	var game *models.Game
+
+	fields := func(changes []*models.AuditChange) []string {
+		result := []string{}
+		for _, change := range changes {
+			result = append(result, change.Field)
+		}
+		return result
+	}
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		session, db = GetTestMongoDB()
+
+		var err error
+		game, err = GetTestGame(db, logger, true)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		session.Close()
+		session = nil
+		db = nil
+	})
+
+	Describe("Diffing games", func() {
+		It("Should return the changed fields and items", func() {
+			after := game.Clone()
+			after.DonationCooldownHours = game.DonationCooldownHours + 1
+			item := after.Items["item-1"]
+			item.WeightPerDonation = 10
+			item.Metadata = map[string]interface{}{"x": 100}
+			after.Items["item-1"] = item
+			delete(after.Items, "item-2")
+			after.Items["new-item"] = *models.NewItem("new-item", nil, 1, 1, 1)
+
+			changes := models.DiffGames(game, after)
+			Expect(fields(changes)).To(Equal([]string{
+				"donationCooldownHours",
+				"items.item-1.metadata",
+				"items.item-1.weightPerDonation",
+				"items.item-2",
+				"items.new-item",
+			}))
+			Expect(changes[0].Before).To(Equal(game.DonationCooldownHours))
+			Expect(changes[0].After).To(Equal(game.DonationCooldownHours + 1))
+			Expect(changes[3].After).To(BeNil())
+			Expect(changes[4].Before).To(BeNil())
+		})
+
+		It("Should not consider update timestamps as changes", func() {
+			after := game.Clone()
+			item := after.Items["item-1"]
+			item.UpdatedAt = item.UpdatedAt + 100
+			after.Items["item-1"] = item
+
+			Expect(models.DiffGames(game, after)).To(BeEmpty())
+		})
+	})
+
+	Describe("Recording changes", func() {
+		It("Should record increasing versions per game", func() {
+			first, err := models.RecordAuditEntry(
+				game.ID, "admin", "", models.AuditUpdateGame, nil, game,
+				&MockClock{Time: 100}, db, logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(first.Version).To(Equal(1))
+			Expect(first.Actor).To(Equal("admin"))
+			Expect(first.CreatedAt).To(BeEquivalentTo(100))
+
+			after := game.Clone()
+			after.Name = "other name"
+			second, err := models.RecordAuditEntry(
+				game.ID, "admin", "", models.AuditUpdateGame, game, after,
+				&MockClock{Time: 200}, db, logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(second.Version).To(Equal(2))
+			Expect(fields(second.Changes)).To(Equal([]string{"name"}))
+
+			entries, err := models.GetAuditLog(game.ID, 10, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[0].Version).To(Equal(2))
+			Expect(entries[0].After.Name).To(Equal("other name"))
+			Expect(entries[1].Version).To(Equal(1))
+		})
+
+		It("Should not record when nothing changed", func() {
+			entry, err := models.RecordAuditEntry(
+				game.ID, "admin", "", models.AuditUpdateGame, game, game.Clone(),
+				&MockClock{Time: 100}, db, logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry).To(BeNil())
+
+			entries, err := models.GetAuditLog(game.ID, 10, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(BeEmpty())
+		})
+	})
+
+	Describe("Rolling back", func() {
+		It("Should restore the game to a version", func() {
+			_, err := models.RecordAuditEntry(
+				game.ID, "admin", "", models.AuditUpdateGame, nil, game,
+				&MockClock{Time: 100}, db, logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			before := game.Clone()
+			game.DonationCooldownHours = 100
+			_, err = game.AddItem("new-item", nil, 1, 1, 1, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = models.RecordAuditEntry(
+				game.ID, "admin", "", models.AuditUpsertItem, before, game,
+				&MockClock{Time: 200}, db, logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			restored, entry, err := models.RollbackGame(game.ID, 1, "other", "jane", &MockClock{Time: 300}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(restored.DonationCooldownHours).To(Equal(before.DonationCooldownHours))
+			Expect(restored.Items).NotTo(HaveKey("new-item"))
+			Expect(entry.Version).To(Equal(3))
+			Expect(entry.Action).To(Equal(models.AuditRollback))
+			Expect(entry.RollbackOf).To(Equal(1))
+			Expect(entry.Actor).To(Equal("other"))
+			Expect(entry.ClaimedActor).To(Equal("jane"))
+
+			dbGame, err := models.GetGameByID(game.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dbGame.DonationCooldownHours).To(Equal(before.DonationCooldownHours))
+			Expect(dbGame.Items).To(HaveLen(len(before.Items)))
+		})
+
+		It("Should fail if the version does not exist", func() {
+			_, _, err := models.RollbackGame(game.ID, 1, "admin", "", &MockClock{Time: 100}, db, logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err).To(BeAssignableToTypeOf(&errors.DocumentNotFoundError{}))
+		})
+	})
+})
@@ -0,0 +1,275 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/topfreegames/donations/log"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	//MissingStandaloneDonation means the donation is embedded in the request but not in the donations collection
+	MissingStandaloneDonation = "missing-standalone-donation"
+	//MissingEmbeddedDonation means the donation is in the donations collection but not embedded in the request
+	MissingEmbeddedDonation = "missing-embedded-donation"
+	//MismatchedDonation means both copies of the donation exist but differ
+	MismatchedDonation = "mismatched-donation"
+	//UnexpectedFinishedAt means the request is finished but has not reached the item limit
+	UnexpectedFinishedAt = "unexpected-finished-at"
+	//MissingFinishedAt means the request reached the item limit but is not finished
+	MissingFinishedAt = "missing-finished-at"
+)
+
+//DonationDiscrepancy represents an inconsistency between a donation request and the donations collection
+type DonationDiscrepancy struct {
+	Type              string    `json:"type"`
+	GameID            string    `json:"gameID"`
+	DonationRequestID string    `json:"donationRequestID"`
+	DonationID        string    `json:"donationID,omitempty"`
+	Embedded          *Donation `json:"embedded,omitempty"`
+	Standalone        *Donation `json:"standalone,omitempty"`
+	DonationCount     int       `json:"donationCount,omitempty"`
+	Limit             int       `json:"limit,omitempty"`
+	FinishedAt        int64     `json:"finishedAt,omitempty"`
+	Repaired          bool      `json:"repaired"`
+}
+
+func isSameDonation(embedded, standalone *Donation) bool {
+	return embedded.GameID == standalone.GameID &&
+		embedded.Clan == standalone.Clan &&
+		embedded.Player == standalone.Player &&
+		embedded.Amount == standalone.Amount &&
+		embedded.Weight == standalone.Weight &&
+		embedded.CreatedAt == standalone.CreatedAt
+}
+
+//VerifyDonationRequest compares the donations embedded in the request with the ones in the
+//donations collection and validates the request's FinishedAt against its item limit.
+//
+//A donation is only valid when both copies exist: the write path only succeeds after writing
+//both of them, so a single copy is a leftover of a failed (and reported) donation.
+//Donations created after maxCreatedAt may still be being written, so they are not reported
+//and are counted as valid.
+func VerifyDonationRequest(
+	d *DonationRequest, game *Game, maxCreatedAt int64, db *mgo.Database, logger zap.Logger,
+) ([]*DonationDiscrepancy, error) {
+	var standalone []Donation
+	err := GetDonationsCollection(db).Find(bson.M{"donationRequestID": d.ID}).All(&standalone)
+	if err != nil {
+		return nil, err
+	}
+
+	standaloneByID := map[string]*Donation{}
+	for i := range standalone {
+		standaloneByID[standalone[i].ID] = &standalone[i]
+	}
+
+	discrepancies := []*DonationDiscrepancy{}
+	embeddedIDs := map[string]bool{}
+	validCount := 0
+	var lastDonationAt int64
+	for i := range d.Donations {
+		embedded := &d.Donations[i]
+		embeddedIDs[embedded.ID] = true
+
+		other, ok := standaloneByID[embedded.ID]
+		if !ok && embedded.CreatedAt > maxCreatedAt {
+			validCount += embedded.Amount
+			continue
+		}
+		if !ok {
+			discrepancies = append(discrepancies, &DonationDiscrepancy{
+				Type:              MissingStandaloneDonation,
+				GameID:            d.GameID,
+				DonationRequestID: d.ID,
+				DonationID:        embedded.ID,
+				Embedded:          embedded,
+			})
+			continue
+		}
+
+		validCount += embedded.Amount
+		if embedded.CreatedAt > lastDonationAt {
+			lastDonationAt = embedded.CreatedAt
+		}
+
+		if !isSameDonation(embedded, other) {
+			discrepancies = append(discrepancies, &DonationDiscrepancy{
+				Type:              MismatchedDonation,
+				GameID:            d.GameID,
+				DonationRequestID: d.ID,
+				DonationID:        embedded.ID,
+				Embedded:          embedded,
+				Standalone:        other,
+			})
+		}
+	}
+
+	for i := range standalone {
+		if embeddedIDs[standalone[i].ID] || standalone[i].CreatedAt > maxCreatedAt {
+			continue
+		}
+		discrepancies = append(discrepancies, &DonationDiscrepancy{
+			Type:              MissingEmbeddedDonation,
+			GameID:            d.GameID,
+			DonationRequestID: d.ID,
+			DonationID:        standalone[i].ID,
+			Standalone:        &standalone[i],
+		})
+	}
+
+	//Items removed from the game have no limit to validate against
+	item, ok := game.Items[d.Item]
+	if !ok {
+		return discrepancies, nil
+	}
+
+	limit := item.LimitOfItemsInEachDonationRequest
+	if d.FinishedAt != 0 && validCount < limit {
+		discrepancies = append(discrepancies, &DonationDiscrepancy{
+			Type:              UnexpectedFinishedAt,
+			GameID:            d.GameID,
+			DonationRequestID: d.ID,
+			DonationCount:     validCount,
+			Limit:             limit,
+		})
+	}
+	if d.FinishedAt == 0 && validCount >= limit {
+		discrepancies = append(discrepancies, &DonationDiscrepancy{
+			Type:              MissingFinishedAt,
+			GameID:            d.GameID,
+			DonationRequestID: d.ID,
+			DonationCount:     validCount,
+			Limit:             limit,
+			FinishedAt:        lastDonationAt,
+		})
+	}
+
+	return discrepancies, nil
+}
+
+//RepairDonationDiscrepancy fixes the given discrepancy, removing leftover copies of failed donations,
+//making the standalone copy match the embedded one and fixing the request's FinishedAt. Changes to a request
+//increment its version, so donations and archiving that read it before the repair don't overwrite it.
+func RepairDonationDiscrepancy(discrepancy *DonationDiscrepancy, db *mgo.Database, logger zap.Logger) error {
+	l := logger.With(
+		zap.String("source", "ConsistencyModel"),
+		zap.String("operation", "RepairDonationDiscrepancy"),
+		zap.String("type", discrepancy.Type),
+		zap.String("donationRequestID", discrepancy.DonationRequestID),
+		zap.String("donationID", discrepancy.DonationID),
+	)
+
+	var err error
+	switch discrepancy.Type {
+	case MissingStandaloneDonation:
+		err = GetDonationRequestsCollection(db).UpdateId(
+			discrepancy.DonationRequestID,
+			bson.M{
+				"$pull": bson.M{"donations": bson.M{"_id": discrepancy.DonationID}},
+				"$inc":  bson.M{"version": 1},
+			},
+		)
+	case MissingEmbeddedDonation:
+		err = GetDonationsCollection(db).RemoveId(discrepancy.DonationID)
+	case MismatchedDonation:
+		donation := *discrepancy.Embedded
+		donation.DonationRequestID = discrepancy.DonationRequestID
+		err = GetDonationsCollection(db).UpdateId(discrepancy.DonationID, donation)
+	case UnexpectedFinishedAt:
+		err = GetDonationRequestsCollection(db).UpdateId(
+			discrepancy.DonationRequestID,
+			bson.M{"$unset": bson.M{"finishedAt": ""}, "$inc": bson.M{"version": 1}},
+		)
+	case MissingFinishedAt:
+		err = GetDonationRequestsCollection(db).UpdateId(
+			discrepancy.DonationRequestID,
+			bson.M{"$set": bson.M{"finishedAt": discrepancy.FinishedAt}, "$inc": bson.M{"version": 1}},
+		)
+	default:
+		err = fmt.Errorf("Unknown discrepancy type %s.", discrepancy.Type)
+	}
+
+	if err != nil {
+		log.E(l, "Failed to repair discrepancy.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return err
+	}
+
+	discrepancy.Repaired = true
+	log.D(l, "Discrepancy repaired successfully.")
+	return nil
+}
+
+//VerifyDonationRequests verifies all donation requests (optionally only the ones for a game),
+//calling report for each discrepancy found. If repair is true, discrepancies are fixed before being reported.
+//Donations newer than minAgeSeconds are skipped, so donations being written are not removed.
+func VerifyDonationRequests(
+	gameID string, repair bool, minAgeSeconds int,
+	clock Clock, db *mgo.Database, logger zap.Logger,
+	report func(*DonationDiscrepancy) error,
+) error {
+	l := logger.With(
+		zap.String("source", "ConsistencyModel"),
+		zap.String("operation", "VerifyDonationRequests"),
+		zap.String("gameID", gameID),
+		zap.Bool("repair", repair),
+	)
+
+	maxCreatedAt := clock.GetUTCTime().Unix() - int64(minAgeSeconds)
+	query := bson.M{}
+	if gameID != "" {
+		query["gameID"] = gameID
+	}
+
+	games := map[string]*Game{}
+	iter := GetDonationRequestsCollection(db).Find(query).Iter()
+	var donationRequest DonationRequest
+	for iter.Next(&donationRequest) {
+		game, ok := games[donationRequest.GameID]
+		if !ok {
+			var err error
+			game, err = GetGameByID(donationRequest.GameID, db, logger)
+			if err != nil {
+				iter.Close()
+				return err
+			}
+			games[donationRequest.GameID] = game
+		}
+
+		discrepancies, err := VerifyDonationRequest(&donationRequest, game, maxCreatedAt, db, logger)
+		if err != nil {
+			iter.Close()
+			return err
+		}
+
+		for _, discrepancy := range discrepancies {
+			if repair {
+				err = RepairDonationDiscrepancy(discrepancy, db, logger)
+				if err != nil {
+					iter.Close()
+					return err
+				}
+			}
+			err = report(discrepancy)
+			if err != nil {
+				iter.Close()
+				return err
+			}
+		}
+
+		donationRequest = DonationRequest{}
+	}
+
+	if err := iter.Close(); err != nil {
+		log.E(l, "Failed to verify donation requests.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return err
+	}
+
+	return nil
+}
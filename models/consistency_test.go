@@ -0,0 +1,182 @@
+package models_test
+
+import (
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/garyburd/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	uuid "github.com/satori/go.uuid"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Consistency Model", func() {
+	var logger zap.Logger
+	var session *mgo.Session
+	var db *mgo.Database
+	var r *redis.Pool
+	var game *models.Game
+	var dr *models.DonationRequest
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		session, db = GetTestMongoDB()
+		r = GetTestRedis()
+
+		var err error
+		game, err = GetTestGame(db, logger, true, map[string]interface{}{
+			"LimitOfItemsInEachDonationRequest": 2,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		player, err := GetTestPlayer(game, db, logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		dr, err = GetTestDonationRequest(game, db, logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = dr.Donate(player.ID, 1, 100, r, db, logger)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		session.Close()
+		session = nil
+		db = nil
+	})
+
+	verify := func() []*models.DonationDiscrepancy {
+		dbDonationRequest, err := models.GetDonationRequestByID(dr.ID, db, logger)
+		Expect(err).NotTo(HaveOccurred())
+		discrepancies, err := models.VerifyDonationRequest(dbDonationRequest, game, time.Now().UTC().Unix(), db, logger)
+		Expect(err).NotTo(HaveOccurred())
+		return discrepancies
+	}
+
+	Describe("Verify Donation Request", func() {
+		It("Should not find discrepancies in a consistent donation request", func() {
+			Expect(verify()).To(BeEmpty())
+		})
+
+		It("Should find and repair donations missing in the donations collection", func() {
+			before, err := models.GetDonationRequestByID(dr.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			err = models.GetDonationsCollection(db).RemoveId(dr.Donations[0].ID)
+			Expect(err).NotTo(HaveOccurred())
+
+			discrepancies := verify()
+			Expect(discrepancies).To(HaveLen(1))
+			Expect(discrepancies[0].Type).To(Equal(models.MissingStandaloneDonation))
+			Expect(discrepancies[0].DonationID).To(Equal(dr.Donations[0].ID))
+
+			err = models.RepairDonationDiscrepancy(discrepancies[0], db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(discrepancies[0].Repaired).To(BeTrue())
+
+			dbDonationRequest, err := models.GetDonationRequestByID(dr.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dbDonationRequest.Donations).To(BeEmpty())
+			Expect(dbDonationRequest.Version).To(Equal(before.Version + 1))
+			Expect(verify()).To(BeEmpty())
+		})
+
+		It("Should skip donations newer than the minimum age", func() {
+			err := models.GetDonationsCollection(db).RemoveId(dr.Donations[0].ID)
+			Expect(err).NotTo(HaveOccurred())
+
+			dbDonationRequest, err := models.GetDonationRequestByID(dr.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			discrepancies, err := models.VerifyDonationRequest(
+				dbDonationRequest, game, dr.Donations[0].CreatedAt-1, db, logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(discrepancies).To(BeEmpty())
+		})
+
+		It("Should find and repair donations missing in the donation request", func() {
+			orphan := dr.Donations[0]
+			orphan.ID = uuid.NewV4().String()
+			orphan.DonationRequestID = dr.ID
+			err := models.GetDonationsCollection(db).Insert(orphan)
+			Expect(err).NotTo(HaveOccurred())
+
+			discrepancies := verify()
+			Expect(discrepancies).To(HaveLen(1))
+			Expect(discrepancies[0].Type).To(Equal(models.MissingEmbeddedDonation))
+
+			err = models.RepairDonationDiscrepancy(discrepancies[0], db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			count, err := models.GetDonationsCollection(db).FindId(orphan.ID).Count()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(count).To(Equal(0))
+		})
+
+		It("Should find and repair mismatched donations", func() {
+			err := models.GetDonationsCollection(db).UpdateId(
+				dr.Donations[0].ID,
+				bson.M{"$set": bson.M{"amount": 10}},
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			discrepancies := verify()
+			Expect(discrepancies).To(HaveLen(1))
+			Expect(discrepancies[0].Type).To(Equal(models.MismatchedDonation))
+			Expect(discrepancies[0].Standalone.Amount).To(Equal(10))
+
+			err = models.RepairDonationDiscrepancy(discrepancies[0], db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(verify()).To(BeEmpty())
+		})
+
+		It("Should find and repair requests finished before reaching the limit", func() {
+			err := models.GetDonationRequestsCollection(db).UpdateId(
+				dr.ID,
+				bson.M{"$set": bson.M{"finishedAt": dr.CreatedAt}},
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			discrepancies := verify()
+			Expect(discrepancies).To(HaveLen(1))
+			Expect(discrepancies[0].Type).To(Equal(models.UnexpectedFinishedAt))
+			Expect(discrepancies[0].DonationCount).To(Equal(1))
+			Expect(discrepancies[0].Limit).To(Equal(2))
+
+			before, err := models.GetDonationRequestByID(dr.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			err = models.RepairDonationDiscrepancy(discrepancies[0], db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(verify()).To(BeEmpty())
+
+			dbDonationRequest, err := models.GetDonationRequestByID(dr.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dbDonationRequest.Version).To(Equal(before.Version + 1))
+		})
+
+		It("Should report discrepancies for all donation requests in a game", func() {
+			err := models.GetDonationsCollection(db).RemoveId(dr.Donations[0].ID)
+			Expect(err).NotTo(HaveOccurred())
+
+			reported := []*models.DonationDiscrepancy{}
+			err = models.VerifyDonationRequests(
+				game.ID, false, 0, &models.RealClock{}, db, logger,
+				func(d *models.DonationDiscrepancy) error {
+					reported = append(reported, d)
+					return nil
+				},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reported).To(HaveLen(1))
+			Expect(reported[0].Repaired).To(BeFalse())
+		})
+	})
+})
@@ -4,6 +4,7 @@ package models
 
 import (
 	"fmt"
+	"math/rand"
 	"strconv"
 	"time"
 
@@ -13,7 +14,9 @@ import (
 	uuid "github.com/satori/go.uuid"
 	"github.com/topfreegames/donations/errors"
 	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/tracing"
 	"github.com/uber-go/zap"
+	"golang.org/x/net/context"
 	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
@@ -32,6 +35,19 @@ const (
 	MonthlyReset = iota
 )
 
+//MaxDonationAttempts is the number of times a donation is attempted when the donation request is updated concurrently
+const MaxDonationAttempts = 5
+
+//DonationRetryDelay is the base delay before retrying a donation to a donation request updated concurrently.
+//It doubles at each attempt and is jittered, so that concurrent donations do not retry in lockstep.
+const DonationRetryDelay = 10 * time.Millisecond
+
+//getDonationRetryDelay returns a random delay between half and all of the backoff of the attempt
+func getDonationRetryDelay(attempt int) time.Duration {
+	backoff := DonationRetryDelay << uint(attempt-1)
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
 //GetDonationWeightKey returns the key to be used in redis for the scores
 func GetDonationWeightKey(prefix, gameID, clanID string, date time.Time, resetType ResetType) string {
 	switch resetType {
@@ -122,8 +138,15 @@ type DonationRequest struct {
 	CreatedAt  int64 `json:"createdAt" bson:"createdAt"`
 	UpdatedAt  int64 `json:"updatedAt" bson:"updatedAt,omitempty"`
 	FinishedAt int64 `json:"finishedAt" bson:"finishedAt,omitempty"`
+	ExpiredAt  int64 `json:"expiredAt" bson:"expiredAt,omitempty"`
+
+	//Version is incremented at every donation, so concurrent donations can be detected
+	Version int `json:"version" bson:"version"`
 
 	Clock Clock `json:"-" bson:"-"`
+
+	//Context holds the span the datastore operations of the donation request are traced in, if any
+	Context context.Context `json:"-" bson:"-"`
 }
 
 //NewDonationRequest returns a new instance of DonationRequest
@@ -145,7 +168,32 @@ func NewDonationRequest(gameID string, item, player, clan string, clock ...Clock
 
 //GetGame associated to this donation request
 func (d *DonationRequest) GetGame(db *mgo.Database, logger zap.Logger) (*Game, error) {
-	return GetGameByID(d.GameID, db, logger)
+	var game *Game
+	err := d.traceMongo("games", "find", func() error {
+		var err error
+		game, err = GetGameByID(d.GameID, db, logger)
+		return err
+	})
+	return game, err
+}
+
+//traceMongo runs an operation of a collection in a span child of the span in d.Context and observes its latency
+func (d *DonationRequest) traceMongo(collection, operation string, f func() error) error {
+	return tracing.WithDatastoreSpan(d.Context, &tracing.Datastore{
+		System:     tracing.MongoDB,
+		Collection: collection,
+		Operation:  operation,
+	}, func() error {
+		return observeMongo(collection, operation, f)
+	})
+}
+
+//traceRedis runs redis commands in a span child of the span in d.Context
+func (d *DonationRequest) traceRedis(operation string, f func() error) error {
+	return tracing.WithDatastoreSpan(d.Context, &tracing.Datastore{
+		System:    tracing.Redis,
+		Operation: operation,
+	}, f)
 }
 
 func (d *DonationRequest) validateDonationRequest(logger zap.Logger) error {
@@ -179,12 +227,18 @@ func (d *DonationRequest) validateDonationRequest(logger zap.Logger) error {
 
 func (d *DonationRequest) validateDonationRequestCooldown(gameID string, cooldown int, db *mgo.Database, logger zap.Logger) error {
 	currentTime := d.Clock.GetUTCTime()
-	c, err := GetDonationRequestsCollection(db).Find(bson.M{
-		"player": d.Player,
-		"createdAt": bson.M{
-			"$gte": (currentTime.Add(-1 * (time.Duration(cooldown) * time.Hour))).Unix(),
-		},
-	}).Count()
+	var c int
+	err := d.traceMongo("requests", "count", func() error {
+		var err error
+		c, err = GetDonationRequestsCollection(db).Find(bson.M{
+			"gameID": gameID,
+			"player": d.Player,
+			"createdAt": bson.M{
+				"$gte": (currentTime.Add(-1 * (time.Duration(cooldown) * time.Hour))).Unix(),
+			},
+		}).Count()
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -241,7 +295,9 @@ func (d *DonationRequest) Create(db *mgo.Database, logger zap.Logger) error {
 	d.UpdatedAt = d.Clock.GetUTCTime().Unix()
 
 	log.D(l, "Saving donation request...")
-	err = GetDonationRequestsCollection(db).Insert(d)
+	err = d.traceMongo("requests", "insert", func() error {
+		return GetDonationRequestsCollection(db).Insert(d)
+	})
 
 	if err != nil {
 		log.E(l, "Failed to save donation request.", func(cm log.CM) {
@@ -252,6 +308,8 @@ func (d *DonationRequest) Create(db *mgo.Database, logger zap.Logger) error {
 
 	log.D(l, "Donation Request saved successfully.")
 
+	emitEvent(d.GameID, DonationRequestCreatedEvent, d.ToJSON, d.Clock, db, l)
+
 	return nil
 }
 
@@ -300,7 +358,12 @@ func (d *DonationRequest) validateDonationCooldownPerPlayer(
 	from := player.DonationWindowStart
 	to := d.Clock.GetUTCTime().Unix()
 
-	totalWeight, err := GetDonationWeightForPlayer(player.ID, from, to, db, logger)
+	var totalWeight int
+	err := d.traceMongo("donations", "aggregate", func() error {
+		var err error
+		totalWeight, err = GetDonationWeightForPlayer(game.ID, player.ID, from, to, db, logger)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -316,7 +379,7 @@ func (d *DonationRequest) validateDonationCooldownPerPlayer(
 }
 
 //Donate an item in a given Donation Request
-func (d *DonationRequest) Donate(playerID string, amount, maxWeightPerPlayer int, r redis.Conn, db *mgo.Database, logger zap.Logger) error {
+func (d *DonationRequest) Donate(playerID string, amount, maxWeightPerPlayer int, r *redis.Pool, db *mgo.Database, logger zap.Logger) error {
 	l := logger.With(
 		zap.String("source", "DonationRequestModel"),
 		zap.String("operation", "Donate"),
@@ -338,7 +401,12 @@ func (d *DonationRequest) Donate(playerID string, amount, maxWeightPerPlayer int
 		return err
 	}
 
-	player, err := GetPlayerByID(playerID, db, logger)
+	var player *Player
+	err = d.traceMongo("players", "find", func() error {
+		var err error
+		player, err = GetPlayerByID(d.GameID, playerID, db, logger)
+		return err
+	})
 	if err != nil {
 		log.E(l, "Could not find player.", func(cm log.CM) {
 			cm.Write(zap.Error(err))
@@ -347,65 +415,100 @@ func (d *DonationRequest) Donate(playerID string, amount, maxWeightPerPlayer int
 		return err
 	}
 
-	err = d.ValidateDonationRequestLimit(game, amount, logger)
-	if err != nil {
-		log.E(l, err.Error(), func(cm log.CM) {
-			cm.Write(zap.Error(err))
-		})
+	for attempt := 1; ; attempt++ {
+		err = d.validateDonation(game, player, amount, maxWeightPerPlayer, db, logger)
+		if err != nil {
+			log.E(l, err.Error(), func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
 
-		return err
-	}
+			return err
+		}
 
-	err = d.ValidateDonationRequestLimitPerPlayer(game, playerID, amount, logger)
-	if err != nil {
-		log.E(l, err.Error(), func(cm log.CM) {
-			cm.Write(zap.Error(err))
-		})
+		log.D(l, "Saving donation...")
+		err = d.insertDonationAndUpdatePlayer(player, amount, game, r, db, l)
+		if _, ok := err.(*errors.DonationRequestConcurrentlyUpdatedError); ok && attempt < MaxDonationAttempts {
+			delay := getDonationRetryDelay(attempt)
+			log.D(l, "Donation request updated concurrently, retrying...", func(cm log.CM) {
+				cm.Write(zap.Int("attempt", attempt), zap.Duration("delay", delay))
+			})
+			time.Sleep(delay)
+			err = d.reload(db)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			log.E(l, err.Error(), func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
 
-		return err
+			return err
+		}
+
+		break
 	}
 
-	err = d.validateDonationCooldownPerPlayer(game, player, maxWeightPerPlayer, db, logger)
-	if err != nil {
-		log.E(l, err.Error(), func(cm log.CM) {
-			cm.Write(zap.Error(err))
-		})
+	log.D(l, "Donation added successfully.")
+
+	return nil
+}
 
+func (d *DonationRequest) validateDonation(
+	game *Game, player *Player,
+	amount, maxWeightPerPlayer int,
+	db *mgo.Database, logger zap.Logger,
+) error {
+	err := d.ValidateDonationRequestLimit(game, amount, logger)
+	if err != nil {
 		return err
 	}
 
-	log.D(l, "Saving donation...")
-	err = d.insertDonationAndUpdatePlayer(player, amount, game, r, db, l)
+	err = d.ValidateDonationRequestLimitPerPlayer(game, player.ID, amount, logger)
 	if err != nil {
-		log.E(l, err.Error(), func(cm log.CM) {
-			cm.Write(zap.Error(err))
-		})
-
 		return err
 	}
 
-	log.D(l, "Donation added successfully.")
+	return d.validateDonationCooldownPerPlayer(game, player, maxWeightPerPlayer, db, logger)
+}
 
+//reload the donation request from the database, keeping its clock and context
+func (d *DonationRequest) reload(db *mgo.Database) error {
+	var donationRequest DonationRequest
+	err := d.traceMongo("requests", "find", func() error {
+		return GetDonationRequestsCollection(db).FindId(d.ID).One(&donationRequest)
+	})
+	if err != nil {
+		if err.Error() == NotFoundString {
+			return errors.NewDocumentNotFoundError("donationRequest", d.ID)
+		}
+		return err
+	}
+	donationRequest.Clock = d.Clock
+	donationRequest.Context = d.Context
+	*d = donationRequest
 	return nil
 }
 
+//getVersionQuery matches the current version of the donation request.
+//Donation requests created before versioning have no version field.
+func (d *DonationRequest) getVersionQuery() interface{} {
+	if d.Version == 0 {
+		return bson.M{"$in": []interface{}{0, nil}}
+	}
+	return d.Version
+}
+
 func (d *DonationRequest) insertDonationAndUpdatePlayer(
 	player *Player, amount int,
 	game *Game,
-	r redis.Conn,
+	r *redis.Pool,
 	db *mgo.Database, l zap.Logger,
 ) error {
 	log.D(l, "Saving donation...")
 
 	txID := uuid.NewV4().String()
-	rb := func() error {
-		err := GetDonationRequestsCollection(db).Update(
-			bson.M{"_id": d.ID},
-			bson.M{"$pull": bson.M{"donations": bson.M{"$eq": []string{"txID", txID}}}},
-		)
-		return err
-	}
-
 	item := game.Items[d.Item]
 
 	donation := Donation{
@@ -418,19 +521,54 @@ func (d *DonationRequest) insertDonationAndUpdatePlayer(
 		Weight:            item.WeightPerDonation,
 		CreatedAt:         d.Clock.GetUTCTime().Unix(),
 	}
-	d.Donations = append(d.Donations, donation)
 
 	set := bson.M{
 		"updatedAt": d.Clock.GetUTCTime().Unix(),
 	}
 
+	var finishedAt int64
 	if d.GetDonationCount()+amount >= item.LimitOfItemsInEachDonationRequest {
-		set["finishedAt"] = d.Clock.GetUTCTime().Unix()
+		finishedAt = d.Clock.GetUTCTime().Unix()
+		set["finishedAt"] = finishedAt
+	}
+
+	inserted := false
+	rb := func(cause error) error {
+		d.Donations = d.Donations[:len(d.Donations)-1]
+		if inserted {
+			err := d.traceMongo("donations", "remove", func() error {
+				return GetDonationsCollection(db).RemoveId(donation.ID)
+			})
+			if err != nil {
+				log.E(l, "Failed to rollback donation.", func(cm log.CM) {
+					cm.Write(zap.Error(err))
+				})
+			}
+		}
+		rollback := bson.M{
+			"$pull": bson.M{"donations": bson.M{"txID": txID}},
+			"$inc":  bson.M{"version": 1},
+		}
+		if finishedAt != 0 {
+			rollback["$unset"] = bson.M{"finishedAt": ""}
+			d.FinishedAt = 0
+		}
+		err := d.traceMongo("requests", "update", func() error {
+			return GetDonationRequestsCollection(db).Update(bson.M{"_id": d.ID}, rollback)
+		})
+		if err != nil {
+			log.E(l, "Failed to rollback donation.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+		}
+		return cause
 	}
 
-	query := bson.M{"_id": d.ID}
+	//The update only succeeds if nobody changed the donation request since it was validated
+	query := bson.M{"_id": d.ID, "version": d.getVersionQuery()}
 	update := bson.M{
 		"$set": set,
+		"$inc": bson.M{"version": 1},
 		"$push": bson.M{"donations": bson.M{
 			"_id":       donation.ID,
 			"gameID":    donation.GameID,
@@ -443,23 +581,37 @@ func (d *DonationRequest) insertDonationAndUpdatePlayer(
 		}},
 	}
 
-	err := GetDonationRequestsCollection(db).Update(query, update)
+	err := d.traceMongo("requests", "update", func() error {
+		return GetDonationRequestsCollection(db).Update(query, update)
+	})
 	if err != nil {
-		d.Donations = d.Donations[:len(d.Donations)-1]
+		if err == mgo.ErrNotFound {
+			return &errors.DonationRequestConcurrentlyUpdatedError{
+				DonationRequestID: d.ID,
+				Version:           d.Version,
+			}
+		}
 		log.E(l, "Failed to add donation.", func(cm log.CM) {
 			cm.Write(zap.Error(err))
 		})
 		return err
 	}
+	d.Donations = append(d.Donations, donation)
+	d.Version++
+	if finishedAt != 0 {
+		d.FinishedAt = finishedAt
+	}
 
-	err = GetDonationsCollection(db).Insert(donation)
+	err = d.traceMongo("donations", "insert", func() error {
+		return GetDonationsCollection(db).Insert(donation)
+	})
 	if err != nil {
 		log.E(l, "Failed to add donation.", func(cm log.CM) {
 			cm.Write(zap.Error(err))
 		})
-		err = rb()
-		return err
+		return rb(err)
 	}
+	inserted = true
 
 	//Number of seconds in playerDonationWindowHours
 	cooldown := int((time.Duration(game.DonationCooldownHours) * time.Hour).Seconds())
@@ -473,34 +625,68 @@ func (d *DonationRequest) insertDonationAndUpdatePlayer(
 
 	//If no window found for player or last window is older than player window cooldown, update it
 	if noWindow || windowElapsed > cooldown {
-		err = UpdateDonationWindowStart(game.ID, player.ID, d.Clock.GetUTCTime().Unix(), db, l)
+		err = d.traceMongo("players", "update", func() error {
+			return UpdateDonationWindowStart(game.ID, player.ID, d.Clock.GetUTCTime().Unix(), db, l)
+		})
 		if err != nil {
-			d.Donations = d.Donations[:len(d.Donations)-1]
 			log.E(l, "Failed to set player update window start.", func(cm log.CM) {
 				cm.Write(zap.Error(err))
 			})
-			err = rb()
-			return err
+			return rb(err)
 		}
 	}
 
 	if d.Clan != "" {
-		err = IncrementDonationWeightForClan(r, d.GameID, d.Clan, donation.Weight, d.Clock)
+		err = d.traceRedis("incrby", func() error {
+			return IncrementDonationWeightForClan(r, d.GameID, d.Clan, donation.Weight, d.Clock)
+		})
 		if err != nil {
-			err = rb()
-			return err
+			return rb(err)
 		}
 	}
 
-	err = IncrementDonationWeightForPlayer(r, d.GameID, donation.Player, donation.Weight, d.Clock)
+	err = d.traceRedis("incrby", func() error {
+		return IncrementDonationWeightForPlayer(r, d.GameID, donation.Player, donation.Weight, d.Clock)
+	})
 	if err != nil {
-		err = rb()
-		return err
+		return rb(err)
+	}
+
+	emitEvent(d.GameID, DonationCreatedEvent, donation.ToJSON, d.Clock, db, l)
+	if finishedAt != 0 {
+		emitEvent(d.GameID, DonationRequestFinishedEvent, d.ToJSON, d.Clock, db, l)
 	}
 
 	return nil
 }
 
+//IsExpired returns true if the donation request was not finished within expiration of its creation, even if
+//ExpireDonationRequests did not mark it as expired yet. Expired donation requests can't receive donations and
+//are archived. An expiration of zero never expires.
+func (d *DonationRequest) IsExpired(expiration time.Duration) bool {
+	if d.ExpiredAt != 0 {
+		return true
+	}
+	if d.FinishedAt != 0 || expiration <= 0 {
+		return false
+	}
+	return d.CreatedAt <= d.Clock.GetUTCTime().Add(-expiration).Unix()
+}
+
+//ValidateDonationRequestNotExpired ensures that the donation request can still receive donations
+func (d *DonationRequest) ValidateDonationRequestNotExpired(expiration time.Duration, logger zap.Logger) error {
+	if !d.IsExpired(expiration) {
+		return nil
+	}
+	err := &errors.DonationRequestExpiredError{DonationRequestID: d.ID}
+	log.E(logger, err.Error(), func(cm log.CM) {
+		cm.Write(zap.Error(err))
+		cm.Write(zap.String("GameID", d.GameID))
+		cm.Write(zap.String("DonationRequestID", d.ID))
+	})
+	return err
+}
+
 //ValidateDonationRequestLimit ensures that no more than the allowed number of donations has been donated
 func (d *DonationRequest) ValidateDonationRequestLimit(game *Game, amount int, logger zap.Logger) error {
 	item := game.Items[d.Item]
@@ -573,6 +759,13 @@ func (d *DonationRequest) ToJSON() ([]byte, error) {
 	return w.BuildBytes()
 }
 
+//ToJSON returns the donation as JSON
+func (d *Donation) ToJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	d.MarshalEasyJSON(&w)
+	return w.BuildBytes()
+}
+
 //GetDonationRequestFromJSON unmarshals the donation request from the specified JSON
 func GetDonationRequestFromJSON(data []byte) (*DonationRequest, error) {
 	donationRequest := &DonationRequest{}
@@ -602,7 +795,9 @@ func GetDonationsCollection(db *mgo.Database) *mgo.Collection {
 //GetDonationRequestByID rtrieves the game by its id
 func GetDonationRequestByID(id string, db *mgo.Database, logger zap.Logger) (*DonationRequest, error) {
 	var donationRequest DonationRequest
-	err := GetDonationRequestsCollection(db).FindId(id).One(&donationRequest)
+	err := observeMongo("requests", "find", func() error {
+		return GetDonationRequestsCollection(db).FindId(id).One(&donationRequest)
+	})
 	if err != nil {
 		if err.Error() == NotFoundString {
 			return nil, errors.NewDocumentNotFoundError("donationRequest", id)
@@ -613,21 +808,48 @@ func GetDonationRequestByID(id string, db *mgo.Database, logger zap.Logger) (*Do
 	return &donationRequest, nil
 }
 
-//GetDonationWeightForPlayer returns the donation weight for a given player in a given interval
-func GetDonationWeightForPlayer(playerID string, from, to int64, db *mgo.Database, logger zap.Logger) (int, error) {
-	coll := GetDonationRequestsCollection(db)
+//GetDonationWeightForPlayer returns the donation weight for a given player of a game in a given interval,
+//including the donations to archived donation requests. ArchiveDonationRequests copies a request to the
+//archive before removing it from the requests, so archived requests that are still in the requests are
+//only counted once.
+func GetDonationWeightForPlayer(gameID, playerID string, from, to int64, db *mgo.Database, logger zap.Logger) (int, error) {
+	weight, requestIDs, err := getDonationWeightForPlayer(
+		GetDonationRequestsCollection(db), gameID, playerID, from, to, nil,
+	)
+	if err != nil {
+		return 0, err
+	}
 
+	archivedWeight, _, err := getDonationWeightForPlayer(
+		GetArchivedDonationRequestsCollection(db), gameID, playerID, from, to, requestIDs,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return weight + archivedWeight, nil
+}
+
+//getDonationWeightForPlayer returns the donation weight of the player in the donation requests of coll,
+//except the ones in excludedIDs, and the ids of the donation requests the player donated to
+func getDonationWeightForPlayer(
+	coll *mgo.Collection, gameID, playerID string, from, to int64, excludedIDs []interface{},
+) (int, []interface{}, error) {
+	match := bson.M{
+		"gameID":              gameID,
+		"donations.player":    playerID,
+		"donations.createdAt": bson.M{"$gte": from, "$lte": to},
+	}
+	if len(excludedIDs) > 0 {
+		match["_id"] = bson.M{"$nin": excludedIDs}
+	}
 	query := []bson.M{
-		bson.M{
-			"$match": bson.M{
-				"donations.player":    playerID,
-				"donations.createdAt": bson.M{"$gte": from, "$lte": to},
-			},
-		},
+		bson.M{"$match": match},
 		bson.M{"$unwind": "$donations"},
 		bson.M{
 			"$project": bson.M{
 				"_id":       "$donations._id",
+				"requestID": "$_id",
 				"player":    "$donations.player",
 				"weight":    "$donations.weight",
 				"amount":    "$donations.amount",
@@ -636,6 +858,7 @@ func GetDonationWeightForPlayer(playerID string, from, to int64, db *mgo.Databas
 		},
 		bson.M{
 			"$match": bson.M{
+				"player":    playerID,
 				"createdAt": bson.M{"$gte": from, "$lte": to},
 			},
 		},
@@ -644,6 +867,7 @@ func GetDonationWeightForPlayer(playerID string, from, to int64, db *mgo.Databas
 				"_id":         "$player",
 				"player":      bson.M{"$first": "$player"},
 				"totalWeight": bson.M{"$sum": "$weight"},
+				"requestIDs":  bson.M{"$addToSet": "$requestID"},
 			},
 		},
 	}
@@ -652,20 +876,24 @@ func GetDonationWeightForPlayer(playerID string, from, to int64, db *mgo.Databas
 	resp := []bson.M{}
 	err := pipe.All(&resp)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 
 	if len(resp) == 0 {
-		return 0, nil
+		return 0, nil, nil
 	}
 
-	return resp[0]["totalWeight"].(int), nil
+	requestIDs, _ := resp[0]["requestIDs"].([]interface{})
+	return resp[0]["totalWeight"].(int), requestIDs, nil
 }
 
 //GetDonationWeightForClan returns the donation weight for a clan in a given interval
-func GetDonationWeightForClan(gameID, clanID string, dt time.Time, resetType ResetType, r redis.Conn, logger zap.Logger) (int, error) {
+func GetDonationWeightForClan(gameID, clanID string, dt time.Time, resetType ResetType, r *redis.Pool, logger zap.Logger) (int, error) {
+	conn := r.Get()
+	defer conn.Close()
+
 	key := GetDonationWeightKey("clan", gameID, clanID, dt, resetType)
-	result, err := r.Do("GET", key)
+	result, err := conn.Do("GET", key)
 	if err != nil {
 		return 0, err
 	}
@@ -680,19 +908,22 @@ func GetDonationWeightForClan(gameID, clanID string, dt time.Time, resetType Res
 }
 
 //IncrementDonationWeightForClan should increment the donation weight for a clan for all time periods
-func IncrementDonationWeightForClan(redis redis.Conn, gameID, clanID string, weight int, clock Clock) error {
-	return incrementDonationWeight(redis, "clan", gameID, clanID, weight, clock)
+func IncrementDonationWeightForClan(r *redis.Pool, gameID, clanID string, weight int, clock Clock) error {
+	return incrementDonationWeight(r, "clan", gameID, clanID, weight, clock)
 }
 
 //IncrementDonationWeightForPlayer should increment the donation weight for a player for all time periods
-func IncrementDonationWeightForPlayer(redis redis.Conn, gameID, playerID string, weight int, clock Clock) error {
-	return incrementDonationWeight(redis, "player", gameID, playerID, weight, clock)
+func IncrementDonationWeightForPlayer(r *redis.Pool, gameID, playerID string, weight int, clock Clock) error {
+	return incrementDonationWeight(r, "player", gameID, playerID, weight, clock)
 }
 
-func incrementDonationWeight(redis redis.Conn, prefix, gameID, id string, weight int, clock Clock) error {
-	if redis == nil {
-		return fmt.Errorf("The redis client must not be nil and must be connected to redis.")
+func incrementDonationWeight(r *redis.Pool, prefix, gameID, id string, weight int, clock Clock) error {
+	if r == nil {
+		return fmt.Errorf("The redis pool must not be nil and must be connected to redis.")
 	}
+	conn := r.Get()
+	defer conn.Close()
+
 	dt := clock.GetUTCTime()
 	key := GetDonationWeightKey(prefix, gameID, id, dt, NoReset)
 
@@ -705,17 +936,17 @@ func incrementDonationWeight(redis redis.Conn, prefix, gameID, id string, weight
 	monthlyKey := GetDonationWeightKey(prefix, gameID, id, dt, MonthlyReset)
 	monthlyExpiration := GetExpirationDate(dt, MonthlyReset, clock)
 
-	redis.Send("MULTI")
+	conn.Send("MULTI")
 
-	redis.Send("INCRBY", key, weight)
-	redis.Send("INCRBY", dailyKey, weight)
-	redis.Send("INCRBY", weeklyKey, weight)
-	redis.Send("INCRBY", monthlyKey, weight)
-	redis.Send("EXPIRE", dailyKey, dailyExpiration)
-	redis.Send("EXPIRE", weeklyKey, weeklyExpiration)
-	redis.Send("EXPIRE", monthlyKey, monthlyExpiration)
+	conn.Send("INCRBY", key, weight)
+	conn.Send("INCRBY", dailyKey, weight)
+	conn.Send("INCRBY", weeklyKey, weight)
+	conn.Send("INCRBY", monthlyKey, weight)
+	conn.Send("EXPIRE", dailyKey, dailyExpiration)
+	conn.Send("EXPIRE", weeklyKey, weeklyExpiration)
+	conn.Send("EXPIRE", monthlyKey, monthlyExpiration)
 
-	_, err := redis.Do("EXEC")
+	_, err := conn.Do("EXEC")
 	if err != nil {
 		return err
 	}
@@ -65,6 +65,10 @@ func easyjsonF464aa0aDecodeGithubComTopfreegamesDonationsModels(in *jlexer.Lexer
 			out.UpdatedAt = int64(in.Int64())
 		case "finishedAt":
 			out.FinishedAt = int64(in.Int64())
+		case "expiredAt":
+			out.ExpiredAt = int64(in.Int64())
+		case "version":
+			out.Version = int(in.Int())
 		default:
 			in.SkipRecursive()
 		}
@@ -141,6 +145,18 @@ func easyjsonF464aa0aEncodeGithubComTopfreegamesDonationsModels(out *jwriter.Wri
 	first = false
 	out.RawString("\"finishedAt\":")
 	out.Int64(int64(in.FinishedAt))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"expiredAt\":")
+	out.Int64(int64(in.ExpiredAt))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"version\":")
+	out.Int(int(in.Version))
 	out.RawByte('}')
 }
 
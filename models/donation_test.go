@@ -5,6 +5,7 @@ import (
 	"time"
 
 	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
 	redsync "gopkg.in/redsync.v1"
 
 	"github.com/garyburd/redigo/redis"
@@ -16,11 +17,14 @@ import (
 	"github.com/uber-go/zap"
 )
 
-func validateDonationWeightInRedis(r redis.Conn, prefix, gameID, id string, resetType models.ResetType, weight int) {
+func validateDonationWeightInRedis(r *redis.Pool, prefix, gameID, id string, resetType models.ResetType, weight int) {
+	conn := r.Get()
+	defer conn.Close()
+
 	clock := &models.RealClock{}
 	dt := clock.GetUTCTime()
 	key := models.GetDonationWeightKey(prefix, gameID, id, dt, resetType)
-	res, err := r.Do("GET", key)
+	res, err := conn.Do("GET", key)
 	Expect(err).NotTo(HaveOccurred())
 	result := string(res.([]uint8))
 	Expect(result).To(Equal(fmt.Sprintf("%d", weight)))
@@ -29,7 +33,7 @@ func validateDonationWeightInRedis(r redis.Conn, prefix, gameID, id string, rese
 	if resetType == models.NoReset {
 		expiration = -1
 	}
-	res, err = r.Do("TTL", key)
+	res, err = conn.Do("TTL", key)
 	Expect(err).NotTo(HaveOccurred())
 	ttl := res.(int64)
 	Expect(ttl).To(Equal(expiration))
@@ -40,7 +44,7 @@ var _ = Describe("Donation Model", func() {
 	var session *mgo.Session
 	var db *mgo.Database
 	var rs *redsync.Redsync
-	var r redis.Conn
+	var r *redis.Pool
 
 	BeforeEach(func() {
 		logger = zap.New(
@@ -382,7 +386,7 @@ var _ = Describe("Donation Model", func() {
 					err = dr.Donate(playerID, 2, 10, r, db, logger)
 					Expect(err).NotTo(HaveOccurred())
 
-					dbPlayer, err := models.GetPlayerByID(playerID, db, logger)
+					dbPlayer, err := models.GetPlayerByID(game.ID, playerID, db, logger)
 					Expect(err).NotTo(HaveOccurred())
 					Expect(dbPlayer.DonationWindowStart).To(BeNumerically(">", start.Unix()-10))
 				})
@@ -409,7 +413,7 @@ var _ = Describe("Donation Model", func() {
 					err = dr.Donate(playerID, 1, 10, r, db, logger)
 					Expect(err).NotTo(HaveOccurred())
 
-					dbPlayer, err := models.GetPlayerByID(playerID, db, logger)
+					dbPlayer, err := models.GetPlayerByID(game.ID, playerID, db, logger)
 					Expect(err).NotTo(HaveOccurred())
 					Expect(dbPlayer.DonationWindowStart).To(Equal(int64(100)))
 				})
@@ -586,6 +590,99 @@ var _ = Describe("Donation Model", func() {
 					Expect(err).NotTo(HaveOccurred())
 				})
 			})
+
+			Describe("Optimistic Concurrency", func() {
+				It("Should increment the donation request version", func() {
+					game, err := GetTestGame(db, logger, true)
+					Expect(err).NotTo(HaveOccurred())
+
+					player, err := GetTestPlayer(game, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+
+					dr, err := GetTestDonationRequest(game, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(dr.Version).To(Equal(0))
+
+					err = dr.Donate(player.ID, 1, 10, r, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(dr.Version).To(Equal(1))
+
+					dbDonationRequest, err := models.GetDonationRequestByID(dr.ID, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(dbDonationRequest.Version).To(Equal(1))
+				})
+
+				It("Should retry the donation when the request was concurrently updated", func() {
+					game, err := GetTestGame(db, logger, true, map[string]interface{}{
+						"LimitOfItemsInEachDonationRequest": 4,
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					player, err := GetTestPlayer(game, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+
+					player2, err := GetTestPlayer(game, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+
+					dr, err := GetTestDonationRequest(game, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+
+					stale, err := models.GetDonationRequestByID(dr.ID, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+
+					err = dr.Donate(player.ID, 1, 10, r, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+
+					err = stale.Donate(player2.ID, 1, 10, r, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(stale.Donations).To(HaveLen(2))
+					Expect(stale.Version).To(Equal(2))
+
+					dbDonationRequest, err := models.GetDonationRequestByID(dr.ID, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(dbDonationRequest.Donations).To(HaveLen(2))
+					Expect(dbDonationRequest.Version).To(Equal(2))
+
+					count, err := models.GetDonationsCollection(db).Find(bson.M{"donationRequestID": dr.ID}).Count()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(count).To(Equal(2))
+				})
+
+				It("Should revalidate the donation against the updated request", func() {
+					game, err := GetTestGame(db, logger, true, map[string]interface{}{
+						"LimitOfItemsInEachDonationRequest": 2,
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					player, err := GetTestPlayer(game, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+
+					player2, err := GetTestPlayer(game, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+
+					dr, err := GetTestDonationRequest(game, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+
+					stale, err := models.GetDonationRequestByID(dr.ID, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+
+					err = dr.Donate(player.ID, 2, 10, r, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+
+					err = stale.Donate(player2.ID, 1, 10, r, db, logger)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("This donation request can't accept this donation."))
+
+					dbDonationRequest, err := models.GetDonationRequestByID(dr.ID, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(dbDonationRequest.Donations).To(HaveLen(1))
+					Expect(dbDonationRequest.Version).To(Equal(1))
+
+					count, err := models.GetDonationsCollection(db).Find(bson.M{"donationRequestID": dr.ID}).Count()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(count).To(Equal(1))
+				})
+			})
 		})
 
 		Describe("Measure", func() {
@@ -725,7 +822,7 @@ var _ = Describe("Donation Model", func() {
 				err = dr.Donate(donerID, 1, 10, r, db, logger)
 				Expect(err).NotTo(HaveOccurred())
 
-				weight, err := models.GetDonationWeightForPlayer(donerID, 300, 20000, db, logger)
+				weight, err := models.GetDonationWeightForPlayer(game.ID, donerID, 300, 20000, db, logger)
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(weight).To(Equal(1))
@@ -753,7 +850,7 @@ var _ = Describe("Donation Model", func() {
 					Expect(err).NotTo(HaveOccurred())
 				}
 
-				weight, err := models.GetDonationWeightForPlayer(donerID, 300, 500, db, logger)
+				weight, err := models.GetDonationWeightForPlayer(game.ID, donerID, 300, 500, db, logger)
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(weight).To(Equal(0))
@@ -762,6 +859,7 @@ var _ = Describe("Donation Model", func() {
 
 		Describe("Measure", func() {
 			var donationRequest *models.DonationRequest
+			var gameID string
 			var donerID string
 
 			BeforeOnce(func() {
@@ -778,6 +876,7 @@ var _ = Describe("Donation Model", func() {
 				Expect(err).NotTo(HaveOccurred())
 				donationRequest.Clock = clock
 
+				gameID = game.ID
 				donerID = player.ID
 
 				for i := 0; i < 100; i++ {
@@ -788,7 +887,7 @@ var _ = Describe("Donation Model", func() {
 
 			Measure("it should get player weight fast", func(b Benchmarker) {
 				runtime := b.Time("runtime", func() {
-					_, err := models.GetDonationWeightForPlayer(donerID, 100, 100, db, logger)
+					_, err := models.GetDonationWeightForPlayer(gameID, donerID, 100, 100, db, logger)
 					Expect(err).NotTo(HaveOccurred())
 				})
 
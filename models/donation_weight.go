@@ -0,0 +1,248 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/topfreegames/donations/log"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var resetTypes = []ResetType{NoReset, DailyReset, WeeklyReset, MonthlyReset}
+
+//DonationWeightCounter represents the value a donation weight key in redis should have
+type DonationWeightCounter struct {
+	Key        string `json:"key"`
+	Weight     int    `json:"weight"`
+	Expiration int64  `json:"expiration"`
+}
+
+//DonationWeightDiff represents the difference between a weight counter in redis and the one computed from mongo
+type DonationWeightDiff struct {
+	Key        string `json:"key"`
+	Current    int    `json:"current"`
+	Expected   int    `json:"expected"`
+	Expiration int64  `json:"expiration"`
+}
+
+func addDonationWeight(counters map[string]*DonationWeightCounter, prefix, gameID, id string, weight int, dt time.Time, clock Clock) {
+	for _, resetType := range resetTypes {
+		expiration := GetExpirationDate(dt, resetType, clock)
+
+		//Counters for periods that already expired in redis should not be rebuilt
+		if resetType != NoReset && expiration <= 0 {
+			continue
+		}
+
+		key := GetDonationWeightKey(prefix, gameID, id, dt, resetType)
+		counter, ok := counters[key]
+		if !ok {
+			counter = &DonationWeightCounter{Key: key}
+			counters[key] = counter
+		}
+		counter.Weight += weight
+
+		//The write path renews the expiration at every donation, so the latest one wins
+		if expiration > counter.Expiration {
+			counter.Expiration = expiration
+		}
+	}
+}
+
+//GetDonationWeightCounters computes from mongo the weight counters for all donations in a game.
+//If clanID is not empty, only the counters for that clan are computed, since player
+//counters aggregate donations made to every clan.
+func GetDonationWeightCounters(gameID, clanID string, clock Clock, db *mgo.Database, logger zap.Logger) (map[string]*DonationWeightCounter, error) {
+	query := bson.M{"gameID": gameID}
+	if clanID != "" {
+		query["clan"] = clanID
+	}
+
+	counters := map[string]*DonationWeightCounter{}
+	iter := GetDonationsCollection(db).Find(query).Iter()
+	var donation Donation
+	for iter.Next(&donation) {
+		dt := time.Unix(donation.CreatedAt, 0).UTC()
+		if donation.Clan != "" {
+			addDonationWeight(counters, "clan", gameID, donation.Clan, donation.Weight, dt, clock)
+		}
+		if clanID == "" {
+			addDonationWeight(counters, "player", gameID, donation.Player, donation.Weight, dt, clock)
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	return counters, nil
+}
+
+//scanPatternEscaper escapes the glob characters of SCAN MATCH patterns
+var scanPatternEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+//EscapeScanPattern returns value matching itself in a SCAN MATCH pattern, so ids with glob characters
+//do not match the keys of other ids
+func EscapeScanPattern(value string) string {
+	return scanPatternEscaper.Replace(value)
+}
+
+func getDonationWeightKeyPatterns(gameID, clanID string) []string {
+	if clanID != "" {
+		key := EscapeScanPattern(GetDonationWeightKey("clan", gameID, clanID, time.Time{}, NoReset))
+		return []string{key, fmt.Sprintf("%s::*", key)}
+	}
+	return []string{
+		fmt.Sprintf("donations::donation-weight::clan::%s::*", EscapeScanPattern(gameID)),
+		fmt.Sprintf("donations::donation-weight::player::%s::*", EscapeScanPattern(gameID)),
+	}
+}
+
+//ScanKeys returns the keys in redis matching pattern. Ids in the pattern must be escaped with EscapeScanPattern.
+func ScanKeys(conn redis.Conn, pattern string) ([]string, error) {
+	keys := []string{}
+	cursor := 0
+	for {
+		values, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", pattern, "COUNT", 1000))
+		if err != nil {
+			return nil, err
+		}
+		cursor, err = redis.Int(values[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		page, err := redis.Strings(values[1], nil)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, page...)
+		if cursor == 0 {
+			return keys, nil
+		}
+	}
+}
+
+//GetDonationWeightDiffs returns the weight counters in redis that do not match the ones computed from mongo
+func GetDonationWeightDiffs(gameID, clanID string, clock Clock, r *redis.Pool, db *mgo.Database, logger zap.Logger) ([]*DonationWeightDiff, error) {
+	conn := r.Get()
+	defer conn.Close()
+
+	counters, err := GetDonationWeightCounters(gameID, clanID, clock, db, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]bool{}
+	for key := range counters {
+		keys[key] = true
+	}
+
+	//Keys in redis with no donations in mongo must be removed as well
+	for _, pattern := range getDonationWeightKeyPatterns(gameID, clanID) {
+		found, err := ScanKeys(conn, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range found {
+			keys[key] = true
+		}
+	}
+
+	sortedKeys := []string{}
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
+	diffs := []*DonationWeightDiff{}
+	for _, key := range sortedKeys {
+		current, err := redis.Int(conn.Do("GET", key))
+		if err != nil && err != redis.ErrNil {
+			return nil, err
+		}
+
+		expected := 0
+		var expiration int64
+		if counter, ok := counters[key]; ok {
+			expected = counter.Weight
+			expiration = counter.Expiration
+		}
+
+		if current != expected {
+			diffs = append(diffs, &DonationWeightDiff{
+				Key:        key,
+				Current:    current,
+				Expected:   expected,
+				Expiration: expiration,
+			})
+		}
+	}
+
+	return diffs, nil
+}
+
+//rebuildDonationWeightScript adds to a counter the difference to its expected weight instead of setting it,
+//so donations counted after the counter was read by GetDonationWeightDiffs are kept. Donations are stored in
+//mongo before being counted in redis, so a donation stored after the mongo scan but counted before its counter
+//was read is removed from the counter until the next rebuild. Counters that end up empty are removed.
+var rebuildDonationWeightScript = redis.NewScript(1, `
+local weight = redis.call("INCRBY", KEYS[1], ARGV[1])
+if weight == 0 then
+  redis.call("DEL", KEYS[1])
+elseif tonumber(ARGV[2]) > 0 then
+  redis.call("EXPIRE", KEYS[1], ARGV[2])
+end
+return weight
+`)
+
+//RebuildDonationWeights recomputes the weight counters in redis for a game (and optionally a clan)
+//from the donations stored in mongo. If dryRun is true, the differences are returned but not applied.
+func RebuildDonationWeights(gameID, clanID string, dryRun bool, clock Clock, r *redis.Pool, db *mgo.Database, logger zap.Logger) ([]*DonationWeightDiff, error) {
+	l := logger.With(
+		zap.String("source", "DonationWeightModel"),
+		zap.String("operation", "RebuildDonationWeights"),
+		zap.String("gameID", gameID),
+		zap.String("clanID", clanID),
+		zap.Bool("dryRun", dryRun),
+	)
+
+	if r == nil {
+		return nil, fmt.Errorf("The redis pool must not be nil and must be connected to redis.")
+	}
+
+	log.D(l, "Computing donation weight differences...")
+	diffs, err := GetDonationWeightDiffs(gameID, clanID, clock, r, db, logger)
+	if err != nil {
+		log.E(l, "Failed to compute donation weight differences.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return nil, err
+	}
+
+	if dryRun || len(diffs) == 0 {
+		return diffs, nil
+	}
+
+	conn := r.Get()
+	defer conn.Close()
+
+	for _, diff := range diffs {
+		_, err = rebuildDonationWeightScript.Do(conn, diff.Key, diff.Expected-diff.Current, diff.Expiration)
+		if err != nil {
+			log.E(l, "Failed to rebuild donation weights.", func(cm log.CM) {
+				cm.Write(zap.String("key", diff.Key), zap.Error(err))
+			})
+			return nil, err
+		}
+	}
+
+	log.I(l, "Donation weights rebuilt successfully.", func(cm log.CM) {
+		cm.Write(zap.Int("changedKeys", len(diffs)))
+	})
+
+	return diffs, nil
+}
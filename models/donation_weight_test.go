@@ -0,0 +1,159 @@
+package models_test
+
+import (
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+
+	"github.com/garyburd/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	uuid "github.com/satori/go.uuid"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Donation Weight Model", func() {
+	var logger zap.Logger
+	var session *mgo.Session
+	var db *mgo.Database
+	var r *redis.Pool
+	var conn redis.Conn
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		session, db = GetTestMongoDB()
+		r = GetTestRedis()
+		conn = r.Get()
+	})
+
+	AfterEach(func() {
+		conn.Close()
+		session.Close()
+		session = nil
+		db = nil
+	})
+
+	getWeight := func(key string) int {
+		weight, err := redis.Int(conn.Do("GET", key))
+		if err == redis.ErrNil {
+			return 0
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return weight
+	}
+
+	Describe("Rebuild Donation Weights", func() {
+		var game *models.Game
+		var clanID string
+		var donor *models.Player
+
+		BeforeEach(func() {
+			var err error
+			game, err = GetTestGame(db, logger, true, map[string]interface{}{
+				"WeightPerDonation": 3,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			donor, err = GetTestPlayer(game, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			clanID = uuid.NewV4().String()
+			dr, err := GetTestDonationRequest(game, db, logger, clanID)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = dr.Donate(donor.ID, 1, 100, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should compute the weight counters from mongo", func() {
+			counters, err := models.GetDonationWeightCounters(game.ID, "", &models.RealClock{}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(counters).To(HaveLen(8))
+
+			key := models.GetDonationWeightKey("clan", game.ID, clanID, time.Now().UTC(), models.NoReset)
+			Expect(counters[key].Weight).To(Equal(3))
+			Expect(counters[key].Expiration).To(BeEquivalentTo(0))
+
+			key = models.GetDonationWeightKey("player", game.ID, donor.ID, time.Now().UTC(), models.DailyReset)
+			Expect(counters[key].Weight).To(Equal(3))
+			Expect(counters[key].Expiration).To(BeNumerically(">", 0))
+		})
+
+		It("Should only compute clan counters when scoped to a clan", func() {
+			counters, err := models.GetDonationWeightCounters(game.ID, clanID, &models.RealClock{}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(counters).To(HaveLen(4))
+			for key := range counters {
+				Expect(key).To(ContainSubstring("::clan::"))
+			}
+		})
+
+		It("Should not report differences if redis is up to date", func() {
+			diffs, err := models.GetDonationWeightDiffs(game.ID, "", &models.RealClock{}, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diffs).To(BeEmpty())
+		})
+
+		It("Should report but not change missing counters in dry run", func() {
+			key := models.GetDonationWeightKey("clan", game.ID, clanID, time.Now().UTC(), models.NoReset)
+			_, err := conn.Do("DEL", key)
+			Expect(err).NotTo(HaveOccurred())
+
+			diffs, err := models.RebuildDonationWeights(game.ID, "", true, &models.RealClock{}, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diffs).To(HaveLen(1))
+			Expect(diffs[0].Key).To(Equal(key))
+			Expect(diffs[0].Current).To(Equal(0))
+			Expect(diffs[0].Expected).To(Equal(3))
+
+			Expect(getWeight(key)).To(Equal(0))
+		})
+
+		It("Should rebuild missing counters", func() {
+			dt := time.Now().UTC()
+			allTimeKey := models.GetDonationWeightKey("clan", game.ID, clanID, dt, models.NoReset)
+			dailyKey := models.GetDonationWeightKey("player", game.ID, donor.ID, dt, models.DailyReset)
+			_, err := conn.Do("DEL", allTimeKey, dailyKey)
+			Expect(err).NotTo(HaveOccurred())
+
+			diffs, err := models.RebuildDonationWeights(game.ID, "", false, &models.RealClock{}, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diffs).To(HaveLen(2))
+
+			Expect(getWeight(allTimeKey)).To(Equal(3))
+			Expect(getWeight(dailyKey)).To(Equal(3))
+
+			ttl, err := redis.Int(conn.Do("TTL", dailyKey))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ttl).To(BeNumerically(">", 0))
+		})
+
+		It("Should remove counters without donations", func() {
+			key := models.GetDonationWeightKey("clan", game.ID, uuid.NewV4().String(), time.Now().UTC(), models.NoReset)
+			_, err := conn.Do("SET", key, 10)
+			Expect(err).NotTo(HaveOccurred())
+
+			diffs, err := models.RebuildDonationWeights(game.ID, "", false, &models.RealClock{}, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diffs).To(HaveLen(1))
+			Expect(diffs[0].Expected).To(Equal(0))
+
+			Expect(getWeight(key)).To(Equal(0))
+		})
+
+		It("Should not change counters of other clans when the clan id has glob characters", func() {
+			diffs, err := models.RebuildDonationWeights(game.ID, "*", false, &models.RealClock{}, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diffs).To(BeEmpty())
+
+			key := models.GetDonationWeightKey("clan", game.ID, clanID, time.Now().UTC(), models.NoReset)
+			Expect(getWeight(key)).To(Equal(3))
+		})
+	})
+})
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"github.com/topfreegames/donations/events"
+	"github.com/topfreegames/donations/log"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+)
+
+const (
+	//DonationRequestCreatedEvent is emitted when a donation request is created
+	DonationRequestCreatedEvent = "donationRequest.created"
+	//DonationCreatedEvent is emitted when a donation is made to a donation request
+	DonationCreatedEvent = "donation.created"
+	//DonationRequestFinishedEvent is emitted when a donation request reaches its item limit
+	DonationRequestFinishedEvent = "donationRequest.finished"
+	//DonationRequestExpiredEvent is emitted when an unfinished donation request expires
+	DonationRequestExpiredEvent = "donationRequest.expired"
+)
+
+var eventPublisher events.EventPublisher = &events.NoopPublisher{}
+
+//SetEventPublisher sets the publisher that receives every event emitted by the models
+func SetEventPublisher(publisher events.EventPublisher) {
+	eventPublisher = publisher
+}
+
+//GetEventPublisher returns the publisher that receives every event emitted by the models
+func GetEventPublisher() events.EventPublisher {
+	return eventPublisher
+}
+
+//emitEvent dispatches webhooks and publishes an event for a change that was already saved.
+//Failures are only logged, since the change can't be rolled back anymore.
+func emitEvent(gameID, eventType string, toJSON func() ([]byte, error), clock Clock, db *mgo.Database, l zap.Logger) {
+	data, err := toJSON()
+	if err != nil {
+		log.E(l, "Failed to emit event.", func(cm log.CM) {
+			cm.Write(zap.String("event", eventType), zap.Error(err))
+		})
+		return
+	}
+
+	err = DispatchWebhookEvent(gameID, eventType, data, clock, db, l)
+	if err != nil {
+		log.E(l, "Failed to dispatch webhook event.", func(cm log.CM) {
+			cm.Write(zap.String("event", eventType), zap.Error(err))
+		})
+	}
+
+	err = eventPublisher.Publish(events.NewEvent(eventType, gameID, clock.GetUTCTime(), data))
+	if err != nil {
+		log.E(l, "Failed to publish event.", func(cm log.CM) {
+			cm.Write(zap.String("event", eventType), zap.Error(err))
+		})
+	}
+}
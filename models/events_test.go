@@ -0,0 +1,89 @@
+package models_test
+
+import (
+	"encoding/json"
+
+	mgo "gopkg.in/mgo.v2"
+
+	"github.com/garyburd/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/events"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Events Model", func() {
+	var logger zap.Logger
+	var session *mgo.Session
+	var db *mgo.Database
+	var r *redis.Pool
+	var game *models.Game
+	var player *models.Player
+	var publisher *MockEventPublisher
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		session, db = GetTestMongoDB()
+		r = GetTestRedis()
+
+		publisher = &MockEventPublisher{}
+		models.SetEventPublisher(publisher)
+
+		var err error
+		game, err = GetTestGame(db, logger, true, map[string]interface{}{
+			"LimitOfItemsInEachDonationRequest": 2,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		player, err = GetTestPlayer(game, db, logger)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		models.SetEventPublisher(&events.NoopPublisher{})
+		session.Close()
+		session = nil
+		db = nil
+	})
+
+	Describe("Publishing events", func() {
+		It("Should publish donation request and donation events", func() {
+			dr, err := GetTestDonationRequest(game, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = dr.Donate(player.ID, 2, 100, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			published := publisher.Events(game.ID)
+			Expect(published).To(HaveLen(3))
+			Expect(published[0].Type).To(Equal(models.DonationRequestCreatedEvent))
+			Expect(published[1].Type).To(Equal(models.DonationCreatedEvent))
+			Expect(published[2].Type).To(Equal(models.DonationRequestFinishedEvent))
+
+			var donation models.Donation
+			err = json.Unmarshal(published[1].Data, &donation)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(donation.Player).To(Equal(player.ID))
+			Expect(donation.Amount).To(Equal(2))
+			Expect(donation.DonationRequestID).To(Equal(dr.ID))
+		})
+
+		It("Should not publish events for failed donations", func() {
+			dr, err := GetTestDonationRequest(game, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = dr.Donate(player.ID, 3, 100, r, db, logger)
+			Expect(err).To(HaveOccurred())
+
+			published := publisher.Events(game.ID)
+			Expect(published).To(HaveLen(1))
+			Expect(published[0].Type).To(Equal(models.DonationRequestCreatedEvent))
+		})
+	})
+})
@@ -3,6 +3,7 @@ package models
 //go:generate easyjson -no_std_marshalers $GOFILE
 
 import (
+	"sort"
 	"time"
 
 	"github.com/mailru/easyjson/jlexer"
@@ -45,17 +46,15 @@ func (g *Game) Save(db *mgo.Database, logger zap.Logger) error {
 	}
 
 	log.D(l, "Saving game...")
-	info, err := GetGamesCollection(db).Upsert(
-		M{"_id": g.ID},
-		M{
-			"_id":                          g.ID,
-			"name":                         g.Name,
-			"items":                        g.Items,
-			"donationCooldownHours":        g.DonationCooldownHours,
-			"donationRequestCooldownHours": g.DonationRequestCooldownHours,
-			"updatedAt":                    time.Now().UTC(),
-		},
-	)
+	var info *mgo.ChangeInfo
+	err := observeMongo("games", "upsert", func() error {
+		var err error
+		info, err = GetGamesCollection(db).Upsert(
+			M{"_id": g.ID},
+			g.getDocument(time.Now().UTC()),
+		)
+		return err
+	})
 
 	if err != nil {
 		log.E(l, "Failed to save game.", func(cm log.CM) {
@@ -75,6 +74,18 @@ func (g *Game) Save(db *mgo.Database, logger zap.Logger) error {
 	return nil
 }
 
+//getDocument returns the document that replaces the game in mongo
+func (g *Game) getDocument(updatedAt time.Time) M {
+	return M{
+		"_id":                          g.ID,
+		"name":                         g.Name,
+		"items":                        g.Items,
+		"donationCooldownHours":        g.DonationCooldownHours,
+		"donationRequestCooldownHours": g.DonationRequestCooldownHours,
+		"updatedAt":                    updatedAt,
+	}
+}
+
 //AddItem to this game
 func (g *Game) AddItem(
 	key string, metadata map[string]interface{},
@@ -97,6 +108,16 @@ func (g *Game) AddItem(
 	return item, nil
 }
 
+//Clone returns a copy of the game that is not affected by changes to its items
+func (g *Game) Clone() *Game {
+	clone := *g
+	clone.Items = map[string]Item{}
+	for key, item := range g.Items {
+		clone.Items[key] = item
+	}
+	return &clone
+}
+
 //ToJSON marshals game to json
 func (g *Game) ToJSON() ([]byte, error) {
 	w := jwriter.Writer{}
@@ -135,7 +156,9 @@ func GetGamesCollection(db *mgo.Database) *mgo.Collection {
 //GetGameByID rtrieves the game by its id
 func GetGameByID(id string, db *mgo.Database, logger zap.Logger) (*Game, error) {
 	var game Game
-	err := GetGamesCollection(db).FindId(id).One(&game)
+	err := observeMongo("games", "find", func() error {
+		return GetGamesCollection(db).FindId(id).One(&game)
+	})
 	if err != nil {
 		if err.Error() == NotFoundString {
 			return nil, errors.NewDocumentNotFoundError("games", id)
@@ -144,3 +167,16 @@ func GetGameByID(id string, db *mgo.Database, logger zap.Logger) (*Game, error)
 	}
 	return &game, nil
 }
+
+//GetGameIDs returns the ids of all games
+func GetGameIDs(db *mgo.Database) ([]string, error) {
+	var ids []string
+	err := observeMongo("games", "distinct", func() error {
+		return GetGamesCollection(db).Find(nil).Distinct("_id", &ids)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
@@ -0,0 +1,265 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/log"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+	yaml "gopkg.in/yaml.v2"
+)
+
+//Formats of the game configuration files
+const (
+	//GameConfigJSON is the JSON format of game configuration files
+	GameConfigJSON = "json"
+	//GameConfigYAML is the YAML format of game configuration files
+	GameConfigYAML = "yaml"
+)
+
+//ItemConfig is the declarative configuration of an item in a game configuration file
+type ItemConfig struct {
+	Metadata map[string]interface{} `json:"metadata" yaml:"metadata"`
+
+	LimitOfItemsInEachDonationRequest int `json:"limitOfItemsInEachDonationRequest" yaml:"limitOfItemsInEachDonationRequest"`
+	LimitOfItemsPerPlayerDonation     int `json:"limitOfItemsPerPlayerDonation" yaml:"limitOfItemsPerPlayerDonation"`
+	WeightPerDonation                 int `json:"weightPerDonation" yaml:"weightPerDonation"`
+}
+
+//GameConfig is the declarative configuration of a game and all of its items, kept in files.
+//Items missing from the file are removed from the game when it is applied.
+type GameConfig struct {
+	ID                           string                 `json:"id" yaml:"id"`
+	Name                         string                 `json:"name" yaml:"name"`
+	DonationCooldownHours        int                    `json:"donationCooldownHours" yaml:"donationCooldownHours"`
+	DonationRequestCooldownHours int                    `json:"donationRequestCooldownHours" yaml:"donationRequestCooldownHours"`
+	Items                        map[string]*ItemConfig `json:"items" yaml:"items"`
+}
+
+//NewGameConfig returns the configuration of a game
+func NewGameConfig(game *Game) *GameConfig {
+	config := &GameConfig{
+		ID:                           game.ID,
+		Name:                         game.Name,
+		DonationCooldownHours:        game.DonationCooldownHours,
+		DonationRequestCooldownHours: game.DonationRequestCooldownHours,
+		Items:                        map[string]*ItemConfig{},
+	}
+	for key, item := range game.Items {
+		config.Items[key] = &ItemConfig{
+			Metadata:                          item.Metadata,
+			LimitOfItemsInEachDonationRequest: item.LimitOfItemsInEachDonationRequest,
+			LimitOfItemsPerPlayerDonation:     item.LimitOfItemsPerPlayerDonation,
+			WeightPerDonation:                 item.WeightPerDonation,
+		}
+	}
+	return config
+}
+
+//normalizeYAML converts the maps decoded by yaml, which have interface{} keys, to maps with string keys
+func normalizeYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		normalized := map[string]interface{}{}
+		for key, item := range v {
+			normalized[fmt.Sprintf("%v", key)] = normalizeYAML(item)
+		}
+		return normalized
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeYAML(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+//GetGameConfigFromBytes parses a game configuration file in the given format
+func GetGameConfigFromBytes(data []byte, format string) (*GameConfig, error) {
+	switch format {
+	case GameConfigJSON:
+	case GameConfigYAML:
+		//Decoding through JSON makes metadata from YAML files look like the one sent to the API
+		var value interface{}
+		err := yaml.Unmarshal(data, &value)
+		if err != nil {
+			return nil, err
+		}
+		data, err = json.Marshal(normalizeYAML(value))
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("Unknown game configuration format %s.", format)
+	}
+
+	config := &GameConfig{}
+	err := json.Unmarshal(data, config)
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+//Marshal returns the game configuration in the given format
+func (c *GameConfig) Marshal(format string) ([]byte, error) {
+	switch format {
+	case GameConfigJSON:
+		data, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case GameConfigYAML:
+		return yaml.Marshal(c)
+	default:
+		return nil, fmt.Errorf("Unknown game configuration format %s.", format)
+	}
+}
+
+//Validate returns the missing fields of the game configuration, with the same rules of the API
+func (c *GameConfig) Validate() []string {
+	errs := []string{}
+	required := func(name string, missing bool) {
+		if missing {
+			errs = append(errs, fmt.Sprintf("%s is required", name))
+		}
+	}
+	required("id", c.ID == "")
+	required("name", c.Name == "")
+	required("donationCooldownHours", c.DonationCooldownHours == 0)
+	required("donationRequestCooldownHours", c.DonationRequestCooldownHours == 0)
+
+	for _, key := range getSortedItemConfigKeys(c.Items) {
+		item := c.Items[key]
+		field := fmt.Sprintf("items.%s", key)
+		if item == nil {
+			required(field, true)
+			continue
+		}
+		required(fmt.Sprintf("%s.metadata", field), len(item.Metadata) == 0)
+		required(fmt.Sprintf("%s.limitOfItemsInEachDonationRequest", field), item.LimitOfItemsInEachDonationRequest == 0)
+		required(fmt.Sprintf("%s.limitOfItemsPerPlayerDonation", field), item.LimitOfItemsPerPlayerDonation == 0)
+		required(fmt.Sprintf("%s.weightPerDonation", field), item.WeightPerDonation == 0)
+	}
+	return errs
+}
+
+func getSortedItemConfigKeys(items map[string]*ItemConfig) []string {
+	keys := []string{}
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//ToGame returns the game described by the configuration. Items that did not change
+//keep their update time from current, which is nil if the game does not exist yet.
+func (c *GameConfig) ToGame(current *Game, clock Clock) *Game {
+	game := NewGame(c.Name, c.ID, c.DonationCooldownHours, c.DonationRequestCooldownHours)
+	for key, config := range c.Items {
+		item := NewItem(
+			key, config.Metadata,
+			config.WeightPerDonation,
+			config.LimitOfItemsPerPlayerDonation,
+			config.LimitOfItemsInEachDonationRequest,
+		)
+		item.UpdatedAt = clock.GetUTCTime().Unix()
+		if current != nil {
+			if existing, ok := current.Items[key]; ok && len(diffItems(key, &existing, item)) == 0 {
+				item.UpdatedAt = existing.UpdatedAt
+			}
+		}
+		game.Items[key] = *item
+	}
+	return game
+}
+
+//getCurrentGame returns the game or nil if it does not exist
+func getCurrentGame(gameID string, db *mgo.Database, logger zap.Logger) (*Game, error) {
+	game, err := GetGameByID(gameID, db, logger)
+	if _, ok := err.(*errors.DocumentNotFoundError); ok {
+		return nil, nil
+	}
+	return game, err
+}
+
+//DiffGameConfig returns the changes that applying the configuration would make to the game in the database
+func DiffGameConfig(config *GameConfig, clock Clock, db *mgo.Database, logger zap.Logger) ([]*AuditChange, error) {
+	current, err := getCurrentGame(config.ID, db, logger)
+	if err != nil {
+		return nil, err
+	}
+	return DiffGames(current, config.ToGame(current, clock)), nil
+}
+
+//ApplyGameConfig replaces the game and all of its items with the configuration in a single write,
+//creating the game if it does not exist. The write fails with GameConcurrentlyUpdatedError if the game
+//is changed after it is read. The changes are recorded in the audit log on behalf of actor.
+func ApplyGameConfig(
+	config *GameConfig, actor string,
+	clock Clock, db *mgo.Database, logger zap.Logger,
+) ([]*AuditChange, error) {
+	l := logger.With(
+		zap.String("source", "GameConfigModel"),
+		zap.String("operation", "ApplyGameConfig"),
+		zap.String("gameID", config.ID),
+		zap.String("actor", actor),
+	)
+
+	current, err := getCurrentGame(config.ID, db, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	game := config.ToGame(current, clock)
+	changes := DiffGames(current, game)
+	if len(changes) == 0 {
+		log.D(l, "Game configuration did not change.")
+		return changes, nil
+	}
+
+	log.D(l, "Applying game configuration...", func(cm log.CM) {
+		cm.Write(zap.Int("changes", len(changes)))
+	})
+	game.UpdatedAt = clock.GetUTCTime()
+	if current == nil {
+		err = observeMongo("games", "insert", func() error {
+			return GetGamesCollection(db).Insert(game.getDocument(game.UpdatedAt))
+		})
+		if mgo.IsDup(err) {
+			err = &errors.GameConcurrentlyUpdatedError{GameID: config.ID}
+		}
+	} else {
+		err = observeMongo("games", "update", func() error {
+			return GetGamesCollection(db).Update(
+				M{"_id": config.ID, "updatedAt": current.UpdatedAt},
+				game.getDocument(game.UpdatedAt),
+			)
+		})
+		if err == mgo.ErrNotFound {
+			err = &errors.GameConcurrentlyUpdatedError{GameID: config.ID}
+		}
+	}
+	if err != nil {
+		log.E(l, "Failed to apply game configuration.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return nil, err
+	}
+
+	_, err = RecordAuditEntry(config.ID, actor, "", AuditImportConfig, current, game.Clone(), clock, db, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	log.I(l, "Game configuration applied successfully.", func(cm log.CM) {
+		cm.Write(zap.Int("changes", len(changes)))
+	})
+	return changes, nil
+}
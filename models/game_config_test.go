@@ -0,0 +1,169 @@
+package models_test
+
+import (
+	mgo "gopkg.in/mgo.v2"
+
+	uuid "github.com/satori/go.uuid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Game Config Model", func() {
+	var logger zap.Logger
+	var session *mgo.Session
+	var db *mgo.Database
+	var game *models.Game
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		session, db = GetTestMongoDB()
+
+		var err error
+		game, err = GetTestGame(db, logger, true)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		session.Close()
+		session = nil
+		db = nil
+	})
+
+	Describe("Parsing configuration files", func() {
+		It("Should parse YAML files with nested metadata", func() {
+			config, err := models.GetGameConfigFromBytes([]byte(`
+id: my-game
+name: My Game
+donationCooldownHours: 8
+donationRequestCooldownHours: 24
+items:
+  sword:
+    metadata:
+      rarity: rare
+      stats:
+        attack: 10
+    limitOfItemsInEachDonationRequest: 6
+    limitOfItemsPerPlayerDonation: 2
+    weightPerDonation: 1
+`), models.GameConfigYAML)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.ID).To(Equal("my-game"))
+			Expect(config.DonationRequestCooldownHours).To(Equal(24))
+			Expect(config.Items["sword"].WeightPerDonation).To(Equal(1))
+			Expect(config.Items["sword"].Metadata["stats"]).To(Equal(map[string]interface{}{"attack": float64(10)}))
+			Expect(config.Validate()).To(BeEmpty())
+		})
+
+		It("Should export and parse the same configuration in every format", func() {
+			config := models.NewGameConfig(game)
+			for _, format := range []string{models.GameConfigJSON, models.GameConfigYAML} {
+				data, err := config.Marshal(format)
+				Expect(err).NotTo(HaveOccurred())
+
+				parsed, err := models.GetGameConfigFromBytes(data, format)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(models.DiffGames(game, parsed.ToGame(game, &MockClock{Time: 100}))).To(BeEmpty())
+			}
+		})
+
+		It("Should fail for unknown formats", func() {
+			_, err := models.GetGameConfigFromBytes([]byte("{}"), "xml")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should return missing fields", func() {
+			config := &models.GameConfig{
+				ID:    "my-game",
+				Items: map[string]*models.ItemConfig{"sword": &models.ItemConfig{WeightPerDonation: 1}},
+			}
+			Expect(config.Validate()).To(Equal([]string{
+				"name is required",
+				"donationCooldownHours is required",
+				"donationRequestCooldownHours is required",
+				"items.sword.metadata is required",
+				"items.sword.limitOfItemsInEachDonationRequest is required",
+				"items.sword.limitOfItemsPerPlayerDonation is required",
+			}))
+		})
+	})
+
+	Describe("Diffing configuration files", func() {
+		It("Should return the changes to the game in the database", func() {
+			config := models.NewGameConfig(game)
+			config.Name = "other name"
+			config.Items["item-1"].WeightPerDonation = 10
+			delete(config.Items, "item-2")
+
+			changes, err := models.DiffGameConfig(config, &MockClock{Time: 100}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changes).To(HaveLen(3))
+			Expect(changes[0].Field).To(Equal("name"))
+			Expect(changes[1].Field).To(Equal("items.item-1.weightPerDonation"))
+			Expect(changes[2].Field).To(Equal("items.item-2"))
+
+			dbGame, err := models.GetGameByID(game.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dbGame.Name).To(Equal(game.Name))
+		})
+	})
+
+	Describe("Applying configuration files", func() {
+		It("Should replace the game and its items and record the change", func() {
+			config := models.NewGameConfig(game)
+			config.DonationCooldownHours = 12
+			config.Items["item-1"].LimitOfItemsPerPlayerDonation = 5
+			delete(config.Items, "item-2")
+
+			changes, err := models.ApplyGameConfig(config, "deployer", &MockClock{Time: 100}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changes).To(HaveLen(3))
+
+			dbGame, err := models.GetGameByID(game.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dbGame.DonationCooldownHours).To(Equal(12))
+			Expect(dbGame.Items).To(HaveLen(len(game.Items) - 1))
+			Expect(dbGame.Items["item-1"].LimitOfItemsPerPlayerDonation).To(Equal(5))
+			Expect(dbGame.Items["item-1"].UpdatedAt).To(BeEquivalentTo(100))
+			Expect(dbGame.Items["item-3"].UpdatedAt).To(Equal(game.Items["item-3"].UpdatedAt))
+
+			entries, err := models.GetAuditLog(game.ID, 10, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Action).To(Equal(models.AuditImportConfig))
+			Expect(entries[0].Actor).To(Equal("deployer"))
+			Expect(entries[0].Changes).To(HaveLen(3))
+		})
+
+		It("Should create the game if it does not exist", func() {
+			config := models.NewGameConfig(game)
+			config.ID = uuid.NewV4().String()
+
+			changes, err := models.ApplyGameConfig(config, "deployer", &MockClock{Time: 100}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changes).NotTo(BeEmpty())
+
+			dbGame, err := models.GetGameByID(config.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dbGame.Name).To(Equal(game.Name))
+			Expect(dbGame.Items).To(HaveLen(len(game.Items)))
+		})
+
+		It("Should not change the game if the configuration is the same", func() {
+			changes, err := models.ApplyGameConfig(models.NewGameConfig(game), "deployer", &MockClock{Time: 100}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changes).To(BeEmpty())
+
+			entries, err := models.GetAuditLog(game.ID, 10, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(BeEmpty())
+		})
+	})
+})
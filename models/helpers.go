@@ -1,9 +1,31 @@
 package models
 
-import "gopkg.in/mgo.v2/bson"
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
 
 //M is an alias for bson.M
 type M bson.M
 
 // NotFoundString is the string returned when an element is not found
 var NotFoundString = "not found"
+
+//MongoOperationObserver receives the latency of the MongoDB operations of the models
+type MongoOperationObserver func(collection, operation string, duration time.Duration)
+
+var mongoOperationObserver MongoOperationObserver = func(collection, operation string, duration time.Duration) {}
+
+//SetMongoOperationObserver sets the observer of the latency of the MongoDB operations of the models
+func SetMongoOperationObserver(observer MongoOperationObserver) {
+	mongoOperationObserver = observer
+}
+
+//observeMongo runs an operation of a collection and observes its latency
+func observeMongo(collection, operation string, f func() error) error {
+	start := time.Now()
+	err := f()
+	mongoOperationObserver(collection, operation, time.Now().Sub(start))
+	return err
+}
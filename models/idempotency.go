@@ -0,0 +1,113 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/log"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+//IdempotentResponse represents the outcome of a request sent with an idempotency key.
+//MongoDB removes it after ExpiresAt, through the TTL index in Indexes.
+type IdempotentResponse struct {
+	ID          string    `json:"id" bson:"_id"`
+	GameID      string    `json:"gameID" bson:"gameID"`
+	Route       string    `json:"route" bson:"route"`
+	Key         string    `json:"key" bson:"key"`
+	Path        string    `json:"path" bson:"path"`
+	RequestHash string    `json:"requestHash" bson:"requestHash"`
+	Status      int       `json:"status" bson:"status"`
+	ContentType string    `json:"contentType" bson:"contentType,omitempty"`
+	Body        string    `json:"body" bson:"body"`
+	CreatedAt   time.Time `json:"createdAt" bson:"createdAt"`
+	ExpiresAt   time.Time `json:"expiresAt" bson:"expiresAt"`
+}
+
+//GetIdempotentRequestHash returns the hash of a request body, stored to detect keys reused for different requests
+func GetIdempotentRequestHash(body []byte) string {
+	hash := sha256.Sum256(body)
+	return hex.EncodeToString(hash[:])
+}
+
+//GetIdempotentResponseID returns the document id for an idempotency key in a given game and route
+func GetIdempotentResponseID(gameID, route, key string) string {
+	return fmt.Sprintf("%s::%s::%s", gameID, route, key)
+}
+
+//GetIdempotentResponsesCollection to update or query idempotent responses
+func GetIdempotentResponsesCollection(db *mgo.Database) *mgo.Collection {
+	return db.C("idempotentResponses")
+}
+
+//GetIdempotentResponse retrieves the response stored for the given key if it is not older than ttl seconds
+func GetIdempotentResponse(gameID, route, key string, ttl int, clock Clock, db *mgo.Database, logger zap.Logger) (*IdempotentResponse, error) {
+	id := GetIdempotentResponseID(gameID, route, key)
+	minCreatedAt := clock.GetUTCTime().Add(-time.Duration(ttl) * time.Second)
+
+	var response IdempotentResponse
+	err := observeMongo("idempotentResponses", "find", func() error {
+		return GetIdempotentResponsesCollection(db).Find(bson.M{
+			"_id":       id,
+			"createdAt": bson.M{"$gte": minCreatedAt},
+		}).One(&response)
+	})
+	if err != nil {
+		if err.Error() == NotFoundString {
+			return nil, errors.NewDocumentNotFoundError("idempotentResponses", id)
+		}
+		return nil, err
+	}
+	return &response, nil
+}
+
+//SaveIdempotentResponse stores the response for the given key, replacing any expired one.
+//The response expires after ttl seconds.
+func SaveIdempotentResponse(
+	gameID, route, key, path, requestHash string,
+	status int, contentType, body string,
+	ttl int, clock Clock,
+	db *mgo.Database, logger zap.Logger,
+) error {
+	l := logger.With(
+		zap.String("source", "IdempotentResponseModel"),
+		zap.String("operation", "SaveIdempotentResponse"),
+		zap.String("gameID", gameID),
+		zap.String("route", route),
+		zap.String("key", key),
+	)
+
+	createdAt := clock.GetUTCTime()
+	response := &IdempotentResponse{
+		ID:          GetIdempotentResponseID(gameID, route, key),
+		GameID:      gameID,
+		Route:       route,
+		Key:         key,
+		Path:        path,
+		RequestHash: requestHash,
+		Status:      status,
+		ContentType: contentType,
+		Body:        body,
+		CreatedAt:   createdAt,
+		ExpiresAt:   createdAt.Add(time.Duration(ttl) * time.Second),
+	}
+
+	err := observeMongo("idempotentResponses", "upsert", func() error {
+		_, err := GetIdempotentResponsesCollection(db).UpsertId(response.ID, response)
+		return err
+	})
+	if err != nil {
+		log.E(l, "Failed to save idempotent response.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return err
+	}
+
+	log.D(l, "Idempotent response saved successfully.")
+	return nil
+}
@@ -0,0 +1,96 @@
+package models_test
+
+import (
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	uuid "github.com/satori/go.uuid"
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Idempotent Response Model", func() {
+	var logger zap.Logger
+	var session *mgo.Session
+	var db *mgo.Database
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		session, db = GetTestMongoDB()
+	})
+
+	AfterEach(func() {
+		session.Close()
+		session = nil
+		db = nil
+	})
+
+	Describe("Save and retrieve idempotent responses", func() {
+		It("Should retrieve a stored response", func() {
+			gameID := uuid.NewV4().String()
+			key := uuid.NewV4().String()
+			clock := &MockClock{Time: time.Now().UTC().Unix()}
+
+			err := models.SaveIdempotentResponse(
+				gameID, "CreateDonation", key, "/some/path", "hash", 200, "application/json", "{\"success\":true}", 60, clock, db, logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := models.GetIdempotentResponse(gameID, "CreateDonation", key, 60, clock, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Status).To(Equal(200))
+			Expect(res.ContentType).To(Equal("application/json"))
+			Expect(res.Body).To(Equal("{\"success\":true}"))
+			Expect(res.Path).To(Equal("/some/path"))
+			Expect(res.RequestHash).To(Equal("hash"))
+			Expect(res.ExpiresAt.Unix()).To(Equal(clock.Time + 60))
+		})
+
+		It("Should not retrieve a response for another route", func() {
+			gameID := uuid.NewV4().String()
+			key := uuid.NewV4().String()
+			clock := &MockClock{Time: time.Now().UTC().Unix()}
+
+			err := models.SaveIdempotentResponse(gameID, "CreateDonation", key, "/some/path", "hash", 200, "application/json", "{}", 60, clock, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = models.GetIdempotentResponse(gameID, "CreateDonationRequest", key, 60, clock, db, logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err).To(BeAssignableToTypeOf(&errors.DocumentNotFoundError{}))
+		})
+
+		It("Should not retrieve an expired response", func() {
+			gameID := uuid.NewV4().String()
+			key := uuid.NewV4().String()
+			clock := &MockClock{Time: time.Now().UTC().Unix() - 120}
+
+			err := models.SaveIdempotentResponse(gameID, "CreateDonation", key, "/some/path", "hash", 200, "application/json", "{}", 60, clock, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			clock.Time = clock.Time + 120
+			_, err = models.GetIdempotentResponse(gameID, "CreateDonation", key, 60, clock, db, logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err).To(BeAssignableToTypeOf(&errors.DocumentNotFoundError{}))
+		})
+	})
+
+	Describe("Request hash", func() {
+		It("Should hash equal bodies equally", func() {
+			Expect(models.GetIdempotentRequestHash([]byte("{\"amount\":1}"))).To(
+				Equal(models.GetIdempotentRequestHash([]byte("{\"amount\":1}"))),
+			)
+			Expect(models.GetIdempotentRequestHash([]byte("{\"amount\":1}"))).NotTo(
+				Equal(models.GetIdempotentRequestHash([]byte("{\"amount\":2}"))),
+			)
+		})
+	})
+})
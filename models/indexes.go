@@ -0,0 +1,212 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/topfreegames/donations/log"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+)
+
+//CollectionIndex represents an index the application expects to exist in a given collection
+type CollectionIndex struct {
+	Collection string
+	Index      mgo.Index
+}
+
+//GetKey returns the index key as a comma separated string (i.e.: player,-createdAt)
+func (c *CollectionIndex) GetKey() string {
+	return strings.Join(c.Index.Key, ",")
+}
+
+//Indexes registers every index used by the queries in this package.
+//The games collection is only queried by _id, so it needs no extra indexes.
+var Indexes = []*CollectionIndex{
+	//Donation request cooldown per player and donation verification per game
+	&CollectionIndex{
+		Collection: "requests",
+		Index:      mgo.Index{Key: []string{"gameID", "player", "createdAt"}, Background: true},
+	},
+	//Donation weight per player
+	&CollectionIndex{
+		Collection: "requests",
+		Index:      mgo.Index{Key: []string{"gameID", "donations.player", "donations.createdAt"}, Background: true},
+	},
+	//Archiving of donation requests
+	&CollectionIndex{
+		Collection: "requests",
+		Index:      mgo.Index{Key: []string{"updatedAt"}, Background: true},
+	},
+	//Expiration of donation requests
+	&CollectionIndex{
+		Collection: "requests",
+		Index:      mgo.Index{Key: []string{"createdAt"}, Background: true},
+	},
+	//Donation weight per player in archived donation requests
+	&CollectionIndex{
+		Collection: "archivedRequests",
+		Index:      mgo.Index{Key: []string{"gameID", "donations.player", "donations.createdAt"}, Background: true},
+	},
+	//Donation verification per donation request
+	&CollectionIndex{
+		Collection: "donations",
+		Index:      mgo.Index{Key: []string{"donationRequestID"}, Background: true},
+	},
+	//Donation weight rebuild per game and clan
+	&CollectionIndex{
+		Collection: "donations",
+		Index:      mgo.Index{Key: []string{"gameID", "clan"}, Background: true},
+	},
+	//Players are identified by game and player id
+	&CollectionIndex{
+		Collection: "players",
+		Index:      mgo.Index{Key: []string{"gameID", "playerID"}, Unique: true, Background: true},
+	},
+	//Webhooks subscribed to an event of a game
+	&CollectionIndex{
+		Collection: "webhooks",
+		Index:      mgo.Index{Key: []string{"gameID", "events"}, Background: true},
+	},
+	//Pending webhook deliveries that are due
+	&CollectionIndex{
+		Collection: "webhookDeliveries",
+		Index:      mgo.Index{Key: []string{"status", "nextAttemptAt"}, Background: true},
+	},
+	//Webhook dead-letter store per game
+	&CollectionIndex{
+		Collection: "webhookDeliveries",
+		Index:      mgo.Index{Key: []string{"gameID", "status", "createdAt"}, Background: true},
+	},
+	//Versions of the configuration of a game
+	&CollectionIndex{
+		Collection: "auditLog",
+		Index:      mgo.Index{Key: []string{"gameID", "version"}, Unique: true, Background: true},
+	},
+	//API keys of a game
+	&CollectionIndex{
+		Collection: "apiKeys",
+		Index:      mgo.Index{Key: []string{"gameID", "createdAt"}, Background: true},
+	},
+	//Removal of expired idempotent responses
+	IdempotentResponsesTTLIndex,
+}
+
+//IdempotentResponsesTTLIndex makes MongoDB remove idempotent responses once they expire.
+//Documents are removed by a background task that runs every minute, so reads still check the TTL.
+var IdempotentResponsesTTLIndex = &CollectionIndex{
+	Collection: "idempotentResponses",
+	Index:      mgo.Index{Key: []string{"expiresAt"}, ExpireAfter: time.Second, Background: true},
+}
+
+//isNamespaceNotFound returns true if the error means the collection does not exist yet
+func isNamespaceNotFound(err error) bool {
+	if qErr, ok := err.(*mgo.QueryError); ok && qErr.Code == 26 {
+		return true
+	}
+	return strings.Contains(err.Error(), "ns does not exist")
+}
+
+//GetMissingIndexes returns the registered indexes that do not exist in the database
+func GetMissingIndexes(db *mgo.Database, logger zap.Logger) ([]*CollectionIndex, error) {
+	return GetMissingCollectionIndexes(Indexes, db, logger)
+}
+
+//GetMissingCollectionIndexes returns the given indexes that do not exist in the database
+func GetMissingCollectionIndexes(indexes []*CollectionIndex, db *mgo.Database, logger zap.Logger) ([]*CollectionIndex, error) {
+	existing := map[string]map[string]bool{}
+	missing := []*CollectionIndex{}
+
+	for _, index := range indexes {
+		keys, ok := existing[index.Collection]
+		if !ok {
+			indexes, err := db.C(index.Collection).Indexes()
+			if err != nil && !isNamespaceNotFound(err) {
+				return nil, err
+			}
+
+			keys = map[string]bool{}
+			for _, dbIndex := range indexes {
+				keys[strings.Join(dbIndex.Key, ",")] = true
+			}
+			existing[index.Collection] = keys
+		}
+
+		if !keys[index.GetKey()] {
+			missing = append(missing, index)
+		}
+	}
+
+	return missing, nil
+}
+
+//EnsureIndexes creates the registered indexes that do not exist in the database and returns them
+func EnsureIndexes(db *mgo.Database, logger zap.Logger) ([]*CollectionIndex, error) {
+	return EnsureCollectionIndexes(Indexes, db, logger)
+}
+
+//EnsureCollectionIndexes creates the given indexes that do not exist in the database and returns them
+func EnsureCollectionIndexes(indexes []*CollectionIndex, db *mgo.Database, logger zap.Logger) ([]*CollectionIndex, error) {
+	l := logger.With(
+		zap.String("source", "IndexesModel"),
+		zap.String("operation", "EnsureCollectionIndexes"),
+	)
+
+	missing, err := GetMissingCollectionIndexes(indexes, db, logger)
+	if err != nil {
+		log.E(l, "Failed to list indexes.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return nil, err
+	}
+
+	for _, index := range missing {
+		log.D(l, "Creating index...", func(cm log.CM) {
+			cm.Write(
+				zap.String("collection", index.Collection),
+				zap.String("key", index.GetKey()),
+			)
+		})
+		err = db.C(index.Collection).EnsureIndex(index.Index)
+		if err != nil {
+			log.E(l, "Failed to create index.", func(cm log.CM) {
+				cm.Write(
+					zap.String("collection", index.Collection),
+					zap.String("key", index.GetKey()),
+					zap.Error(err),
+				)
+			})
+			return nil, err
+		}
+	}
+
+	log.I(l, "Indexes ensured successfully.", func(cm log.CM) {
+		cm.Write(zap.Int("created", len(missing)))
+	})
+
+	return missing, nil
+}
+
+//DropCollectionIndexes drops the given indexes, ignoring the ones that do not exist
+func DropCollectionIndexes(indexes []*CollectionIndex, db *mgo.Database, logger zap.Logger) error {
+	l := logger.With(
+		zap.String("source", "IndexesModel"),
+		zap.String("operation", "DropCollectionIndexes"),
+	)
+
+	for _, index := range indexes {
+		err := db.C(index.Collection).DropIndex(index.Index.Key...)
+		if err != nil && !strings.Contains(err.Error(), "not found") {
+			log.E(l, "Failed to drop index.", func(cm log.CM) {
+				cm.Write(
+					zap.String("collection", index.Collection),
+					zap.String("key", index.GetKey()),
+					zap.Error(err),
+				)
+			})
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package models_test
+
+import (
+	mgo "gopkg.in/mgo.v2"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Indexes Model", func() {
+	var logger zap.Logger
+	var session *mgo.Session
+	var db *mgo.Database
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		session, db = GetTestMongoDB()
+	})
+
+	AfterEach(func() {
+		session.Close()
+		session = nil
+		db = nil
+	})
+
+	Describe("Ensuring indexes", func() {
+		It("Should create all registered indexes", func() {
+			_, err := models.EnsureIndexes(db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			missing, err := models.GetMissingIndexes(db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(missing).To(BeEmpty())
+
+			indexes, err := models.GetDonationRequestsCollection(db).Indexes()
+			Expect(err).NotTo(HaveOccurred())
+			keys := []string{}
+			for _, index := range indexes {
+				keys = append(keys, index.Name)
+			}
+			Expect(keys).To(ContainElement("gameID_1_player_1_createdAt_1"))
+			Expect(keys).To(ContainElement("gameID_1_donations.player_1_donations.createdAt_1"))
+		})
+
+		It("Should only create missing indexes", func() {
+			_, err := models.EnsureIndexes(db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = models.GetPlayersCollection(db).DropIndex("gameID", "playerID")
+			Expect(err).NotTo(HaveOccurred())
+
+			missing, err := models.GetMissingIndexes(db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(missing).To(HaveLen(1))
+			Expect(missing[0].Collection).To(Equal("players"))
+			Expect(missing[0].GetKey()).To(Equal("gameID,playerID"))
+
+			created, err := models.EnsureIndexes(db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(created).To(HaveLen(1))
+			Expect(created[0].Collection).To(Equal("players"))
+
+			missing, err = models.GetMissingIndexes(db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(missing).To(BeEmpty())
+		})
+	})
+})
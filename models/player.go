@@ -12,11 +12,12 @@ import (
 	"gopkg.in/mgo.v2/bson"
 )
 
-//Player represents one player in a given game
+//Player represents one player in a given game.
+//Players are identified by GameID and ID, so the same player ID in two games are different players.
 //easyjson:json
 type Player struct {
 	GameID              string `json:"gameID" bson:"gameID"`
-	ID                  string `json:"id" bson:"_id,omitempty"`
+	ID                  string `json:"id" bson:"playerID"`
 	DonationWindowStart int64  `json:"donationWindowStart" bson:"donationWindowStart"`
 }
 
@@ -34,6 +35,11 @@ func GetPlayersCollection(db *mgo.Database) *mgo.Collection {
 	return db.C("players")
 }
 
+//getPlayerQuery returns the query for the player with the specified id in a given game
+func getPlayerQuery(gameID, id string) bson.M {
+	return bson.M{"gameID": gameID, "playerID": id}
+}
+
 //EnsurePlayerExists upserts the player
 func EnsurePlayerExists(gameID, id string, db *mgo.Database, logger zap.Logger) error {
 	l := logger.With(
@@ -43,12 +49,15 @@ func EnsurePlayerExists(gameID, id string, db *mgo.Database, logger zap.Logger)
 		zap.String("playerID", id),
 	)
 
-	query := bson.M{"_id": id}
+	query := getPlayerQuery(gameID, id)
 	update := bson.M{
-		"gameID": gameID,
+		"$set": query,
 	}
 
-	_, err := GetPlayersCollection(db).Upsert(query, update)
+	err := observeMongo("players", "upsert", func() error {
+		_, err := GetPlayersCollection(db).Upsert(query, update)
+		return err
+	})
 	if err != nil {
 		log.E(l, "Failed to upsert player.", func(cm log.CM) {
 			cm.Write(zap.Error(err))
@@ -69,13 +78,15 @@ func UpdateDonationWindowStart(gameID, id string, timestamp int64, db *mgo.Datab
 		zap.Int64("timestamp", timestamp),
 	)
 
-	query := bson.M{"_id": id}
+	query := getPlayerQuery(gameID, id)
 	update := bson.M{
-		"donationWindowStart": timestamp,
-		"gameID":              gameID,
+		"$set": bson.M{"donationWindowStart": timestamp},
 	}
 
-	_, err := GetPlayersCollection(db).Upsert(query, update)
+	err := observeMongo("players", "upsert", func() error {
+		_, err := GetPlayersCollection(db).Upsert(query, update)
+		return err
+	})
 	if err != nil {
 		log.E(l, "Failed to set player donationWindowStart.", func(cm log.CM) {
 			cm.Write(zap.Error(err))
@@ -89,10 +100,12 @@ func UpdateDonationWindowStart(gameID, id string, timestamp int64, db *mgo.Datab
 
 }
 
-//GetPlayerByID rtrieves the game by its id
-func GetPlayerByID(id string, db *mgo.Database, logger zap.Logger) (*Player, error) {
+//GetPlayerByID rtrieves the player by its game and id
+func GetPlayerByID(gameID, id string, db *mgo.Database, logger zap.Logger) (*Player, error) {
 	var player Player
-	err := GetPlayersCollection(db).FindId(id).One(&player)
+	err := observeMongo("players", "find", func() error {
+		return GetPlayersCollection(db).Find(getPlayerQuery(gameID, id)).One(&player)
+	})
 	if err != nil {
 		if err.Error() == NotFoundString {
 			return nil, errors.NewDocumentNotFoundError("player", id)
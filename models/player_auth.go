@@ -0,0 +1,167 @@
+package models
+
+import (
+	"fmt"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/log"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+//PlayerAuthProviderJWT verifies players with JSON Web Tokens signed by the game
+const PlayerAuthProviderJWT = "jwt"
+
+//PlayerAuthAlgorithms are the signing algorithms player tokens can use. HS256 tokens are verified
+//with a shared secret, RS256 and ES256 tokens with a PEM encoded public key.
+var PlayerAuthAlgorithms = []string{"HS256", "RS256", "ES256"}
+
+//PlayerAuthConfig makes a game require signed player tokens to create donation requests and donate.
+//Games without it accept the player of the payload on trust.
+type PlayerAuthConfig struct {
+	GameID    string `json:"gameID" bson:"_id"`
+	Provider  string `json:"provider" bson:"provider"`
+	Algorithm string `json:"algorithm" bson:"algorithm"`
+	Key       string `json:"-" bson:"key"`
+	UpdatedAt int64  `json:"updatedAt" bson:"updatedAt"`
+}
+
+//PlayerClaims are the verified claims of a player token
+type PlayerClaims struct {
+	Player string
+	Clan   string
+}
+
+type playerTokenClaims struct {
+	jwt.StandardClaims
+	Clan string `json:"clan"`
+}
+
+//GetPlayerAuthCollection to update or query player authentication configs
+func GetPlayerAuthCollection(db *mgo.Database) *mgo.Collection {
+	return db.C("playerAuth")
+}
+
+//IsValidPlayerAuthAlgorithm returns true if player tokens can be signed with the algorithm
+func IsValidPlayerAuthAlgorithm(algorithm string) bool {
+	for _, a := range PlayerAuthAlgorithms {
+		if a == algorithm {
+			return true
+		}
+	}
+	return false
+}
+
+//getVerificationKey returns the key that verifies the signature of tokens
+func (p *PlayerAuthConfig) getVerificationKey() (interface{}, error) {
+	switch p.Algorithm {
+	case "HS256":
+		return []byte(p.Key), nil
+	case "RS256":
+		return jwt.ParseRSAPublicKeyFromPEM([]byte(p.Key))
+	case "ES256":
+		return jwt.ParseECPublicKeyFromPEM([]byte(p.Key))
+	}
+	return nil, fmt.Errorf("Algorithm %s is not supported.", p.Algorithm)
+}
+
+//ValidateKey returns an error if the key can't verify tokens of the algorithm
+func (p *PlayerAuthConfig) ValidateKey() error {
+	_, err := p.getVerificationKey()
+	return err
+}
+
+//AuthenticatePlayer verifies the signature and expiration of a token and that its subject is the player.
+//Any failure is returned as an AuthenticationFailed error, without the token.
+func (p *PlayerAuthConfig) AuthenticatePlayer(token, player string) (*PlayerClaims, error) {
+	failed := errors.NewAuthenticationFailed(p.Provider, player, "")
+	if token == "" {
+		return nil, failed
+	}
+
+	claims := &playerTokenClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method.Alg() != p.Algorithm {
+			return nil, fmt.Errorf("Unexpected signing algorithm %s.", t.Method.Alg())
+		}
+		return p.getVerificationKey()
+	})
+	//tokens must expire, so a leaked token can't be used forever
+	if err != nil || !parsed.Valid || claims.ExpiresAt == 0 || claims.Subject != player {
+		return nil, failed
+	}
+
+	return &PlayerClaims{Player: claims.Subject, Clan: claims.Clan}, nil
+}
+
+//SetPlayerAuthConfig makes a game require player tokens signed with the algorithm and verified with the key
+func SetPlayerAuthConfig(gameID, provider, algorithm, key string, clock Clock, db *mgo.Database, logger zap.Logger) (*PlayerAuthConfig, error) {
+	l := logger.With(
+		zap.String("source", "PlayerAuthModel"),
+		zap.String("operation", "SetPlayerAuthConfig"),
+		zap.String("gameID", gameID),
+		zap.String("provider", provider),
+		zap.String("algorithm", algorithm),
+	)
+
+	if provider != PlayerAuthProviderJWT {
+		return nil, errors.NewAuthenticationProviderNotSupported(provider)
+	}
+
+	config := &PlayerAuthConfig{
+		GameID:    gameID,
+		Provider:  provider,
+		Algorithm: algorithm,
+		Key:       key,
+		UpdatedAt: clock.GetUTCTime().Unix(),
+	}
+	//fail now instead of rejecting every player if the key can't be parsed
+	if err := config.ValidateKey(); err != nil {
+		return nil, err
+	}
+
+	err := observeMongo("playerAuth", "upsert", func() error {
+		_, err := GetPlayerAuthCollection(db).UpsertId(gameID, config)
+		return err
+	})
+	if err != nil {
+		log.E(l, "Failed to save player authentication config.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return nil, err
+	}
+
+	log.D(l, "Player authentication config saved successfully.")
+	return config, nil
+}
+
+//GetPlayerAuthConfig returns the player authentication config of a game
+func GetPlayerAuthConfig(gameID string, db *mgo.Database, logger zap.Logger) (*PlayerAuthConfig, error) {
+	var config PlayerAuthConfig
+	err := observeMongo("playerAuth", "find", func() error {
+		return GetPlayerAuthCollection(db).FindId(gameID).One(&config)
+	})
+	if err != nil {
+		if err.Error() == NotFoundString {
+			return nil, errors.NewDocumentNotFoundError("playerAuth", gameID)
+		}
+		return nil, err
+	}
+	return &config, nil
+}
+
+//RemovePlayerAuthConfig stops requiring player tokens in a game
+func RemovePlayerAuthConfig(gameID string, db *mgo.Database, logger zap.Logger) error {
+	err := observeMongo("playerAuth", "remove", func() error {
+		return GetPlayerAuthCollection(db).Remove(bson.M{"_id": gameID})
+	})
+	if err != nil {
+		if err.Error() == NotFoundString {
+			return errors.NewDocumentNotFoundError("playerAuth", gameID)
+		}
+		return err
+	}
+	return nil
+}
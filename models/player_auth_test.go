@@ -0,0 +1,142 @@
+package models_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Player Auth Model", func() {
+	var logger zap.Logger
+	var session *mgo.Session
+	var db *mgo.Database
+	var game *models.Game
+
+	sign := func(method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		Expect(err).NotTo(HaveOccurred())
+		return token
+	}
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		session, db = GetTestMongoDB()
+
+		var err error
+		game, err = GetTestGame(db, logger, true)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		session.Close()
+	})
+
+	Describe("Set player auth config", func() {
+		It("Should save the config of the game", func() {
+			_, err := models.SetPlayerAuthConfig(game.ID, models.PlayerAuthProviderJWT, "HS256", "secret", &models.RealClock{}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			config, err := models.GetPlayerAuthConfig(game.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Algorithm).To(Equal("HS256"))
+			Expect(config.Key).To(Equal("secret"))
+
+			err = models.RemovePlayerAuthConfig(game.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = models.GetPlayerAuthConfig(game.ID, db, logger)
+			Expect(err).To(Equal(errors.NewDocumentNotFoundError("playerAuth", game.ID)))
+		})
+
+		It("Should fail for unsupported providers and invalid keys", func() {
+			_, err := models.SetPlayerAuthConfig(game.ID, "facebook", "HS256", "secret", &models.RealClock{}, db, logger)
+			Expect(err).To(Equal(errors.NewAuthenticationProviderNotSupported("facebook")))
+
+			_, err = models.SetPlayerAuthConfig(game.ID, models.PlayerAuthProviderJWT, "RS256", "not a pem", &models.RealClock{}, db, logger)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Authenticate player", func() {
+		It("Should accept HS256 tokens of the player", func() {
+			config := &models.PlayerAuthConfig{Provider: models.PlayerAuthProviderJWT, Algorithm: "HS256", Key: "secret"}
+			token := sign(jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{
+				"sub":  "player-0",
+				"clan": "clan-0",
+				"exp":  time.Now().Add(time.Hour).Unix(),
+			})
+
+			claims, err := config.AuthenticatePlayer(token, "player-0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claims).To(Equal(&models.PlayerClaims{Player: "player-0", Clan: "clan-0"}))
+
+			_, err = config.AuthenticatePlayer(token, "player-1")
+			Expect(err).To(Equal(errors.NewAuthenticationFailed(models.PlayerAuthProviderJWT, "player-1", "")))
+			Expect(err.Error()).NotTo(ContainSubstring(token))
+		})
+
+		It("Should accept RS256 tokens verified with the public key", func() {
+			privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).NotTo(HaveOccurred())
+			publicKey, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+			Expect(err).NotTo(HaveOccurred())
+			publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})
+
+			config, err := models.SetPlayerAuthConfig(
+				game.ID, models.PlayerAuthProviderJWT, "RS256", string(publicPEM),
+				&models.RealClock{}, db, logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			token := sign(jwt.SigningMethodRS256, privateKey, jwt.MapClaims{
+				"sub": "player-0",
+				"exp": time.Now().Add(time.Hour).Unix(),
+			})
+			_, err = config.AuthenticatePlayer(token, "player-0")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should reject tokens with other keys, algorithms or without expiration", func() {
+			config := &models.PlayerAuthConfig{Provider: models.PlayerAuthProviderJWT, Algorithm: "HS256", Key: "secret"}
+			tokens := []string{
+				"",
+				"invalid",
+				sign(jwt.SigningMethodHS256, []byte("other"), jwt.MapClaims{
+					"sub": "player-0",
+					"exp": time.Now().Add(time.Hour).Unix(),
+				}),
+				sign(jwt.SigningMethodHS512, []byte("secret"), jwt.MapClaims{
+					"sub": "player-0",
+					"exp": time.Now().Add(time.Hour).Unix(),
+				}),
+				sign(jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{
+					"sub": "player-0",
+					"exp": time.Now().Add(-time.Hour).Unix(),
+				}),
+				sign(jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{
+					"sub": "player-0",
+				}),
+			}
+
+			for _, token := range tokens {
+				_, err := config.AuthenticatePlayer(token, "player-0")
+				Expect(err).To(BeAssignableToTypeOf(&errors.AuthenticationFailed{}), token)
+			}
+		})
+	})
+})
@@ -68,7 +68,7 @@ var _ = Describe("Player Model", func() {
 					err = models.UpdateDonationWindowStart(game.ID, player.ID, 500, db, logger)
 					Expect(err).NotTo(HaveOccurred())
 
-					dbPlayer, err := models.GetPlayerByID(player.ID, db, logger)
+					dbPlayer, err := models.GetPlayerByID(game.ID, player.ID, db, logger)
 					Expect(err).NotTo(HaveOccurred())
 					Expect(dbPlayer.DonationWindowStart).To(Equal(int64(500)))
 				})
@@ -82,10 +82,34 @@ var _ = Describe("Player Model", func() {
 					err = models.UpdateDonationWindowStart(game.ID, playerID, 500, db, logger)
 					Expect(err).NotTo(HaveOccurred())
 
-					dbPlayer, err := models.GetPlayerByID(playerID, db, logger)
+					dbPlayer, err := models.GetPlayerByID(game.ID, playerID, db, logger)
 					Expect(err).NotTo(HaveOccurred())
 					Expect(dbPlayer.DonationWindowStart).To(Equal(int64(500)))
 				})
+
+				It("Should keep players with the same id in different games apart", func() {
+					game, err := GetTestGame(db, logger, true)
+					Expect(err).NotTo(HaveOccurred())
+
+					otherGame, err := GetTestGame(db, logger, true)
+					Expect(err).NotTo(HaveOccurred())
+
+					playerID := uuid.NewV4().String()
+
+					err = models.UpdateDonationWindowStart(game.ID, playerID, 500, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+
+					err = models.UpdateDonationWindowStart(otherGame.ID, playerID, 1000, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+
+					dbPlayer, err := models.GetPlayerByID(game.ID, playerID, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(dbPlayer.DonationWindowStart).To(Equal(int64(500)))
+
+					dbPlayer, err = models.GetPlayerByID(otherGame.ID, playerID, db, logger)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(dbPlayer.DonationWindowStart).To(Equal(int64(1000)))
+				})
 			})
 		})
 	})
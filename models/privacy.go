@@ -0,0 +1,317 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	uuid "github.com/satori/go.uuid"
+	"github.com/topfreegames/donations/log"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+//PlayerDataExport represents everything stored about a player in a game
+type PlayerDataExport struct {
+	GameID           string             `json:"gameID"`
+	PlayerID         string             `json:"playerID"`
+	ExportedAt       int64              `json:"exportedAt"`
+	Player           *Player            `json:"player"`
+	DonationRequests []*DonationRequest `json:"donationRequests"`
+	Donations        []*Donation        `json:"donations"`
+	DonationWeights  map[string]int     `json:"donationWeights"`
+}
+
+//PlayerErasureResult represents what was changed when erasing a player
+type PlayerErasureResult struct {
+	GameID              string `json:"gameID"`
+	PlayerID            string `json:"playerID"`
+	Pseudonym           string `json:"pseudonym"`
+	PlayersRemoved      int    `json:"playersRemoved"`
+	DonationRequests    int    `json:"donationRequests"`
+	EmbeddedDonations   int    `json:"embeddedDonations"`
+	Donations           int    `json:"donations"`
+	DonationWeightKeys  int    `json:"donationWeightKeys"`
+	IdempotentResponses int    `json:"idempotentResponses"`
+	WebhookDeliveries   int    `json:"webhookDeliveries"`
+	StreamMessages      int    `json:"streamMessages"`
+}
+
+//ReplacePlayerInJSON replaces the player id by the pseudonym wherever it is a string in data, a JSON document.
+//It returns data unchanged if the player id is not in it.
+func ReplacePlayerInJSON(data, playerID, pseudonym string) string {
+	quotedID, _ := json.Marshal(playerID)
+	quotedPseudonym, _ := json.Marshal(pseudonym)
+	return strings.Replace(data, string(quotedID), string(quotedPseudonym), -1)
+}
+
+//pseudonymizeWebhookDeliveries replaces the player id in the payloads of the webhook deliveries of a game,
+//pending, delivered or dead, so deliveries still being retried keep their events
+func pseudonymizeWebhookDeliveries(gameID, playerID, pseudonym string, db *mgo.Database) (int, error) {
+	quotedID, _ := json.Marshal(playerID)
+	deliveries := GetWebhookDeliveriesCollection(db)
+
+	updated := 0
+	iter := deliveries.Find(bson.M{
+		"gameID":  gameID,
+		"payload": bson.RegEx{Pattern: regexp.QuoteMeta(string(quotedID))},
+	}).Iter()
+	var delivery WebhookDelivery
+	for iter.Next(&delivery) {
+		err := deliveries.UpdateId(delivery.ID, bson.M{"$set": bson.M{
+			"payload": ReplacePlayerInJSON(delivery.Payload, playerID, pseudonym),
+		}})
+		if err != nil {
+			iter.Close()
+			return updated, err
+		}
+		updated++
+		delivery = WebhookDelivery{}
+	}
+	return updated, iter.Close()
+}
+
+//getPlayerDonationWeightKeys returns the donation weight keys of a player in redis
+func getPlayerDonationWeightKeys(conn redis.Conn, gameID, playerID string) ([]string, error) {
+	key := GetDonationWeightKey("player", gameID, playerID, time.Time{}, NoReset)
+	keys, err := ScanKeys(conn, fmt.Sprintf("%s::*", EscapeScanPattern(key)))
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := redis.Bool(conn.Do("EXISTS", key))
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func findDonationRequestsForPlayer(coll *mgo.Collection, gameID, playerID string) ([]*DonationRequest, error) {
+	var donationRequests []*DonationRequest
+	err := coll.Find(bson.M{
+		"gameID": gameID,
+		"$or": []bson.M{
+			bson.M{"player": playerID},
+			bson.M{"donations.player": playerID},
+		},
+	}).All(&donationRequests)
+	return donationRequests, err
+}
+
+//ExportPlayerData returns everything stored about a player in a game, including archived donation requests
+func ExportPlayerData(gameID, playerID string, clock Clock, r *redis.Pool, db *mgo.Database, logger zap.Logger) (*PlayerDataExport, error) {
+	l := logger.With(
+		zap.String("source", "PrivacyModel"),
+		zap.String("operation", "ExportPlayerData"),
+		zap.String("gameID", gameID),
+		zap.String("playerID", playerID),
+	)
+
+	export := &PlayerDataExport{
+		GameID:           gameID,
+		PlayerID:         playerID,
+		ExportedAt:       clock.GetUTCTime().Unix(),
+		DonationRequests: []*DonationRequest{},
+		Donations:        []*Donation{},
+		DonationWeights:  map[string]int{},
+	}
+
+	var player Player
+	err := observeMongo("players", "find", func() error {
+		return GetPlayersCollection(db).Find(getPlayerQuery(gameID, playerID)).One(&player)
+	})
+	if err != nil && err != mgo.ErrNotFound {
+		log.E(l, "Failed to export player.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return nil, err
+	}
+	if err == nil {
+		export.Player = &player
+	}
+
+	collections := []*mgo.Collection{
+		GetDonationRequestsCollection(db),
+		GetArchivedDonationRequestsCollection(db),
+	}
+	for _, coll := range collections {
+		donationRequests, err := findDonationRequestsForPlayer(coll, gameID, playerID)
+		if err != nil {
+			log.E(l, "Failed to export donation requests.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return nil, err
+		}
+		export.DonationRequests = append(export.DonationRequests, donationRequests...)
+	}
+
+	err = observeMongo("donations", "find", func() error {
+		return GetDonationsCollection(db).Find(bson.M{"gameID": gameID, "player": playerID}).All(&export.Donations)
+	})
+	if err != nil {
+		log.E(l, "Failed to export donations.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return nil, err
+	}
+
+	conn := r.Get()
+	defer conn.Close()
+
+	keys, err := getPlayerDonationWeightKeys(conn, gameID, playerID)
+	if err != nil {
+		log.E(l, "Failed to export donation weights.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return nil, err
+	}
+	for _, key := range keys {
+		weight, err := redis.Int(conn.Do("GET", key))
+		if err != nil && err != redis.ErrNil {
+			return nil, err
+		}
+		export.DonationWeights[key] = weight
+	}
+
+	log.D(l, "Player data exported successfully.")
+	return export, nil
+}
+
+//ErasePlayerData erases a player from a game. The player document is removed and the player id is replaced
+//by a random pseudonym in donation requests, donations, donation weights and webhook deliveries, so clan
+//aggregates (and weights rebuilt from mongo) stay the same. Stored idempotent responses mentioning the player
+//are removed. The clan stream history is kept by the stream package, which erases the player from it with
+//the pseudonym of the result.
+func ErasePlayerData(gameID, playerID string, r *redis.Pool, db *mgo.Database, logger zap.Logger) (*PlayerErasureResult, error) {
+	l := logger.With(
+		zap.String("source", "PrivacyModel"),
+		zap.String("operation", "ErasePlayerData"),
+		zap.String("gameID", gameID),
+		zap.String("playerID", playerID),
+	)
+
+	result := &PlayerErasureResult{
+		GameID:    gameID,
+		PlayerID:  playerID,
+		Pseudonym: fmt.Sprintf("erased-%s", uuid.NewV4().String()),
+	}
+
+	fail := func(message string, err error) (*PlayerErasureResult, error) {
+		log.E(l, message, func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return nil, err
+	}
+
+	var info *mgo.ChangeInfo
+	err := observeMongo("players", "remove", func() error {
+		var err error
+		info, err = GetPlayersCollection(db).RemoveAll(getPlayerQuery(gameID, playerID))
+		return err
+	})
+	if err != nil {
+		return fail("Failed to remove player.", err)
+	}
+	result.PlayersRemoved = info.Removed
+
+	collections := []*mgo.Collection{
+		GetDonationRequestsCollection(db),
+		GetArchivedDonationRequestsCollection(db),
+	}
+	for _, coll := range collections {
+		info, err = coll.UpdateAll(
+			bson.M{"gameID": gameID, "player": playerID},
+			bson.M{"$set": bson.M{"player": result.Pseudonym}},
+		)
+		if err != nil {
+			return fail("Failed to erase player from donation requests.", err)
+		}
+		result.DonationRequests += info.Updated
+
+		//The positional operator only updates the first matching donation of each request
+		for {
+			info, err = coll.UpdateAll(
+				bson.M{"gameID": gameID, "donations.player": playerID},
+				bson.M{"$set": bson.M{"donations.$.player": result.Pseudonym}},
+			)
+			if err != nil {
+				return fail("Failed to erase player from embedded donations.", err)
+			}
+			if info.Updated == 0 {
+				break
+			}
+			result.EmbeddedDonations += info.Updated
+		}
+	}
+
+	err = observeMongo("donations", "update", func() error {
+		var err error
+		info, err = GetDonationsCollection(db).UpdateAll(
+			bson.M{"gameID": gameID, "player": playerID},
+			bson.M{"$set": bson.M{"player": result.Pseudonym}},
+		)
+		return err
+	})
+	if err != nil {
+		return fail("Failed to erase player from donations.", err)
+	}
+	result.Donations = info.Updated
+
+	//the player id is matched as a JSON string, so the responses of players whose id contains it are kept
+	quotedID, _ := json.Marshal(playerID)
+	err = observeMongo("idempotentResponses", "remove", func() error {
+		var err error
+		info, err = GetIdempotentResponsesCollection(db).RemoveAll(bson.M{
+			"gameID": gameID,
+			"body":   bson.RegEx{Pattern: regexp.QuoteMeta(string(quotedID))},
+		})
+		return err
+	})
+	if err != nil {
+		return fail("Failed to remove idempotent responses.", err)
+	}
+	result.IdempotentResponses = info.Removed
+
+	result.WebhookDeliveries, err = pseudonymizeWebhookDeliveries(gameID, playerID, result.Pseudonym, db)
+	if err != nil {
+		return fail("Failed to erase player from webhook deliveries.", err)
+	}
+
+	conn := r.Get()
+	defer conn.Close()
+
+	keys, err := getPlayerDonationWeightKeys(conn, gameID, playerID)
+	if err != nil {
+		return fail("Failed to get donation weights.", err)
+	}
+	prefix := GetDonationWeightKey("player", gameID, playerID, time.Time{}, NoReset)
+	pseudonymPrefix := GetDonationWeightKey("player", gameID, result.Pseudonym, time.Time{}, NoReset)
+	for _, key := range keys {
+		//RENAME keeps the expiration of periodic weights
+		_, err = conn.Do("RENAME", key, strings.Replace(key, prefix, pseudonymPrefix, 1))
+		if err != nil {
+			return fail("Failed to erase player from donation weights.", err)
+		}
+		result.DonationWeightKeys++
+	}
+
+	log.I(l, "Player data erased successfully.", func(cm log.CM) {
+		cm.Write(
+			zap.Int("donationRequests", result.DonationRequests),
+			zap.Int("embeddedDonations", result.EmbeddedDonations),
+			zap.Int("donations", result.Donations),
+			zap.Int("donationWeightKeys", result.DonationWeightKeys),
+			zap.Int("webhookDeliveries", result.WebhookDeliveries),
+		)
+	})
+	return result, nil
+}
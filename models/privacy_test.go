@@ -0,0 +1,182 @@
+package models_test
+
+import (
+	"fmt"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/garyburd/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Privacy Model", func() {
+	var logger zap.Logger
+	var session *mgo.Session
+	var db *mgo.Database
+	var r *redis.Pool
+	var game *models.Game
+	var player *models.Player
+	var dr *models.DonationRequest
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		session, db = GetTestMongoDB()
+		r = GetTestRedis()
+
+		var err error
+		game, err = GetTestGame(db, logger, true, map[string]interface{}{
+			"LimitOfItemsInEachDonationRequest": 4,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		player, err = GetTestPlayer(game, db, logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		dr, err = GetTestDonationRequest(game, db, logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = dr.Donate(player.ID, 1, 100, r, db, logger)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		session.Close()
+		session = nil
+		db = nil
+	})
+
+	Describe("Exporting player data", func() {
+		It("Should export everything stored about a donor", func() {
+			export, err := models.ExportPlayerData(game.ID, player.ID, &MockClock{Time: 100}, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(export.ExportedAt).To(BeEquivalentTo(100))
+			Expect(export.Player).NotTo(BeNil())
+			Expect(export.Player.ID).To(Equal(player.ID))
+			Expect(export.DonationRequests).To(HaveLen(1))
+			Expect(export.DonationRequests[0].ID).To(Equal(dr.ID))
+			Expect(export.Donations).To(HaveLen(1))
+			Expect(export.Donations[0].Player).To(Equal(player.ID))
+			Expect(export.DonationWeights).To(HaveLen(4))
+		})
+
+		It("Should export the donation requests of a requester", func() {
+			export, err := models.ExportPlayerData(game.ID, dr.Player, &MockClock{Time: 100}, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(export.DonationRequests).To(HaveLen(1))
+			Expect(export.DonationRequests[0].Player).To(Equal(dr.Player))
+			Expect(export.Donations).To(BeEmpty())
+		})
+	})
+
+	Describe("Erasing player data", func() {
+		It("Should pseudonymise a donor across all stores", func() {
+			clanWeight, err := models.GetDonationWeightForClan(game.ID, dr.Clan, time.Now(), models.NoReset, r, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			result, err := models.ErasePlayerData(game.ID, player.ID, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.PlayersRemoved).To(Equal(1))
+			Expect(result.EmbeddedDonations).To(Equal(1))
+			Expect(result.Donations).To(Equal(1))
+			Expect(result.DonationWeightKeys).To(Equal(4))
+
+			_, err = models.GetPlayerByID(game.ID, player.ID, db, logger)
+			Expect(err).To(HaveOccurred())
+
+			dbDonationRequest, err := models.GetDonationRequestByID(dr.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dbDonationRequest.Donations[0].Player).To(Equal(result.Pseudonym))
+
+			count, err := models.GetDonationsCollection(db).Find(bson.M{"player": player.ID}).Count()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(count).To(Equal(0))
+
+			export, err := models.ExportPlayerData(game.ID, player.ID, &MockClock{Time: 100}, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(export.Player).To(BeNil())
+			Expect(export.DonationRequests).To(BeEmpty())
+			Expect(export.Donations).To(BeEmpty())
+			Expect(export.DonationWeights).To(BeEmpty())
+
+			newClanWeight, err := models.GetDonationWeightForClan(game.ID, dr.Clan, time.Now(), models.NoReset, r, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newClanWeight).To(Equal(clanWeight))
+
+			diffs, err := models.GetDonationWeightDiffs(game.ID, "", &models.RealClock{}, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(diffs).To(BeEmpty())
+		})
+
+		It("Should pseudonymise the player in webhook deliveries", func() {
+			_, err := models.CreateWebhook(game.ID, "http://localhost/hook", []string{models.AllEvents}, "", &MockClock{Time: 100}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			data := []byte(fmt.Sprintf(`{"player":"%s","amount":1}`, player.ID))
+			err = models.DispatchWebhookEvent(game.ID, models.DonationCreatedEvent, data, &MockClock{Time: 100}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			result, err := models.ErasePlayerData(game.ID, player.ID, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.WebhookDeliveries).To(Equal(1))
+
+			var delivery models.WebhookDelivery
+			err = models.GetWebhookDeliveriesCollection(db).Find(bson.M{"gameID": game.ID}).One(&delivery)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(delivery.Payload).NotTo(ContainSubstring(player.ID))
+			Expect(delivery.Payload).To(ContainSubstring(result.Pseudonym))
+		})
+
+		It("Should only remove the idempotent responses of the player", func() {
+			clock := &MockClock{Time: time.Now().UTC().Unix()}
+			for i, playerID := range []string{player.ID, player.ID + "-other"} {
+				body := fmt.Sprintf(`{"player":"%s"}`, playerID)
+				err := models.SaveIdempotentResponse(
+					game.ID, "CreateDonation", fmt.Sprintf("key-%d", i), "/some/path", "hash",
+					200, "application/json", body, 60, clock, db, logger,
+				)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			result, err := models.ErasePlayerData(game.ID, player.ID, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.IdempotentResponses).To(Equal(1))
+
+			_, err = models.GetIdempotentResponse(game.ID, "CreateDonation", "key-1", 60, clock, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should only erase the donation weights of the player if its id has glob characters", func() {
+			other, err := GetTestPlayer(game, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			err = dr.Donate(other.ID, 1, 100, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			result, err := models.ErasePlayerData(game.ID, "*", r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.DonationWeightKeys).To(Equal(0))
+
+			export, err := models.ExportPlayerData(game.ID, other.ID, &MockClock{Time: 100}, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(export.DonationWeights).To(HaveLen(4))
+		})
+
+		It("Should pseudonymise a requester", func() {
+			result, err := models.ErasePlayerData(game.ID, dr.Player, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.DonationRequests).To(Equal(1))
+
+			dbDonationRequest, err := models.GetDonationRequestByID(dr.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dbDonationRequest.Player).To(Equal(result.Pseudonym))
+		})
+	})
+})
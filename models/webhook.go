@@ -0,0 +1,364 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/events"
+	"github.com/topfreegames/donations/log"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+//AllEvents subscribes a webhook to every event
+const AllEvents = "*"
+
+const (
+	//WebhookDeliveryPending means the delivery is waiting to be (re)sent
+	WebhookDeliveryPending = "pending"
+	//WebhookDeliveryDelivered means the webhook responded with a 2xx status
+	WebhookDeliveryDelivered = "delivered"
+	//WebhookDeliveryDead means the delivery failed too many times and is in the dead-letter store
+	WebhookDeliveryDead = "dead"
+)
+
+//WebhookEvents are the events a webhook can subscribe to
+var WebhookEvents = []string{
+	DonationRequestCreatedEvent,
+	DonationCreatedEvent,
+	DonationRequestFinishedEvent,
+	DonationRequestExpiredEvent,
+}
+
+//Webhook represents a subscription of an URL to events of a game
+type Webhook struct {
+	ID        string   `json:"id" bson:"_id"`
+	GameID    string   `json:"gameID" bson:"gameID"`
+	URL       string   `json:"url" bson:"url"`
+	Events    []string `json:"events" bson:"events"`
+	Secret    string   `json:"-" bson:"secret"`
+	CreatedAt int64    `json:"createdAt" bson:"createdAt"`
+}
+
+//WebhookDelivery represents an event to be sent to a webhook
+type WebhookDelivery struct {
+	ID            string `json:"id" bson:"_id"`
+	GameID        string `json:"gameID" bson:"gameID"`
+	WebhookID     string `json:"webhookID" bson:"webhookID"`
+	URL           string `json:"url" bson:"url"`
+	Event         string `json:"event" bson:"event"`
+	Payload       string `json:"payload" bson:"payload"`
+	Status        string `json:"status" bson:"status"`
+	Attempts      int    `json:"attempts" bson:"attempts"`
+	NextAttemptAt int64  `json:"nextAttemptAt" bson:"nextAttemptAt"`
+	LockedUntil   int64  `json:"-" bson:"lockedUntil"`
+	LastError     string `json:"lastError,omitempty" bson:"lastError,omitempty"`
+	CreatedAt     int64  `json:"createdAt" bson:"createdAt"`
+	DeliveredAt   int64  `json:"deliveredAt,omitempty" bson:"deliveredAt,omitempty"`
+}
+
+//IsValidWebhookEvent returns true if webhooks can subscribe to the event
+func IsValidWebhookEvent(event string) bool {
+	if event == AllEvents {
+		return true
+	}
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+//GetWebhooksCollection to update or query webhooks
+func GetWebhooksCollection(db *mgo.Database) *mgo.Collection {
+	return db.C("webhooks")
+}
+
+//GetWebhookDeliveriesCollection to update or query webhook deliveries
+func GetWebhookDeliveriesCollection(db *mgo.Database) *mgo.Collection {
+	return db.C("webhookDeliveries")
+}
+
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+//CreateWebhook subscribes the url to the given events of a game. If secret is empty, a random one is generated.
+func CreateWebhook(gameID, url string, events []string, secret string, clock Clock, db *mgo.Database, logger zap.Logger) (*Webhook, error) {
+	l := logger.With(
+		zap.String("source", "WebhookModel"),
+		zap.String("operation", "CreateWebhook"),
+		zap.String("gameID", gameID),
+		zap.String("url", url),
+	)
+
+	if secret == "" {
+		var err error
+		secret, err = newWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	webhook := &Webhook{
+		ID:        uuid.NewV4().String(),
+		GameID:    gameID,
+		URL:       url,
+		Events:    events,
+		Secret:    secret,
+		CreatedAt: clock.GetUTCTime().Unix(),
+	}
+
+	err := observeMongo("webhooks", "insert", func() error {
+		return GetWebhooksCollection(db).Insert(webhook)
+	})
+	if err != nil {
+		log.E(l, "Failed to create webhook.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return nil, err
+	}
+
+	log.D(l, "Webhook created successfully.")
+	return webhook, nil
+}
+
+//GetWebhooks returns the webhooks of a game
+func GetWebhooks(gameID string, db *mgo.Database, logger zap.Logger) ([]*Webhook, error) {
+	webhooks := []*Webhook{}
+	err := observeMongo("webhooks", "find", func() error {
+		return GetWebhooksCollection(db).Find(bson.M{"gameID": gameID}).Sort("createdAt").All(&webhooks)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+//GetWebhookByID retrieves a webhook of a game by its id
+func GetWebhookByID(gameID, id string, db *mgo.Database, logger zap.Logger) (*Webhook, error) {
+	var webhook Webhook
+	err := observeMongo("webhooks", "find", func() error {
+		return GetWebhooksCollection(db).Find(bson.M{"_id": id, "gameID": gameID}).One(&webhook)
+	})
+	if err != nil {
+		if err.Error() == NotFoundString {
+			return nil, errors.NewDocumentNotFoundError("webhooks", id)
+		}
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+//RemoveWebhook removes a webhook of a game
+func RemoveWebhook(gameID, id string, db *mgo.Database, logger zap.Logger) error {
+	err := observeMongo("webhooks", "remove", func() error {
+		return GetWebhooksCollection(db).Remove(bson.M{"_id": id, "gameID": gameID})
+	})
+	if err != nil {
+		if err.Error() == NotFoundString {
+			return errors.NewDocumentNotFoundError("webhooks", id)
+		}
+		return err
+	}
+	return nil
+}
+
+//DispatchWebhookEvent creates a delivery of the event for each webhook of the game subscribed to it
+func DispatchWebhookEvent(gameID, event string, data []byte, clock Clock, db *mgo.Database, logger zap.Logger) error {
+	l := logger.With(
+		zap.String("source", "WebhookModel"),
+		zap.String("operation", "DispatchWebhookEvent"),
+		zap.String("gameID", gameID),
+		zap.String("event", event),
+	)
+
+	var webhooks []*Webhook
+	err := observeMongo("webhooks", "find", func() error {
+		return GetWebhooksCollection(db).Find(bson.M{
+			"gameID": gameID,
+			"events": bson.M{"$in": []string{event, AllEvents}},
+		}).All(&webhooks)
+	})
+	if err != nil {
+		log.E(l, "Failed to get webhooks.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return err
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	now := clock.GetUTCTime().Unix()
+	payload, err := json.Marshal(events.NewEvent(event, gameID, clock.GetUTCTime(), data))
+	if err != nil {
+		return err
+	}
+
+	for _, webhook := range webhooks {
+		err = observeMongo("webhookDeliveries", "insert", func() error {
+			return GetWebhookDeliveriesCollection(db).Insert(&WebhookDelivery{
+				ID:            uuid.NewV4().String(),
+				GameID:        gameID,
+				WebhookID:     webhook.ID,
+				URL:           webhook.URL,
+				Event:         event,
+				Payload:       string(payload),
+				Status:        WebhookDeliveryPending,
+				NextAttemptAt: now,
+				CreatedAt:     now,
+			})
+		})
+		if err != nil {
+			log.E(l, "Failed to create webhook delivery.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return err
+		}
+	}
+
+	log.D(l, "Webhook event dispatched successfully.", func(cm log.CM) {
+		cm.Write(zap.Int("webhooks", len(webhooks)))
+	})
+	return nil
+}
+
+//ClaimWebhookDelivery atomically takes a pending delivery that is due, locking it for lockSeconds
+//so other workers won't send it. Returns nil if there are no due deliveries.
+func ClaimWebhookDelivery(lockSeconds int, clock Clock, db *mgo.Database) (*WebhookDelivery, error) {
+	now := clock.GetUTCTime().Unix()
+	var delivery WebhookDelivery
+	err := observeMongo("webhookDeliveries", "findAndModify", func() error {
+		_, err := GetWebhookDeliveriesCollection(db).Find(bson.M{
+			"status":        WebhookDeliveryPending,
+			"nextAttemptAt": bson.M{"$lte": now},
+			"lockedUntil":   bson.M{"$lte": now},
+		}).Sort("nextAttemptAt").Apply(mgo.Change{
+			Update:    bson.M{"$set": bson.M{"lockedUntil": now + int64(lockSeconds)}},
+			ReturnNew: true,
+		}, &delivery)
+		return err
+	})
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+//MarkWebhookDelivered records that the delivery was sent successfully
+func MarkWebhookDelivered(delivery *WebhookDelivery, clock Clock, db *mgo.Database) error {
+	delivery.Attempts++
+	delivery.Status = WebhookDeliveryDelivered
+	delivery.DeliveredAt = clock.GetUTCTime().Unix()
+	delivery.LastError = ""
+	return observeMongo("webhookDeliveries", "update", func() error {
+		return GetWebhookDeliveriesCollection(db).UpdateId(delivery.ID, bson.M{
+			"$set": bson.M{
+				"status":      delivery.Status,
+				"attempts":    delivery.Attempts,
+				"deliveredAt": delivery.DeliveredAt,
+				"lockedUntil": 0,
+			},
+			"$unset": bson.M{"lastError": ""},
+		})
+	})
+}
+
+//MarkWebhookDeliveryFailed records a failed attempt, scheduling a retry with exponential backoff
+//or moving the delivery to the dead-letter store after maxAttempts
+func MarkWebhookDeliveryFailed(
+	delivery *WebhookDelivery, cause error,
+	maxAttempts int, backoffBase, backoffMax time.Duration,
+	clock Clock, db *mgo.Database,
+) error {
+	delivery.Attempts++
+	delivery.LastError = cause.Error()
+	if delivery.Attempts >= maxAttempts {
+		delivery.Status = WebhookDeliveryDead
+	} else {
+		backoff := backoffBase
+		for i := 1; i < delivery.Attempts && backoff < backoffMax; i++ {
+			backoff = backoff * 2
+		}
+		if backoff > backoffMax {
+			backoff = backoffMax
+		}
+		delivery.NextAttemptAt = clock.GetUTCTime().Add(backoff).Unix()
+	}
+
+	return observeMongo("webhookDeliveries", "update", func() error {
+		return GetWebhookDeliveriesCollection(db).UpdateId(delivery.ID, bson.M{
+			"$set": bson.M{
+				"status":        delivery.Status,
+				"attempts":      delivery.Attempts,
+				"nextAttemptAt": delivery.NextAttemptAt,
+				"lastError":     delivery.LastError,
+				"lockedUntil":   0,
+			},
+		})
+	})
+}
+
+//GetDeadWebhookDeliveries returns the deliveries of a game in the dead-letter store
+func GetDeadWebhookDeliveries(gameID string, db *mgo.Database, logger zap.Logger) ([]*WebhookDelivery, error) {
+	deliveries := []*WebhookDelivery{}
+	err := observeMongo("webhookDeliveries", "find", func() error {
+		return GetWebhookDeliveriesCollection(db).Find(bson.M{
+			"gameID": gameID,
+			"status": WebhookDeliveryDead,
+		}).Sort("createdAt").All(&deliveries)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+//RedeliverWebhookDelivery schedules a delivery of a game to be sent again as soon as possible
+func RedeliverWebhookDelivery(gameID, id string, clock Clock, db *mgo.Database, logger zap.Logger) error {
+	l := logger.With(
+		zap.String("source", "WebhookModel"),
+		zap.String("operation", "RedeliverWebhookDelivery"),
+		zap.String("gameID", gameID),
+		zap.String("deliveryID", id),
+	)
+
+	err := observeMongo("webhookDeliveries", "update", func() error {
+		return GetWebhookDeliveriesCollection(db).Update(
+			bson.M{"_id": id, "gameID": gameID},
+			bson.M{"$set": bson.M{
+				"status":        WebhookDeliveryPending,
+				"attempts":      0,
+				"nextAttemptAt": clock.GetUTCTime().Unix(),
+				"lockedUntil":   0,
+			}},
+		)
+	})
+	if err != nil {
+		if err.Error() == NotFoundString {
+			return errors.NewDocumentNotFoundError("webhookDeliveries", id)
+		}
+		log.E(l, "Failed to redeliver webhook.", func(cm log.CM) {
+			cm.Write(zap.Error(err))
+		})
+		return err
+	}
+
+	log.D(l, "Webhook delivery scheduled successfully.")
+	return nil
+}
@@ -0,0 +1,276 @@
+package models_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/garyburd/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/events"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Webhook Model", func() {
+	var logger zap.Logger
+	var session *mgo.Session
+	var db *mgo.Database
+	var r *redis.Pool
+	var game *models.Game
+	var player *models.Player
+
+	getDeliveries := func(webhookID string) []*models.WebhookDelivery {
+		var deliveries []*models.WebhookDelivery
+		err := models.GetWebhookDeliveriesCollection(db).Find(bson.M{"webhookID": webhookID}).Sort("createdAt").All(&deliveries)
+		Expect(err).NotTo(HaveOccurred())
+		return deliveries
+	}
+
+	getEvents := func(webhookID string) []string {
+		events := []string{}
+		for _, delivery := range getDeliveries(webhookID) {
+			events = append(events, delivery.Event)
+		}
+		return events
+	}
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		session, db = GetTestMongoDB()
+		r = GetTestRedis()
+
+		var err error
+		game, err = GetTestGame(db, logger, true, map[string]interface{}{
+			"LimitOfItemsInEachDonationRequest": 2,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		player, err = GetTestPlayer(game, db, logger)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		session.Close()
+		session = nil
+		db = nil
+	})
+
+	Describe("Managing webhooks", func() {
+		It("Should create a webhook with a random secret", func() {
+			webhook, err := models.CreateWebhook(
+				game.ID, "http://localhost/hook", []string{models.AllEvents}, "",
+				&MockClock{Time: 100}, db, logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(webhook.ID).NotTo(BeEmpty())
+			Expect(webhook.Secret).To(HaveLen(64))
+			Expect(webhook.CreatedAt).To(BeEquivalentTo(100))
+
+			webhooks, err := models.GetWebhooks(game.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(webhooks).To(HaveLen(1))
+			Expect(webhooks[0].ID).To(Equal(webhook.ID))
+		})
+
+		It("Should remove a webhook", func() {
+			webhook, err := models.CreateWebhook(
+				game.ID, "http://localhost/hook", []string{models.AllEvents}, "secret",
+				&MockClock{Time: 100}, db, logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = models.RemoveWebhook(game.ID, webhook.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = models.RemoveWebhook(game.ID, webhook.ID, db, logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("not found"))
+		})
+
+		It("Should not remove a webhook of another game", func() {
+			webhook, err := models.CreateWebhook(
+				game.ID, "http://localhost/hook", []string{models.AllEvents}, "secret",
+				&MockClock{Time: 100}, db, logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = models.RemoveWebhook("other-game", webhook.ID, db, logger)
+			Expect(err).To(HaveOccurred())
+
+			_, err = models.GetWebhookByID(game.ID, webhook.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("Dispatching events", func() {
+		It("Should only create deliveries for subscribed webhooks", func() {
+			all, err := models.CreateWebhook(
+				game.ID, "http://localhost/all", []string{models.AllEvents}, "secret",
+				&MockClock{Time: 100}, db, logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+			donations, err := models.CreateWebhook(
+				game.ID, "http://localhost/donations", []string{models.DonationCreatedEvent}, "secret",
+				&MockClock{Time: 100}, db, logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			dr, err := GetTestDonationRequest(game, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			err = dr.Donate(player.ID, 1, 100, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(getEvents(all.ID)).To(ConsistOf(
+				models.DonationRequestCreatedEvent,
+				models.DonationCreatedEvent,
+			))
+			Expect(getEvents(donations.ID)).To(Equal([]string{models.DonationCreatedEvent}))
+
+			delivery := getDeliveries(donations.ID)[0]
+			Expect(delivery.Status).To(Equal(models.WebhookDeliveryPending))
+
+			var event events.Event
+			err = json.Unmarshal([]byte(delivery.Payload), &event)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(event.Type).To(Equal(models.DonationCreatedEvent))
+			Expect(event.GameID).To(Equal(game.ID))
+
+			var donation models.Donation
+			err = json.Unmarshal(event.Data, &donation)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(donation.Player).To(Equal(player.ID))
+			Expect(donation.DonationRequestID).To(Equal(dr.ID))
+		})
+
+		It("Should dispatch finished event when the donation request reaches its limit", func() {
+			webhook, err := models.CreateWebhook(
+				game.ID, "http://localhost/hook", []string{models.DonationRequestFinishedEvent}, "secret",
+				&MockClock{Time: 100}, db, logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			dr, err := GetTestDonationRequest(game, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			err = dr.Donate(player.ID, 1, 100, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(getDeliveries(webhook.ID)).To(BeEmpty())
+
+			err = dr.Donate(player.ID, 1, 100, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(getEvents(webhook.ID)).To(Equal([]string{models.DonationRequestFinishedEvent}))
+		})
+
+		It("Should dispatch expired event when unfinished donation requests expire", func() {
+			webhook, err := models.CreateWebhook(
+				game.ID, "http://localhost/hook", []string{models.DonationRequestExpiredEvent}, "secret",
+				&MockClock{Time: 100}, db, logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			unfinished, err := GetTestDonationRequest(game, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			unfinished.Clock = &MockClock{Time: 100}
+			err = unfinished.Donate(player.ID, 1, 100, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			finished, err := GetTestDonationRequest(game, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			finished.Clock = &MockClock{Time: 100}
+			err = finished.Donate(player.ID, 2, 100, r, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = models.ExpireDonationRequests(
+				game.ID, time.Hour, &MockClock{Time: time.Now().UTC().Unix() + 7200}, db, logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			deliveries := getDeliveries(webhook.ID)
+			Expect(deliveries).To(HaveLen(1))
+			Expect(deliveries[0].Event).To(Equal(models.DonationRequestExpiredEvent))
+			Expect(deliveries[0].Payload).To(ContainSubstring(fmt.Sprintf(`"id":"%s"`, unfinished.ID)))
+		})
+	})
+
+	Describe("Delivering events", func() {
+		var delivery *models.WebhookDelivery
+
+		BeforeEach(func() {
+			webhook, err := models.CreateWebhook(
+				game.ID, "http://localhost/hook", []string{models.AllEvents}, "secret",
+				&MockClock{Time: 100}, db, logger,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = models.DispatchWebhookEvent(game.ID, models.DonationCreatedEvent, []byte("{}"), &MockClock{Time: 100}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			delivery = getDeliveries(webhook.ID)[0]
+		})
+
+		It("Should retry failed deliveries with exponential backoff", func() {
+			backoffs := []int64{}
+			for i := 0; i < 4; i++ {
+				err := models.MarkWebhookDeliveryFailed(
+					delivery, fmt.Errorf("failed"), 10, 10*time.Second, 30*time.Second, &MockClock{Time: 100}, db,
+				)
+				Expect(err).NotTo(HaveOccurred())
+				backoffs = append(backoffs, delivery.NextAttemptAt-100)
+			}
+			Expect(backoffs).To(Equal([]int64{10, 20, 30, 30}))
+
+			dbDelivery := getDeliveries(delivery.WebhookID)[0]
+			Expect(dbDelivery.Attempts).To(Equal(4))
+			Expect(dbDelivery.Status).To(Equal(models.WebhookDeliveryPending))
+			Expect(dbDelivery.LastError).To(Equal("failed"))
+		})
+
+		It("Should move deliveries to the dead-letter store after max attempts", func() {
+			for i := 0; i < 2; i++ {
+				err := models.MarkWebhookDeliveryFailed(
+					delivery, fmt.Errorf("failed"), 2, time.Second, time.Second, &MockClock{Time: 100}, db,
+				)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			dead, err := models.GetDeadWebhookDeliveries(game.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dead).To(HaveLen(1))
+			Expect(dead[0].ID).To(Equal(delivery.ID))
+			Expect(dead[0].Attempts).To(Equal(2))
+		})
+
+		It("Should redeliver dead deliveries", func() {
+			err := models.MarkWebhookDeliveryFailed(
+				delivery, fmt.Errorf("failed"), 1, time.Second, time.Second, &MockClock{Time: 100}, db,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = models.RedeliverWebhookDelivery(game.ID, delivery.ID, &MockClock{Time: 200}, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			dbDelivery := getDeliveries(delivery.WebhookID)[0]
+			Expect(dbDelivery.Status).To(Equal(models.WebhookDeliveryPending))
+			Expect(dbDelivery.Attempts).To(Equal(0))
+			Expect(dbDelivery.NextAttemptAt).To(BeEquivalentTo(200))
+
+			dead, err := models.GetDeadWebhookDeliveries(game.ID, db, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dead).To(BeEmpty())
+		})
+
+		It("Should fail to redeliver a delivery of another game", func() {
+			err := models.RedeliverWebhookDelivery("other-game", delivery.ID, &MockClock{Time: 200}, db, logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("not found"))
+		})
+	})
+})
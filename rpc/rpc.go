@@ -0,0 +1,6 @@
+//Package rpc has the gRPC service of donations. The service is defined in donations.proto,
+//which clients in other languages can use to generate their stubs.
+package rpc
+
+//donations.pb.go is generated with the protoc-gen-go of github.com/golang/protobuf v1.0.0
+//go:generate protoc --go_out=plugins=grpc:. donations.proto
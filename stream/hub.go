@@ -0,0 +1,387 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/topfreegames/donations/events"
+	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/models"
+	"github.com/uber-go/zap"
+)
+
+const channelPrefix = "clan-stream:"
+
+//StreamedEvents are the events sent to clan streams
+var StreamedEvents = []string{
+	models.DonationRequestCreatedEvent,
+	models.DonationCreatedEvent,
+	models.DonationRequestFinishedEvent,
+}
+
+//appendScript adds a message to the history of a clan and publishes it to the instances
+//streaming the clan. The cursor of the message is a sequence number per clan.
+var appendScript = redis.NewScript(2, `
+local cursor = redis.call("INCR", KEYS[2])
+local message = cursor .. ":" .. ARGV[1]
+redis.call("ZADD", KEYS[1], cursor, message)
+redis.call("ZREMRANGEBYRANK", KEYS[1], 0, -(tonumber(ARGV[2]) + 1))
+redis.call("EXPIRE", KEYS[1], ARGV[3])
+redis.call("EXPIRE", KEYS[2], ARGV[3])
+redis.call("PUBLISH", KEYS[1], message)
+return cursor
+`)
+
+//replaceScript replaces a message of the history of a clan, keeping its cursor.
+//Messages removed from the history in the meantime are not added back.
+var replaceScript = redis.NewScript(1, `
+local removed = redis.call("ZREM", KEYS[1], ARGV[1])
+if removed == 1 then
+	redis.call("ZADD", KEYS[1], ARGV[3], ARGV[2])
+end
+return removed
+`)
+
+//Message is an event streamed to a clan
+type Message struct {
+	Cursor int64
+	Event  *events.Event
+}
+
+//Hub streams the events of clans. Events are kept in a per clan history in redis and fanned out
+//to every API instance through redis pub/sub. Each instance has a single subscriber connection,
+//shared by all of its clients.
+type Hub struct {
+	Redis       *redis.Pool
+	Dial        func() (redis.Conn, error)
+	Logger      zap.Logger
+	HistorySize int
+	HistoryTTL  time.Duration
+	BufferSize  int
+
+	lock        sync.Mutex
+	conn        redis.Conn
+	subscribers map[string]map[*Subscription]bool
+}
+
+//Subscription receives the events of a clan
+type Subscription struct {
+	//Replay has the events after the cursor the subscription was created with
+	Replay []*Message
+	//Truncated is true if events after the cursor are not in the history anymore
+	Truncated bool
+	//Messages has the new events. It is closed if the subscription falls behind or the hub
+	//loses its connection, in which case the client should resume from its last cursor.
+	Messages chan *Message
+
+	hub     *Hub
+	channel string
+	closed  bool
+}
+
+//NewHub returns a hub. dial must return connections without read timeout, since they wait for published events.
+func NewHub(r *redis.Pool, dial func() (redis.Conn, error), historySize int, historyTTL time.Duration, bufferSize int, logger zap.Logger) *Hub {
+	return &Hub{
+		Redis:       r,
+		Dial:        dial,
+		Logger:      logger,
+		HistorySize: historySize,
+		HistoryTTL:  historyTTL,
+		BufferSize:  bufferSize,
+		subscribers: map[string]map[*Subscription]bool{},
+	}
+}
+
+//GetChannel returns the redis channel, which is also the history key, of a clan
+func GetChannel(gameID, clan string) string {
+	return fmt.Sprintf("%s%s:%s", channelPrefix, gameID, clan)
+}
+
+func getCursorKey(gameID, clan string) string {
+	return fmt.Sprintf("clan-stream-cursor:%s:%s", gameID, clan)
+}
+
+func parseMessage(data []byte) (*Message, error) {
+	parts := strings.SplitN(string(data), ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("Invalid clan stream message.")
+	}
+	cursor, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	var event events.Event
+	err = json.Unmarshal([]byte(parts[1]), &event)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{Cursor: cursor, Event: &event}, nil
+}
+
+func isStreamed(eventType string) bool {
+	for _, streamed := range StreamedEvents {
+		if streamed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+//Publish appends a donation event to the stream of its clan. Other events are ignored.
+func (h *Hub) Publish(event *events.Event) error {
+	if !isStreamed(event.Type) {
+		return nil
+	}
+
+	var data struct {
+		Clan string `json:"clan"`
+	}
+	err := json.Unmarshal(event.Data, &data)
+	if err != nil {
+		return err
+	}
+	if data.Clan == "" {
+		return nil
+	}
+
+	message, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	conn := h.Redis.Get()
+	defer conn.Close()
+	_, err = appendScript.Do(
+		conn,
+		GetChannel(event.GameID, data.Clan), getCursorKey(event.GameID, data.Clan),
+		message, h.HistorySize, int(h.HistoryTTL.Seconds()),
+	)
+	return err
+}
+
+//ErasePlayer replaces the player id by the pseudonym in the history of the clans of a game and returns
+//the number of messages replaced. Events already sent to connected clients are not changed.
+func (h *Hub) ErasePlayer(gameID, playerID, pseudonym string) (int, error) {
+	conn := h.Redis.Get()
+	defer conn.Close()
+
+	keys, err := models.ScanKeys(conn, fmt.Sprintf("%s%s:*", channelPrefix, models.EscapeScanPattern(gameID)))
+	if err != nil {
+		return 0, err
+	}
+
+	replaced := 0
+	for _, key := range keys {
+		values, err := redis.Strings(conn.Do("ZRANGE", key, 0, -1, "WITHSCORES"))
+		if err != nil {
+			return replaced, err
+		}
+		for i := 0; i+1 < len(values); i += 2 {
+			message := values[i]
+			erased := models.ReplacePlayerInJSON(message, playerID, pseudonym)
+			if erased == message {
+				continue
+			}
+			removed, err := redis.Int(replaceScript.Do(conn, key, message, erased, values[i+1]))
+			if err != nil {
+				return replaced, err
+			}
+			replaced += removed
+		}
+	}
+	return replaced, nil
+}
+
+//Close stops receiving events and closes all subscriptions
+func (h *Hub) Close() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.conn != nil {
+		h.conn.Close()
+		h.conn = nil
+	}
+	h.closeSubscribers()
+	return nil
+}
+
+//Subscribe returns a subscription to the events of a clan. If cursor is not negative,
+//the events after it that are still in the history are replayed.
+func (h *Hub) Subscribe(gameID, clan string, cursor int64) (*Subscription, error) {
+	sub := &Subscription{
+		Replay:   []*Message{},
+		Messages: make(chan *Message, h.BufferSize),
+		hub:      h,
+		channel:  GetChannel(gameID, clan),
+	}
+
+	//The subscription is registered before reading the history, so no events are lost in between
+	err := h.register(sub)
+	if err != nil {
+		return nil, err
+	}
+	if cursor < 0 {
+		return sub, nil
+	}
+
+	err = h.replay(sub, gameID, clan, cursor)
+	if err != nil {
+		sub.Close()
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (h *Hub) replay(sub *Subscription, gameID, clan string, cursor int64) error {
+	conn := h.Redis.Get()
+	defer conn.Close()
+
+	last, err := redis.Int64(conn.Do("GET", getCursorKey(gameID, clan)))
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+	//The cursors restarted since the client last connected
+	if cursor > last {
+		cursor = 0
+	}
+
+	values, err := redis.ByteSlices(conn.Do("ZRANGEBYSCORE", sub.channel, fmt.Sprintf("(%d", cursor), "+inf"))
+	if err != nil {
+		return err
+	}
+	for _, value := range values {
+		message, err := parseMessage(value)
+		if err != nil {
+			return err
+		}
+		sub.Replay = append(sub.Replay, message)
+	}
+
+	if len(sub.Replay) > 0 {
+		sub.Truncated = sub.Replay[0].Cursor > cursor+1
+	} else {
+		sub.Truncated = last > cursor
+	}
+	return nil
+}
+
+//register adds a subscription, connecting the hub to redis pub/sub if needed
+func (h *Hub) register(sub *Subscription) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.conn == nil {
+		conn, err := h.Dial()
+		if err != nil {
+			return err
+		}
+		psc := redis.PubSubConn{Conn: conn}
+		err = psc.PSubscribe(channelPrefix + "*")
+		if err == nil {
+			//Waits for the confirmation, so events published after Subscribe returns are received
+			if e, ok := psc.Receive().(error); ok {
+				err = e
+			}
+		}
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		h.conn = conn
+		go h.receive(psc)
+	}
+
+	if _, ok := h.subscribers[sub.channel]; !ok {
+		h.subscribers[sub.channel] = map[*Subscription]bool{}
+	}
+	h.subscribers[sub.channel][sub] = true
+	return nil
+}
+
+func (h *Hub) receive(psc redis.PubSubConn) {
+	l := h.Logger.With(
+		zap.String("source", "ClanStreamHub"),
+		zap.String("operation", "receive"),
+	)
+
+	for {
+		switch v := psc.Receive().(type) {
+		case redis.PMessage:
+			message, err := parseMessage(v.Data)
+			if err != nil {
+				log.E(l, "Failed to parse clan stream message.", func(cm log.CM) {
+					cm.Write(zap.Error(err))
+				})
+				continue
+			}
+			h.dispatch(v.Channel, message)
+		case error:
+			h.lock.Lock()
+			//Close was not called, so the connection was lost
+			if h.conn == psc.Conn {
+				log.W(l, "Lost clan stream connection, closing subscriptions.", func(cm log.CM) {
+					cm.Write(zap.Error(v))
+				})
+				h.conn.Close()
+				h.conn = nil
+				h.closeSubscribers()
+			}
+			h.lock.Unlock()
+			return
+		}
+	}
+}
+
+func (h *Hub) dispatch(channel string, message *Message) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for sub := range h.subscribers[channel] {
+		select {
+		case sub.Messages <- message:
+		default:
+			//Slow clients are disconnected and resume from their cursor
+			h.unregister(sub)
+		}
+	}
+}
+
+//unregister must be called with the lock held
+func (h *Hub) unregister(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.Messages)
+	delete(h.subscribers[sub.channel], sub)
+	if len(h.subscribers[sub.channel]) == 0 {
+		delete(h.subscribers, sub.channel)
+	}
+}
+
+//closeSubscribers must be called with the lock held
+func (h *Hub) closeSubscribers() {
+	for _, subs := range h.subscribers {
+		for sub := range subs {
+			h.unregister(sub)
+		}
+	}
+}
+
+//Closed returns true if the subscription was closed
+func (s *Subscription) Closed() bool {
+	s.hub.lock.Lock()
+	defer s.hub.lock.Unlock()
+	return s.closed
+}
+
+//Close stops receiving events
+func (s *Subscription) Close() {
+	s.hub.lock.Lock()
+	defer s.hub.lock.Unlock()
+	s.hub.unregister(s)
+}
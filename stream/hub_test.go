@@ -0,0 +1,174 @@
+package stream_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	uuid "github.com/satori/go.uuid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/events"
+	"github.com/topfreegames/donations/models"
+	"github.com/topfreegames/donations/stream"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Clan Stream Hub", func() {
+	var logger zap.Logger
+	var r *redis.Pool
+	var hub *stream.Hub
+	var gameID, clan string
+
+	publish := func(eventType string, amount int) {
+		data := []byte(fmt.Sprintf(`{"clan":"%s","amount":%d}`, clan, amount))
+		err := hub.Publish(events.NewEvent(eventType, gameID, time.Unix(100, 0), data))
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	cursors := func(messages []*stream.Message) []int64 {
+		result := []int64{}
+		for _, message := range messages {
+			result = append(result, message.Cursor)
+		}
+		return result
+	}
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		r = GetTestRedis()
+		hub = stream.NewHub(r, r.Dial, 3, time.Hour, 2, logger)
+		gameID = uuid.NewV4().String()
+		clan = uuid.NewV4().String()
+	})
+
+	AfterEach(func() {
+		hub.Close()
+	})
+
+	Describe("Publishing events", func() {
+		It("Should keep the last events of the clan in the history", func() {
+			for i := 1; i <= 5; i++ {
+				publish(models.DonationCreatedEvent, i)
+			}
+
+			sub, err := hub.Subscribe(gameID, clan, 0)
+			Expect(err).NotTo(HaveOccurred())
+			defer sub.Close()
+			Expect(cursors(sub.Replay)).To(Equal([]int64{3, 4, 5}))
+			Expect(sub.Replay[0].Event.Type).To(Equal(models.DonationCreatedEvent))
+			Expect(string(sub.Replay[0].Event.Data)).To(ContainSubstring(`"amount":3`))
+			Expect(sub.Truncated).To(BeTrue())
+		})
+
+		It("Should ignore events that are not streamed or have no clan", func() {
+			publish(models.DonationRequestExpiredEvent, 1)
+			err := hub.Publish(events.NewEvent(models.DonationCreatedEvent, gameID, time.Unix(100, 0), []byte(`{"amount":1}`)))
+			Expect(err).NotTo(HaveOccurred())
+
+			sub, err := hub.Subscribe(gameID, clan, 0)
+			Expect(err).NotTo(HaveOccurred())
+			defer sub.Close()
+			Expect(sub.Replay).To(BeEmpty())
+			Expect(sub.Truncated).To(BeFalse())
+		})
+	})
+
+	Describe("Erasing players", func() {
+		It("Should replace the player in the history of the clans of the game", func() {
+			publishFrom := func(player string) {
+				data := []byte(fmt.Sprintf(`{"clan":"%s","player":"%s"}`, clan, player))
+				err := hub.Publish(events.NewEvent(models.DonationCreatedEvent, gameID, time.Unix(100, 0), data))
+				Expect(err).NotTo(HaveOccurred())
+			}
+			publishFrom("player-1")
+			publishFrom("player-2")
+
+			replaced, err := hub.ErasePlayer(gameID, "player-1", "pseudonym")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(replaced).To(Equal(1))
+
+			sub, err := hub.Subscribe(gameID, clan, 0)
+			Expect(err).NotTo(HaveOccurred())
+			defer sub.Close()
+			Expect(cursors(sub.Replay)).To(Equal([]int64{1, 2}))
+			Expect(string(sub.Replay[0].Event.Data)).To(ContainSubstring(`"player":"pseudonym"`))
+			Expect(string(sub.Replay[1].Event.Data)).To(ContainSubstring(`"player":"player-2"`))
+		})
+	})
+
+	Describe("Subscribing", func() {
+		It("Should replay events after the cursor", func() {
+			publish(models.DonationRequestCreatedEvent, 0)
+			publish(models.DonationCreatedEvent, 1)
+			publish(models.DonationCreatedEvent, 2)
+
+			sub, err := hub.Subscribe(gameID, clan, 1)
+			Expect(err).NotTo(HaveOccurred())
+			defer sub.Close()
+			Expect(cursors(sub.Replay)).To(Equal([]int64{2, 3}))
+			Expect(sub.Truncated).To(BeFalse())
+		})
+
+		It("Should replay everything if the cursor is newer than the clan cursor", func() {
+			publish(models.DonationCreatedEvent, 1)
+
+			sub, err := hub.Subscribe(gameID, clan, 10)
+			Expect(err).NotTo(HaveOccurred())
+			defer sub.Close()
+			Expect(cursors(sub.Replay)).To(Equal([]int64{1}))
+		})
+
+		It("Should not replay events without cursor", func() {
+			publish(models.DonationCreatedEvent, 1)
+
+			sub, err := hub.Subscribe(gameID, clan, -1)
+			Expect(err).NotTo(HaveOccurred())
+			defer sub.Close()
+			Expect(sub.Replay).To(BeEmpty())
+		})
+
+		It("Should receive events published by any instance", func() {
+			sub, err := hub.Subscribe(gameID, clan, -1)
+			Expect(err).NotTo(HaveOccurred())
+			defer sub.Close()
+
+			other := stream.NewHub(r, r.Dial, 3, time.Hour, 2, logger)
+			data := []byte(fmt.Sprintf(`{"clan":"%s"}`, clan))
+			err = other.Publish(events.NewEvent(models.DonationRequestFinishedEvent, gameID, time.Unix(100, 0), data))
+			Expect(err).NotTo(HaveOccurred())
+
+			var message *stream.Message
+			Eventually(sub.Messages).Should(Receive(&message))
+			Expect(message.Cursor).To(BeEquivalentTo(1))
+			Expect(message.Event.Type).To(Equal(models.DonationRequestFinishedEvent))
+		})
+
+		It("Should not receive events of other clans", func() {
+			sub, err := hub.Subscribe(gameID, uuid.NewV4().String(), -1)
+			Expect(err).NotTo(HaveOccurred())
+			defer sub.Close()
+
+			publish(models.DonationCreatedEvent, 1)
+			Consistently(sub.Messages, 100*time.Millisecond).ShouldNot(Receive())
+		})
+
+		It("Should close subscriptions that fall behind", func() {
+			sub, err := hub.Subscribe(gameID, clan, -1)
+			Expect(err).NotTo(HaveOccurred())
+
+			for i := 1; i <= 3; i++ {
+				publish(models.DonationCreatedEvent, i)
+			}
+
+			Eventually(sub.Closed).Should(BeTrue())
+			Expect(sub.Messages).To(HaveLen(2))
+		})
+	})
+})
@@ -1,9 +1,11 @@
 package testing
 
 import (
+	"sync"
 	"time"
 
 	"github.com/onsi/ginkgo"
+	"github.com/topfreegames/donations/events"
 )
 
 //MockClock abstracts time
@@ -16,6 +18,38 @@ func (m *MockClock) GetUTCTime() time.Time {
 	return time.Unix(m.Time, 0)
 }
 
+//MockEventPublisher records published events
+type MockEventPublisher struct {
+	published []*events.Event
+	lock      sync.Mutex
+}
+
+//Publish records the event
+func (p *MockEventPublisher) Publish(event *events.Event) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.published = append(p.published, event)
+	return nil
+}
+
+//Close does nothing
+func (p *MockEventPublisher) Close() error {
+	return nil
+}
+
+//Events returns the published events of a game
+func (p *MockEventPublisher) Events(gameID string) []*events.Event {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	gameEvents := []*events.Event{}
+	for _, event := range p.published {
+		if event.GameID == gameID {
+			gameEvents = append(gameEvents, event)
+		}
+	}
+	return gameEvents
+}
+
 //BeforeOnce runs the before each block only once
 func BeforeOnce(beforeBlock func()) {
 	hasRun := false
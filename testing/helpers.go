@@ -21,37 +21,31 @@ import (
 	"github.com/uber-go/zap"
 )
 
-//GetTestRedis returns a configured redsync connection
-func GetTestRedis() redis.Conn {
-	conn, err := redis.DialURL("redis://localhost:9998/1")
-	if err != nil {
-		panic("Failed to connect to redis")
+//GetTestRedis returns a configured redis pool
+func GetTestRedis() *redis.Pool {
+	return &redis.Pool{
+		MaxIdle:     3,
+		IdleTimeout: 240 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			conn, err := redis.DialURL("redis://localhost:9998/1")
+			if err != nil {
+				return nil, err
+			}
+			return conn, nil
+		},
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			if err != nil {
+				return err
+			}
+			return nil
+		},
 	}
-	return conn
 }
 
 //GetTestRedsync returns a configured redsync connection
 func GetTestRedsync() *redsync.Redsync {
-	return redsync.New([]redsync.Pool{
-		&redis.Pool{
-			MaxIdle:     3,
-			IdleTimeout: 240 * time.Second,
-			Dial: func() (redis.Conn, error) {
-				conn, err := redis.DialURL("redis://localhost:9998/1")
-				if err != nil {
-					return nil, err
-				}
-				return conn, nil
-			},
-			TestOnBorrow: func(c redis.Conn, t time.Time) error {
-				_, err := c.Do("PING")
-				if err != nil {
-					return err
-				}
-				return nil
-			},
-		},
-	})
+	return redsync.New([]redsync.Pool{GetTestRedis()})
 }
 
 //GetTestMutex returns a mutex for the name specified
@@ -112,11 +106,21 @@ func Post(app *api.App, url, body string) (int, string) {
 	return doRequest(app, "POST", url, body)
 }
 
+//PostWithHeaders to server
+func PostWithHeaders(app *api.App, url, body string, headers map[string]string) (int, string) {
+	return doRequest(app, "POST", url, body, headers)
+}
+
 //Put to server
 func Put(app *api.App, url, body string) (int, string) {
 	return doRequest(app, "PUT", url, body)
 }
 
+//PutWithHeaders to server
+func PutWithHeaders(app *api.App, url, body string, headers map[string]string) (int, string) {
+	return doRequest(app, "PUT", url, body, headers)
+}
+
 //Delete from server
 func Delete(app *api.App, url, body string) (int, string) {
 	return doRequest(app, "DELETE", url, body)
@@ -165,8 +169,28 @@ func ReadBody(res *http.Response) []byte {
 	return b
 }
 
-func doRequest(app *api.App, method, url, body string) (int, string) {
-	ts := InitializeTestServer(app)
+//InitializeAdminTestServer for tests of the admin routes served at a separate port
+func InitializeAdminTestServer(app *api.App) *httptest.Server {
+	initClient()
+	app.AdminEngine.SetHandler(app.Admin)
+	return httptest.NewServer(app.AdminEngine.(*standard.Server))
+}
+
+//GetAdmin from the admin server
+func GetAdmin(app *api.App, url string) (int, string) {
+	return doRequestTo(InitializeAdminTestServer(app), app, "GET", url, "")
+}
+
+//PutAdmin to the admin server
+func PutAdmin(app *api.App, url, body string) (int, string) {
+	return doRequestTo(InitializeAdminTestServer(app), app, "PUT", url, body)
+}
+
+func doRequest(app *api.App, method, url, body string, headers ...map[string]string) (int, string) {
+	return doRequestTo(InitializeTestServer(app), app, method, url, body, headers...)
+}
+
+func doRequestTo(ts *httptest.Server, app *api.App, method, url, body string, headers ...map[string]string) (int, string) {
 	defer transport.CloseIdleConnections()
 	defer ts.Close()
 
@@ -176,6 +200,11 @@ func doRequest(app *api.App, method, url, body string) (int, string) {
 	}
 
 	req := GetRequest(app, ts, method, url, bodyBuff)
+	if len(headers) == 1 {
+		for key, value := range headers[0] {
+			req.Header.Set(key, value)
+		}
+	}
 	res := PerformRequest(ts, req)
 	bodyRes := ReadBody(res)
 	return res.StatusCode, string(bodyRes)
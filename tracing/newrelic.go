@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	newrelic "github.com/newrelic/go-agent"
+	"golang.org/x/net/context"
+)
+
+//newRelicTransactionKey is the context key of the New Relic transaction of a request
+type newRelicTransactionKey struct{}
+
+//NewRelicTracer exports requests as New Relic transactions and their spans as segments.
+//New Relic does not support W3C trace context, so propagated traces are not continued.
+type NewRelicTracer struct {
+	App newrelic.Application
+}
+
+//NewNewRelicTracer returns a tracer that exports to the New Relic application
+func NewNewRelicTracer(app newrelic.Application) *NewRelicTracer {
+	return &NewRelicTracer{App: app}
+}
+
+//GetTransaction returns the New Relic transaction of ctx, or nil if ctx has none
+func GetTransaction(ctx context.Context) newrelic.Transaction {
+	if ctx == nil {
+		return nil
+	}
+	if txn, ok := ctx.Value(newRelicTransactionKey{}).(newrelic.Transaction); ok {
+		return txn
+	}
+	return nil
+}
+
+//StartTrace starts a New Relic transaction
+func (t *NewRelicTracer) StartTrace(ctx context.Context, name string, carrier Carrier) (context.Context, Span) {
+	txn := t.App.StartTransaction(name, nil, nil)
+	return context.WithValue(ctx, newRelicTransactionKey{}, txn), &newRelicTransactionSpan{txn: txn}
+}
+
+//StartSpan starts a segment of the transaction in ctx
+func (t *NewRelicTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	txn := GetTransaction(ctx)
+	if txn == nil {
+		return ctx, &noopSpan{}
+	}
+	segment := newrelic.StartSegment(txn, name)
+	return ctx, &newRelicSegmentSpan{end: func() { segment.End() }}
+}
+
+//StartDatastoreSpan starts a datastore segment of the transaction in ctx
+func (t *NewRelicTracer) StartDatastoreSpan(ctx context.Context, datastore *Datastore) (context.Context, Span) {
+	txn := GetTransaction(ctx)
+	if txn == nil {
+		return ctx, &noopSpan{}
+	}
+
+	product := newrelic.DatastoreProduct(datastore.System)
+	switch datastore.System {
+	case MongoDB:
+		product = newrelic.DatastoreMongoDB
+	case Redis:
+		product = newrelic.DatastoreRedis
+	}
+	segment := &newrelic.DatastoreSegment{
+		StartTime:  newrelic.StartSegmentNow(txn),
+		Product:    product,
+		Collection: datastore.Collection,
+		Operation:  datastore.Operation,
+	}
+	return ctx, &newRelicSegmentSpan{end: func() { segment.End() }}
+}
+
+//Close does nothing, the New Relic application is shared with the rest of the app
+func (t *NewRelicTracer) Close() error {
+	return nil
+}
+
+type newRelicTransactionSpan struct {
+	txn newrelic.Transaction
+}
+
+func (s *newRelicTransactionSpan) SetAttribute(key string, value interface{}) {
+	s.txn.AddAttribute(key, value)
+}
+
+func (s *newRelicTransactionSpan) RecordError(err error) {
+	s.txn.NoticeError(err)
+}
+
+func (s *newRelicTransactionSpan) End() {
+	s.txn.End()
+}
+
+//newRelicSegmentSpan ends a segment. Attributes and errors are only recorded in transactions.
+type newRelicSegmentSpan struct {
+	end func()
+}
+
+func (s *newRelicSegmentSpan) SetAttribute(key string, value interface{}) {}
+func (s *newRelicSegmentSpan) RecordError(err error)                      {}
+
+func (s *newRelicSegmentSpan) End() {
+	s.end()
+}
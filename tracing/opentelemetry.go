@@ -0,0 +1,308 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+//instrumentationName is the name of the instrumentation scope of the spans of the app
+const instrumentationName = "github.com/topfreegames/donations"
+
+//traceParentHeader is the W3C trace context header with the trace and span of the caller
+const traceParentHeader = "traceparent"
+
+//TraceID identifies a trace
+type TraceID [16]byte
+
+//String returns the trace ID in hex, as propagated in traceparent headers
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+//SpanID identifies a span of a trace
+type SpanID [8]byte
+
+//String returns the span ID in hex, as propagated in traceparent headers
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+//SpanContext identifies a span and whether its trace is sampled
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+//IsValid returns whether the trace and span IDs are set
+func (s SpanContext) IsValid() bool {
+	return s.TraceID != TraceID{} && s.SpanID != SpanID{}
+}
+
+//ParseTraceParent parses a W3C trace context traceparent header, such as
+//00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01. Headers of future versions are parsed as version 00.
+func ParseTraceParent(header string) (SpanContext, error) {
+	var spanContext SpanContext
+	invalid := fmt.Errorf("Invalid traceparent header '%s'.", header)
+
+	header = strings.TrimSpace(header)
+	if len(header) < 55 || header[2] != '-' || header[35] != '-' || header[52] != '-' {
+		return spanContext, invalid
+	}
+	version, err := hex.DecodeString(header[0:2])
+	if err != nil || version[0] == 0xff || header[0:2] != strings.ToLower(header[0:2]) {
+		return spanContext, invalid
+	}
+	if (version[0] == 0 && len(header) != 55) || (len(header) > 55 && header[55] != '-') {
+		return spanContext, invalid
+	}
+
+	//IDs must be lowercase, so the same trace is not propagated with different headers
+	traceID, traceErr := hex.DecodeString(header[3:35])
+	spanID, spanErr := hex.DecodeString(header[36:52])
+	flags, flagsErr := hex.DecodeString(header[53:55])
+	if traceErr != nil || spanErr != nil || flagsErr != nil || header[3:55] != strings.ToLower(header[3:55]) {
+		return spanContext, invalid
+	}
+	copy(spanContext.TraceID[:], traceID)
+	copy(spanContext.SpanID[:], spanID)
+	spanContext.Sampled = flags[0]&1 == 1
+	if !spanContext.IsValid() {
+		return spanContext, invalid
+	}
+	return spanContext, nil
+}
+
+//SpanKind is the OTLP kind of a span
+type SpanKind int
+
+const (
+	//SpanKindInternal is the kind of the spans of segments of a request
+	SpanKindInternal SpanKind = 1
+	//SpanKindServer is the kind of the root span of a request
+	SpanKindServer SpanKind = 2
+	//SpanKindClient is the kind of the spans of datastore operations
+	SpanKindClient SpanKind = 3
+)
+
+//SpanStatus is the status of a span, failed if an error was recorded
+type SpanStatus struct {
+	Error       bool
+	Description string
+}
+
+//SpanEvent is an event of a span, such as the exception event of a recorded error
+type SpanEvent struct {
+	Name       string
+	Time       time.Time
+	Attributes map[string]interface{}
+}
+
+//SpanData is an ended span, as sent to span processors. Parent is not valid in the root span of a trace.
+type SpanData struct {
+	Name        string
+	Kind        SpanKind
+	SpanContext SpanContext
+	Parent      SpanContext
+	StartTime   time.Time
+	EndTime     time.Time
+	Attributes  map[string]interface{}
+	Events      []*SpanEvent
+	Status      SpanStatus
+}
+
+//OpenTelemetryTracer exports spans in the OpenTelemetry format, continuing traces propagated with W3C trace
+//context headers. Only sampled spans are sent to the processor.
+type OpenTelemetryTracer struct {
+	Processor   SpanProcessor
+	SampleRatio float64
+}
+
+//NewOpenTelemetryTracer returns a tracer that exports spans in batches to an OTLP/HTTP collector at endpoint,
+//such as localhost:4318. Traces without a propagated sampling decision are sampled with sampleRatio.
+func NewOpenTelemetryTracer(
+	endpoint string, insecure bool, serviceName string, sampleRatio float64,
+) (*OpenTelemetryTracer, error) {
+	exporter, err := NewOTLPExporter(endpoint, insecure, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	return NewOpenTelemetryTracerWithProcessor(NewBatchSpanProcessor(exporter), sampleRatio), nil
+}
+
+//NewOpenTelemetryTracerWithProcessor returns a tracer that sends ended spans to processor
+func NewOpenTelemetryTracerWithProcessor(processor SpanProcessor, sampleRatio float64) *OpenTelemetryTracer {
+	return &OpenTelemetryTracer{
+		Processor:   processor,
+		SampleRatio: sampleRatio,
+	}
+}
+
+//spanKey is the context key of the current span of a tracer, so that many tracers can trace the same request
+type spanKey struct {
+	tracer *OpenTelemetryTracer
+}
+
+//StartTrace starts a server span, child of the W3C trace context in carrier, if any
+func (t *OpenTelemetryTracer) StartTrace(ctx context.Context, name string, carrier Carrier) (context.Context, Span) {
+	if carrier != nil {
+		if parent, err := ParseTraceParent(carrier.Get(traceParentHeader)); err == nil {
+			return t.start(ctx, name, SpanKindServer, parent)
+		}
+	}
+	return t.start(ctx, name, SpanKindServer, t.getParent(ctx))
+}
+
+//StartSpan starts a child of the span in ctx
+func (t *OpenTelemetryTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	return t.start(ctx, name, SpanKindInternal, t.getParent(ctx))
+}
+
+//StartDatastoreSpan starts a client span, child of the span in ctx, with the database semantic conventions
+func (t *OpenTelemetryTracer) StartDatastoreSpan(ctx context.Context, datastore *Datastore) (context.Context, Span) {
+	ctx, span := t.start(ctx, datastore.Name(), SpanKindClient, t.getParent(ctx))
+	span.SetAttribute("db.system", datastore.System)
+	span.SetAttribute("db.operation", datastore.Operation)
+	if datastore.Collection != "" {
+		span.SetAttribute("db.collection", datastore.Collection)
+	}
+	return ctx, span
+}
+
+//Close exports the pending spans and stops the processor
+func (t *OpenTelemetryTracer) Close() error {
+	return t.Processor.Shutdown()
+}
+
+//getParent returns the span context of the span of the tracer in ctx, if any
+func (t *OpenTelemetryTracer) getParent(ctx context.Context) SpanContext {
+	if span, ok := ctx.Value(spanKey{t}).(*openTelemetrySpan); ok {
+		return span.data.SpanContext
+	}
+	return SpanContext{}
+}
+
+//start starts a span, child of parent if it is valid. Root spans start a new trace, sampled with the
+//sample ratio, and the other spans keep the sampling decision of their parent.
+func (t *OpenTelemetryTracer) start(
+	ctx context.Context, name string, kind SpanKind, parent SpanContext,
+) (context.Context, *openTelemetrySpan) {
+	spanContext := SpanContext{
+		TraceID: parent.TraceID,
+		SpanID:  newSpanID(),
+		Sampled: parent.Sampled,
+	}
+	if !parent.IsValid() {
+		spanContext.TraceID = newTraceID()
+		spanContext.Sampled = t.shouldSample(spanContext.TraceID)
+	}
+
+	span := &openTelemetrySpan{
+		processor: t.Processor,
+		data: &SpanData{
+			Name:        name,
+			Kind:        kind,
+			SpanContext: spanContext,
+			Parent:      parent,
+			StartTime:   time.Now(),
+			Attributes:  map[string]interface{}{},
+		},
+	}
+	return context.WithValue(ctx, spanKey{t}, span), span
+}
+
+//shouldSample samples a trace by its ID, like the TraceIDRatioBased sampler of the OpenTelemetry SDKs,
+//so every service with the same ratio takes the same decision
+func (t *OpenTelemetryTracer) shouldSample(traceID TraceID) bool {
+	if t.SampleRatio >= 1 {
+		return true
+	}
+	if t.SampleRatio <= 0 {
+		return false
+	}
+	upperBound := uint64(t.SampleRatio * (1 << 63))
+	return binary.BigEndian.Uint64(traceID[8:16])>>1 < upperBound
+}
+
+//newTraceID returns a random trace ID
+func newTraceID() TraceID {
+	var traceID TraceID
+	for traceID == (TraceID{}) {
+		rand.Read(traceID[:])
+	}
+	return traceID
+}
+
+//newSpanID returns a random span ID
+func newSpanID() SpanID {
+	var spanID SpanID
+	for spanID == (SpanID{}) {
+		rand.Read(spanID[:])
+	}
+	return spanID
+}
+
+type openTelemetrySpan struct {
+	processor SpanProcessor
+	data      *SpanData
+	mutex     sync.Mutex
+	ended     bool
+}
+
+//record runs f with the data of the span, unless the span is not sampled or already ended
+func (s *openTelemetrySpan) record(f func(data *SpanData)) {
+	if !s.data.SpanContext.Sampled {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.ended {
+		f(s.data)
+	}
+}
+
+func (s *openTelemetrySpan) SetAttribute(key string, value interface{}) {
+	switch v := value.(type) {
+	case string, int64, bool, float64:
+	case int:
+		value = int64(v)
+	default:
+		value = fmt.Sprintf("%v", v)
+	}
+	s.record(func(data *SpanData) {
+		data.Attributes[key] = value
+	})
+}
+
+func (s *openTelemetrySpan) RecordError(err error) {
+	s.record(func(data *SpanData) {
+		data.Events = append(data.Events, &SpanEvent{
+			Name: "exception",
+			Time: time.Now(),
+			Attributes: map[string]interface{}{
+				"exception.type":    fmt.Sprintf("%T", err),
+				"exception.message": err.Error(),
+			},
+		})
+		data.Status = SpanStatus{Error: true, Description: err.Error()}
+	})
+}
+
+func (s *openTelemetrySpan) End() {
+	var ended *SpanData
+	s.record(func(data *SpanData) {
+		data.EndTime = time.Now()
+		s.ended = true
+		ended = data
+	})
+	if ended != nil {
+		s.processor.OnEnd(ended)
+	}
+}
@@ -0,0 +1,350 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	//otlpQueueSize is the number of ended spans waiting to be exported. Spans are dropped when it is full.
+	otlpQueueSize = 2048
+	//otlpBatchSize is the maximum number of spans in an export request
+	otlpBatchSize = 512
+	//otlpBatchInterval is the longest time an ended span waits to be exported
+	otlpBatchInterval = 5 * time.Second
+	//otlpTimeout is the timeout of the export requests
+	otlpTimeout = 10 * time.Second
+)
+
+//SpanProcessor receives the spans of a tracer as they end
+type SpanProcessor interface {
+	OnEnd(span *SpanData)
+	Shutdown() error
+}
+
+//SpanExporter sends spans to a tracing backend
+type SpanExporter interface {
+	ExportSpans(spans []*SpanData) error
+	Shutdown() error
+}
+
+//NewSimpleSpanProcessor returns a processor that exports each span as it ends
+func NewSimpleSpanProcessor(exporter SpanExporter) SpanProcessor {
+	return &simpleSpanProcessor{exporter: exporter}
+}
+
+type simpleSpanProcessor struct {
+	exporter SpanExporter
+}
+
+func (p *simpleSpanProcessor) OnEnd(span *SpanData) {
+	p.exporter.ExportSpans([]*SpanData{span})
+}
+
+func (p *simpleSpanProcessor) Shutdown() error {
+	return p.exporter.Shutdown()
+}
+
+//BatchSpanProcessor exports ended spans in batches in the background, so requests never wait for the
+//tracing backend. Spans that don't fit in its queue, or that fail to be exported, are dropped.
+type BatchSpanProcessor struct {
+	Exporter SpanExporter
+	queue    chan *SpanData
+	done     chan struct{}
+	mutex    sync.RWMutex
+	closed   bool
+}
+
+//NewBatchSpanProcessor returns a processor that exports spans in batches with exporter
+func NewBatchSpanProcessor(exporter SpanExporter) *BatchSpanProcessor {
+	p := &BatchSpanProcessor{
+		Exporter: exporter,
+		queue:    make(chan *SpanData, otlpQueueSize),
+		done:     make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+//OnEnd queues the span to be exported
+func (p *BatchSpanProcessor) OnEnd(span *SpanData) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	if p.closed {
+		return
+	}
+	select {
+	case p.queue <- span:
+	default:
+	}
+}
+
+//Shutdown exports the queued spans and shuts down the exporter
+func (p *BatchSpanProcessor) Shutdown() error {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.queue)
+	p.mutex.Unlock()
+
+	<-p.done
+	return p.Exporter.Shutdown()
+}
+
+func (p *BatchSpanProcessor) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(otlpBatchInterval)
+	defer ticker.Stop()
+
+	batch := []*SpanData{}
+	export := func() {
+		if len(batch) > 0 {
+			p.Exporter.ExportSpans(batch)
+			batch = []*SpanData{}
+		}
+	}
+	for {
+		select {
+		case span, ok := <-p.queue:
+			if !ok {
+				export()
+				return
+			}
+			batch = append(batch, span)
+			if len(batch) >= otlpBatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		}
+	}
+}
+
+//OTLPExporter sends spans to an OTLP/HTTP collector, such as the OpenTelemetry Collector or Jaeger,
+//in the JSON encoding of OTLP
+type OTLPExporter struct {
+	URL         string
+	ServiceName string
+	Client      *http.Client
+}
+
+//NewOTLPExporter returns an exporter to the collector at endpoint, such as localhost:4318.
+//If insecure, spans are sent over HTTP instead of HTTPS.
+func NewOTLPExporter(endpoint string, insecure bool, serviceName string) (*OTLPExporter, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("The OTLP endpoint is required.")
+	}
+	scheme := "https"
+	if insecure {
+		scheme = "http"
+	}
+	return &OTLPExporter{
+		URL:         fmt.Sprintf("%s://%s/v1/traces", scheme, endpoint),
+		ServiceName: serviceName,
+		Client:      &http.Client{Timeout: otlpTimeout},
+	}, nil
+}
+
+//ExportSpans sends the spans to the collector in a single request
+func (e *OTLPExporter) ExportSpans(spans []*SpanData) error {
+	body, err := json.Marshal(e.getRequest(spans))
+	if err != nil {
+		return err
+	}
+
+	response, err := e.Client.Post(e.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("The OTLP collector failed to export spans with status %d.", response.StatusCode)
+	}
+	return nil
+}
+
+//Shutdown does nothing, as the exporter keeps no state
+func (e *OTLPExporter) Shutdown() error {
+	return nil
+}
+
+//getRequest returns the OTLP export request of the spans, with IDs in hex and times in nanoseconds as strings,
+//as required by the JSON encoding of OTLP
+func (e *OTLPExporter) getRequest(spans []*SpanData) *otlpRequest {
+	otlpSpans := make([]*otlpSpan, len(spans))
+	for i, span := range spans {
+		otlpSpans[i] = &otlpSpan{
+			TraceID:           span.SpanContext.TraceID.String(),
+			SpanID:            span.SpanContext.SpanID.String(),
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+			Attributes:        getOTLPAttributes(span.Attributes),
+			Events:            []*otlpEvent{},
+		}
+		if span.Parent.IsValid() {
+			otlpSpans[i].ParentSpanID = span.Parent.SpanID.String()
+		}
+		for _, event := range span.Events {
+			otlpSpans[i].Events = append(otlpSpans[i].Events, &otlpEvent{
+				Name:         event.Name,
+				TimeUnixNano: strconv.FormatInt(event.Time.UnixNano(), 10),
+				Attributes:   getOTLPAttributes(event.Attributes),
+			})
+		}
+		if span.Status.Error {
+			otlpSpans[i].Status = otlpStatus{Code: otlpStatusCodeError, Message: span.Status.Description}
+		}
+	}
+
+	return &otlpRequest{
+		ResourceSpans: []*otlpResourceSpans{
+			&otlpResourceSpans{
+				Resource: otlpResource{
+					Attributes: getOTLPAttributes(map[string]interface{}{"service.name": e.ServiceName}),
+				},
+				ScopeSpans: []*otlpScopeSpans{
+					&otlpScopeSpans{
+						Scope: otlpScope{Name: instrumentationName},
+						Spans: otlpSpans,
+					},
+				},
+			},
+		},
+	}
+}
+
+//getOTLPAttributes returns the attributes sorted by key
+func getOTLPAttributes(attributes map[string]interface{}) []*otlpKeyValue {
+	keys := []string{}
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	otlpAttributes := make([]*otlpKeyValue, len(keys))
+	for i, key := range keys {
+		var value otlpAnyValue
+		switch v := attributes[key].(type) {
+		case string:
+			value.StringValue = &v
+		case int64:
+			//64 bit integers are strings in the JSON encoding of protobuf
+			s := strconv.FormatInt(v, 10)
+			value.IntValue = &s
+		case bool:
+			value.BoolValue = &v
+		case float64:
+			value.DoubleValue = &v
+		default:
+			s := fmt.Sprintf("%v", v)
+			value.StringValue = &s
+		}
+		otlpAttributes[i] = &otlpKeyValue{Key: key, Value: value}
+	}
+	return otlpAttributes
+}
+
+//otlpStatusCodeError is the status code of failed spans
+const otlpStatusCodeError = 2
+
+type otlpRequest struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource      `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []*otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope   `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []*otlpKeyValue `json:"attributes"`
+	Events            []*otlpEvent    `json:"events"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpEvent struct {
+	Name         string          `json:"name"`
+	TimeUnixNano string          `json:"timeUnixNano"`
+	Attributes   []*otlpKeyValue `json:"attributes"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+//InMemoryExporter keeps the exported spans in memory, so tests can check them
+type InMemoryExporter struct {
+	mutex sync.Mutex
+	spans []*SpanData
+}
+
+//NewInMemoryExporter returns an exporter without spans
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{spans: []*SpanData{}}
+}
+
+//ExportSpans keeps the spans
+func (e *InMemoryExporter) ExportSpans(spans []*SpanData) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+//Shutdown does nothing, so the spans can be checked after the tracer is closed
+func (e *InMemoryExporter) Shutdown() error {
+	return nil
+}
+
+//GetSpans returns the exported spans, in the order they ended
+func (e *InMemoryExporter) GetSpans() []*SpanData {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	spans := make([]*SpanData, len(e.spans))
+	copy(spans, e.spans)
+	return spans
+}
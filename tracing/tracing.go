@@ -0,0 +1,237 @@
+package tracing
+
+import (
+	"fmt"
+	"strings"
+
+	newrelic "github.com/newrelic/go-agent"
+	"github.com/spf13/viper"
+	"golang.org/x/net/context"
+)
+
+const (
+	//MongoDB is the datastore system of MongoDB operations
+	MongoDB = "mongodb"
+	//Redis is the datastore system of redis commands
+	Redis = "redis"
+)
+
+//Carrier holds the trace context propagated with a request, such as HTTP headers or gRPC metadata.
+//The headers of echo requests are carriers.
+type Carrier interface {
+	Get(key string) string
+	Set(key, value string)
+	Keys() []string
+}
+
+//Datastore describes an operation of a datastore, traced by the spans of StartDatastoreSpan
+type Datastore struct {
+	System     string
+	Collection string
+	Operation  string
+}
+
+//Name returns the name of the spans of the operation, such as mongodb donationRequests.insert
+func (d *Datastore) Name() string {
+	if d.Collection == "" {
+		return fmt.Sprintf("%s %s", d.System, d.Operation)
+	}
+	return fmt.Sprintf("%s %s.%s", d.System, d.Collection, d.Operation)
+}
+
+//Span is a timed operation of a trace, such as a request or a datastore call
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+//Tracer starts the spans of a tracing backend. Child spans are started from the span in ctx,
+//so each backend keeps its spans in the contexts it returns.
+type Tracer interface {
+	//StartTrace starts the root span of a request, continuing the trace propagated in carrier, if any
+	StartTrace(ctx context.Context, name string, carrier Carrier) (context.Context, Span)
+	//StartSpan starts a child of the span in ctx
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+	//StartDatastoreSpan starts a child of the span in ctx for a datastore operation
+	StartDatastoreSpan(ctx context.Context, datastore *Datastore) (context.Context, Span)
+	Close() error
+}
+
+//NewTracer returns the tracer exporting spans to the backends in the tracing.exporters key of config
+func NewTracer(config *viper.Viper, nr newrelic.Application) (Tracer, error) {
+	tracers := []Tracer{}
+	for _, exporter := range strings.Split(config.GetString("tracing.exporters"), ",") {
+		switch strings.TrimSpace(exporter) {
+		case "", "none":
+		case "newrelic":
+			tracers = append(tracers, NewNewRelicTracer(nr))
+		case "opentelemetry":
+			tracer, err := NewOpenTelemetryTracer(
+				config.GetString("tracing.opentelemetry.endpoint"),
+				config.GetBool("tracing.opentelemetry.insecure"),
+				config.GetString("tracing.opentelemetry.serviceName"),
+				config.GetFloat64("tracing.opentelemetry.sampleRatio"),
+			)
+			if err != nil {
+				return nil, err
+			}
+			tracers = append(tracers, tracer)
+		default:
+			return nil, fmt.Errorf(
+				"Invalid tracing.exporters value '%s'. Valid values are none, newrelic and opentelemetry.", exporter,
+			)
+		}
+	}
+
+	switch len(tracers) {
+	case 0:
+		return &NoopTracer{}, nil
+	case 1:
+		return tracers[0], nil
+	}
+	return NewMultiTracer(tracers...), nil
+}
+
+//tracerKey is the context key of the tracer of a request
+type tracerKey struct{}
+
+//NewContext returns a context that starts its spans with tracer
+func NewContext(ctx context.Context, tracer Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, tracer)
+}
+
+//FromContext returns the tracer of ctx, or a NoopTracer if ctx has none
+func FromContext(ctx context.Context) Tracer {
+	if ctx == nil {
+		return &NoopTracer{}
+	}
+	if tracer, ok := ctx.Value(tracerKey{}).(Tracer); ok {
+		return tracer
+	}
+	return &NoopTracer{}
+}
+
+//StartSpan starts a child of the span in ctx with the tracer of ctx
+func StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	return FromContext(ctx).StartSpan(ctx, name)
+}
+
+//WithDatastoreSpan runs f in a span of a datastore operation, child of the span in ctx.
+//ctx may be nil, so models can trace operations of callers without tracing.
+func WithDatastoreSpan(ctx context.Context, datastore *Datastore, f func() error) error {
+	_, span := FromContext(ctx).StartDatastoreSpan(ctx, datastore)
+	defer span.End()
+
+	err := f()
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
+
+//NoopTracer discards all spans
+type NoopTracer struct{}
+
+//StartTrace returns a span that does nothing
+func (t *NoopTracer) StartTrace(ctx context.Context, name string, carrier Carrier) (context.Context, Span) {
+	return ctx, &noopSpan{}
+}
+
+//StartSpan returns a span that does nothing
+func (t *NoopTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, &noopSpan{}
+}
+
+//StartDatastoreSpan returns a span that does nothing
+func (t *NoopTracer) StartDatastoreSpan(ctx context.Context, datastore *Datastore) (context.Context, Span) {
+	return ctx, &noopSpan{}
+}
+
+//Close does nothing
+func (t *NoopTracer) Close() error {
+	return nil
+}
+
+type noopSpan struct{}
+
+func (s *noopSpan) SetAttribute(key string, value interface{}) {}
+func (s *noopSpan) RecordError(err error)                      {}
+func (s *noopSpan) End()                                       {}
+
+//MultiTracer exports spans to many tracers. Each tracer keeps its spans in the context,
+//so the spans of every tracer have the right parents.
+type MultiTracer struct {
+	Tracers []Tracer
+}
+
+//NewMultiTracer returns a tracer that exports spans to all the given tracers
+func NewMultiTracer(tracers ...Tracer) *MultiTracer {
+	return &MultiTracer{Tracers: tracers}
+}
+
+//StartTrace starts the root span of a request in all tracers
+func (t *MultiTracer) StartTrace(ctx context.Context, name string, carrier Carrier) (context.Context, Span) {
+	spans := multiSpan{}
+	for _, tracer := range t.Tracers {
+		var span Span
+		ctx, span = tracer.StartTrace(ctx, name, carrier)
+		spans = append(spans, span)
+	}
+	return ctx, spans
+}
+
+//StartSpan starts a child of the span in ctx in all tracers
+func (t *MultiTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	spans := multiSpan{}
+	for _, tracer := range t.Tracers {
+		var span Span
+		ctx, span = tracer.StartSpan(ctx, name)
+		spans = append(spans, span)
+	}
+	return ctx, spans
+}
+
+//StartDatastoreSpan starts a child of the span in ctx for a datastore operation in all tracers
+func (t *MultiTracer) StartDatastoreSpan(ctx context.Context, datastore *Datastore) (context.Context, Span) {
+	spans := multiSpan{}
+	for _, tracer := range t.Tracers {
+		var span Span
+		ctx, span = tracer.StartDatastoreSpan(ctx, datastore)
+		spans = append(spans, span)
+	}
+	return ctx, spans
+}
+
+//Close closes all tracers, returning the first error
+func (t *MultiTracer) Close() error {
+	var firstErr error
+	for _, tracer := range t.Tracers {
+		err := tracer.Close()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+type multiSpan []Span
+
+func (s multiSpan) SetAttribute(key string, value interface{}) {
+	for _, span := range s {
+		span.SetAttribute(key, value)
+	}
+}
+
+func (s multiSpan) RecordError(err error) {
+	for _, span := range s {
+		span.RecordError(err)
+	}
+}
+
+//End ends the spans in reverse order, as they were started
+func (s multiSpan) End() {
+	for i := len(s) - 1; i >= 0; i-- {
+		s[i].End()
+	}
+}
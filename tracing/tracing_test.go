@@ -0,0 +1,258 @@
+package tracing_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	"github.com/topfreegames/donations/tracing"
+	"golang.org/x/net/context"
+)
+
+//headerCarrier propagates trace context in HTTP headers
+type headerCarrier http.Header
+
+func (h headerCarrier) Get(key string) string { return http.Header(h).Get(key) }
+func (h headerCarrier) Set(key, value string) { http.Header(h).Set(key, value) }
+func (h headerCarrier) Keys() []string {
+	keys := []string{}
+	for key := range h {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+var _ = Describe("Tracing", func() {
+	var exporter *tracing.InMemoryExporter
+	var tracer *tracing.OpenTelemetryTracer
+
+	BeforeEach(func() {
+		exporter = tracing.NewInMemoryExporter()
+		tracer = tracing.NewOpenTelemetryTracerWithProcessor(tracing.NewSimpleSpanProcessor(exporter), 1)
+	})
+
+	AfterEach(func() {
+		tracer.Close()
+	})
+
+	Describe("NewTracer", func() {
+		It("Should not trace without exporters", func() {
+			config := viper.New()
+			config.Set("tracing.exporters", "none")
+			tracer, err := tracing.NewTracer(config, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tracer).To(BeAssignableToTypeOf(&tracing.NoopTracer{}))
+		})
+
+		It("Should fail with invalid exporters", func() {
+			config := viper.New()
+			config.Set("tracing.exporters", "newrelic,zipkin")
+			_, err := tracing.NewTracer(config, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid tracing.exporters value 'zipkin'"))
+		})
+	})
+
+	Describe("Context", func() {
+		It("Should not trace without a tracer in the context", func() {
+			err := tracing.WithDatastoreSpan(nil, &tracing.Datastore{System: tracing.Redis, Operation: "get"}, func() error {
+				return nil
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx, span := tracing.StartSpan(context.Background(), "segment")
+			span.End()
+			Expect(ctx).To(Equal(context.Background()))
+		})
+	})
+
+	Describe("OpenTelemetry", func() {
+		It("Should continue W3C trace contexts of incoming requests", func() {
+			traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+			carrier := headerCarrier(http.Header{})
+			carrier.Set("traceparent", fmt.Sprintf("00-%s-00f067aa0ba902b7-01", traceID))
+
+			ctx := tracing.NewContext(context.Background(), tracer)
+			ctx, span := tracer.StartTrace(ctx, "POST /games/:gameID/donation-requests", carrier)
+			ctx, segment := tracing.StartSpan(ctx, "model")
+			err := tracing.WithDatastoreSpan(ctx, &tracing.Datastore{
+				System:     tracing.MongoDB,
+				Collection: "donations",
+				Operation:  "insert",
+			}, func() error {
+				return fmt.Errorf("failed")
+			})
+			Expect(err).To(MatchError("failed"))
+			segment.End()
+			span.End()
+
+			spans := exporter.GetSpans()
+			Expect(spans).To(HaveLen(3))
+			datastore, model, request := spans[0], spans[1], spans[2]
+
+			Expect(request.Name).To(Equal("POST /games/:gameID/donation-requests"))
+			Expect(request.Kind).To(Equal(tracing.SpanKindServer))
+			Expect(request.SpanContext.TraceID.String()).To(Equal(traceID))
+			Expect(request.Parent.SpanID.String()).To(Equal("00f067aa0ba902b7"))
+
+			Expect(model.Parent.SpanID).To(Equal(request.SpanContext.SpanID))
+			Expect(datastore.Parent.SpanID).To(Equal(model.SpanContext.SpanID))
+			Expect(datastore.Name).To(Equal("mongodb donations.insert"))
+			Expect(datastore.Kind).To(Equal(tracing.SpanKindClient))
+			Expect(datastore.Attributes).To(HaveKeyWithValue("db.collection", "donations"))
+			Expect(datastore.Status.Error).To(BeTrue())
+			Expect(datastore.Status.Description).To(Equal("failed"))
+		})
+
+		It("Should start new traces without a valid trace context", func() {
+			carrier := headerCarrier(http.Header{})
+			carrier.Set("traceparent", "00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+
+			_, span := tracer.StartTrace(context.Background(), "GET /healthcheck", carrier)
+			span.End()
+
+			spans := exporter.GetSpans()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].SpanContext.IsValid()).To(BeTrue())
+			Expect(spans[0].Parent.IsValid()).To(BeFalse())
+		})
+
+		It("Should sample new traces with the sample ratio", func() {
+			tracer := tracing.NewOpenTelemetryTracerWithProcessor(tracing.NewSimpleSpanProcessor(exporter), 0)
+
+			ctx, span := tracer.StartTrace(context.Background(), "GET /healthcheck", nil)
+			_, segment := tracer.StartSpan(ctx, "dependencies")
+			segment.End()
+			span.End()
+
+			Expect(exporter.GetSpans()).To(BeEmpty())
+		})
+
+		It("Should not sample traces the caller did not sample", func() {
+			carrier := headerCarrier(http.Header{})
+			carrier.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+			_, span := tracer.StartTrace(context.Background(), "GET /healthcheck", carrier)
+			span.End()
+
+			Expect(exporter.GetSpans()).To(BeEmpty())
+		})
+	})
+
+	Describe("ParseTraceParent", func() {
+		It("Should parse the trace, span and sampling decision", func() {
+			spanContext, err := tracing.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spanContext.TraceID.String()).To(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+			Expect(spanContext.SpanID.String()).To(Equal("00f067aa0ba902b7"))
+			Expect(spanContext.Sampled).To(BeTrue())
+
+			spanContext, err = tracing.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-02")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spanContext.Sampled).To(BeFalse())
+		})
+
+		It("Should parse the fields of version 00 in headers of future versions", func() {
+			spanContext, err := tracing.ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spanContext.SpanID.String()).To(Equal("00f067aa0ba902b7"))
+		})
+
+		It("Should fail with invalid headers", func() {
+			for _, header := range []string{
+				"",
+				"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+				"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+				"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+				"00-4bf92f3577b34da6a3ce929d0e0e473g-00f067aa0ba902b7-01",
+				"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+				"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			} {
+				_, err := tracing.ParseTraceParent(header)
+				Expect(err).To(HaveOccurred(), header)
+			}
+		})
+	})
+
+	Describe("OTLPExporter", func() {
+		It("Should export spans in the OTLP JSON encoding", func() {
+			var path, contentType string
+			var request map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				contentType = r.Header.Get("Content-Type")
+				body, err := ioutil.ReadAll(r.Body)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(json.Unmarshal(body, &request)).To(Succeed())
+			}))
+			defer server.Close()
+
+			otlp, err := tracing.NewOTLPExporter(strings.TrimPrefix(server.URL, "http://"), true, "donations")
+			Expect(err).NotTo(HaveOccurred())
+			tracer := tracing.NewOpenTelemetryTracerWithProcessor(tracing.NewBatchSpanProcessor(otlp), 1)
+
+			carrier := headerCarrier(http.Header{})
+			carrier.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			_, span := tracer.StartTrace(context.Background(), "GET /healthcheck", carrier)
+			span.SetAttribute("http.status_code", 500)
+			span.RecordError(fmt.Errorf("failed"))
+			span.End()
+			Expect(tracer.Close()).To(Succeed())
+
+			Expect(path).To(Equal("/v1/traces"))
+			Expect(contentType).To(Equal("application/json"))
+			resourceSpans := request["resourceSpans"].([]interface{})[0].(map[string]interface{})
+			resource := resourceSpans["resource"].(map[string]interface{})
+			Expect(resource["attributes"]).To(ConsistOf(map[string]interface{}{
+				"key":   "service.name",
+				"value": map[string]interface{}{"stringValue": "donations"},
+			}))
+
+			scopeSpans := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})
+			spans := scopeSpans["spans"].([]interface{})
+			Expect(spans).To(HaveLen(1))
+			exported := spans[0].(map[string]interface{})
+			Expect(exported["traceId"]).To(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+			Expect(exported["parentSpanId"]).To(Equal("00f067aa0ba902b7"))
+			Expect(exported["name"]).To(Equal("GET /healthcheck"))
+			Expect(exported["kind"]).To(BeEquivalentTo(tracing.SpanKindServer))
+			Expect(exported["attributes"]).To(ConsistOf(map[string]interface{}{
+				"key":   "http.status_code",
+				"value": map[string]interface{}{"intValue": "500"},
+			}))
+			Expect(exported["status"]).To(Equal(map[string]interface{}{"code": float64(2), "message": "failed"}))
+			Expect(exported["events"]).To(HaveLen(1))
+		})
+
+		It("Should fail without an endpoint", func() {
+			_, err := tracing.NewOTLPExporter("", true, "donations")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("MultiTracer", func() {
+		It("Should export spans to all tracers", func() {
+			other := tracing.NewInMemoryExporter()
+			otherTracer := tracing.NewOpenTelemetryTracerWithProcessor(tracing.NewSimpleSpanProcessor(other), 1)
+			multi := tracing.NewMultiTracer(tracer, otherTracer)
+
+			ctx, span := multi.StartTrace(context.Background(), "GET /healthcheck", nil)
+			_, segment := multi.StartSpan(ctx, "dependencies")
+			segment.End()
+			span.End()
+
+			Expect(exporter.GetSpans()).To(HaveLen(2))
+			Expect(other.GetSpans()).To(HaveLen(2))
+			Expect(other.GetSpans()[0].Parent.SpanID).To(Equal(other.GetSpans()[1].SpanContext.SpanID))
+			err := otherTracer.Close()
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,39 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	//EventHeader contains the name of the event being delivered
+	EventHeader = "X-Donations-Event"
+	//DeliveryHeader contains the id of the delivery, which is kept between retries
+	DeliveryHeader = "X-Donations-Delivery"
+	//TimestampHeader contains the unix timestamp used to sign the request
+	TimestampHeader = "X-Donations-Timestamp"
+	//SignatureHeader contains the HMAC-SHA256 signature of the request, as sha256=<hex>
+	SignatureHeader = "X-Donations-Signature"
+
+	signaturePrefix = "sha256="
+)
+
+//Sign returns the HMAC-SHA256 signature of "<timestamp>.<body>" using the webhook secret
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%d.", timestamp)))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+//Verify checks that signature (the value of the X-Donations-Signature header) matches the timestamp and body
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	expected := Sign(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
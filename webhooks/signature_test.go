@@ -0,0 +1,31 @@
+package webhooks_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/webhooks"
+)
+
+var _ = Describe("Webhook Signature", func() {
+	body := []byte(`{"event":"donation.created"}`)
+
+	It("Should sign the timestamp and body with HMAC-SHA256", func() {
+		signature := webhooks.Sign("secret", 100, body)
+		Expect(signature).To(HavePrefix("sha256="))
+		Expect(signature).To(HaveLen(71))
+		Expect(webhooks.Sign("secret", 100, body)).To(Equal(signature))
+	})
+
+	It("Should verify valid signatures", func() {
+		signature := webhooks.Sign("secret", 100, body)
+		Expect(webhooks.Verify("secret", 100, body, signature)).To(BeTrue())
+	})
+
+	It("Should not verify signatures with another secret, timestamp or body", func() {
+		signature := webhooks.Sign("secret", 100, body)
+		Expect(webhooks.Verify("other", 100, body, signature)).To(BeFalse())
+		Expect(webhooks.Verify("secret", 101, body, signature)).To(BeFalse())
+		Expect(webhooks.Verify("secret", 100, []byte("{}"), signature)).To(BeFalse())
+		Expect(webhooks.Verify("secret", 100, body, signature[7:])).To(BeFalse())
+	})
+})
@@ -0,0 +1,179 @@
+package webhooks
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/topfreegames/donations/errors"
+	"github.com/topfreegames/donations/log"
+	"github.com/topfreegames/donations/models"
+	"github.com/uber-go/zap"
+	mgo "gopkg.in/mgo.v2"
+)
+
+//Worker sends pending webhook deliveries, retrying failed ones with exponential backoff
+type Worker struct {
+	MongoDb     *mgo.Database
+	Logger      zap.Logger
+	Client      *http.Client
+	Clock       models.Clock
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	Interval    time.Duration
+	BatchSize   int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+//NewWorker returns a webhook worker configured with the webhooks.* keys of config
+func NewWorker(config *viper.Viper, db *mgo.Database, logger zap.Logger) *Worker {
+	timeout := time.Duration(config.GetInt("webhooks.timeoutMilliseconds")) * time.Millisecond
+	return &Worker{
+		MongoDb:     db,
+		Logger:      logger,
+		Client:      &http.Client{Timeout: timeout},
+		Clock:       &models.RealClock{},
+		MaxAttempts: config.GetInt("webhooks.maxAttempts"),
+		BackoffBase: time.Duration(config.GetInt("webhooks.backoffBaseSeconds")) * time.Second,
+		BackoffMax:  time.Duration(config.GetInt("webhooks.backoffMaxSeconds")) * time.Second,
+		Interval:    time.Duration(config.GetInt("webhooks.worker.intervalMilliseconds")) * time.Millisecond,
+		BatchSize:   config.GetInt("webhooks.worker.batchSize"),
+	}
+}
+
+//Start sends pending deliveries every Interval until Stop is called
+func (w *Worker) Start() {
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				w.ProcessDeliveries()
+			}
+		}
+	}()
+}
+
+//Stop waits for the current batch to finish and stops the worker
+func (w *Worker) Stop() {
+	if w.stop == nil {
+		return
+	}
+	close(w.stop)
+	<-w.done
+	w.stop = nil
+}
+
+//ProcessDeliveries sends up to BatchSize due deliveries and returns how many were sent successfully
+func (w *Worker) ProcessDeliveries() (int, error) {
+	l := w.Logger.With(
+		zap.String("source", "WebhookWorker"),
+		zap.String("operation", "ProcessDeliveries"),
+	)
+
+	//Deliveries stay locked while being sent, so other workers won't send them at the same time
+	lockSeconds := int(w.Client.Timeout/time.Second) + 30
+
+	delivered := 0
+	for i := 0; i < w.BatchSize; i++ {
+		delivery, err := models.ClaimWebhookDelivery(lockSeconds, w.Clock, w.MongoDb)
+		if err != nil {
+			log.E(l, "Failed to claim webhook delivery.", func(cm log.CM) {
+				cm.Write(zap.Error(err))
+			})
+			return delivered, err
+		}
+		if delivery == nil {
+			break
+		}
+
+		err = w.process(delivery)
+		if err != nil {
+			log.E(l, "Failed to update webhook delivery.", func(cm log.CM) {
+				cm.Write(zap.String("deliveryID", delivery.ID), zap.Error(err))
+			})
+			return delivered, err
+		}
+		if delivery.Status == models.WebhookDeliveryDelivered {
+			delivered++
+		}
+	}
+
+	return delivered, nil
+}
+
+func (w *Worker) process(delivery *models.WebhookDelivery) error {
+	l := w.Logger.With(
+		zap.String("source", "WebhookWorker"),
+		zap.String("operation", "process"),
+		zap.String("deliveryID", delivery.ID),
+		zap.String("webhookID", delivery.WebhookID),
+		zap.String("event", delivery.Event),
+	)
+
+	webhook, err := models.GetWebhookByID(delivery.GameID, delivery.WebhookID, w.MongoDb, w.Logger)
+	if _, ok := err.(*errors.DocumentNotFoundError); ok {
+		//The webhook was removed, so the delivery goes straight to the dead-letter store
+		log.W(l, "Webhook of delivery was removed.")
+		return models.MarkWebhookDeliveryFailed(delivery, err, 1, w.BackoffBase, w.BackoffMax, w.Clock, w.MongoDb)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = w.Deliver(webhook, delivery)
+	if err != nil {
+		log.W(l, "Failed to deliver webhook.", func(cm log.CM) {
+			cm.Write(zap.Int("attempts", delivery.Attempts+1), zap.Error(err))
+		})
+		return models.MarkWebhookDeliveryFailed(
+			delivery, err, w.MaxAttempts, w.BackoffBase, w.BackoffMax, w.Clock, w.MongoDb,
+		)
+	}
+
+	log.D(l, "Webhook delivered successfully.")
+	return models.MarkWebhookDelivered(delivery, w.Clock, w.MongoDb)
+}
+
+//Deliver sends a signed delivery to the webhook url. Any non-2xx response is an error.
+func (w *Worker) Deliver(webhook *models.Webhook, delivery *models.WebhookDelivery) error {
+	body := []byte(delivery.Payload)
+	timestamp := w.Clock.GetUTCTime().Unix()
+
+	req, err := http.NewRequest("POST", webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, body))
+
+	res, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("Webhook responded with status %d.", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package webhooks_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/topfreegames/donations/models"
+	. "github.com/topfreegames/donations/testing"
+	"github.com/topfreegames/donations/webhooks"
+	"github.com/uber-go/zap"
+)
+
+var _ = Describe("Webhook Worker", func() {
+	var logger zap.Logger
+	var session *mgo.Session
+	var db *mgo.Database
+	var game *models.Game
+	var webhook *models.Webhook
+	var worker *webhooks.Worker
+	var server *httptest.Server
+	var status int
+	var requests []*http.Request
+	var bodies [][]byte
+	var lock sync.Mutex
+
+	getDelivery := func() *models.WebhookDelivery {
+		var delivery models.WebhookDelivery
+		err := models.GetWebhookDeliveriesCollection(db).Find(bson.M{"webhookID": webhook.ID}).One(&delivery)
+		Expect(err).NotTo(HaveOccurred())
+		return &delivery
+	}
+
+	BeforeEach(func() {
+		logger = zap.New(
+			zap.NewJSONEncoder(zap.NoTime()), // drop timestamps in tests
+			zap.FatalLevel,
+		)
+
+		session, db = GetTestMongoDB()
+
+		//The worker claims deliveries of any game
+		_, err := models.GetWebhookDeliveriesCollection(db).RemoveAll(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		status = http.StatusOK
+		requests = []*http.Request{}
+		bodies = [][]byte{}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			lock.Lock()
+			requests = append(requests, r)
+			bodies = append(bodies, body)
+			lock.Unlock()
+			w.WriteHeader(status)
+		}))
+
+		game, err = GetTestGame(db, logger, true)
+		Expect(err).NotTo(HaveOccurred())
+
+		webhook, err = models.CreateWebhook(
+			game.ID, server.URL, []string{models.AllEvents}, "secret",
+			&MockClock{Time: 100}, db, logger,
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = models.DispatchWebhookEvent(game.ID, models.DonationCreatedEvent, []byte(`{"amount":1}`), &MockClock{Time: 100}, db, logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		worker = &webhooks.Worker{
+			MongoDb:     db,
+			Logger:      logger,
+			Client:      &http.Client{Timeout: time.Second},
+			Clock:       &MockClock{Time: 100},
+			MaxAttempts: 2,
+			BackoffBase: 10 * time.Second,
+			BackoffMax:  time.Minute,
+			Interval:    10 * time.Millisecond,
+			BatchSize:   10,
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+		session.Close()
+		session = nil
+		db = nil
+	})
+
+	It("Should send signed deliveries", func() {
+		delivered, err := worker.ProcessDeliveries()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(delivered).To(Equal(1))
+
+		Expect(requests).To(HaveLen(1))
+		req := requests[0]
+		Expect(req.Header.Get(webhooks.EventHeader)).To(Equal(models.DonationCreatedEvent))
+		Expect(req.Header.Get(webhooks.DeliveryHeader)).To(Equal(getDelivery().ID))
+		timestamp, err := strconv.ParseInt(req.Header.Get(webhooks.TimestampHeader), 10, 64)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(timestamp).To(BeEquivalentTo(100))
+		Expect(webhooks.Verify("secret", timestamp, bodies[0], req.Header.Get(webhooks.SignatureHeader))).To(BeTrue())
+		Expect(string(bodies[0])).To(ContainSubstring(`"data":{"amount":1}`))
+
+		delivery := getDelivery()
+		Expect(delivery.Status).To(Equal(models.WebhookDeliveryDelivered))
+		Expect(delivery.Attempts).To(Equal(1))
+		Expect(delivery.DeliveredAt).To(BeEquivalentTo(100))
+	})
+
+	It("Should retry failed deliveries after the backoff", func() {
+		status = http.StatusInternalServerError
+
+		delivered, err := worker.ProcessDeliveries()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(delivered).To(Equal(0))
+
+		delivery := getDelivery()
+		Expect(delivery.Status).To(Equal(models.WebhookDeliveryPending))
+		Expect(delivery.Attempts).To(Equal(1))
+		Expect(delivery.NextAttemptAt).To(BeEquivalentTo(110))
+		Expect(delivery.LastError).To(ContainSubstring("500"))
+
+		//Not due yet
+		_, err = worker.ProcessDeliveries()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(HaveLen(1))
+
+		status = http.StatusOK
+		worker.Clock = &MockClock{Time: 110}
+		delivered, err = worker.ProcessDeliveries()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(delivered).To(Equal(1))
+		Expect(requests).To(HaveLen(2))
+		Expect(requests[1].Header.Get(webhooks.DeliveryHeader)).To(Equal(delivery.ID))
+	})
+
+	It("Should move deliveries to the dead-letter store after max attempts", func() {
+		status = http.StatusInternalServerError
+
+		_, err := worker.ProcessDeliveries()
+		Expect(err).NotTo(HaveOccurred())
+		worker.Clock = &MockClock{Time: 200}
+		_, err = worker.ProcessDeliveries()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(getDelivery().Status).To(Equal(models.WebhookDeliveryDead))
+
+		worker.Clock = &MockClock{Time: 10000}
+		_, err = worker.ProcessDeliveries()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(HaveLen(2))
+	})
+
+	It("Should move deliveries of removed webhooks to the dead-letter store", func() {
+		err := models.RemoveWebhook(game.ID, webhook.ID, db, logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = worker.ProcessDeliveries()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(BeEmpty())
+		Expect(getDelivery().Status).To(Equal(models.WebhookDeliveryDead))
+	})
+
+	It("Should send deliveries in background until stopped", func() {
+		worker.Start()
+		Eventually(func() int {
+			lock.Lock()
+			defer lock.Unlock()
+			return len(requests)
+		}).Should(Equal(1))
+		worker.Stop()
+		Expect(getDelivery().Status).To(Equal(models.WebhookDeliveryDelivered))
+	})
+})